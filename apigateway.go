@@ -41,6 +41,11 @@ const (
 	// that stores the APIGateway provisioned URL
 	// @enum OutputKey
 	OutputAPIGatewayURL = "APIGatewayURL"
+
+	// OutputAPIGatewayCustomDomainURL is the keyname used in the
+	// CloudFormation Output that stores the APIV2 custom domain URL
+	// @enum OutputKey
+	OutputAPIGatewayCustomDomainURL = "APIGatewayCustomDomainURL"
 )
 
 func corsMethodResponseParams(api *API) map[string]bool {
@@ -321,6 +326,10 @@ type APIGatewayContext struct {
 	Stage string `json:"stage"`
 	// User identity
 	Identity APIGatewayIdentity `json:"identity"`
+	// Authorizer context. Includes the Lambda authorizer principalId and
+	// context values, or the Cognito user pool token claims as a nested
+	// claims object.
+	Authorizer map[string]interface{} `json:"authorizer,omitempty"`
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -392,10 +401,20 @@ type Integration struct {
 
 	Responses map[int]*IntegrationResponse
 
-	// Typically "AWS", but for OPTIONS CORS support is set to "MOCK"
+	// Typically "AWS", but for OPTIONS CORS support is set to "MOCK" and
+	// for NewProxyResource methods is set to "AWS_PROXY"
 	integrationType string
 }
 
+const (
+	// integrationTypeAWS is the VTL mapped Lambda integration type
+	integrationTypeAWS = "AWS"
+	// integrationTypeAWSProxy is the Lambda proxy integration type
+	integrationTypeAWSProxy = "AWS_PROXY"
+	// proxyHTTPMethod is the HTTP method that matches all requests
+	proxyHTTPMethod = "ANY"
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 //
 
@@ -403,10 +422,14 @@ type Integration struct {
 // http://docs.aws.amazon.com/sdk-for-go/api/service/apigateway.html#type-Method
 type Method struct {
 	authorizationID         string
+	authorizer              *APIAuthorizer
 	httpMethod              string
 	defaultHTTPResponseCode int
 
 	APIKeyRequired bool
+	// AuthorizationScopes are the OAuth scopes required by a Cognito
+	// authorizer method
+	AuthorizationScopes []string
 
 	// Request data
 	Parameters map[string]bool
@@ -446,6 +469,11 @@ type Stage struct {
 	CacheClusterSize    string
 	Description         string
 	Variables           map[string]string
+	// MethodSettings are the per-method throttling, caching and logging
+	// settings. Use NewMethodSetting to create entries.
+	MethodSettings []*gofapig.Deployment_MethodSetting
+	// AccessLogging, if non-nil, enables stage access logging
+	AccessLogging *StageAccessLogging
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -478,6 +506,12 @@ type API struct {
 	Description string
 	// Non-empty map of urlPaths->Resource definitions
 	resources map[string]*Resource
+	// Map of authorizer names->APIAuthorizer definitions
+	authorizers map[string]*APIAuthorizer
+	// Map of usage plan names->UsagePlan definitions
+	usagePlans map[string]*UsagePlan
+	// Map of API key names->APIKey definitions
+	apiKeys map[string]*APIKey
 	// Should CORS be enabled for this API?
 	CORSEnabled bool
 	// CORS options - if non-nil, supersedes CORSEnabled
@@ -492,6 +526,20 @@ func (api *API) LogicalResourceName() string {
 	return CloudFormationResourceName("APIGateway", api.name)
 }
 
+// Name returns the API name
+func (api *API) Name() string {
+	return api.name
+}
+
+// StageName returns the name of the stage the API is deployed to, or an
+// empty string if the API isn't deployed
+func (api *API) StageName() string {
+	if api.stage == nil {
+		return ""
+	}
+	return api.stage.name
+}
+
 // RestAPIURL returns the dynamically assigned
 // Rest API URL including the scheme
 func (api *API) RestAPIURL() string {
@@ -588,6 +636,11 @@ func (api *API) Marshal(serviceName string,
 	template.Resources[apiGatewayResName] = apiGatewayRes
 	apiGatewayRestAPIID := gof.Ref(apiGatewayResName)
 
+	// Authorizers
+	for _, eachAuthorizer := range api.authorizers {
+		eachAuthorizer.marshal(apiGatewayRestAPIID, template)
+	}
+
 	// List of all the method resources we're creating s.t. the
 	// deployment can DependOn them
 	optionsMethodPathMap := make(map[string]bool)
@@ -595,10 +648,15 @@ func (api *API) Marshal(serviceName string,
 	for eachResourceMethodKey, eachResourceDef := range api.resources {
 		// First walk all the user resources and create intermediate paths
 		// to repreesent all the resources
-		var parentResource string
-		pathParts := strings.Split(strings.TrimLeft(eachResourceDef.pathPart, "/"), "/")
+		// The root path uses the RestApi root resource
+		parentResource := gof.GetAtt(apiGatewayResName, "RootResourceId")
+		pathParts := []string{}
+		trimmedPath := strings.Trim(eachResourceDef.pathPart, "/")
+		if trimmedPath != "" {
+			pathParts = strings.Split(trimmedPath, "/")
+		}
 		pathAccumulator := []string{"/"}
-		for index, eachPathPart := range pathParts {
+		for _, eachPathPart := range pathParts {
 			pathAccumulator = append(pathAccumulator, eachPathPart)
 			resourcePathName := apiGatewayResourceNameForPath(strings.Join(pathAccumulator, "/"))
 			if _, exists := template.Resources[resourcePathName]; !exists {
@@ -606,11 +664,7 @@ func (api *API) Marshal(serviceName string,
 					RestApiId: apiGatewayRestAPIID,
 					PathPart:  eachPathPart,
 				}
-				if index <= 0 {
-					cfResource.ParentId = gof.GetAtt(apiGatewayResName, "RootResourceId")
-				} else {
-					cfResource.ParentId = parentResource
-				}
+				cfResource.ParentId = parentResource
 				template.Resources[resourcePathName] = cfResource
 			}
 			parentResource = gof.Ref(resourcePathName)
@@ -647,18 +701,13 @@ func (api *API) Marshal(serviceName string,
 		// BEGIN - user defined verbs
 		for eachMethodName, eachMethodDef := range eachResourceDef.Methods {
 
-			methodRequestTemplates, methodRequestTemplatesErr := methodRequestTemplates(eachMethodDef)
-			if methodRequestTemplatesErr != nil {
-				return methodRequestTemplatesErr
-			}
 			apiGatewayMethod := &gofapig.Method{
 				HttpMethod: eachMethodName,
 				ResourceId: parentResource,
 				RestApiId:  apiGatewayRestAPIID,
 				Integration: &gofapig.Method_Integration{
 					IntegrationHttpMethod: "POST",
-					Type:                  "AWS",
-					RequestTemplates:      methodRequestTemplates,
+					Type:                  eachMethodDef.Integration.integrationType,
 					Uri: gof.Join("", []string{
 						"arn:aws:apigateway:",
 						gof.Ref("AWS::Region"),
@@ -668,8 +717,22 @@ func (api *API) Marshal(serviceName string,
 					}),
 				},
 			}
+			// Proxy integrations pass the request through unmodified
+			if eachMethodDef.Integration.integrationType != integrationTypeAWSProxy {
+				methodRequestTemplates, methodRequestTemplatesErr := methodRequestTemplates(eachMethodDef)
+				if methodRequestTemplatesErr != nil {
+					return methodRequestTemplatesErr
+				}
+				apiGatewayMethod.Integration.RequestTemplates = methodRequestTemplates
+			}
 			// Handle authorization
-			if eachMethodDef.authorizationID != "" {
+			if eachMethodDef.authorizer != nil {
+				apiGatewayMethod.AuthorizationType = eachMethodDef.authorizer.methodAuthorizationType()
+				if eachMethodDef.authorizer.authorizerType != AuthorizerTypeIAM {
+					apiGatewayMethod.AuthorizerId = gof.Ref(eachMethodDef.authorizer.LogicalResourceName())
+				}
+				apiGatewayMethod.AuthorizationScopes = eachMethodDef.AuthorizationScopes
+			} else if eachMethodDef.authorizationID != "" {
 				// See https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-apigateway-method.html#cfn-apigateway-method-authorizationtype
 				apiGatewayMethod.AuthorizationType = "CUSTOM"
 				apiGatewayMethod.AuthorizerId = eachMethodDef.authorizationID
@@ -702,6 +765,9 @@ func (api *API) Marshal(serviceName string,
 		}
 	}
 	// END
+	if nil == api.stage && (len(api.usagePlans) != 0 || len(api.apiKeys) != 0) {
+		return fmt.Errorf("API %s usage plans and API keys require a Stage", api.name)
+	}
 	if nil != api.stage {
 		// Is the stack already deployed?
 		stageName := api.stage.name
@@ -714,49 +780,16 @@ func (api *API) Marshal(serviceName string,
 		if nil != stageInfoErr {
 			return stageInfoErr
 		}
-		if nil == stageInfo {
-			// Use a stable identifier so that we can update the existing deployment
-			apiDeploymentResName := CloudFormationResourceName("APIGatewayDeployment",
-				serviceName)
-			apiDeployment := &gofapig.Deployment{
-				Description: api.stage.Description,
-				RestApiId:   apiGatewayRestAPIID,
-				StageName:   stageName,
-				StageDescription: &gofapig.Deployment_StageDescription{
-					Description: api.stage.Description,
-					Variables:   api.stage.Variables,
-				},
-			}
-			if api.stage.CacheClusterEnabled {
-				apiDeployment.StageDescription.CacheClusterEnabled =
-					api.stage.CacheClusterEnabled
-			}
-			if api.stage.CacheClusterSize != "" {
-				apiDeployment.StageDescription.CacheClusterSize =
-					api.stage.CacheClusterSize
-			}
-			apiDeployment.AWSCloudFormationDependsOn = apiMethodCloudFormationResources
-			apiDeployment.AWSCloudFormationDependsOn = append(apiDeployment.AWSCloudFormationDependsOn,
-				apiGatewayResName)
-
-			template.Resources[apiDeploymentResName] = apiDeployment
-
-		} else {
-			newDeployment := &gofapig.Deployment{
-				Description: "Deployment",
-				RestApiId:   apiGatewayRestAPIID,
-			}
-			if stageInfo.StageName != nil {
-				newDeployment.StageName = *stageInfo.StageName
-			}
-			// Use an unstable ID s.t. we can actually create a new deployment event.  Not sure how this
-			// is going to work with deletes...
-			deploymentResName := CloudFormationResourceName("APIGatewayDeployment")
-
-			newDeployment.AWSCloudFormationDependsOn = apiMethodCloudFormationResources
-			newDeployment.AWSCloudFormationDependsOn = append(newDeployment.AWSCloudFormationDependsOn,
-				apiGatewayResName)
-			template.Resources[deploymentResName] = newDeployment
+		dependencyResName := api.marshalStage(serviceName,
+			apiGatewayResName,
+			apiMethodCloudFormationResources,
+			stageInfo,
+			template)
+		usagePlansErr := api.marshalUsagePlans(apiGatewayRestAPIID,
+			dependencyResName,
+			template)
+		if usagePlansErr != nil {
+			return usagePlansErr
 		}
 		// Outputs...
 		template.Outputs[OutputAPIGatewayURL] = gof.Output{
@@ -781,6 +814,9 @@ func NewAPIGateway(name string, stage *Stage) *API {
 		name:        name,
 		stage:       stage,
 		resources:   make(map[string]*Resource),
+		authorizers: make(map[string]*APIAuthorizer),
+		usagePlans:  make(map[string]*UsagePlan),
+		apiKeys:     make(map[string]*APIKey),
 		CORSEnabled: false,
 		CORSOptions: nil,
 	}
@@ -816,6 +852,44 @@ func (api *API) NewResource(pathPart string, parentLambda *LambdaAWSInfo) (*Reso
 	return resource, nil
 }
 
+// NewProxyResource routes every request for basePath and its descendants
+// (basePath/{proxy+}) to the LambdaAWSInfo using a Lambda proxy
+// integration. The function receives the unmodified request, typically via
+// an httpadapter.NewHandler http.Handler adapter, and is responsible for
+// the complete response. The returned Resource is the greedy
+// basePath/{proxy+} resource.
+func (api *API) NewProxyResource(basePath string, lambdaFn *LambdaAWSInfo) (*Resource, error) {
+	basePath = "/" + strings.Trim(basePath, "/")
+	greedyPath := strings.TrimSuffix(basePath, "/") + "/{proxy+}"
+
+	var greedyResource *Resource
+	for _, eachPath := range []string{basePath, greedyPath} {
+		resource, resourceErr := api.NewResource(eachPath, lambdaFn)
+		if resourceErr != nil {
+			return nil, resourceErr
+		}
+		method := &Method{
+			httpMethod:              proxyHTTPMethod,
+			defaultHTTPResponseCode: http.StatusOK,
+			Parameters:              make(map[string]bool),
+			Models:                  make(map[string]*Model),
+			Responses:               make(map[int]*Response),
+			Integration: Integration{
+				Parameters:       make(map[string]string),
+				RequestTemplates: make(map[string]string),
+				Responses:        make(map[int]*IntegrationResponse),
+				integrationType:  integrationTypeAWSProxy,
+			},
+		}
+		if eachPath == greedyPath {
+			method.Parameters["method.request.path.proxy"] = true
+			greedyResource = resource
+		}
+		resource.Methods[proxyHTTPMethod] = method
+	}
+	return greedyResource, nil
+}
+
 // NewMethod associates the httpMethod name with the given Resource.  The returned Method
 // has no authorization requirements. To limit the amount of API gateway resource mappings,
 // supply the variadic slice of  possibleHTTPStatusCodeResponses which is the universe
@@ -849,7 +923,7 @@ func (resource *Resource) NewMethod(httpMethod string,
 		Parameters:       make(map[string]string),
 		RequestTemplates: make(map[string]string),
 		Responses:        make(map[int]*IntegrationResponse),
-		integrationType:  "AWS", // Type used for Lambda integration
+		integrationType:  integrationTypeAWS,
 	}
 
 	method := &Method{
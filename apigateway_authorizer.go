@@ -0,0 +1,235 @@
+package sparta
+
+import (
+	"fmt"
+	"strings"
+
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	gofapig "github.com/awslabs/goformation/v5/cloudformation/apigateway"
+	goflambda "github.com/awslabs/goformation/v5/cloudformation/lambda"
+	"github.com/pkg/errors"
+)
+
+// APIAuthorizerType is the type of an APIAuthorizer. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-apigateway-authorizer.html#cfn-apigateway-authorizer-type
+type APIAuthorizerType string
+
+const (
+	// AuthorizerTypeToken is a Lambda authorizer that receives the
+	// caller identity in a single header
+	AuthorizerTypeToken APIAuthorizerType = "TOKEN"
+	// AuthorizerTypeRequest is a Lambda authorizer that receives the
+	// caller identity in headers, query string parameters, stage
+	// variables and context variables
+	AuthorizerTypeRequest APIAuthorizerType = "REQUEST"
+	// AuthorizerTypeCognitoUserPools is an Amazon Cognito user pool
+	// authorizer
+	AuthorizerTypeCognitoUserPools APIAuthorizerType = "COGNITO_USER_POOLS"
+	// AuthorizerTypeIAM uses IAM permissions (SigV4 signed requests)
+	AuthorizerTypeIAM APIAuthorizerType = "AWS_IAM"
+)
+
+const (
+	// defaultAuthorizerIdentitySource is the default TOKEN and
+	// COGNITO_USER_POOLS identity source
+	defaultAuthorizerIdentitySource = "method.request.header.Authorization"
+)
+
+// APIAuthorizer is a REST API authorizer that Methods reference via
+// Resource.NewAuthorizerMethod. Create instances with API.NewTokenAuthorizer,
+// API.NewRequestAuthorizer, API.NewCognitoAuthorizer or API.NewIAMAuthorizer.
+// Lambda authorizer functions must also be included in the slice of
+// LambdaAWSInfo provisioned by the service.
+type APIAuthorizer struct {
+	apiName        string
+	name           string
+	authorizerType APIAuthorizerType
+	lambdaFn       *LambdaAWSInfo
+	providerARNs   []string
+	// IdentitySources are the request values that identify the caller
+	// (eg: method.request.header.Authorization)
+	IdentitySources []string
+	// IdentityValidationExpression is an optional regular expression
+	// that TOKEN authorizer identity values must match
+	IdentityValidationExpression string
+	// ResultTTLSeconds is the number of seconds API Gateway caches the
+	// authorizer result. Zero uses the API Gateway default (300).
+	ResultTTLSeconds int
+}
+
+// Name returns the authorizer name
+func (authorizer *APIAuthorizer) Name() string {
+	return authorizer.name
+}
+
+// Type returns the authorizer type
+func (authorizer *APIAuthorizer) Type() APIAuthorizerType {
+	return authorizer.authorizerType
+}
+
+// LogicalResourceName returns the CloudFormation logical resource name
+// of the AWS::ApiGateway::Authorizer resource. IAM authorizers don't have
+// a resource and return an empty string.
+func (authorizer *APIAuthorizer) LogicalResourceName() string {
+	if authorizer.authorizerType == AuthorizerTypeIAM {
+		return ""
+	}
+	return CloudFormationResourceName("APIGatewayAuthorizer", authorizer.apiName, authorizer.name)
+}
+
+// methodAuthorizationType returns the AWS::ApiGateway::Method
+// AuthorizationType value
+func (authorizer *APIAuthorizer) methodAuthorizationType() string {
+	switch authorizer.authorizerType {
+	case AuthorizerTypeToken, AuthorizerTypeRequest:
+		return "CUSTOM"
+	}
+	return string(authorizer.authorizerType)
+}
+
+// marshal adds the authorizer and Lambda invoke permission resources
+// to the template
+func (authorizer *APIAuthorizer) marshal(restAPIID string, template *gof.Template) {
+	if authorizer.authorizerType == AuthorizerTypeIAM {
+		return
+	}
+	authorizerResName := authorizer.LogicalResourceName()
+	authorizerRes := &gofapig.Authorizer{
+		Name:                         authorizer.name,
+		RestApiId:                    restAPIID,
+		Type:                         string(authorizer.authorizerType),
+		IdentitySource:               strings.Join(authorizer.IdentitySources, ","),
+		IdentityValidationExpression: authorizer.IdentityValidationExpression,
+		AuthorizerResultTtlInSeconds: authorizer.ResultTTLSeconds,
+		ProviderARNs:                 authorizer.providerARNs,
+	}
+	if authorizer.lambdaFn != nil {
+		authorizerRes.AuthorizerUri = gof.Join("", []string{
+			"arn:aws:apigateway:",
+			gof.Ref("AWS::Region"),
+			":lambda:path/2015-03-31/functions/",
+			gof.GetAtt(authorizer.lambdaFn.LogicalResourceName(), "Arn"),
+			"/invocations",
+		})
+		template.Resources[CloudFormationResourceName("APIGatewayAuthorizerPerm",
+			authorizer.apiName,
+			authorizer.name)] = &goflambda.Permission{
+			Action:       "lambda:InvokeFunction",
+			FunctionName: gof.GetAtt(authorizer.lambdaFn.LogicalResourceName(), "Arn"),
+			Principal:    APIGatewayPrincipal,
+			SourceArn: gof.Join("", []string{
+				"arn:aws:execute-api:",
+				gof.Ref("AWS::Region"),
+				":",
+				gof.Ref("AWS::AccountId"),
+				":",
+				restAPIID,
+				"/authorizers/",
+				gof.Ref(authorizerResName),
+			}),
+		}
+	}
+	template.Resources[authorizerResName] = authorizerRes
+}
+
+// addAuthorizer registers the authorizer with the API
+func (api *API) addAuthorizer(authorizer *APIAuthorizer) (*APIAuthorizer, error) {
+	if authorizer.name == "" {
+		return nil, errors.Errorf("Authorizer name must not be empty")
+	}
+	if _, exists := api.authorizers[authorizer.name]; exists {
+		return nil, errors.Errorf("Authorizer %s already defined for API %s",
+			authorizer.name,
+			api.name)
+	}
+	authorizer.apiName = api.name
+	api.authorizers[authorizer.name] = authorizer
+	return authorizer, nil
+}
+
+// NewTokenAuthorizer returns a TOKEN Lambda authorizer that receives the
+// caller identity from the identitySource header mapping expression. An
+// empty identitySource defaults to method.request.header.Authorization.
+// The authorizer response context is available to handlers in the
+// APIGatewayContext Authorizer map.
+func (api *API) NewTokenAuthorizer(name string,
+	lambdaFn *LambdaAWSInfo,
+	identitySource string) (*APIAuthorizer, error) {
+	if lambdaFn == nil {
+		return nil, errors.Errorf("TOKEN authorizer %s requires a Lambda function", name)
+	}
+	if identitySource == "" {
+		identitySource = defaultAuthorizerIdentitySource
+	}
+	return api.addAuthorizer(&APIAuthorizer{
+		name:            name,
+		authorizerType:  AuthorizerTypeToken,
+		lambdaFn:        lambdaFn,
+		IdentitySources: []string{identitySource},
+	})
+}
+
+// NewRequestAuthorizer returns a REQUEST Lambda authorizer. The
+// identitySources are the request mapping expressions (eg:
+// method.request.querystring.token, stageVariables.name) used as the
+// cache key. At least one identity source is required if the result
+// is cached.
+func (api *API) NewRequestAuthorizer(name string,
+	lambdaFn *LambdaAWSInfo,
+	identitySources ...string) (*APIAuthorizer, error) {
+	if lambdaFn == nil {
+		return nil, errors.Errorf("REQUEST authorizer %s requires a Lambda function", name)
+	}
+	return api.addAuthorizer(&APIAuthorizer{
+		name:            name,
+		authorizerType:  AuthorizerTypeRequest,
+		lambdaFn:        lambdaFn,
+		IdentitySources: identitySources,
+	})
+}
+
+// NewCognitoAuthorizer returns a COGNITO_USER_POOLS authorizer for the
+// user pool ARNs. The ARNs may be CloudFormation intrinsics (eg:
+// gof.GetAtt("UserPool", "Arn")). Use Method.AuthorizationScopes to
+// require OAuth scopes for an access token. The token claims are
+// available to handlers in the nested claims object of the
+// APIGatewayContext Authorizer map.
+func (api *API) NewCognitoAuthorizer(name string,
+	userPoolARNs ...string) (*APIAuthorizer, error) {
+	if len(userPoolARNs) == 0 {
+		return nil, errors.Errorf("Cognito authorizer %s requires at least one user pool ARN", name)
+	}
+	return api.addAuthorizer(&APIAuthorizer{
+		name:            name,
+		authorizerType:  AuthorizerTypeCognitoUserPools,
+		providerARNs:    userPoolARNs,
+		IdentitySources: []string{defaultAuthorizerIdentitySource},
+	})
+}
+
+// NewIAMAuthorizer returns an AWS_IAM authorizer. Callers must sign
+// requests with SigV4 credentials that allow execute-api:Invoke.
+func (api *API) NewIAMAuthorizer() *APIAuthorizer {
+	return &APIAuthorizer{
+		name:           string(AuthorizerTypeIAM),
+		authorizerType: AuthorizerTypeIAM,
+	}
+}
+
+// NewAuthorizerMethod associates the httpMethod name and authorizer with
+// the given Resource. See NewMethod for the HTTP status code parameters.
+func (resource *Resource) NewAuthorizerMethod(httpMethod string,
+	authorizer *APIAuthorizer,
+	defaultHTTPStatusCode int,
+	possibleHTTPStatusCodeResponses ...int) (*Method, error) {
+	if authorizer == nil {
+		return nil, fmt.Errorf("authorizer must not be `nil` for Authorizer Method")
+	}
+	method, methodErr := resource.NewMethod(httpMethod,
+		defaultHTTPStatusCode,
+		possibleHTTPStatusCodeResponses...)
+	if methodErr == nil {
+		method.authorizer = authorizer
+	}
+	return method, methodErr
+}
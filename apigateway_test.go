@@ -2,12 +2,23 @@ package sparta
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
+	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsv2APIGTypes "github.com/aws/aws-sdk-go-v2/service/apigateway/types"
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	gofapig "github.com/awslabs/goformation/v5/cloudformation/apigateway"
+	gofapigv2 "github.com/awslabs/goformation/v5/cloudformation/apigatewayv2"
+	goflambda "github.com/awslabs/goformation/v5/cloudformation/lambda"
+	goflogs "github.com/awslabs/goformation/v5/cloudformation/logs"
 	spartaAPIGateway "github.com/mweagle/Sparta/v3/aws/apigateway"
+	spartaHTTPAdapter "github.com/mweagle/Sparta/v3/aws/apigateway/httpadapter"
 	spartaAWSEvents "github.com/mweagle/Sparta/v3/aws/events"
 	"github.com/rs/zerolog"
 )
@@ -106,3 +117,532 @@ func TestAPIV2Gateway(t *testing.T) {
 		false,
 		nil)
 }
+
+func TestAPIGatewayAuthorizers(t *testing.T) {
+	apiGateway := NewAPIGateway("SpartaAPIGatewayAuthorizers", nil)
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+	authorizerFn, _ := NewAWSLambda(LambdaName(mockLambda2),
+		mockLambda2,
+		IAMRoleDefinition{})
+
+	tokenAuthorizer, tokenErr := apiGateway.NewTokenAuthorizer("Token", authorizerFn, "")
+	if tokenErr != nil {
+		t.Fatal(tokenErr)
+	}
+	tokenAuthorizer.ResultTTLSeconds = 60
+	_, duplicateErr := apiGateway.NewRequestAuthorizer("Token", authorizerFn)
+	if duplicateErr == nil {
+		t.Fatal("Expected duplicate authorizer name error")
+	}
+	cognitoAuthorizer, cognitoErr := apiGateway.NewCognitoAuthorizer("Cognito",
+		"arn:aws:cognito-idp:us-west-2:123412341234:userpool/us-west-2_abc")
+	if cognitoErr != nil {
+		t.Fatal(cognitoErr)
+	}
+	iamAuthorizer := apiGateway.NewIAMAuthorizer()
+
+	resource, _ := apiGateway.NewResource("/test", lambdaFn)
+	for httpMethod, eachAuthorizer := range map[string]*APIAuthorizer{
+		http.MethodGet:    tokenAuthorizer,
+		http.MethodPost:   cognitoAuthorizer,
+		http.MethodDelete: iamAuthorizer,
+	} {
+		method, methodErr := resource.NewAuthorizerMethod(httpMethod,
+			eachAuthorizer,
+			http.StatusOK)
+		if methodErr != nil {
+			t.Fatal(methodErr)
+		}
+		if eachAuthorizer == cognitoAuthorizer {
+			method.AuthorizationScopes = []string{"email"}
+		}
+	}
+
+	logger, _ := NewLogger(zerolog.InfoLevel.String())
+	template := gof.NewTemplate()
+	marshalErr := apiGateway.Marshal("AuthorizerTest",
+		awsv2.Config{},
+		nil,
+		nil,
+		template,
+		true,
+		logger)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+
+	// Authorizer resources
+	tokenRes, tokenResOk := template.Resources[tokenAuthorizer.LogicalResourceName()].(*gofapig.Authorizer)
+	if !tokenResOk ||
+		tokenRes.Type != "TOKEN" ||
+		tokenRes.IdentitySource != defaultAuthorizerIdentitySource ||
+		tokenRes.AuthorizerResultTtlInSeconds != 60 ||
+		tokenRes.AuthorizerUri == "" {
+		t.Fatalf("Unexpected TOKEN authorizer: %#v", tokenRes)
+	}
+	if _, permOk := template.Resources[CloudFormationResourceName("APIGatewayAuthorizerPerm",
+		apiGateway.name,
+		"Token")].(*goflambda.Permission); !permOk {
+		t.Fatalf("Expected TOKEN authorizer invoke permission")
+	}
+	cognitoRes, cognitoResOk := template.Resources[cognitoAuthorizer.LogicalResourceName()].(*gofapig.Authorizer)
+	if !cognitoResOk ||
+		cognitoRes.Type != "COGNITO_USER_POOLS" ||
+		len(cognitoRes.ProviderARNs) != 1 {
+		t.Fatalf("Unexpected Cognito authorizer: %#v", cognitoRes)
+	}
+
+	// Method authorization
+	authorizationTypes := make(map[string]*gofapig.Method)
+	for _, eachResource := range template.Resources {
+		if method, methodOk := eachResource.(*gofapig.Method); methodOk {
+			authorizationTypes[method.HttpMethod] = method
+		}
+	}
+	getMethod := authorizationTypes[http.MethodGet]
+	if getMethod == nil ||
+		getMethod.AuthorizationType != "CUSTOM" ||
+		getMethod.AuthorizerId != gof.Ref(tokenAuthorizer.LogicalResourceName()) {
+		t.Fatalf("Unexpected GET method: %#v", getMethod)
+	}
+	postMethod := authorizationTypes[http.MethodPost]
+	if postMethod == nil ||
+		postMethod.AuthorizationType != "COGNITO_USER_POOLS" ||
+		len(postMethod.AuthorizationScopes) != 1 {
+		t.Fatalf("Unexpected POST method: %#v", postMethod)
+	}
+	deleteMethod := authorizationTypes[http.MethodDelete]
+	if deleteMethod == nil ||
+		deleteMethod.AuthorizationType != "AWS_IAM" ||
+		deleteMethod.AuthorizerId != "" {
+		t.Fatalf("Unexpected DELETE method: %#v", deleteMethod)
+	}
+}
+
+func TestAPIGatewayCognitoAuthorizerClaims(t *testing.T) {
+	// Shape of the inputmapping_*.vtl output for a Cognito authorizer
+	payload := `{
+		"method": "GET",
+		"body": {},
+		"headers": {},
+		"queryParams": {},
+		"pathParams": {},
+		"context": {
+			"apiId": "abc123",
+			"method": "GET",
+			"requestId": "req",
+			"resourceId": "res",
+			"resourcePath": "/hello",
+			"stage": "v1",
+			"identity": {},
+			"authorizer": {
+				"principalId": "user",
+				"claims": {
+					"sub": "1234",
+					"email": "user@example.com",
+					"cognito:groups": "admin"
+				}
+			}
+		}
+	}`
+	var jsonEvent APIGatewayLambdaJSONEvent
+	decodeErr := json.Unmarshal([]byte(payload), &jsonEvent)
+	if decodeErr != nil {
+		t.Fatalf("Failed to decode APIGatewayLambdaJSONEvent: %s", decodeErr)
+	}
+	var request spartaAWSEvents.APIGatewayRequest
+	decodeErr = json.Unmarshal([]byte(payload), &request)
+	if decodeErr != nil {
+		t.Fatalf("Failed to decode APIGatewayRequest: %s", decodeErr)
+	}
+	for _, eachAuthorizer := range []map[string]interface{}{
+		jsonEvent.Context.Authorizer,
+		request.Context.Authorizer,
+	} {
+		claims, claimsOk := eachAuthorizer["claims"].(map[string]interface{})
+		if !claimsOk {
+			t.Fatalf("Failed to decode claims object: %#v", eachAuthorizer)
+		}
+		if claims["email"] != "user@example.com" ||
+			eachAuthorizer["principalId"] != "user" {
+			t.Fatalf("Unexpected authorizer context: %#v", eachAuthorizer)
+		}
+	}
+	// The templates emit the context authorizer object with nested claims
+	templates, templatesErr := methodRequestTemplates(&Method{})
+	if templatesErr != nil {
+		t.Fatal(templatesErr)
+	}
+	for eachContentType, template := range templates {
+		if !strings.Contains(template, `"authorizer" : {`) ||
+			!strings.Contains(template, "$context.authorizer.claims.keySet()") {
+			t.Fatalf("Unexpected %s authorizer mapping: %s", eachContentType, template)
+		}
+	}
+}
+
+func TestAPIGatewayUsagePlans(t *testing.T) {
+	stage := NewStage("v1")
+	stage.NewMethodSetting("/test/{id}", http.MethodGet).ThrottlingRateLimit = 10
+	stage.NewMethodSetting("*", "*").LoggingLevel = MethodLoggingLevelError
+	stage.AccessLogging = &StageAccessLogging{
+		RetentionInDays: 7,
+	}
+	apiGateway := NewAPIGateway("SpartaAPIGatewayUsagePlans", stage)
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+	resource, _ := apiGateway.NewResource("/test/{id}", lambdaFn)
+	method, _ := resource.NewMethod(http.MethodGet, http.StatusOK)
+	method.APIKeyRequired = true
+
+	apiKey, apiKeyErr := apiGateway.NewAPIKey("Partner", "")
+	if apiKeyErr != nil {
+		t.Fatal(apiKeyErr)
+	}
+	plan, planErr := apiGateway.NewUsagePlan("Basic")
+	if planErr != nil {
+		t.Fatal(planErr)
+	}
+	plan.WithQuota(1000, QuotaPeriodDay).
+		WithThrottle(5, 10).
+		WithMethodThrottle("/test/{id}", "get", 1, 2).
+		WithAPIKeys(apiKey)
+
+	logger, _ := NewLogger(zerolog.InfoLevel.String())
+	template := gof.NewTemplate()
+	marshalErr := apiGateway.Marshal("UsagePlanTest",
+		awsv2.Config{},
+		nil,
+		nil,
+		template,
+		true,
+		logger)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+
+	// Stage settings
+	var stageRes *gofapig.Stage
+	for _, eachResource := range template.Resources {
+		if typedStage, ok := eachResource.(*gofapig.Stage); ok {
+			stageRes = typedStage
+		}
+	}
+	if stageRes == nil || stageRes.StageName != "v1" {
+		t.Fatalf("Expected stage resource")
+	}
+	methodSettings := stageRes.MethodSettings
+	if len(methodSettings) != 2 ||
+		methodSettings[0].ResourcePath != "/~1test~1{id}" ||
+		methodSettings[0].HttpMethod != http.MethodGet ||
+		methodSettings[1].ResourcePath != "/*" {
+		t.Fatalf("Unexpected method settings: %#v", methodSettings)
+	}
+	accessLogSetting := stageRes.AccessLogSetting
+	if accessLogSetting == nil || accessLogSetting.Format != DefaultStageAccessLogFormat {
+		t.Fatalf("Unexpected access log setting: %#v", accessLogSetting)
+	}
+
+	// Usage plan, key and association
+	usagePlan, usagePlanOk := template.Resources[plan.LogicalResourceName()].(*gofapig.UsagePlan)
+	if !usagePlanOk ||
+		usagePlan.Quota.Limit != 1000 ||
+		usagePlan.Throttle.BurstLimit != 10 ||
+		len(usagePlan.ApiStages) != 1 ||
+		usagePlan.ApiStages[0].Stage != "v1" ||
+		usagePlan.ApiStages[0].Throttle["/test/{id}/GET"].BurstLimit != 2 {
+		t.Fatalf("Unexpected usage plan: %#v", usagePlan)
+	}
+	if _, keyOk := template.Resources[apiKey.LogicalResourceName()].(*gofapig.ApiKey); !keyOk {
+		t.Fatalf("Expected API key resource")
+	}
+	planKeyCount := 0
+	for _, eachResource := range template.Resources {
+		if planKey, ok := eachResource.(*gofapig.UsagePlanKey); ok &&
+			planKey.KeyId == gof.Ref(apiKey.LogicalResourceName()) {
+			planKeyCount++
+		}
+	}
+	if planKeyCount != 1 {
+		t.Fatalf("Expected one usage plan key, found: %d", planKeyCount)
+	}
+
+	// Usage plans require a stage
+	unstagedAPI := NewAPIGateway("SpartaAPIGatewayUnstaged", nil)
+	unstagedAPI.NewUsagePlan("Basic")
+	unstagedErr := unstagedAPI.Marshal("UsagePlanTest",
+		awsv2.Config{},
+		nil,
+		nil,
+		gof.NewTemplate(),
+		true,
+		logger)
+	if unstagedErr == nil {
+		t.Fatal("Expected usage plan without stage error")
+	}
+}
+
+func testHTTPAPILambda(ctx context.Context,
+	request awsLambdaEvents.APIGatewayV2HTTPRequest) (*awsLambdaEvents.APIGatewayV2HTTPResponse, error) {
+	return spartaAPIGateway.NewHTTPResponse(http.StatusOK, map[string]string{
+		"path": request.RawPath,
+	})
+}
+
+func TestAPIGatewayDeployedStage(t *testing.T) {
+	stage := NewStage("v1")
+	stage.NewMethodSetting("*", "*").MetricsEnabled = true
+	stage.AccessLogging = &StageAccessLogging{}
+	apiGateway := NewAPIGateway("SpartaAPIGatewayDeployedStage", stage)
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+	resource, _ := apiGateway.NewResource("/test", lambdaFn)
+	resource.NewMethod(http.MethodGet, http.StatusOK)
+	logger, _ := NewLogger(zerolog.InfoLevel.String())
+	template := gof.NewTemplate()
+	marshalErr := apiGateway.Marshal("DeployedStageTest",
+		awsv2.Config{},
+		nil,
+		nil,
+		template,
+		true,
+		logger)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+	var stageResName string
+	var stageTags map[string]string
+	for eachName, eachResource := range template.Resources {
+		if typedStage, ok := eachResource.(*gofapig.Stage); ok {
+			stageResName = eachName
+			stageTags = make(map[string]string)
+			for _, eachTag := range typedStage.Tags {
+				stageTags[eachTag.Key] = eachTag.Value
+			}
+		}
+	}
+
+	// Redeploy the stage created by the Stage resource and a stage created
+	// by the deployment StageName of an earlier release
+	testCases := map[string]*awsv2APIGTypes.Stage{
+		"stage resource": {
+			StageName: awsv2.String("v1"),
+			Tags:      stageTags,
+		},
+		"deployment": {
+			StageName: awsv2.String("v1"),
+		},
+	}
+	for eachName, eachStageInfo := range testCases {
+		redeployTemplate := gof.NewTemplate()
+		dependencyResName := apiGateway.marshalStage("DeployedStageTest",
+			"RestAPI",
+			[]string{},
+			eachStageInfo,
+			redeployTemplate)
+		logGroups := 0
+		var deployment *gofapig.Deployment
+		for _, eachResource := range redeployTemplate.Resources {
+			switch typedResource := eachResource.(type) {
+			case *goflogs.LogGroup:
+				logGroups++
+			case *gofapig.Deployment:
+				deployment = typedResource
+			}
+		}
+		if logGroups != 1 || deployment == nil {
+			t.Fatalf("Expected access log group and deployment for %s: %#v",
+				eachName,
+				redeployTemplate.Resources)
+		}
+		stageRes, stageResOk := redeployTemplate.Resources[stageResName].(*gofapig.Stage)
+		if eachStageInfo.Tags != nil {
+			if !stageResOk ||
+				dependencyResName != stageResName ||
+				len(stageRes.MethodSettings) != 1 ||
+				stageRes.AccessLogSetting == nil ||
+				deployment.StageName != "" {
+				t.Fatalf("Expected stage resource update for %s", eachName)
+			}
+		} else {
+			if stageResOk ||
+				deployment.StageName != "v1" ||
+				deployment.StageDescription == nil ||
+				len(deployment.StageDescription.MethodSettings) != 1 ||
+				deployment.StageDescription.AccessLogSetting == nil {
+				t.Fatalf("Expected deployment stage description for %s", eachName)
+			}
+		}
+	}
+}
+
+func TestHTTPAPIGateway(t *testing.T) {
+	httpAPI, httpAPIErr := NewHTTPAPI("SpartaHTTPAPI")
+	if httpAPIErr != nil {
+		t.Fatal(httpAPIErr)
+	}
+	httpAPI.CORSConfiguration = &gofapigv2.Api_Cors{
+		AllowOrigins: []string{"*"},
+	}
+	httpAPI.CustomDomain = &APIV2CustomDomain{
+		DomainName:     "api.example.com",
+		CertificateARN: "arn:aws:acm:us-east-1:123412341234:certificate/abc",
+		HostedZoneID:   "Z123",
+	}
+	lambdaFn, _ := NewAWSLambda(LambdaName(testHTTPAPILambda),
+		testHTTPAPILambda,
+		IAMRoleDefinition{})
+	authorizerFn, _ := NewAWSLambda(LambdaName(mockLambda2),
+		mockLambda2,
+		IAMRoleDefinition{})
+
+	jwtAuthorizer, jwtErr := httpAPI.NewJWTAuthorizer("Cognito",
+		"https://cognito-idp.us-east-1.amazonaws.com/us-east-1_abc",
+		"client")
+	if jwtErr != nil {
+		t.Fatal(jwtErr)
+	}
+	lambdaAuthorizer, lambdaAuthorizerErr := httpAPI.NewLambdaAuthorizer("Custom",
+		authorizerFn,
+		"$request.header.Authorization")
+	if lambdaAuthorizerErr != nil {
+		t.Fatal(lambdaAuthorizerErr)
+	}
+	proxyRoute, proxyRouteErr := httpAPI.NewAPIV2Route("ANY /{proxy+}", lambdaFn)
+	if proxyRouteErr != nil {
+		t.Fatal(proxyRouteErr)
+	}
+	proxyRoute.WithAuthorizer(jwtAuthorizer, "email")
+	defaultRoute, _ := httpAPI.NewAPIV2Route(APIV2DefaultRoute, lambdaFn)
+	defaultRoute.WithAuthorizer(lambdaAuthorizer)
+	_, invalidRouteErr := httpAPI.NewAPIV2Route("$connect", lambdaFn)
+	if invalidRouteErr == nil {
+		t.Fatal("Expected invalid HTTP API route key error")
+	}
+
+	logger, _ := NewLogger(zerolog.InfoLevel.String())
+	template := gof.NewTemplate()
+	marshalErr := httpAPI.Marshal("HTTPAPITest",
+		awsv2.Config{},
+		nil,
+		nil,
+		template,
+		true,
+		logger)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+	var routes []*gofapigv2.Route
+	for _, eachResource := range template.Resources {
+		switch typedResource := eachResource.(type) {
+		case *gofapigv2.Api:
+			if typedResource.ProtocolType != "HTTP" || typedResource.CorsConfiguration == nil {
+				t.Fatalf("Unexpected HTTP API: %#v", typedResource)
+			}
+		case *gofapigv2.Integration:
+			if typedResource.PayloadFormatVersion != "2.0" {
+				t.Fatalf("Unexpected integration: %#v", typedResource)
+			}
+		case *gofapigv2.Stage:
+			if !typedResource.AutoDeploy ||
+				typedResource.StageName != APIV2DefaultStage ||
+				typedResource.DeploymentId != "" {
+				t.Fatalf("Unexpected stage: %#v", typedResource)
+			}
+		case *gofapigv2.Deployment:
+			t.Fatalf("Unexpected deployment for auto-deployed stage")
+		case *gofapigv2.Route:
+			routes = append(routes, typedResource)
+		}
+	}
+	if len(routes) != 2 {
+		t.Fatalf("Expected 2 routes, found: %d", len(routes))
+	}
+	for _, eachRoute := range routes {
+		expectedType := "JWT"
+		if eachRoute.RouteKey == string(APIV2DefaultRoute) {
+			expectedType = "CUSTOM"
+		}
+		if eachRoute.AuthorizationType != expectedType || eachRoute.AuthorizerId == "" {
+			t.Fatalf("Unexpected route authorization: %#v", eachRoute)
+		}
+	}
+	if _, outputExists := template.Outputs[OutputAPIGatewayCustomDomainURL]; !outputExists {
+		t.Fatalf("Expected custom domain output")
+	}
+
+	// Payload format 2.0 handler
+	mockRequest, mockRequestErr := spartaAWSEvents.NewAPIGatewayV2HTTPMockRequest("ANY /{proxy+}",
+		http.MethodGet,
+		"/hello?name=world",
+		nil)
+	if mockRequestErr != nil {
+		t.Fatal(mockRequestErr)
+	}
+	response, responseErr := testHTTPAPILambda(context.Background(), *mockRequest)
+	if responseErr != nil {
+		t.Fatal(responseErr)
+	}
+	if response.StatusCode != http.StatusOK ||
+		response.Body != `{"path":"/hello"}` ||
+		response.Headers["content-type"] != "application/json" {
+		t.Fatalf("Unexpected response: %#v", response)
+	}
+}
+
+func TestAPIGatewayProxyResource(t *testing.T) {
+	apiGateway := NewAPIGateway("SpartaAPIGatewayProxy", NewStage("v1"))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Hello %s", r.URL.Path)
+	})
+	lambdaFn, _ := NewAWSLambda("ProxyHandler",
+		spartaHTTPAdapter.NewHandler(handler),
+		IAMRoleDefinition{})
+	proxyResource, proxyResourceErr := apiGateway.NewProxyResource("/", lambdaFn)
+	if proxyResourceErr != nil {
+		t.Fatal(proxyResourceErr)
+	}
+	if proxyResource.pathPart != "/{proxy+}" {
+		t.Fatalf("Unexpected proxy resource path: %s", proxyResource.pathPart)
+	}
+	_, duplicateErr := apiGateway.NewProxyResource("/", lambdaFn)
+	if duplicateErr == nil {
+		t.Fatalf("Expected error for duplicate proxy resource")
+	}
+
+	logger, _ := NewLogger(zerolog.InfoLevel.String())
+	template := gof.NewTemplate()
+	marshalErr := apiGateway.Marshal("ProxyTest",
+		awsv2.Config{},
+		nil,
+		nil,
+		template,
+		true,
+		logger)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+	proxyMethods := 0
+	for _, eachResource := range template.Resources {
+		switch typedResource := eachResource.(type) {
+		case *gofapig.Resource:
+			if typedResource.PathPart != "{proxy+}" {
+				t.Fatalf("Unexpected API Gateway resource path: %s", typedResource.PathPart)
+			}
+		case *gofapig.Method:
+			if typedResource.HttpMethod != "ANY" ||
+				typedResource.Integration.Type != "AWS_PROXY" ||
+				len(typedResource.Integration.RequestTemplates) != 0 {
+				t.Fatalf("Unexpected proxy method: %#v", typedResource)
+			}
+			proxyMethods++
+		}
+	}
+	if proxyMethods != 2 {
+		t.Fatalf("Expected root and greedy proxy methods, got: %d", proxyMethods)
+	}
+}
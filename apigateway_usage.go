@@ -0,0 +1,372 @@
+package sparta
+
+import (
+	"fmt"
+	"strings"
+
+	awsv2APIGTypes "github.com/aws/aws-sdk-go-v2/service/apigateway/types"
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	gofapig "github.com/awslabs/goformation/v5/cloudformation/apigateway"
+	goflogs "github.com/awslabs/goformation/v5/cloudformation/logs"
+	goftags "github.com/awslabs/goformation/v5/cloudformation/tags"
+	"github.com/pkg/errors"
+)
+
+// Usage plan quota periods
+const (
+	// QuotaPeriodDay is a daily usage plan quota
+	QuotaPeriodDay = "DAY"
+	// QuotaPeriodWeek is a weekly usage plan quota
+	QuotaPeriodWeek = "WEEK"
+	// QuotaPeriodMonth is a monthly usage plan quota
+	QuotaPeriodMonth = "MONTH"
+)
+
+// Stage method setting logging levels
+const (
+	// MethodLoggingLevelOff disables execution logging
+	MethodLoggingLevelOff = "OFF"
+	// MethodLoggingLevelError logs errors
+	MethodLoggingLevelError = "ERROR"
+	// MethodLoggingLevelInfo logs errors and informational events
+	MethodLoggingLevelInfo = "INFO"
+)
+
+// DefaultStageAccessLogFormat is the JSON access log format used if
+// StageAccessLogging.Format is empty. See
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/set-up-logging.html
+const DefaultStageAccessLogFormat = `{"requestId":"$context.requestId",` +
+	`"ip":"$context.identity.sourceIp",` +
+	`"caller":"$context.identity.caller",` +
+	`"user":"$context.identity.user",` +
+	`"requestTime":"$context.requestTime",` +
+	`"httpMethod":"$context.httpMethod",` +
+	`"resourcePath":"$context.resourcePath",` +
+	`"status":"$context.status",` +
+	`"protocol":"$context.protocol",` +
+	`"responseLength":"$context.responseLength"}`
+
+// StageAccessLogging configures stage access logging to a CloudWatch
+// Logs log group provisioned with the stack. API Gateway requires an
+// account level CloudWatch Logs role to write access and execution logs.
+// See https://docs.aws.amazon.com/apigateway/latest/developerguide/set-up-logging.html
+type StageAccessLogging struct {
+	// Format is the access log format. Defaults to
+	// DefaultStageAccessLogFormat
+	Format string
+	// RetentionInDays is the log group retention. Zero retains the logs
+	// indefinitely.
+	RetentionInDays int
+}
+
+// NewMethodSetting returns a new stage method setting for the resource
+// path and HTTP method. Use "*" for either value to apply the setting
+// to all resources or methods. The resource path is escaped as required
+// by API Gateway (eg: /users/{id} => /~1users~1{id}).
+func (stage *Stage) NewMethodSetting(resourcePath string,
+	httpMethod string) *gofapig.Deployment_MethodSetting {
+
+	escapedPath := "*"
+	if strings.TrimPrefix(resourcePath, "/") != "*" {
+		escapedPath = strings.ReplaceAll("/"+strings.TrimPrefix(resourcePath, "/"), "/", "~1")
+	}
+	methodSetting := &gofapig.Deployment_MethodSetting{
+		ResourcePath: "/" + escapedPath,
+		HttpMethod:   strings.ToUpper(httpMethod),
+	}
+	stage.MethodSettings = append(stage.MethodSettings, methodSetting)
+	return methodSetting
+}
+
+// stageResourceTagKey is the stage tag that identifies stages managed
+// by an AWS::ApiGateway::Stage resource
+var stageResourceTagKey = spartaTagName("stageResource")
+
+// marshalStage adds the deployment and stage resources and returns the
+// name of the resource that the usage plans depend on. Every provision
+// creates a new deployment. New stages are AWS::ApiGateway::Stage
+// resources that reference the deployment, so that the stage settings
+// are applied on every update. Stages created by the deployment StageName
+// of an earlier release are updated by the deployment's StageDescription.
+func (api *API) marshalStage(serviceName string,
+	restAPIResName string,
+	methodResNames []string,
+	stageInfo *awsv2APIGTypes.Stage,
+	template *gof.Template) string {
+
+	// Use an unstable ID s.t. we can actually create a new deployment event
+	deploymentResName := CloudFormationResourceName("APIGatewayDeployment")
+	deployment := &gofapig.Deployment{
+		Description: "Deployment",
+		RestApiId:   gof.Ref(restAPIResName),
+	}
+	deployment.AWSCloudFormationDependsOn = append([]string{}, methodResNames...)
+	deployment.AWSCloudFormationDependsOn = append(deployment.AWSCloudFormationDependsOn,
+		restAPIResName)
+	template.Resources[deploymentResName] = deployment
+
+	stageResName := CloudFormationResourceName("APIGatewayStage", serviceName)
+	if stageInfo != nil && stageInfo.Tags[stageResourceTagKey] == "" {
+		deployment.StageName = api.stage.name
+		if stageInfo.StageName != nil {
+			deployment.StageName = *stageInfo.StageName
+		}
+		deployment.StageDescription = api.stageDescription(template)
+		return deploymentResName
+	}
+	stageRes := &gofapig.Stage{
+		StageName:           api.stage.name,
+		RestApiId:           gof.Ref(restAPIResName),
+		DeploymentId:        gof.Ref(deploymentResName),
+		Description:         api.stage.Description,
+		Variables:           api.stage.Variables,
+		CacheClusterEnabled: api.stage.CacheClusterEnabled,
+		CacheClusterSize:    api.stage.CacheClusterSize,
+		Tags: []goftags.Tag{
+			{
+				Key:   stageResourceTagKey,
+				Value: stageResName,
+			},
+		},
+	}
+	for _, eachMethodSetting := range api.stage.MethodSettings {
+		stageRes.MethodSettings = append(stageRes.MethodSettings,
+			gofapig.Stage_MethodSetting(*eachMethodSetting))
+	}
+	if accessLogSetting := api.accessLogSetting(template); accessLogSetting != nil {
+		stageAccessLogSetting := gofapig.Stage_AccessLogSetting(*accessLogSetting)
+		stageRes.AccessLogSetting = &stageAccessLogSetting
+	}
+	template.Resources[stageResName] = stageRes
+	return stageResName
+}
+
+// stageDescription returns the deployment stage description for the
+// stage settings
+func (api *API) stageDescription(template *gof.Template) *gofapig.Deployment_StageDescription {
+	stageDescription := &gofapig.Deployment_StageDescription{
+		Description:         api.stage.Description,
+		Variables:           api.stage.Variables,
+		CacheClusterEnabled: api.stage.CacheClusterEnabled,
+		CacheClusterSize:    api.stage.CacheClusterSize,
+		AccessLogSetting:    api.accessLogSetting(template),
+	}
+	for _, eachMethodSetting := range api.stage.MethodSettings {
+		stageDescription.MethodSettings = append(stageDescription.MethodSettings,
+			*eachMethodSetting)
+	}
+	return stageDescription
+}
+
+// accessLogSetting adds the access log group to the template and returns
+// the stage access log setting. The result is nil if access logging
+// isn't enabled.
+func (api *API) accessLogSetting(template *gof.Template) *gofapig.Deployment_AccessLogSetting {
+	if api.stage.AccessLogging == nil {
+		return nil
+	}
+	logGroupResourceName := CloudFormationResourceName("APIGatewayAccessLogs",
+		api.name,
+		api.stage.name)
+	logGroupResource := &goflogs.LogGroup{}
+	if api.stage.AccessLogging.RetentionInDays != 0 {
+		logGroupResource.RetentionInDays = api.stage.AccessLogging.RetentionInDays
+	}
+	template.Resources[logGroupResourceName] = logGroupResource
+
+	format := api.stage.AccessLogging.Format
+	if format == "" {
+		format = DefaultStageAccessLogFormat
+	}
+	return &gofapig.Deployment_AccessLogSetting{
+		DestinationArn: gof.GetAtt(logGroupResourceName, "Arn"),
+		Format:         format,
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//
+
+// APIKey is an API Gateway API key. Associate keys with one or more
+// UsagePlans to grant access to APIKeyRequired methods.
+type APIKey struct {
+	apiName string
+	name    string
+	// Value is the imported key value. If empty, API Gateway generates
+	// the value. Generated values are available via
+	// `aws apigateway get-api-key --include-value --api-key KEY_ID`
+	Value string
+	// Description is the optional key description
+	Description string
+	// CustomerID is the optional AWS Marketplace customer identifier
+	CustomerID string
+	// Disabled keys are rejected by API Gateway
+	Disabled bool
+}
+
+// LogicalResourceName returns the CloudFormation logical resource name
+// of the AWS::ApiGateway::ApiKey resource
+func (apiKey *APIKey) LogicalResourceName() string {
+	return CloudFormationResourceName("APIGatewayKey", apiKey.apiName, apiKey.name)
+}
+
+// UsagePlan is an API Gateway usage plan for the API stage
+type UsagePlan struct {
+	apiName string
+	name    string
+	apiKeys []*APIKey
+	// Description is the optional usage plan description
+	Description string
+	// Quota is the optional maximum number of requests per period
+	Quota *gofapig.UsagePlan_QuotaSettings
+	// Throttle is the optional steady-state and burst request rate
+	Throttle *gofapig.UsagePlan_ThrottleSettings
+	// MethodThrottles are optional per-method request rates keyed by
+	// resource path and HTTP method (eg: /users/{id}/GET)
+	MethodThrottles map[string]gofapig.UsagePlan_ThrottleSettings
+}
+
+// LogicalResourceName returns the CloudFormation logical resource name
+// of the AWS::ApiGateway::UsagePlan resource
+func (plan *UsagePlan) LogicalResourceName() string {
+	return CloudFormationResourceName("APIGatewayUsagePlan", plan.apiName, plan.name)
+}
+
+// WithQuota sets the maximum number of requests per period, which is one of
+// QuotaPeriodDay, QuotaPeriodWeek or QuotaPeriodMonth
+func (plan *UsagePlan) WithQuota(limit int, period string) *UsagePlan {
+	plan.Quota = &gofapig.UsagePlan_QuotaSettings{
+		Limit:  limit,
+		Period: period,
+	}
+	return plan
+}
+
+// WithThrottle sets the steady-state requests per second and burst limit
+func (plan *UsagePlan) WithThrottle(rateLimit float64, burstLimit int) *UsagePlan {
+	plan.Throttle = &gofapig.UsagePlan_ThrottleSettings{
+		RateLimit:  rateLimit,
+		BurstLimit: burstLimit,
+	}
+	return plan
+}
+
+// WithMethodThrottle sets the steady-state requests per second and burst
+// limit for a single resource path and HTTP method
+func (plan *UsagePlan) WithMethodThrottle(resourcePath string,
+	httpMethod string,
+	rateLimit float64,
+	burstLimit int) *UsagePlan {
+	if plan.MethodThrottles == nil {
+		plan.MethodThrottles = make(map[string]gofapig.UsagePlan_ThrottleSettings)
+	}
+	methodKey := fmt.Sprintf("%s/%s", resourcePath, strings.ToUpper(httpMethod))
+	plan.MethodThrottles[methodKey] = gofapig.UsagePlan_ThrottleSettings{
+		RateLimit:  rateLimit,
+		BurstLimit: burstLimit,
+	}
+	return plan
+}
+
+// WithAPIKeys associates the API keys with the usage plan
+func (plan *UsagePlan) WithAPIKeys(apiKeys ...*APIKey) *UsagePlan {
+	plan.apiKeys = append(plan.apiKeys, apiKeys...)
+	return plan
+}
+
+// NewAPIKey returns a new API key. If value is empty, API Gateway
+// generates the key value.
+func (api *API) NewAPIKey(name string, value string) (*APIKey, error) {
+	if name == "" {
+		return nil, errors.Errorf("API key name must not be empty")
+	}
+	if _, exists := api.apiKeys[name]; exists {
+		return nil, errors.Errorf("API key %s already defined for API %s", name, api.name)
+	}
+	apiKey := &APIKey{
+		apiName: api.name,
+		name:    name,
+		Value:   value,
+	}
+	api.apiKeys[name] = apiKey
+	return apiKey, nil
+}
+
+// NewUsagePlan returns a new usage plan for the API stage. Usage plans
+// require an API with a Stage.
+func (api *API) NewUsagePlan(name string) (*UsagePlan, error) {
+	if name == "" {
+		return nil, errors.Errorf("Usage plan name must not be empty")
+	}
+	if _, exists := api.usagePlans[name]; exists {
+		return nil, errors.Errorf("Usage plan %s already defined for API %s", name, api.name)
+	}
+	plan := &UsagePlan{
+		apiName: api.name,
+		name:    name,
+	}
+	api.usagePlans[name] = plan
+	return plan, nil
+}
+
+// marshalUsagePlans adds the API key, usage plan and usage plan key
+// resources to the template. The usage plans depend on the deployment
+// that creates the stage.
+func (api *API) marshalUsagePlans(restAPIID string,
+	deploymentResName string,
+	template *gof.Template) error {
+
+	if len(api.usagePlans) == 0 && len(api.apiKeys) == 0 {
+		return nil
+	}
+	for _, eachAPIKey := range api.apiKeys {
+		template.Resources[eachAPIKey.LogicalResourceName()] = &gofapig.ApiKey{
+			Name:        eachAPIKey.name,
+			Value:       eachAPIKey.Value,
+			Description: eachAPIKey.Description,
+			CustomerId:  eachAPIKey.CustomerID,
+			Enabled:     !eachAPIKey.Disabled,
+			StageKeys: []gofapig.ApiKey_StageKey{
+				{
+					RestApiId: restAPIID,
+					StageName: api.stage.name,
+				},
+			},
+			AWSCloudFormationDependsOn: []string{deploymentResName},
+		}
+	}
+	for _, eachPlan := range api.usagePlans {
+		planResName := eachPlan.LogicalResourceName()
+		template.Resources[planResName] = &gofapig.UsagePlan{
+			UsagePlanName: eachPlan.name,
+			Description:   eachPlan.Description,
+			Quota:         eachPlan.Quota,
+			Throttle:      eachPlan.Throttle,
+			ApiStages: []gofapig.UsagePlan_ApiStage{
+				{
+					ApiId:    restAPIID,
+					Stage:    api.stage.name,
+					Throttle: eachPlan.MethodThrottles,
+				},
+			},
+			AWSCloudFormationDependsOn: []string{deploymentResName},
+		}
+		for _, eachAPIKey := range eachPlan.apiKeys {
+			if api.apiKeys[eachAPIKey.name] != eachAPIKey {
+				return errors.Errorf("Usage plan %s API key %s isn't defined for API %s",
+					eachPlan.name,
+					eachAPIKey.name,
+					api.name)
+			}
+			planKeyResName := CloudFormationResourceName("APIGatewayUsagePlanKey",
+				planResName,
+				eachAPIKey.name)
+			template.Resources[planKeyResName] = &gofapig.UsagePlanKey{
+				KeyId:       gof.Ref(eachAPIKey.LogicalResourceName()),
+				KeyType:     "API_KEY",
+				UsagePlanId: gof.Ref(planResName),
+			}
+		}
+	}
+	return nil
+}
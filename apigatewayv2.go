@@ -9,6 +9,7 @@ import (
 	gofapigv2 "github.com/awslabs/goformation/v5/cloudformation/apigatewayv2"
 	gofddb "github.com/awslabs/goformation/v5/cloudformation/dynamodb"
 	goflambda "github.com/awslabs/goformation/v5/cloudformation/lambda"
+	spartaWebSocket "github.com/mweagle/Sparta/v3/aws/apigateway/websocket"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
@@ -22,13 +23,17 @@ type APIV2RouteSelectionExpression string
 type APIV2Protocol string
 
 const (
-	// Websocket represents the only supported V2 protocol
+	// Websocket represents the WebSocket API protocol
 	Websocket APIV2Protocol = "WEBSOCKET"
+	// HTTP represents the HTTP API protocol
+	HTTP APIV2Protocol = "HTTP"
 )
 
-// APIV2 contains the information necessary for the routes in here.
-// Please tell me they can use the same routes...
-// They cannot
+// APIV2 is an API Gateway V2 WebSocket or HTTP API. Both protocols share
+// the API, route, integration and stage resources. HTTP API routes use
+// `METHOD /path` route keys (eg: `ANY /{proxy+}`) and the 2.0 Lambda
+// proxy payload format. HTTP APIs also support CORS, JWT and Lambda
+// authorizers, auto-deployed stages and custom domains.
 type APIV2 struct {
 	protocol                  APIV2Protocol
 	name                      string
@@ -39,13 +44,20 @@ type APIV2 struct {
 	DisableSchemaValidation   bool
 	Tags                      map[string]interface{}
 	Version                   string
+	// CORSConfiguration is the optional HTTP API CORS configuration
+	CORSConfiguration *gofapigv2.Api_Cors
+	// CustomDomain is the optional custom domain for the stage
+	CustomDomain *APIV2CustomDomain
 	// Routes mapping selection expression to Route handler
 	routes map[APIV2RouteSelectionExpression]*APIV2Route
+	// Map of authorizer names->APIV2Authorizer definitions
+	authorizers map[string]*APIV2Authorizer
 }
 
 // APIV2GatewayDecorator is the compound decorator that handles both
 // the DDB table creation and the lambda decorator...winning.
 type APIV2GatewayDecorator struct {
+	apiv2           *APIV2
 	envTableKeyName string
 	propertyName    string
 	readCapacity    int64
@@ -141,6 +153,24 @@ func (apigd *APIV2GatewayDecorator) AnnotateLambdas(lambdaFns []*LambdaAWSInfo)
 		},
 	}
 
+	// Allow the functions to post to and close connections
+	if apigd.apiv2 != nil {
+		ddbPermissions = append(ddbPermissions, IAMRolePrivilege{
+			Actions: []string{"execute-api:ManageConnections"},
+			Resource: gof.Join("", []string{
+				"arn:",
+				gof.Ref("AWS::Partition"),
+				":execute-api:",
+				gof.Ref("AWS::Region"),
+				":",
+				gof.Ref("AWS::AccountId"),
+				":",
+				gof.Ref(apigd.apiv2.LogicalResourceName()),
+				"/*",
+			}),
+		})
+	}
+
 	for _, eachLambda := range lambdaFns {
 		// Add the permission
 		eachLambda.RoleDefinition.Privileges = append(eachLambda.RoleDefinition.Privileges,
@@ -152,19 +182,35 @@ func (apigd *APIV2GatewayDecorator) AnnotateLambdas(lambdaFns []*LambdaAWSInfo)
 			env = make(map[string]string)
 		}
 		env[apigd.envTableKeyName] = gof.Ref(apigd.logicalResourceName())
+		if apigd.apiv2 != nil && apigd.apiv2.stage != nil {
+			env[spartaWebSocket.EnvVarManagementEndpoint] = gof.Join("", []string{
+				"https://",
+				gof.Ref(apigd.apiv2.LogicalResourceName()),
+				".execute-api.",
+				gof.Ref("AWS::Region"),
+				".",
+				gof.Ref("AWS::URLSuffix"),
+				"/",
+				apigd.apiv2.stage.name,
+			})
+		}
 		eachLambda.Options.Environment = env
 	}
 	return nil
 }
 
 // NewConnectionTableDecorator returns a *APIV2GatewayDecorator that handles
-// creating the DynamoDDB table and hooking up all the lambda permissions
+// creating the DynamoDDB table and hooking up all the lambda permissions.
+// The functions can also manage connections and receive the management API
+// endpoint in the websocket.EnvVarManagementEndpoint environment variable.
+// See the aws/apigateway/websocket package for the runtime helpers.
 func (apiv2 *APIV2) NewConnectionTableDecorator(envTableNameKey string,
 	propertyName string,
 	readCapacity int64,
 	writeCapacity int64) (*APIV2GatewayDecorator, error) {
 
 	return &APIV2GatewayDecorator{
+		apiv2:           apiv2,
 		envTableKeyName: envTableNameKey,
 		propertyName:    propertyName,
 		readCapacity:    readCapacity,
@@ -188,6 +234,13 @@ func (apiv2 *APIV2) NewAPIV2Route(routeKey APIV2RouteSelectionExpression,
 			IntegrationType: "AWS_PROXY",
 		},
 	}
+	if apiv2.protocol == HTTP {
+		routeKeyErr := validateHTTPRouteKey(routeKey)
+		if routeKeyErr != nil {
+			return nil, routeKeyErr
+		}
+		route.Integration.PayloadFormatVersion = apiV2HTTPPayloadFormatVersion
+	}
 	apiv2.routes[routeKey] = route
 	return route, nil
 }
@@ -259,15 +312,23 @@ func (apiv2 *APIV2) Marshal(serviceName string,
 		Description:               apiv2.Description,
 		DisableSchemaValidation:   apiv2.DisableSchemaValidation,
 		Name:                      apiv2.name,
-		ProtocolType:              string(Websocket),
+		ProtocolType:              string(apiv2.protocol),
 		RouteSelectionExpression:  apiv2.routeSelectionExpression,
 		Version:                   apiv2.Version,
 	}
+	if apiv2.protocol == HTTP {
+		apiV2Entry.CorsConfiguration = apiv2.CORSConfiguration
+	}
 	// Add it
 	template.Resources[apiv2.LogicalResourceName()] = apiV2Entry
 
 	allRouteResources := []string{}
 
+	// Authorizers
+	for _, eachAuthorizer := range apiv2.authorizers {
+		eachAuthorizer.marshal(gof.Ref(apiv2.LogicalResourceName()), template)
+	}
+
 	// Alright, setup the route
 	for eachExpression, eachRoute := range apiv2.routes {
 		routeResourceName := CloudFormationResourceName("Route", string(eachExpression))
@@ -292,6 +353,11 @@ func (apiv2 *APIV2) Marshal(serviceName string,
 			}),
 		}
 
+		if eachRoute.authorizer != nil {
+			routeEntry.AuthorizationType = eachRoute.authorizer.routeAuthorizationType()
+			routeEntry.AuthorizerId = gof.Ref(eachRoute.authorizer.LogicalResourceName())
+		}
+
 		// Add the route resource
 		template.Resources[routeResourceName] = routeEntry
 
@@ -311,7 +377,8 @@ func (apiv2 *APIV2) Marshal(serviceName string,
 				gof.GetAtt(eachRoute.lambdaFn.LogicalResourceName(), "Arn"),
 				"/invocations",
 			}),
-			PassthroughBehavior: eachRoute.Integration.PassthroughBehavior,
+			PassthroughBehavior:  eachRoute.Integration.PassthroughBehavior,
+			PayloadFormatVersion: eachRoute.Integration.PayloadFormatVersion,
 			// TODO - auto create this...
 			RequestParameters:           eachRoute.Integration.RequestParameters,
 			RequestTemplates:            eachRoute.Integration.RequestTemplates,
@@ -333,25 +400,30 @@ func (apiv2 *APIV2) Marshal(serviceName string,
 
 	// Add the Stage and Deploy...
 	stageResourceName := CloudFormationResourceName("APIV2GatewayStage", "APIV2GatewayStage")
-	// Use an unstable ID s.t. we can actually create a new deployment event.
-	deploymentResName := CloudFormationResourceName("APIV2GatewayDeployment")
+	deploymentID := ""
+	if !apiv2.stage.AutoDeploy {
+		// Use an unstable ID s.t. we can actually create a new deployment event.
+		deploymentResName := CloudFormationResourceName("APIV2GatewayDeployment")
+
+		// Unstable name to trigger a deployment
+		newDeployment := &gofapigv2.Deployment{
+			ApiId:       gof.Ref(apiv2.LogicalResourceName()),
+			Description: apiv2.stage.Description,
+		}
+		// Use an unstable ID s.t. we can actually create a new deployment event.  Not sure how this
+		// is going to work with deletes...
+		newDeployment.AWSCloudFormationDeletionPolicy = "Retain"
+		newDeployment.AWSCloudFormationDependsOn = allRouteResources
 
-	// Unstable name to trigger a deployment
-	newDeployment := &gofapigv2.Deployment{
-		ApiId:       gof.Ref(apiv2.LogicalResourceName()),
-		Description: apiv2.stage.Description,
+		template.Resources[deploymentResName] = newDeployment
+		deploymentID = gof.Ref(deploymentResName)
 	}
-	// Use an unstable ID s.t. we can actually create a new deployment event.  Not sure how this
-	// is going to work with deletes...
-	newDeployment.AWSCloudFormationDeletionPolicy = "Retain"
-	newDeployment.AWSCloudFormationDependsOn = allRouteResources
-
-	template.Resources[deploymentResName] = newDeployment
 
 	// Add the stage...
 	stageResource := &gofapigv2.Stage{
 		ApiId:                gof.Ref(apiv2.LogicalResourceName()),
-		DeploymentId:         gof.Ref(deploymentResName),
+		AutoDeploy:           apiv2.stage.AutoDeploy,
+		DeploymentId:         deploymentID,
 		StageName:            apiv2.stage.name,
 		AccessLogSettings:    apiv2.stage.AccessLogSettings,
 		ClientCertificateId:  apiv2.stage.ClientCertificateID,
@@ -361,38 +433,82 @@ func (apiv2 *APIV2) Marshal(serviceName string,
 		StageVariables:       apiv2.stage.StageVariables,
 		//Tags:                 apiv2.stage.Tags,
 	}
+	if apiv2.stage.AutoDeploy {
+		stageResource.AWSCloudFormationDependsOn = allRouteResources
+	}
 	template.Resources[stageResourceName] = stageResource
 
+	if apiv2.CustomDomain != nil {
+		apiv2.marshalCustomDomain(stageResourceName, template)
+	}
+
 	// Outputs...
+	outputDescription := "API Gateway Websocket URL"
+	urlParts := []string{
+		"wss://",
+		gof.Ref(apiv2.LogicalResourceName()),
+		".execute-api.",
+		gof.Ref("AWS::Region"),
+		".amazonaws.com/",
+	}
+	if apiv2.protocol == HTTP {
+		outputDescription = "API Gateway HTTP API URL"
+		urlParts[0] = "https://"
+	}
+	// The $default stage is served from the base URL
+	if apiv2.stage.name != APIV2DefaultStage {
+		urlParts = append(urlParts, apiv2.stage.name)
+	}
 	template.Outputs[OutputAPIGatewayURL] = gof.Output{
-		Description: "API Gateway Websocket URL",
-		Value: gof.Join("", []string{
-			"wss://",
-			gof.Ref(apiv2.LogicalResourceName()),
-			".execute-api.",
-			gof.Ref("AWS::Region"),
-			".amazonaws.com/",
-			apiv2.stage.name,
-		}),
+		Description: outputDescription,
+		Value:       gof.Join("", urlParts),
 	}
 	return nil
 }
 
-// NewAPIV2 returns a new API V2 Gateway instance
+// NewAPIV2 returns a new API V2 Gateway instance. HTTP APIs only support
+// the `$request.method $request.path` routeSelectionExpression, which
+// is the default if routeSelectionExpression is empty.
 func NewAPIV2(protocol APIV2Protocol,
 	name string,
 	routeSelectionExpression string,
 	stage *APIV2Stage) (*APIV2, error) {
+
+	switch protocol {
+	case Websocket:
+	case HTTP:
+		if routeSelectionExpression == "" {
+			routeSelectionExpression = apiV2HTTPRouteSelectionExpression
+		}
+		if routeSelectionExpression != apiV2HTTPRouteSelectionExpression {
+			return nil, errors.Errorf("Unsupported HTTP API route selection expression: %s",
+				routeSelectionExpression)
+		}
+	default:
+		return nil, errors.Errorf("Unsupported APIV2 protocol: %s", protocol)
+	}
+	if stage == nil {
+		return nil, errors.Errorf("APIV2 %s requires a stage", name)
+	}
 	return &APIV2{
 		protocol:                 protocol,
 		name:                     name,
 		routeSelectionExpression: routeSelectionExpression,
 		stage:                    stage,
 		routes:                   make(map[APIV2RouteSelectionExpression]*APIV2Route),
+		authorizers:              make(map[string]*APIV2Authorizer),
 		Tags:                     make(map[string]interface{}),
 	}, nil
 }
 
+// NewHTTPAPI returns a new HTTP API with an auto-deployed `$default`
+// stage that is served from the base URL
+func NewHTTPAPI(name string) (*APIV2, error) {
+	stage, _ := NewAPIV2Stage(APIV2DefaultStage)
+	stage.AutoDeploy = true
+	return NewAPIV2(HTTP, name, "", stage)
+}
+
 // APIV2Route represents a V2 route
 type APIV2Route struct {
 	routeKey                         APIV2RouteSelectionExpression
@@ -407,11 +523,15 @@ type APIV2Route struct {
 	RouteResponseSelectionExpression string
 	Integration                      *APIV2Integration
 	lambdaFn                         *LambdaAWSInfo
+	authorizer                       *APIV2Authorizer
 }
 
 // APIV2Stage represents the deployment stage
 type APIV2Stage struct {
-	AccessLogSettings    *gofapigv2.Stage_AccessLogSettings
+	AccessLogSettings *gofapigv2.Stage_AccessLogSettings
+	// AutoDeploy deploys HTTP API changes to the stage without a
+	// Deployment resource
+	AutoDeploy           bool
 	ClientCertificateID  string
 	DefaultRouteSettings *gofapigv2.Stage_RouteSettings
 	Description          string
@@ -440,6 +560,7 @@ type APIV2Integration struct {
 	IntegrationType         string
 	//IntegrationUri              string
 	PassthroughBehavior         string
+	PayloadFormatVersion        string
 	RequestParameters           interface{}
 	RequestTemplates            interface{}
 	TemplateSelectionExpression string
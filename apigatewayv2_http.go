@@ -0,0 +1,285 @@
+package sparta
+
+import (
+	"net/http"
+	"strings"
+
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	gofapigv2 "github.com/awslabs/goformation/v5/cloudformation/apigatewayv2"
+	goflambda "github.com/awslabs/goformation/v5/cloudformation/lambda"
+	gofroute53 "github.com/awslabs/goformation/v5/cloudformation/route53"
+	"github.com/pkg/errors"
+)
+
+const (
+	// APIV2DefaultStage is the name of the HTTP API stage that is served
+	// from the base URL
+	APIV2DefaultStage = "$default"
+	// APIV2DefaultRoute is the HTTP API route that handles requests that
+	// don't match another route
+	APIV2DefaultRoute APIV2RouteSelectionExpression = "$default"
+	// apiV2HTTPRouteSelectionExpression is the only route selection
+	// expression supported by HTTP APIs
+	apiV2HTTPRouteSelectionExpression = "$request.method $request.path"
+	// apiV2HTTPPayloadFormatVersion is the Lambda proxy payload format.
+	// Handlers receive awsLambdaEvents.APIGatewayV2HTTPRequest events.
+	apiV2HTTPPayloadFormatVersion = "2.0"
+)
+
+// apiV2HTTPRouteMethods are the valid HTTP API route key methods
+var apiV2HTTPRouteMethods = map[string]bool{
+	"ANY":              true,
+	http.MethodDelete:  true,
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPatch:   true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+}
+
+// validateHTTPRouteKey returns an error if the routeKey isn't $default
+// or a METHOD /path expression (eg: ANY /{proxy+})
+func validateHTTPRouteKey(routeKey APIV2RouteSelectionExpression) error {
+	if routeKey == APIV2DefaultRoute {
+		return nil
+	}
+	parts := strings.SplitN(string(routeKey), " ", 2)
+	if len(parts) != 2 ||
+		!apiV2HTTPRouteMethods[parts[0]] ||
+		!strings.HasPrefix(parts[1], "/") {
+		return errors.Errorf("Invalid HTTP API route key `%s`. Route keys must be `$default` or `METHOD /path` (eg: `ANY /{proxy+}`)",
+			routeKey)
+	}
+	return nil
+}
+
+// APIV2AuthorizerType is the type of an APIV2Authorizer
+type APIV2AuthorizerType string
+
+const (
+	// APIV2AuthorizerTypeJWT validates JSON Web Tokens issued by an
+	// OpenID Connect or OAuth 2.0 provider (eg: Amazon Cognito)
+	APIV2AuthorizerTypeJWT APIV2AuthorizerType = "JWT"
+	// APIV2AuthorizerTypeLambda is a Lambda function authorizer
+	APIV2AuthorizerTypeLambda APIV2AuthorizerType = "REQUEST"
+)
+
+// APIV2Authorizer is an HTTP API authorizer. Routes reference
+// authorizers with APIV2Route.WithAuthorizer.
+type APIV2Authorizer struct {
+	apiName        string
+	name           string
+	authorizerType APIV2AuthorizerType
+	lambdaFn       *LambdaAWSInfo
+	jwtIssuer      string
+	jwtAudience    []string
+	// IdentitySources are the request values that identify the caller
+	// (eg: $request.header.Authorization)
+	IdentitySources []string
+	// ResultTTLSeconds is the number of seconds API Gateway caches the
+	// Lambda authorizer result
+	ResultTTLSeconds int
+	// EnableSimpleResponses allows Lambda authorizers to return
+	// {"isAuthorized": bool, "context": {...}} rather than an IAM policy
+	EnableSimpleResponses bool
+}
+
+// Name returns the authorizer name
+func (authorizer *APIV2Authorizer) Name() string {
+	return authorizer.name
+}
+
+// LogicalResourceName returns the CloudFormation logical resource name
+// of the AWS::ApiGatewayV2::Authorizer resource
+func (authorizer *APIV2Authorizer) LogicalResourceName() string {
+	return CloudFormationResourceName("APIV2GatewayAuthorizer",
+		authorizer.apiName,
+		authorizer.name)
+}
+
+// routeAuthorizationType returns the AWS::ApiGatewayV2::Route
+// AuthorizationType value
+func (authorizer *APIV2Authorizer) routeAuthorizationType() string {
+	if authorizer.authorizerType == APIV2AuthorizerTypeLambda {
+		return "CUSTOM"
+	}
+	return string(authorizer.authorizerType)
+}
+
+// marshal adds the authorizer and Lambda invoke permission resources
+// to the template
+func (authorizer *APIV2Authorizer) marshal(apiID string, template *gof.Template) {
+	authorizerResName := authorizer.LogicalResourceName()
+	authorizerRes := &gofapigv2.Authorizer{
+		ApiId:          apiID,
+		Name:           authorizer.name,
+		AuthorizerType: string(authorizer.authorizerType),
+		IdentitySource: authorizer.IdentitySources,
+	}
+	switch authorizer.authorizerType {
+	case APIV2AuthorizerTypeJWT:
+		authorizerRes.JwtConfiguration = &gofapigv2.Authorizer_JWTConfiguration{
+			Issuer:   authorizer.jwtIssuer,
+			Audience: authorizer.jwtAudience,
+		}
+	case APIV2AuthorizerTypeLambda:
+		authorizerRes.AuthorizerPayloadFormatVersion = apiV2HTTPPayloadFormatVersion
+		authorizerRes.AuthorizerResultTtlInSeconds = authorizer.ResultTTLSeconds
+		authorizerRes.EnableSimpleResponses = authorizer.EnableSimpleResponses
+		authorizerRes.AuthorizerUri = gof.Join("", []string{
+			"arn:aws:apigateway:",
+			gof.Ref("AWS::Region"),
+			":lambda:path/2015-03-31/functions/",
+			gof.GetAtt(authorizer.lambdaFn.LogicalResourceName(), "Arn"),
+			"/invocations",
+		})
+		template.Resources[CloudFormationResourceName("APIV2GatewayAuthorizerPerm",
+			authorizer.apiName,
+			authorizer.name)] = &goflambda.Permission{
+			Action:       "lambda:InvokeFunction",
+			FunctionName: gof.GetAtt(authorizer.lambdaFn.LogicalResourceName(), "Arn"),
+			Principal:    APIGatewayPrincipal,
+			SourceArn: gof.Join("", []string{
+				"arn:aws:execute-api:",
+				gof.Ref("AWS::Region"),
+				":",
+				gof.Ref("AWS::AccountId"),
+				":",
+				apiID,
+				"/authorizers/",
+				gof.Ref(authorizerResName),
+			}),
+		}
+	}
+	template.Resources[authorizerResName] = authorizerRes
+}
+
+// addAuthorizer registers the authorizer with the HTTP API
+func (apiv2 *APIV2) addAuthorizer(authorizer *APIV2Authorizer) (*APIV2Authorizer, error) {
+	if apiv2.protocol != HTTP {
+		return nil, errors.Errorf("APIV2 %s authorizers are only supported for HTTP APIs",
+			authorizer.authorizerType)
+	}
+	if authorizer.name == "" {
+		return nil, errors.Errorf("APIV2 authorizer name must not be empty")
+	}
+	if _, exists := apiv2.authorizers[authorizer.name]; exists {
+		return nil, errors.Errorf("APIV2 authorizer %s already defined for API %s",
+			authorizer.name,
+			apiv2.name)
+	}
+	authorizer.apiName = apiv2.name
+	apiv2.authorizers[authorizer.name] = authorizer
+	return authorizer, nil
+}
+
+// NewJWTAuthorizer returns an HTTP API JWT authorizer for tokens issued by
+// the issuer (eg: https://cognito-idp.REGION.amazonaws.com/USER_POOL_ID)
+// for at least one of the audience values. The token is read from the
+// Authorization header. The validated claims are available in the
+// APIGatewayV2HTTPRequest RequestContext.Authorizer.JWT value.
+func (apiv2 *APIV2) NewJWTAuthorizer(name string,
+	issuer string,
+	audience ...string) (*APIV2Authorizer, error) {
+	if issuer == "" || len(audience) == 0 {
+		return nil, errors.Errorf("JWT authorizer %s requires an issuer and audience", name)
+	}
+	return apiv2.addAuthorizer(&APIV2Authorizer{
+		name:            name,
+		authorizerType:  APIV2AuthorizerTypeJWT,
+		jwtIssuer:       issuer,
+		jwtAudience:     audience,
+		IdentitySources: []string{"$request.header.Authorization"},
+	})
+}
+
+// NewLambdaAuthorizer returns an HTTP API Lambda authorizer that uses the
+// 2.0 payload format. The identitySources (eg: $request.header.Authorization)
+// are used as the cache key. Lambda authorizer functions must also be
+// included in the slice of LambdaAWSInfo provisioned by the service.
+func (apiv2 *APIV2) NewLambdaAuthorizer(name string,
+	lambdaFn *LambdaAWSInfo,
+	identitySources ...string) (*APIV2Authorizer, error) {
+	if lambdaFn == nil {
+		return nil, errors.Errorf("Lambda authorizer %s requires a Lambda function", name)
+	}
+	return apiv2.addAuthorizer(&APIV2Authorizer{
+		name:            name,
+		authorizerType:  APIV2AuthorizerTypeLambda,
+		lambdaFn:        lambdaFn,
+		IdentitySources: identitySources,
+	})
+}
+
+// WithAuthorizer sets the route authorizer. The optional scopes are the
+// JWT scopes required by the route.
+func (route *APIV2Route) WithAuthorizer(authorizer *APIV2Authorizer,
+	scopes ...string) *APIV2Route {
+	route.authorizer = authorizer
+	route.AuthorizationScopes = scopes
+	return route
+}
+
+// APIV2CustomDomain is a custom domain name for the API stage. The
+// certificate must be an ACM certificate in the stack region.
+type APIV2CustomDomain struct {
+	// DomainName is the custom domain name (eg: api.example.com)
+	DomainName string
+	// CertificateARN is the ACM certificate ARN for the domain name
+	CertificateARN string
+	// BasePath is the optional API mapping key (eg: v1)
+	BasePath string
+	// HostedZoneID is the optional Route 53 hosted zone ID. If non-empty,
+	// an alias record for the domain name is created in the zone.
+	HostedZoneID string
+}
+
+// marshalCustomDomain adds the custom domain, API mapping and optional
+// Route 53 alias record resources to the template
+func (apiv2 *APIV2) marshalCustomDomain(stageResourceName string,
+	template *gof.Template) {
+
+	customDomain := apiv2.CustomDomain
+	domainResourceName := CloudFormationResourceName("APIV2GatewayDomain",
+		customDomain.DomainName)
+	template.Resources[domainResourceName] = &gofapigv2.DomainName{
+		DomainName: customDomain.DomainName,
+		DomainNameConfigurations: []gofapigv2.DomainName_DomainNameConfiguration{
+			{
+				CertificateArn: customDomain.CertificateARN,
+				EndpointType:   "REGIONAL",
+				SecurityPolicy: "TLS_1_2",
+			},
+		},
+	}
+	apiMapping := &gofapigv2.ApiMapping{
+		ApiId:         gof.Ref(apiv2.LogicalResourceName()),
+		ApiMappingKey: customDomain.BasePath,
+		DomainName:    gof.Ref(domainResourceName),
+		Stage:         gof.Ref(stageResourceName),
+	}
+	template.Resources[CloudFormationResourceName("APIV2GatewayMapping",
+		customDomain.DomainName)] = apiMapping
+
+	if customDomain.HostedZoneID != "" {
+		template.Resources[CloudFormationResourceName("APIV2GatewayDomainRecord",
+			customDomain.DomainName)] = &gofroute53.RecordSet{
+			HostedZoneId: customDomain.HostedZoneID,
+			Name:         customDomain.DomainName,
+			Type:         "A",
+			AliasTarget: &gofroute53.RecordSet_AliasTarget{
+				DNSName:      gof.GetAtt(domainResourceName, "RegionalDomainName"),
+				HostedZoneId: gof.GetAtt(domainResourceName, "RegionalHostedZoneId"),
+			},
+		}
+	}
+	domainURL := "https://" + customDomain.DomainName
+	if customDomain.BasePath != "" {
+		domainURL = domainURL + "/" + customDomain.BasePath
+	}
+	template.Outputs[OutputAPIGatewayCustomDomainURL] = gof.Output{
+		Description: "API Gateway custom domain URL",
+		Value:       domainURL,
+	}
+}
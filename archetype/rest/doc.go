@@ -2,5 +2,8 @@
 Package rest provides a set of utility functions to make
 building REST-based services simpler. See the https://github.com/mweagle/SpartaTodoBackend
 project for a complete example.
+
+NewTypedHandler binds the path parameters,
+querystring parameters, headers and JSON body into a typed request value.
 */
 package rest
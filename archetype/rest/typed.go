@@ -0,0 +1,299 @@
+package rest
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	sparta "github.com/mweagle/Sparta/v3"
+	spartaAPIGateway "github.com/mweagle/Sparta/v3/aws/apigateway"
+	spartaEvents "github.com/mweagle/Sparta/v3/aws/events"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	// tagPath binds a request path parameter to a struct field
+	tagPath = "path"
+	// tagQuery binds a request querystring parameter to a struct field
+	tagQuery = "query"
+	// tagHeader binds a request header to a struct field
+	tagHeader = "header"
+)
+
+var typedValidator = validator.New()
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// StatusCoder is implemented by errors that map to an HTTP status code
+// when returned from a NewTypedHandler function
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// HTTPError is an error that maps to an HTTP status code
+type HTTPError struct {
+	Code int
+	Err  error
+}
+
+// Error returns the error message
+func (httpErr *HTTPError) Error() string {
+	if httpErr.Err == nil {
+		return http.StatusText(httpErr.Code)
+	}
+	return httpErr.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (httpErr *HTTPError) Unwrap() error {
+	return httpErr.Err
+}
+
+// StatusCode returns the HTTP status code
+func (httpErr *HTTPError) StatusCode() int {
+	return httpErr.Code
+}
+
+// NewHTTPError returns an error that a NewTypedHandler function uses to
+// return a non-2xx HTTP status code
+func NewHTTPError(statusCode int, err error) *HTTPError {
+	return &HTTPError{
+		Code: statusCode,
+		Err:  err,
+	}
+}
+
+// typedRequest is the API Gateway request with the body left undecoded
+// so that it can be unmarshalled into the handler's request type
+type typedRequest struct {
+	spartaEvents.APIGatewayEnvelope
+	Body json.RawMessage `json:"body"`
+}
+
+// NewTypedHandler returns a MethodHandler for a function that accepts
+// a typed request and returns a typed response. The request is bound
+// before the function is called:
+//
+//   - The JSON request body is unmarshalled into Req
+//   - Fields tagged with `path:"name"`, `query:"name"` and `header:"name"`
+//     are set from the path parameters, querystring parameters and
+//     headers
+//   - Fields with `validate` tags are checked using
+//     gopkg.in/go-playground/validator.v9
+//
+// Binding and validation failures return a 400 response. The function's
+// response is returned with the defaultCode status code, unless it's a
+// *apigateway.Response. *apigateway.Problem errors return an RFC 7807
+// application/problem+json response. Errors that implement StatusCoder
+// (see NewHTTPError) or are *apigateway.Error values return their status
+// code. All other errors return a 500 response.
+func NewTypedHandler[Req any, Resp any](handler func(context.Context, Req) (Resp, error),
+	defaultCode int) *MethodHandler {
+
+	lambdaHandler := func(ctx context.Context,
+		request typedRequest) (*spartaAPIGateway.Response, error) {
+
+		var typedReq Req
+		bindErr := bindTypedRequest(&request, &typedReq)
+		if bindErr != nil {
+			return typedErrorResponse(ctx, NewHTTPError(http.StatusBadRequest, bindErr)), nil
+		}
+		resp, respErr := handler(ctx, typedReq)
+		if respErr != nil {
+			return typedErrorResponse(ctx, respErr), nil
+		}
+		if apigResponse, apigResponseOk := interface{}(resp).(*spartaAPIGateway.Response); apigResponseOk {
+			return apigResponse, nil
+		}
+		return spartaAPIGateway.NewResponse(defaultCode, resp), nil
+	}
+	return NewMethodHandler(lambdaHandler, defaultCode)
+}
+
+// typedErrorResponse returns the error response for the handler error
+func typedErrorResponse(ctx context.Context, err error) *spartaAPIGateway.Response {
+	var problem *spartaAPIGateway.Problem
+	if errors.As(err, &problem) {
+		return problem.Response()
+	}
+	var apigErr *spartaAPIGateway.Error
+	if errors.As(err, &apigErr) {
+		return spartaAPIGateway.NewResponse(apigErr.Code, apigErr)
+	}
+	var statusCoder StatusCoder
+	if errors.As(err, &statusCoder) {
+		errResponse := spartaAPIGateway.NewErrorResponse(statusCoder.StatusCode(), err)
+		var validationErrs validator.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			fieldErrors := make(map[string]string)
+			for _, eachFieldErr := range validationErrs {
+				fieldErrors[eachFieldErr.Namespace()] = eachFieldErr.Tag()
+			}
+			errResponse.Context["validation"] = fieldErrors
+		}
+		return spartaAPIGateway.NewResponse(errResponse.Code, errResponse)
+	}
+	// Don't leak untyped errors to the client
+	spartaLogger(ctx).Error().
+		Err(err).
+		Msg("Typed handler returned an untyped error")
+	errResponse := spartaAPIGateway.NewErrorResponse(http.StatusInternalServerError)
+	return spartaAPIGateway.NewResponse(errResponse.Code, errResponse)
+}
+
+// spartaLogger returns the invocation logger
+func spartaLogger(ctx context.Context) *zerolog.Logger {
+	logger, loggerOk := ctx.Value(sparta.ContextKeyLogger).(*zerolog.Logger)
+	if !loggerOk {
+		nopLogger := zerolog.Nop()
+		logger = &nopLogger
+	}
+	return logger
+}
+
+// bindTypedRequest populates the typed request value from the API Gateway
+// request and validates it
+func bindTypedRequest(request *typedRequest, typedReq interface{}) error {
+	target := reflect.ValueOf(typedReq).Elem()
+	if target.Kind() == reflect.Ptr {
+		target.Set(reflect.New(target.Type().Elem()))
+	}
+	body := strings.TrimSpace(string(request.Body))
+	if body != "" && body != "null" && body != "{}" && body != `""` {
+		unmarshalErr := json.Unmarshal(request.Body, typedReq)
+		if unmarshalErr != nil {
+			return errors.Wrapf(unmarshalErr, "Invalid request body")
+		}
+	}
+	structValue := reflect.Indirect(target)
+	if structValue.Kind() != reflect.Struct {
+		return nil
+	}
+	bindErr := bindStructFields(request, structValue)
+	if bindErr != nil {
+		return bindErr
+	}
+	return typedValidator.Struct(structValue.Addr().Interface())
+}
+
+// bindStructFields sets the tagged fields, including those of embedded
+// structs, from the request parameters
+func bindStructFields(request *typedRequest, structValue reflect.Value) error {
+	structType := structValue.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		eachField := structType.Field(i)
+		fieldValue := structValue.Field(i)
+		if eachField.Anonymous && eachField.Type.Kind() == reflect.Struct {
+			bindErr := bindStructFields(request, fieldValue)
+			if bindErr != nil {
+				return bindErr
+			}
+			continue
+		}
+		if eachField.PkgPath != "" {
+			continue
+		}
+		bindings := []struct {
+			tag    string
+			values map[string]string
+		}{
+			{tagPath, request.PathParams},
+			{tagQuery, request.QueryParams},
+			{tagHeader, request.Headers},
+		}
+		for _, eachBinding := range bindings {
+			name, nameOk := eachField.Tag.Lookup(eachBinding.tag)
+			if !nameOk || name == "" || name == "-" {
+				continue
+			}
+			value, valueOk := lookupParam(eachBinding.values, name, eachBinding.tag == tagHeader)
+			if !valueOk {
+				continue
+			}
+			setErr := setFieldString(fieldValue, value)
+			if setErr != nil {
+				return errors.Wrapf(setErr,
+					"Invalid %s parameter `%s`", eachBinding.tag, name)
+			}
+		}
+	}
+	return nil
+}
+
+// lookupParam returns the named parameter value. Header names are
+// case insensitive.
+func lookupParam(values map[string]string, name string, caseInsensitive bool) (string, bool) {
+	value, valueOk := values[name]
+	if valueOk || !caseInsensitive {
+		return value, valueOk
+	}
+	for eachKey, eachValue := range values {
+		if strings.EqualFold(eachKey, name) {
+			return eachValue, true
+		}
+	}
+	return "", false
+}
+
+// setFieldString converts the string value to the field's type
+func setFieldString(fieldValue reflect.Value, value string) error {
+	if fieldValue.Kind() == reflect.Ptr {
+		ptrValue := reflect.New(fieldValue.Type().Elem())
+		setErr := setFieldString(ptrValue.Elem(), value)
+		if setErr != nil {
+			return setErr
+		}
+		fieldValue.Set(ptrValue)
+		return nil
+	}
+	if fieldValue.CanAddr() && fieldValue.Addr().Type().Implements(textUnmarshalerType) {
+		return fieldValue.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	switch fieldValue.Kind() {
+	case reflect.String:
+		fieldValue.SetString(value)
+	case reflect.Bool:
+		boolValue, parseErr := strconv.ParseBool(value)
+		if parseErr != nil {
+			return parseErr
+		}
+		fieldValue.SetBool(boolValue)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intValue, parseErr := strconv.ParseInt(value, 10, fieldValue.Type().Bits())
+		if parseErr != nil {
+			return parseErr
+		}
+		fieldValue.SetInt(intValue)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintValue, parseErr := strconv.ParseUint(value, 10, fieldValue.Type().Bits())
+		if parseErr != nil {
+			return parseErr
+		}
+		fieldValue.SetUint(uintValue)
+	case reflect.Float32, reflect.Float64:
+		floatValue, parseErr := strconv.ParseFloat(value, fieldValue.Type().Bits())
+		if parseErr != nil {
+			return parseErr
+		}
+		fieldValue.SetFloat(floatValue)
+	case reflect.Slice:
+		if fieldValue.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported field type: %s", fieldValue.Type())
+		}
+		values := strings.Split(value, ",")
+		for i := range values {
+			values[i] = strings.TrimSpace(values[i])
+		}
+		fieldValue.Set(reflect.ValueOf(values).Convert(fieldValue.Type()))
+	default:
+		return fmt.Errorf("unsupported field type: %s", fieldValue.Type())
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	sparta "github.com/mweagle/Sparta/v3"
+	spartaAPIGateway "github.com/mweagle/Sparta/v3/aws/apigateway"
+	"github.com/pkg/errors"
+)
+
+type typedTestRequest struct {
+	ID      int      `path:"id" validate:"min=1"`
+	Limit   *int     `query:"limit"`
+	Tags    []string `query:"tags"`
+	TraceID string   `header:"X-Trace-Id"`
+	Name    string   `json:"name" validate:"required"`
+}
+
+type typedTestResponse struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Limit   int    `json:"limit"`
+	TraceID string `json:"traceId"`
+}
+
+var errTypedTestNotFound = NewHTTPError(http.StatusNotFound, errors.New("not found"))
+
+func typedTestHandler(ctx context.Context, req typedTestRequest) (*typedTestResponse, error) {
+	switch req.Name {
+	case "missing":
+		return nil, errors.Wrapf(errTypedTestNotFound, "looking up %d", req.ID)
+	case "boom":
+		return nil, errors.New("internal details")
+	}
+	resp := &typedTestResponse{
+		ID:      req.ID,
+		Name:    req.Name,
+		TraceID: req.TraceID,
+	}
+	if req.Limit != nil {
+		resp.Limit = *req.Limit
+	}
+	return resp, nil
+}
+
+type typedTestResource struct {
+}
+
+func (resource *typedTestResource) ResourceDefinition() (ResourceDefinition, error) {
+	return ResourceDefinition{
+		URL: "/items/{id}",
+		MethodHandlers: MethodHandlerMap{
+			http.MethodPut: NewTypedHandler(typedTestHandler, http.StatusOK).
+				StatusCodes(http.StatusBadRequest, http.StatusNotFound),
+		},
+	}, nil
+}
+
+func invokeTypedHandler(t *testing.T,
+	methodHandler *MethodHandler,
+	event string) *spartaAPIGateway.Response {
+	var request typedRequest
+	unmarshalErr := json.Unmarshal([]byte(event), &request)
+	if unmarshalErr != nil {
+		t.Fatal(unmarshalErr)
+	}
+	lambdaHandler := methodHandler.Handler.(func(context.Context,
+		typedRequest) (*spartaAPIGateway.Response, error))
+	resp, respErr := lambdaHandler(context.Background(), request)
+	if respErr != nil {
+		t.Fatal(respErr)
+	}
+	return resp
+}
+
+func TestTypedHandler(t *testing.T) {
+	methodHandler := NewTypedHandler(typedTestHandler, http.StatusOK)
+
+	resp := invokeTypedHandler(t, methodHandler, `{
+		"pathParams": {"id": "42"},
+		"queryParams": {"limit": "10", "tags": "a, b"},
+		"headers": {"x-trace-id": "trace"},
+		"body": {"name": "widget"}
+	}`)
+	typedResp, typedRespOk := resp.Body.(*typedTestResponse)
+	if resp.Code != http.StatusOK || !typedRespOk {
+		t.Fatalf("Unexpected response: %#v", resp)
+	}
+	expected := typedTestResponse{ID: 42, Name: "widget", Limit: 10, TraceID: "trace"}
+	if *typedResp != expected {
+		t.Fatalf("Unexpected response body: %#v", typedResp)
+	}
+
+	testCases := map[string]struct {
+		event string
+		code  int
+	}{
+		"invalid path param": {
+			`{"pathParams": {"id": "abc"}, "body": {"name": "widget"}}`,
+			http.StatusBadRequest,
+		},
+		"failed validation": {
+			`{"pathParams": {"id": "0"}, "body": {}}`,
+			http.StatusBadRequest,
+		},
+		"invalid body": {
+			`{"pathParams": {"id": "1"}, "body": "text"}`,
+			http.StatusBadRequest,
+		},
+		"typed error": {
+			`{"pathParams": {"id": "1"}, "body": {"name": "missing"}}`,
+			http.StatusNotFound,
+		},
+		"untyped error": {
+			`{"pathParams": {"id": "1"}, "body": {"name": "boom"}}`,
+			http.StatusInternalServerError,
+		},
+	}
+	for eachName, eachTestCase := range testCases {
+		resp := invokeTypedHandler(t, methodHandler, eachTestCase.event)
+		apigErr, apigErrOk := resp.Body.(*spartaAPIGateway.Error)
+		if resp.Code != eachTestCase.code || !apigErrOk || apigErr.Code != eachTestCase.code {
+			t.Fatalf("%s: unexpected response: %#v", eachName, resp)
+		}
+		if eachTestCase.code == http.StatusInternalServerError && apigErr.Message != "" {
+			t.Fatalf("%s: leaked error message: %s", eachName, apigErr.Message)
+		}
+		t.Logf("%s: %s", eachName, apigErr.Error())
+	}
+}
+
+func TestRegisterTypedResource(t *testing.T) {
+	apiGateway := sparta.NewAPIGateway("TypedAPI", sparta.NewStage("v1"))
+	lambdaFns, lambdaFnsErr := RegisterResource(apiGateway, &typedTestResource{})
+	if lambdaFnsErr != nil {
+		t.Fatal(lambdaFnsErr)
+	}
+	if len(lambdaFns) != 1 {
+		t.Fatalf("Expected 1 lambda function, got: %d", len(lambdaFns))
+	}
+}
@@ -1,3 +1,6 @@
 /*Package apigateway provides a standard serialization format to wrap API Gateway
-responses that translate into specific end-user errors.*/
+responses that translate into specific end-user errors. NewHTTPResponse and
+NewHTTPErrorResponse return HTTP API (payload format 2.0) responses. Problem
+provides RFC 7807 problem details errors and ContentNegotiator encodes
+response bodies using the media type and compression requested by the client.*/
 package apigateway
@@ -0,0 +1,103 @@
+package apigateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// The CBOR (RFC 8949) and MessagePack encoders support the JSON data
+// model. Values are first converted to their JSON representation so that
+// `json` struct tags and json.Marshaler implementations are respected.
+// The converted values are encoded by the fxamacker/cbor and
+// vmihailenco/msgpack codecs with sorted map keys.
+
+// cborEncMode encodes map keys in the RFC 8949 core deterministic order
+// and floating point values as float64
+var cborEncMode, cborEncModeErr = cbor.EncOptions{
+	Sort:          cbor.SortCoreDeterministic,
+	ShortestFloat: cbor.ShortestFloatNone,
+}.EncMode()
+
+// jsonDataModel returns the JSON representation of the value as nil,
+// bool, int64, uint64, float64, string, []interface{} and
+// map[string]interface{} values
+func jsonDataModel(value interface{}) (interface{}, error) {
+	jsonBytes, jsonBytesErr := json.Marshal(value)
+	if jsonBytesErr != nil {
+		return nil, jsonBytesErr
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.UseNumber()
+	var model interface{}
+	decodeErr := decoder.Decode(&model)
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return jsonNumbers(model)
+}
+
+// jsonNumbers replaces the json.Number values with the int64, uint64 or
+// float64 value that exactly represents them, in that order
+func jsonNumbers(model interface{}) (interface{}, error) {
+	switch typedValue := model.(type) {
+	case json.Number:
+		if intValue, intValueErr := typedValue.Int64(); intValueErr == nil {
+			return intValue, nil
+		}
+		if uintValue, uintValueErr := strconv.ParseUint(typedValue.String(), 10, 64); uintValueErr == nil {
+			return uintValue, nil
+		}
+		return typedValue.Float64()
+	case []interface{}:
+		for eachIndex, eachValue := range typedValue {
+			number, numberErr := jsonNumbers(eachValue)
+			if numberErr != nil {
+				return nil, numberErr
+			}
+			typedValue[eachIndex] = number
+		}
+	case map[string]interface{}:
+		for eachKey, eachValue := range typedValue {
+			number, numberErr := jsonNumbers(eachValue)
+			if numberErr != nil {
+				return nil, numberErr
+			}
+			typedValue[eachKey] = number
+		}
+	}
+	return model, nil
+}
+
+// encodeCBOR returns the CBOR encoding of the value
+func encodeCBOR(value interface{}) ([]byte, error) {
+	if cborEncModeErr != nil {
+		return nil, cborEncModeErr
+	}
+	model, modelErr := jsonDataModel(value)
+	if modelErr != nil {
+		return nil, modelErr
+	}
+	return cborEncMode.Marshal(model)
+}
+
+// encodeMessagePack returns the MessagePack encoding of the value using
+// the smallest integer formats
+func encodeMessagePack(value interface{}) ([]byte, error) {
+	model, modelErr := jsonDataModel(value)
+	if modelErr != nil {
+		return nil, modelErr
+	}
+	var buffer bytes.Buffer
+	encoder := msgpack.NewEncoder(&buffer)
+	encoder.SetSortMapKeys(true)
+	encoder.UseCompactInts(true)
+	encodeErr := encoder.Encode(model)
+	if encodeErr != nil {
+		return nil, encodeErr
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,140 @@
+package apigateway
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// testCBORDecMode decodes CBOR maps with string keys to
+// map[string]interface{} values
+var testCBORDecMode, _ = cbor.DecOptions{
+	DefaultMapType: reflect.TypeOf(map[string]interface{}{}),
+}.DecMode()
+
+// testDecodedJSON returns the JSON encoding of the decoded value so that
+// it can be compared independently of the decoder's number types
+func testDecodedJSON(t *testing.T, unmarshal func([]byte, interface{}) error, encoded []byte) string {
+	var decoded interface{}
+	decodeErr := unmarshal(encoded, &decoded)
+	if decodeErr != nil {
+		t.Fatal(decodeErr)
+	}
+	decodedJSON, decodedJSONErr := json.Marshal(decoded)
+	if decodedJSONErr != nil {
+		t.Fatal(decodedJSONErr)
+	}
+	return string(decodedJSON)
+}
+
+func testArray(length int) []interface{} {
+	array := make([]interface{}, length)
+	for i := range array {
+		array[i] = i % 3
+	}
+	return array
+}
+
+func testMap(length int) map[string]interface{} {
+	mapValue := make(map[string]interface{}, length)
+	for i := 0; i < length; i++ {
+		mapValue[strconv.Itoa(i)] = i
+	}
+	return mapValue
+}
+
+func TestEncodersRoundTrip(t *testing.T) {
+	type testCase struct {
+		value interface{}
+		// Expected leading bytes of the CBOR and MessagePack encodings
+		cborHead    []byte
+		msgPackHead []byte
+	}
+	testCases := map[string]testCase{
+		"zero":          {0, []byte{0x00}, []byte{0x00}},
+		"uint 23":       {23, []byte{0x17}, []byte{0x17}},
+		"uint 24":       {24, []byte{0x18, 0x18}, []byte{0x18}},
+		"uint 127":      {127, []byte{0x18, 0x7f}, []byte{0x7f}},
+		"uint 128":      {128, []byte{0x18, 0x80}, []byte{0xcc, 0x80}},
+		"uint 255":      {255, []byte{0x18, 0xff}, []byte{0xcc, 0xff}},
+		"uint 256":      {256, []byte{0x19, 0x01, 0x00}, []byte{0xcd, 0x01, 0x00}},
+		"uint 65535":    {65535, []byte{0x19, 0xff, 0xff}, []byte{0xcd, 0xff, 0xff}},
+		"uint 65536":    {65536, []byte{0x1a, 0x00, 0x01}, []byte{0xce, 0x00, 0x01}},
+		"uint32 max":    {uint64(math.MaxUint32), []byte{0x1a, 0xff}, []byte{0xce, 0xff}},
+		"uint32 max+1":  {uint64(math.MaxUint32) + 1, []byte{0x1b, 0x00}, []byte{0xcf, 0x00}},
+		"int64 max":     {int64(math.MaxInt64), []byte{0x1b, 0x7f}, []byte{0xcf, 0x7f}},
+		"int64 max+1":   {uint64(math.MaxInt64) + 1, []byte{0x1b, 0x80}, []byte{0xcf, 0x80}},
+		"uint64 max":    {uint64(math.MaxUint64), []byte{0x1b, 0xff}, []byte{0xcf, 0xff}},
+		"neg 1":         {-1, []byte{0x20}, []byte{0xff}},
+		"neg 24":        {-24, []byte{0x37}, []byte{0xe8}},
+		"neg 25":        {-25, []byte{0x38, 0x18}, []byte{0xe7}},
+		"neg 32":        {-32, []byte{0x38, 0x1f}, []byte{0xe0}},
+		"neg 33":        {-33, []byte{0x38, 0x20}, []byte{0xd0, 0xdf}},
+		"neg 128":       {-128, []byte{0x38, 0x7f}, []byte{0xd0, 0x80}},
+		"neg 129":       {-129, []byte{0x38, 0x80}, []byte{0xd1, 0xff, 0x7f}},
+		"neg 256":       {-256, []byte{0x38, 0xff}, []byte{0xd1, 0xff, 0x00}},
+		"neg 257":       {-257, []byte{0x39, 0x01, 0x00}, []byte{0xd1, 0xfe, 0xff}},
+		"neg 32769":     {-32769, []byte{0x39, 0x80, 0x00}, []byte{0xd2, 0xff, 0xff}},
+		"neg 65537":     {-65537, []byte{0x3a, 0x00, 0x01}, []byte{0xd2, 0xff, 0xfe}},
+		"int32 min-1":   {int64(math.MinInt32) - 1, []byte{0x3a, 0x80}, []byte{0xd3, 0xff}},
+		"int64 min":     {int64(math.MinInt64), []byte{0x3b, 0x7f}, []byte{0xd3, 0x80}},
+		"float":         {-0.25, []byte{0xfb, 0xbf, 0xd0}, []byte{0xcb, 0xbf, 0xd0}},
+		"string 0":      {"", []byte{0x60}, []byte{0xa0}},
+		"string 23":     {strings.Repeat("x", 23), []byte{0x77}, []byte{0xb7}},
+		"string 24":     {strings.Repeat("x", 24), []byte{0x78, 0x18}, []byte{0xb8}},
+		"string 31":     {strings.Repeat("x", 31), []byte{0x78, 0x1f}, []byte{0xbf}},
+		"string 32":     {strings.Repeat("x", 32), []byte{0x78, 0x20}, []byte{0xd9, 0x20}},
+		"string 255":    {strings.Repeat("x", 255), []byte{0x78, 0xff}, []byte{0xd9, 0xff}},
+		"string 256":    {strings.Repeat("x", 256), []byte{0x79, 0x01, 0x00}, []byte{0xda, 0x01, 0x00}},
+		"string 65535":  {strings.Repeat("x", 65535), []byte{0x79, 0xff, 0xff}, []byte{0xda, 0xff, 0xff}},
+		"string 65536":  {strings.Repeat("x", 65536), []byte{0x7a, 0x00, 0x01, 0x00, 0x00}, []byte{0xdb, 0x00, 0x01, 0x00, 0x00}},
+		"string utf8":   {"héllo ✓", []byte{0x6a}, []byte{0xaa}},
+		"array 15":      {testArray(15), []byte{0x8f}, []byte{0x9f}},
+		"array 16":      {testArray(16), []byte{0x90}, []byte{0xdc, 0x00, 0x10}},
+		"array 23":      {testArray(23), []byte{0x97}, []byte{0xdc, 0x00, 0x17}},
+		"array 24":      {testArray(24), []byte{0x98, 0x18}, []byte{0xdc, 0x00, 0x18}},
+		"array 256":     {testArray(256), []byte{0x99, 0x01, 0x00}, []byte{0xdc, 0x01, 0x00}},
+		"array 65536":   {testArray(65536), []byte{0x9a, 0x00, 0x01, 0x00, 0x00}, []byte{0xdd, 0x00, 0x01, 0x00, 0x00}},
+		"map 15":        {testMap(15), []byte{0xaf}, []byte{0x8f}},
+		"map 16":        {testMap(16), []byte{0xb0}, []byte{0xde, 0x00, 0x10}},
+		"map 24":        {testMap(24), []byte{0xb8, 0x18}, []byte{0xde, 0x00, 0x18}},
+		"map 65536":     {testMap(65536), []byte{0xba, 0x00, 0x01, 0x00, 0x00}, []byte{0xdf, 0x00, 0x01, 0x00, 0x00}},
+		"nested":        {testEncodingBody, []byte{0xa4}, []byte{0x84}},
+		"nested arrays": {[]interface{}{[]interface{}{}, map[string]interface{}{}, nil, false}, []byte{0x84, 0x80, 0xa0, 0xf6, 0xf4}, []byte{0x94, 0x90, 0x80, 0xc0, 0xc2}},
+	}
+	for eachName, eachTestCase := range testCases {
+		expected, expectedErr := json.Marshal(eachTestCase.value)
+		if expectedErr != nil {
+			t.Fatal(expectedErr)
+		}
+		encoders := []struct {
+			name      string
+			encode    func(interface{}) ([]byte, error)
+			unmarshal func([]byte, interface{}) error
+			head      []byte
+		}{
+			{"CBOR", encodeCBOR, testCBORDecMode.Unmarshal, eachTestCase.cborHead},
+			{"MessagePack", encodeMessagePack, msgpack.Unmarshal, eachTestCase.msgPackHead},
+		}
+		for _, eachEncoder := range encoders {
+			encoded, encodedErr := eachEncoder.encode(eachTestCase.value)
+			if encodedErr != nil {
+				t.Fatalf("Failed to %s encode %s: %s", eachEncoder.name, eachName, encodedErr)
+			}
+			if len(encoded) < len(eachEncoder.head) ||
+				!reflect.DeepEqual(encoded[:len(eachEncoder.head)], eachEncoder.head) {
+				t.Fatalf("Unexpected %s header for %s: %x", eachEncoder.name, eachName, encoded[:len(eachEncoder.head)])
+			}
+			decoded := testDecodedJSON(t, eachEncoder.unmarshal, encoded)
+			if decoded != string(expected) {
+				t.Fatalf("Failed to %s round trip %s: %.200s", eachEncoder.name, eachName, decoded)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package apigateway
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
+)
+
+// NewHTTPResponse returns an HTTP API response that uses the 2.0 Lambda
+// proxy payload format. String bodies are returned as-is, []byte bodies
+// are base64 encoded, and other values are JSON encoded with an
+// application/json Content-Type. Header names are lowercased.
+func NewHTTPResponse(statusCode int,
+	body interface{},
+	headers ...map[string]string) (*awsLambdaEvents.APIGatewayV2HTTPResponse, error) {
+
+	response := &awsLambdaEvents.APIGatewayV2HTTPResponse{
+		StatusCode: statusCode,
+		Headers:    make(map[string]string),
+	}
+	switch typedBody := body.(type) {
+	case nil:
+	case string:
+		response.Body = typedBody
+	case []byte:
+		response.Body = base64.StdEncoding.EncodeToString(typedBody)
+		response.IsBase64Encoded = true
+	default:
+		jsonBody, jsonBodyErr := json.Marshal(typedBody)
+		if jsonBodyErr != nil {
+			return nil, jsonBodyErr
+		}
+		response.Body = string(jsonBody)
+		response.Headers["content-type"] = "application/json"
+	}
+	for _, eachHeaderMap := range headers {
+		for eachKey, eachValue := range eachHeaderMap {
+			response.Headers[strings.ToLower(eachKey)] = eachValue
+		}
+	}
+	return response, nil
+}
+
+// NewHTTPErrorResponse returns an HTTP API response whose JSON body is the
+// Error for the status code and messages. See NewErrorResponse.
+func NewHTTPErrorResponse(statusCode int,
+	messages ...interface{}) *awsLambdaEvents.APIGatewayV2HTTPResponse {
+
+	apigError := NewErrorResponse(statusCode, messages...)
+	return &awsLambdaEvents.APIGatewayV2HTTPResponse{
+		StatusCode: apigError.Code,
+		Headers: map[string]string{
+			"content-type": "application/json",
+		},
+		Body: apigError.Error(),
+	}
+}
@@ -0,0 +1,391 @@
+package httpadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
+	"github.com/pkg/errors"
+)
+
+type contextKey int
+
+const (
+	// contextKeyEvent is the context key for the Lambda event
+	contextKeyEvent contextKey = iota
+)
+
+const (
+	headerContentType     = "Content-Type"
+	headerContentEncoding = "Content-Encoding"
+	headerSetCookie       = "Set-Cookie"
+)
+
+// eventProbe is the subset of event fields used to identify the
+// event source
+type eventProbe struct {
+	Version        string `json:"version"`
+	HTTPMethod     string `json:"httpMethod"`
+	RequestContext struct {
+		ELB  json.RawMessage `json:"elb"`
+		HTTP json.RawMessage `json:"http"`
+	} `json:"requestContext"`
+}
+
+// Event returns the Lambda event for the request context. The value is
+// a *events.APIGatewayProxyRequest, *events.APIGatewayV2HTTPRequest
+// (HTTP APIs and Function URLs) or *events.ALBTargetGroupRequest.
+func Event(ctx context.Context) interface{} {
+	return ctx.Value(contextKeyEvent)
+}
+
+// NewHandler returns a Lambda handler that serves REST API proxy, HTTP
+// API, ALB target group and Function URL events with the http.Handler.
+// The response is an *events.APIGatewayProxyResponse,
+// *events.APIGatewayV2HTTPResponse or *events.ALBTargetGroupResponse
+// respectively. Response bodies that aren't text are base64 encoded.
+func NewHandler(handler http.Handler) func(context.Context, json.RawMessage) (interface{}, error) {
+	return func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+		var probe eventProbe
+		probeErr := json.Unmarshal(event, &probe)
+		if probeErr != nil {
+			return nil, errors.Wrapf(probeErr, "Failed to unmarshal HTTP event")
+		}
+		switch {
+		case len(probe.RequestContext.ELB) != 0:
+			return serveALB(ctx, handler, event)
+		case probe.Version == "2.0" || len(probe.RequestContext.HTTP) != 0:
+			return serveV2(ctx, handler, event)
+		case probe.HTTPMethod != "":
+			return serveProxy(ctx, handler, event)
+		}
+		return nil, errors.Errorf("Unsupported HTTP event. Expected an API Gateway proxy, HTTP API, ALB or Function URL event")
+	}
+}
+
+// serveProxy handles a REST API proxy integration event
+func serveProxy(ctx context.Context,
+	handler http.Handler,
+	event json.RawMessage) (*awsLambdaEvents.APIGatewayProxyResponse, error) {
+
+	var proxyRequest awsLambdaEvents.APIGatewayProxyRequest
+	unmarshalErr := json.Unmarshal(event, &proxyRequest)
+	if unmarshalErr != nil {
+		return nil, errors.Wrapf(unmarshalErr, "Failed to unmarshal API Gateway proxy event")
+	}
+	// Parameter values are decoded by API Gateway
+	query := url.Values(proxyRequest.MultiValueQueryStringParameters)
+	if len(query) == 0 {
+		query = make(url.Values)
+		for eachKey, eachValue := range proxyRequest.QueryStringParameters {
+			query.Set(eachKey, eachValue)
+		}
+	}
+	requestURL := &url.URL{
+		Path:     proxyRequest.Path,
+		RawQuery: query.Encode(),
+	}
+	headers := newHeader(proxyRequest.MultiValueHeaders, proxyRequest.Headers)
+	request, requestErr := newRequest(context.WithValue(ctx, contextKeyEvent, &proxyRequest),
+		proxyRequest.HTTPMethod,
+		requestURL,
+		headers,
+		proxyRequest.Body,
+		proxyRequest.IsBase64Encoded)
+	if requestErr != nil {
+		return nil, requestErr
+	}
+	request.RemoteAddr = proxyRequest.RequestContext.Identity.SourceIP
+
+	writer := newResponseWriter()
+	handler.ServeHTTP(writer, request)
+	body, isBase64Encoded := writer.encodedBody()
+	return &awsLambdaEvents.APIGatewayProxyResponse{
+		StatusCode:        writer.status(),
+		MultiValueHeaders: writer.header,
+		Body:              body,
+		IsBase64Encoded:   isBase64Encoded,
+	}, nil
+}
+
+// serveV2 handles an HTTP API or Function URL event. Both use the 2.0
+// payload format.
+func serveV2(ctx context.Context,
+	handler http.Handler,
+	event json.RawMessage) (*awsLambdaEvents.APIGatewayV2HTTPResponse, error) {
+
+	var v2Request awsLambdaEvents.APIGatewayV2HTTPRequest
+	unmarshalErr := json.Unmarshal(event, &v2Request)
+	if unmarshalErr != nil {
+		return nil, errors.Wrapf(unmarshalErr, "Failed to unmarshal HTTP API event")
+	}
+	rawPath := v2Request.RawPath
+	if rawPath == "" {
+		rawPath = v2Request.RequestContext.HTTP.Path
+	}
+	requestURL, requestURLErr := url.Parse(rawPath)
+	if requestURLErr != nil {
+		return nil, errors.Wrapf(requestURLErr, "Failed to parse request path: %s", rawPath)
+	}
+	requestURL.RawQuery = v2Request.RawQueryString
+
+	// Multiple header values are comma separated and cookies are
+	// delivered separately
+	headers := newHeader(nil, v2Request.Headers)
+	if len(v2Request.Cookies) != 0 {
+		headers.Set("Cookie", strings.Join(v2Request.Cookies, "; "))
+	}
+	request, requestErr := newRequest(context.WithValue(ctx, contextKeyEvent, &v2Request),
+		v2Request.RequestContext.HTTP.Method,
+		requestURL,
+		headers,
+		v2Request.Body,
+		v2Request.IsBase64Encoded)
+	if requestErr != nil {
+		return nil, requestErr
+	}
+	request.RemoteAddr = v2Request.RequestContext.HTTP.SourceIP
+
+	writer := newResponseWriter()
+	handler.ServeHTTP(writer, request)
+	body, isBase64Encoded := writer.encodedBody()
+	response := &awsLambdaEvents.APIGatewayV2HTTPResponse{
+		StatusCode:      writer.status(),
+		Headers:         make(map[string]string),
+		Body:            body,
+		IsBase64Encoded: isBase64Encoded,
+	}
+	for eachKey, eachValues := range writer.header {
+		if http.CanonicalHeaderKey(eachKey) == headerSetCookie {
+			response.Cookies = append(response.Cookies, eachValues...)
+			continue
+		}
+		response.Headers[eachKey] = strings.Join(eachValues, ",")
+	}
+	return response, nil
+}
+
+// serveALB handles an ALB target group event
+func serveALB(ctx context.Context,
+	handler http.Handler,
+	event json.RawMessage) (*awsLambdaEvents.ALBTargetGroupResponse, error) {
+
+	var albRequest awsLambdaEvents.ALBTargetGroupRequest
+	unmarshalErr := json.Unmarshal(event, &albRequest)
+	if unmarshalErr != nil {
+		return nil, errors.Wrapf(unmarshalErr, "Failed to unmarshal ALB event")
+	}
+	multiValue := albRequest.MultiValueHeaders != nil
+	requestURL, requestURLErr := url.Parse(albRequest.Path)
+	if requestURLErr != nil {
+		return nil, errors.Wrapf(requestURLErr, "Failed to parse request path: %s", albRequest.Path)
+	}
+	// Parameter values are passed through as they were sent, so they're
+	// already URL encoded
+	query := albRequest.MultiValueQueryStringParameters
+	if query == nil {
+		query = make(map[string][]string)
+		for eachKey, eachValue := range albRequest.QueryStringParameters {
+			query[eachKey] = []string{eachValue}
+		}
+	}
+	queryKeys := make([]string, 0, len(query))
+	for eachKey := range query {
+		queryKeys = append(queryKeys, eachKey)
+	}
+	sort.Strings(queryKeys)
+	queryParts := make([]string, 0, len(query))
+	for _, eachKey := range queryKeys {
+		for _, eachValue := range query[eachKey] {
+			queryParts = append(queryParts, fmt.Sprintf("%s=%s", eachKey, eachValue))
+		}
+	}
+	requestURL.RawQuery = strings.Join(queryParts, "&")
+
+	headers := newHeader(albRequest.MultiValueHeaders, albRequest.Headers)
+	request, requestErr := newRequest(context.WithValue(ctx, contextKeyEvent, &albRequest),
+		albRequest.HTTPMethod,
+		requestURL,
+		headers,
+		albRequest.Body,
+		albRequest.IsBase64Encoded)
+	if requestErr != nil {
+		return nil, requestErr
+	}
+	if forwardedFor := headers.Get("X-Forwarded-For"); forwardedFor != "" {
+		request.RemoteAddr = strings.TrimSpace(strings.Split(forwardedFor, ",")[0])
+	}
+
+	writer := newResponseWriter()
+	handler.ServeHTTP(writer, request)
+	body, isBase64Encoded := writer.encodedBody()
+	statusCode := writer.status()
+	response := &awsLambdaEvents.ALBTargetGroupResponse{
+		StatusCode:        statusCode,
+		StatusDescription: fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		Body:              body,
+		IsBase64Encoded:   isBase64Encoded,
+	}
+	// The response must use the same header format as the request
+	if multiValue {
+		response.MultiValueHeaders = writer.header
+	} else {
+		response.Headers = make(map[string]string)
+		for eachKey, eachValues := range writer.header {
+			if http.CanonicalHeaderKey(eachKey) == headerSetCookie {
+				response.Headers[eachKey] = eachValues[len(eachValues)-1]
+				continue
+			}
+			response.Headers[eachKey] = strings.Join(eachValues, ",")
+		}
+	}
+	return response, nil
+}
+
+// newHeader returns the canonicalized request headers. Multi-value
+// headers are preferred if they're available.
+func newHeader(multiValueHeaders map[string][]string, headers map[string]string) http.Header {
+	header := make(http.Header)
+	if len(multiValueHeaders) != 0 {
+		for eachKey, eachValues := range multiValueHeaders {
+			for _, eachValue := range eachValues {
+				header.Add(eachKey, eachValue)
+			}
+		}
+		return header
+	}
+	for eachKey, eachValue := range headers {
+		header.Set(eachKey, eachValue)
+	}
+	return header
+}
+
+// newRequest returns the *http.Request for the event values
+func newRequest(ctx context.Context,
+	httpMethod string,
+	requestURL *url.URL,
+	headers http.Header,
+	body string,
+	isBase64Encoded bool) (*http.Request, error) {
+
+	bodyBytes := []byte(body)
+	if isBase64Encoded {
+		decodedBytes, decodedBytesErr := base64.StdEncoding.DecodeString(body)
+		if decodedBytesErr != nil {
+			return nil, errors.Wrapf(decodedBytesErr, "Failed to decode base64 request body")
+		}
+		bodyBytes = decodedBytes
+	}
+	request, requestErr := http.NewRequestWithContext(ctx,
+		httpMethod,
+		requestURL.String(),
+		bytes.NewReader(bodyBytes))
+	if requestErr != nil {
+		return nil, errors.Wrapf(requestErr, "Failed to create %s request", httpMethod)
+	}
+	request.Header = headers
+	request.Host = headers.Get("Host")
+	request.URL.Host = request.Host
+	request.URL.Scheme = "https"
+	if forwardedProto := headers.Get("X-Forwarded-Proto"); forwardedProto != "" {
+		request.URL.Scheme = forwardedProto
+	}
+	request.RequestURI = requestURL.RequestURI()
+	return request, nil
+}
+
+// responseWriter is the http.ResponseWriter that collects the response
+type responseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newResponseWriter() *responseWriter {
+	return &responseWriter{
+		header: make(http.Header),
+	}
+}
+
+// Header returns the response headers
+func (writer *responseWriter) Header() http.Header {
+	return writer.header
+}
+
+// Write appends the data to the response body
+func (writer *responseWriter) Write(data []byte) (int, error) {
+	if writer.statusCode == 0 {
+		writer.WriteHeader(http.StatusOK)
+	}
+	return writer.body.Write(data)
+}
+
+// WriteHeader sets the response status code. Only the first call
+// has an effect.
+func (writer *responseWriter) WriteHeader(statusCode int) {
+	if writer.statusCode != 0 {
+		return
+	}
+	writer.statusCode = statusCode
+}
+
+// status returns the response status code
+func (writer *responseWriter) status() int {
+	if writer.statusCode == 0 {
+		return http.StatusOK
+	}
+	return writer.statusCode
+}
+
+// encodedBody returns the response body and whether it's base64 encoded.
+// Like net/http, the Content-Type is detected if the handler didn't
+// set one.
+func (writer *responseWriter) encodedBody() (string, bool) {
+	bodyBytes := writer.body.Bytes()
+	if len(bodyBytes) == 0 {
+		return "", false
+	}
+	if _, hasContentType := writer.header[headerContentType]; !hasContentType {
+		writer.header.Set(headerContentType, http.DetectContentType(bodyBytes))
+	}
+	if isTextContent(writer.header) && utf8.Valid(bodyBytes) {
+		return string(bodyBytes), false
+	}
+	return base64.StdEncoding.EncodeToString(bodyBytes), true
+}
+
+// isTextContent returns true if the response headers describe an
+// unencoded text response
+func isTextContent(header http.Header) bool {
+	contentEncoding := header.Get(headerContentEncoding)
+	if contentEncoding != "" && contentEncoding != "identity" {
+		return false
+	}
+	mediaType, _, mediaTypeErr := mime.ParseMediaType(header.Get(headerContentType))
+	if mediaTypeErr != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json",
+		"application/xml",
+		"application/javascript",
+		"application/x-www-form-urlencoded":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package httpadapter
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
+)
+
+var testBinaryBody = []byte{0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0xff}
+
+func testMux() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Add("X-Multi", "one")
+		w.Header().Add("X-Multi", "two")
+		http.SetCookie(w, &http.Cookie{Name: "a", Value: "1"})
+		http.SetCookie(w, &http.Cookie{Name: "b", Value: "2"})
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		cookie, _ := r.Cookie("session")
+		sessionValue := ""
+		if cookie != nil {
+			sessionValue = cookie.Value
+		}
+		fmt.Fprintf(w, "%s %s q=%v h=%v session=%s event=%T body=%s",
+			r.Method,
+			r.URL.Path,
+			r.URL.Query()["q"],
+			r.Header.Values("X-Test"),
+			sessionValue,
+			Event(r.Context()),
+			string(body))
+	})
+	mux.HandleFunc("/binary", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(testBinaryBody)
+	})
+	return mux
+}
+
+func invoke(t *testing.T, event interface{}) json.RawMessage {
+	eventBytes, eventBytesErr := json.Marshal(event)
+	if eventBytesErr != nil {
+		t.Fatal(eventBytesErr)
+	}
+	response, responseErr := NewHandler(testMux())(context.Background(), eventBytes)
+	if responseErr != nil {
+		t.Fatal(responseErr)
+	}
+	responseBytes, responseBytesErr := json.Marshal(response)
+	if responseBytesErr != nil {
+		t.Fatal(responseBytesErr)
+	}
+	return responseBytes
+}
+
+func TestProxyEvent(t *testing.T) {
+	responseBytes := invoke(t, &awsLambdaEvents.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodPost,
+		Path:       "/echo",
+		MultiValueHeaders: map[string][]string{
+			"x-test": {"1", "2"},
+			"cookie": {"session=abc"},
+		},
+		MultiValueQueryStringParameters: map[string][]string{
+			"q": {"a b", "c"},
+		},
+		Body:            base64.StdEncoding.EncodeToString([]byte("payload")),
+		IsBase64Encoded: true,
+	})
+	var response awsLambdaEvents.APIGatewayProxyResponse
+	_ = json.Unmarshal(responseBytes, &response)
+	expectedBody := "POST /echo q=[a b c] h=[1 2] session=abc event=*events.APIGatewayProxyRequest body=payload"
+	if response.StatusCode != http.StatusCreated ||
+		response.Body != expectedBody ||
+		response.IsBase64Encoded {
+		t.Fatalf("Unexpected response: %#v", response)
+	}
+	if len(response.MultiValueHeaders["X-Multi"]) != 2 ||
+		len(response.MultiValueHeaders["Set-Cookie"]) != 2 {
+		t.Fatalf("Unexpected response headers: %#v", response.MultiValueHeaders)
+	}
+}
+
+func TestV2Event(t *testing.T) {
+	// Function URL events use the same payload format
+	request := &awsLambdaEvents.APIGatewayV2HTTPRequest{
+		Version:        "2.0",
+		RawPath:        "/echo",
+		RawQueryString: "q=a%20b&q=c",
+		Cookies:        []string{"session=abc", "other=1"},
+		Headers: map[string]string{
+			"x-test": "1,2",
+		},
+		Body: "payload",
+	}
+	request.RequestContext.HTTP.Method = http.MethodPut
+	responseBytes := invoke(t, request)
+	var response awsLambdaEvents.APIGatewayV2HTTPResponse
+	_ = json.Unmarshal(responseBytes, &response)
+	expectedBody := "PUT /echo q=[a b c] h=[1,2] session=abc event=*events.APIGatewayV2HTTPRequest body=payload"
+	if response.StatusCode != http.StatusCreated || response.Body != expectedBody {
+		t.Fatalf("Unexpected response: %#v", response)
+	}
+	if response.Headers["X-Multi"] != "one,two" ||
+		len(response.Cookies) != 2 ||
+		response.Headers["Set-Cookie"] != "" {
+		t.Fatalf("Unexpected response headers: %#v", response)
+	}
+
+	request.RawPath = "/binary"
+	responseBytes = invoke(t, request)
+	response = awsLambdaEvents.APIGatewayV2HTTPResponse{}
+	_ = json.Unmarshal(responseBytes, &response)
+	if !response.IsBase64Encoded ||
+		response.Body != base64.StdEncoding.EncodeToString(testBinaryBody) ||
+		response.Headers["Content-Type"] != "image/png" {
+		t.Fatalf("Unexpected binary response: %#v", response)
+	}
+}
+
+func TestALBEvent(t *testing.T) {
+	request := &awsLambdaEvents.ALBTargetGroupRequest{
+		HTTPMethod: http.MethodGet,
+		Path:       "/echo",
+		QueryStringParameters: map[string]string{
+			"q": "a%20b",
+		},
+		Headers: map[string]string{
+			"x-test":          "1",
+			"x-forwarded-for": "10.0.0.1, 10.0.0.2",
+		},
+	}
+	request.RequestContext.ELB.TargetGroupArn = "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/test/abc"
+	responseBytes := invoke(t, request)
+	var response awsLambdaEvents.ALBTargetGroupResponse
+	_ = json.Unmarshal(responseBytes, &response)
+	expectedBody := "GET /echo q=[a b] h=[1] session= event=*events.ALBTargetGroupRequest body="
+	if response.StatusCode != http.StatusCreated ||
+		response.StatusDescription != "201 Created" ||
+		response.Body != expectedBody {
+		t.Fatalf("Unexpected response: %#v", response)
+	}
+	if response.MultiValueHeaders != nil ||
+		response.Headers["X-Multi"] != "one,two" ||
+		response.Headers["Set-Cookie"] != "b=2" {
+		t.Fatalf("Unexpected response headers: %#v", response)
+	}
+
+	// Multi-value requests return multi-value headers
+	request.MultiValueHeaders = map[string][]string{
+		"x-test": {"1", "2"},
+	}
+	responseBytes = invoke(t, request)
+	response = awsLambdaEvents.ALBTargetGroupResponse{}
+	_ = json.Unmarshal(responseBytes, &response)
+	if len(response.MultiValueHeaders["Set-Cookie"]) != 2 || response.Headers != nil {
+		t.Fatalf("Unexpected multi-value response headers: %#v", response)
+	}
+}
+
+func TestUnsupportedEvent(t *testing.T) {
+	_, responseErr := NewHandler(testMux())(context.Background(), json.RawMessage(`{"Records": []}`))
+	if responseErr == nil {
+		t.Fatalf("Expected error for unsupported event")
+	}
+}
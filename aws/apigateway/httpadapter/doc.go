@@ -0,0 +1,7 @@
+// Package httpadapter runs standard net/http handlers in AWS Lambda.
+// NewHandler translates REST API proxy, HTTP API (payload format 2.0),
+// ALB target group and Lambda Function URL events into *http.Request
+// values and returns the handler's response in the shape expected by the
+// event source. Use sparta.API.NewProxyResource to route every request
+// for a REST API path to the handler.
+package httpadapter
@@ -0,0 +1,405 @@
+package apigateway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ContentTypeJSON is the JSON media type
+	ContentTypeJSON = "application/json"
+	// ContentTypeCBOR is the CBOR (RFC 8949) media type
+	ContentTypeCBOR = "application/cbor"
+	// ContentTypeMessagePack is the MessagePack media type
+	ContentTypeMessagePack = "application/msgpack"
+	// ContentTypeMessagePackLegacy is the unregistered MessagePack media
+	// type used by many clients
+	ContentTypeMessagePackLegacy = "application/x-msgpack"
+	// ContentTypeText is the plain text media type
+	ContentTypeText = "text/plain"
+	// DefaultCompressionThreshold is the minimum body size in bytes that's
+	// gzip compressed
+	DefaultCompressionThreshold = 1024
+)
+
+// ErrNotAcceptable is returned when the body can't be encoded using any
+// of the media types in the Accept header
+var ErrNotAcceptable = errors.New("No acceptable response media type")
+
+// bodyEncoder returns the encoded body
+type bodyEncoder func(body interface{}) ([]byte, error)
+
+// encodeText returns string, []byte, error and fmt.Stringer bodies
+func encodeText(body interface{}) ([]byte, error) {
+	switch typedBody := body.(type) {
+	case string:
+		return []byte(typedBody), nil
+	case []byte:
+		return typedBody, nil
+	case error:
+		return []byte(typedBody.Error()), nil
+	case fmt.Stringer:
+		return []byte(typedBody.String()), nil
+	}
+	return nil, fmt.Errorf("unsupported text body type: %T", body)
+}
+
+var bodyEncoders = map[string]bodyEncoder{
+	ContentTypeJSON:              json.Marshal,
+	ContentTypeCBOR:              encodeCBOR,
+	ContentTypeMessagePack:       encodeMessagePack,
+	ContentTypeMessagePackLegacy: encodeMessagePack,
+	ContentTypeText:              encodeText,
+}
+
+// NegotiatedBody is a response body encoded for the request
+type NegotiatedBody struct {
+	// ContentType is the media type of the body
+	ContentType string
+	// ContentEncoding is the body compression, if any
+	ContentEncoding string
+	// Body is the encoded body
+	Body []byte
+}
+
+// Headers returns the Content-Type, Content-Encoding and Vary headers
+// for the body
+func (negotiated *NegotiatedBody) Headers() map[string]string {
+	headers := map[string]string{
+		"content-type": negotiated.ContentType,
+		"vary":         "Accept, Accept-Encoding",
+	}
+	if negotiated.ContentType == ContentTypeText {
+		headers["content-type"] = ContentTypeText + "; charset=utf-8"
+	}
+	if negotiated.ContentEncoding != "" {
+		headers["content-encoding"] = negotiated.ContentEncoding
+	}
+	return headers
+}
+
+// isBinary returns true if the body must be base64 encoded
+func (negotiated *NegotiatedBody) isBinary() bool {
+	switch {
+	case negotiated.ContentEncoding != "":
+		return true
+	case negotiated.ContentType == ContentTypeJSON,
+		negotiated.ContentType == ContentTypeProblemJSON,
+		negotiated.ContentType == ContentTypeText:
+		return false
+	}
+	return true
+}
+
+// encodedBody returns the body and whether it's base64 encoded
+func (negotiated *NegotiatedBody) encodedBody() (string, bool) {
+	if negotiated.isBinary() {
+		return base64.StdEncoding.EncodeToString(negotiated.Body), true
+	}
+	return string(negotiated.Body), false
+}
+
+// ContentNegotiator encodes response bodies using the media type and
+// compression preferred by the request Accept and Accept-Encoding
+// headers. *Problem bodies are always encoded as application/problem+json.
+type ContentNegotiator struct {
+	// MediaTypes are the supported media types in order of preference.
+	// Eligible values are ContentTypeJSON, ContentTypeCBOR,
+	// ContentTypeMessagePack, ContentTypeMessagePackLegacy and
+	// ContentTypeText.
+	MediaTypes []string
+	// CompressionThreshold is the minimum body size in bytes that's gzip
+	// compressed if the client accepts it. Negative values disable
+	// compression.
+	CompressionThreshold int
+}
+
+// NewContentNegotiator returns a ContentNegotiator that supports JSON,
+// CBOR, MessagePack and plain text bodies and compresses bodies of at
+// least DefaultCompressionThreshold bytes
+func NewContentNegotiator() *ContentNegotiator {
+	return &ContentNegotiator{
+		MediaTypes: []string{
+			ContentTypeJSON,
+			ContentTypeCBOR,
+			ContentTypeMessagePack,
+			ContentTypeMessagePackLegacy,
+			ContentTypeText,
+		},
+		CompressionThreshold: DefaultCompressionThreshold,
+	}
+}
+
+// headerValue returns the case insensitive header value
+func headerValue(headers map[string]string, name string) string {
+	if value, valueOk := headers[name]; valueOk {
+		return value
+	}
+	for eachKey, eachValue := range headers {
+		if strings.EqualFold(eachKey, name) {
+			return eachValue
+		}
+	}
+	return ""
+}
+
+// acceptRange is a parsed Accept or Accept-Encoding header entry
+type acceptRange struct {
+	value   string
+	quality float64
+}
+
+// parseAccept returns the header entries
+func parseAccept(header string) []acceptRange {
+	ranges := make([]acceptRange, 0)
+	for _, eachPart := range strings.Split(header, ",") {
+		params := strings.Split(eachPart, ";")
+		value := strings.ToLower(strings.TrimSpace(params[0]))
+		if value == "" {
+			continue
+		}
+		entry := acceptRange{
+			value:   value,
+			quality: 1,
+		}
+		for _, eachParam := range params[1:] {
+			paramName, paramValue, paramOk := cutParam(eachParam)
+			if paramOk && paramName == "q" {
+				quality, qualityErr := strconv.ParseFloat(paramValue, 64)
+				if qualityErr == nil {
+					entry.quality = quality
+				}
+			}
+		}
+		ranges = append(ranges, entry)
+	}
+	return ranges
+}
+
+func cutParam(param string) (string, string, bool) {
+	index := strings.Index(param, "=")
+	if index < 0 {
+		return "", "", false
+	}
+	return strings.ToLower(strings.TrimSpace(param[:index])),
+		strings.TrimSpace(param[index+1:]),
+		true
+}
+
+// mediaTypeQuality returns the quality of the most specific matching
+// media range
+func mediaTypeQuality(ranges []acceptRange, mediaType string) float64 {
+	quality := 0.0
+	specificity := -1
+	mainType := strings.Split(mediaType, "/")[0]
+	for _, eachRange := range ranges {
+		rangeSpecificity := -1
+		switch {
+		case eachRange.value == mediaType:
+			rangeSpecificity = 2
+		case eachRange.value == mainType+"/*":
+			rangeSpecificity = 1
+		case eachRange.value == "*/*":
+			rangeSpecificity = 0
+		}
+		if rangeSpecificity > specificity {
+			specificity = rangeSpecificity
+			quality = eachRange.quality
+		}
+	}
+	return quality
+}
+
+// acceptsGzip returns true if the Accept-Encoding header allows gzip
+func acceptsGzip(acceptEncoding string) bool {
+	quality := 0.0
+	explicit := false
+	for _, eachRange := range parseAccept(acceptEncoding) {
+		switch eachRange.value {
+		case "gzip", "x-gzip":
+			quality = eachRange.quality
+			explicit = true
+		case "*":
+			if !explicit {
+				quality = eachRange.quality
+			}
+		}
+	}
+	return quality > 0
+}
+
+// NegotiateContentType returns the media types ordered by the Accept
+// header preference. Media types with equal preference keep their order.
+// Media types that aren't acceptable are excluded. All media types are
+// acceptable if the Accept header is empty.
+func NegotiateContentType(accept string, mediaTypes ...string) []string {
+	if strings.TrimSpace(accept) == "" {
+		return mediaTypes
+	}
+	ranges := parseAccept(accept)
+	type candidate struct {
+		mediaType string
+		quality   float64
+	}
+	candidates := make([]candidate, 0, len(mediaTypes))
+	for _, eachMediaType := range mediaTypes {
+		quality := mediaTypeQuality(ranges, eachMediaType)
+		if quality > 0 {
+			candidates = append(candidates, candidate{eachMediaType, quality})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+	negotiated := make([]string, len(candidates))
+	for eachIndex, eachCandidate := range candidates {
+		negotiated[eachIndex] = eachCandidate.mediaType
+	}
+	return negotiated
+}
+
+// Negotiate encodes the body for the request headers. It returns
+// ErrNotAcceptable if none of the acceptable media types can encode the
+// body.
+func (negotiator *ContentNegotiator) Negotiate(body interface{},
+	requestHeaders map[string]string) (*NegotiatedBody, error) {
+
+	negotiated := &NegotiatedBody{}
+	switch typedBody := body.(type) {
+	case *Problem:
+		problemBytes, problemBytesErr := json.Marshal(typedBody)
+		if problemBytesErr != nil {
+			return nil, problemBytesErr
+		}
+		negotiated.ContentType = ContentTypeProblemJSON
+		negotiated.Body = problemBytes
+	default:
+		mediaTypes := NegotiateContentType(headerValue(requestHeaders, "Accept"),
+			negotiator.MediaTypes...)
+		for _, eachMediaType := range mediaTypes {
+			encoder, encoderOk := bodyEncoders[eachMediaType]
+			if !encoderOk {
+				return nil, errors.Errorf("Unsupported response media type: %s", eachMediaType)
+			}
+			encodedBody, encodedBodyErr := encoder(body)
+			if encodedBodyErr == nil {
+				negotiated.ContentType = eachMediaType
+				negotiated.Body = encodedBody
+				break
+			}
+		}
+		if negotiated.ContentType == "" {
+			return nil, ErrNotAcceptable
+		}
+	}
+
+	if negotiator.CompressionThreshold >= 0 &&
+		len(negotiated.Body) >= negotiator.CompressionThreshold &&
+		acceptsGzip(headerValue(requestHeaders, "Accept-Encoding")) {
+		var compressed bytes.Buffer
+		gzipWriter := gzip.NewWriter(&compressed)
+		_, writeErr := gzipWriter.Write(negotiated.Body)
+		if writeErr != nil {
+			return nil, errors.Wrapf(writeErr, "Failed to compress response body")
+		}
+		closeErr := gzipWriter.Close()
+		if closeErr != nil {
+			return nil, errors.Wrapf(closeErr, "Failed to compress response body")
+		}
+		negotiated.Body = compressed.Bytes()
+		negotiated.ContentEncoding = "gzip"
+	}
+	return negotiated, nil
+}
+
+// negotiateOrProblem returns the negotiated body or, if the body isn't
+// acceptable, a 406 problem body
+func (negotiator *ContentNegotiator) negotiateOrProblem(statusCode int,
+	body interface{},
+	requestHeaders map[string]string) (int, *NegotiatedBody, error) {
+
+	negotiated, negotiatedErr := negotiator.Negotiate(body, requestHeaders)
+	if negotiatedErr == ErrNotAcceptable {
+		problem := NewProblem(http.StatusNotAcceptable, negotiatedErr.Error()).
+			WithExtension("supported", negotiator.MediaTypes)
+		negotiated, negotiatedErr = negotiator.Negotiate(problem, requestHeaders)
+		statusCode = http.StatusNotAcceptable
+	}
+	if negotiatedErr != nil {
+		return 0, nil, negotiatedErr
+	}
+	if problem, problemOk := body.(*Problem); problemOk {
+		statusCode = problem.StatusCode()
+	}
+	return statusCode, negotiated, nil
+}
+
+// mergeHeaders returns the negotiated headers together with the
+// lowercased user headers
+func mergeHeaders(negotiated *NegotiatedBody, headers []map[string]string) map[string]string {
+	merged := negotiated.Headers()
+	for _, eachHeaderMap := range headers {
+		for eachKey, eachValue := range eachHeaderMap {
+			merged[strings.ToLower(eachKey)] = eachValue
+		}
+	}
+	return merged
+}
+
+// NewHTTPResponse returns an HTTP API (payload format 2.0) response whose
+// body is encoded for the request headers. Bodies that aren't acceptable
+// return a 406 problem response. *Problem bodies use the problem status
+// code.
+func (negotiator *ContentNegotiator) NewHTTPResponse(statusCode int,
+	body interface{},
+	requestHeaders map[string]string,
+	headers ...map[string]string) (*awsLambdaEvents.APIGatewayV2HTTPResponse, error) {
+
+	statusCode, negotiated, negotiatedErr := negotiator.negotiateOrProblem(statusCode,
+		body,
+		requestHeaders)
+	if negotiatedErr != nil {
+		return nil, negotiatedErr
+	}
+	encodedBody, isBase64Encoded := negotiated.encodedBody()
+	return &awsLambdaEvents.APIGatewayV2HTTPResponse{
+		StatusCode:      statusCode,
+		Headers:         mergeHeaders(negotiated, headers),
+		Body:            encodedBody,
+		IsBase64Encoded: isBase64Encoded,
+	}, nil
+}
+
+// NewProxyResponse returns a REST API proxy integration response whose
+// body is encoded for the request headers. Binary media types must be
+// enabled for the REST API to return CBOR, MessagePack and compressed
+// bodies. See NewHTTPResponse.
+func (negotiator *ContentNegotiator) NewProxyResponse(statusCode int,
+	body interface{},
+	requestHeaders map[string]string,
+	headers ...map[string]string) (*awsLambdaEvents.APIGatewayProxyResponse, error) {
+
+	statusCode, negotiated, negotiatedErr := negotiator.negotiateOrProblem(statusCode,
+		body,
+		requestHeaders)
+	if negotiatedErr != nil {
+		return nil, negotiatedErr
+	}
+	encodedBody, isBase64Encoded := negotiated.encodedBody()
+	return &awsLambdaEvents.APIGatewayProxyResponse{
+		StatusCode:      statusCode,
+		Headers:         mergeHeaders(negotiated, headers),
+		Body:            encodedBody,
+		IsBase64Encoded: isBase64Encoded,
+	}, nil
+}
@@ -0,0 +1,193 @@
+package apigateway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testEncodingBody = map[string]interface{}{
+	"a": 1,
+	"b": []interface{}{true, nil, "x"},
+	"c": -2,
+	"d": 1.5,
+}
+
+func TestProblem(t *testing.T) {
+	problem := NewProblem(http.StatusForbidden, "Your balance is 30, but that costs 50.").
+		WithType("https://example.com/probs/out-of-credit", "You do not have enough credit.").
+		WithInstance("/account/12345/msgs/abc").
+		WithExtension("balance", 30).
+		WithExtension("status", 200)
+
+	problemJSON, problemJSONErr := json.Marshal(problem)
+	if problemJSONErr != nil {
+		t.Fatal(problemJSONErr)
+	}
+	var members map[string]interface{}
+	_ = json.Unmarshal(problemJSON, &members)
+	if members["status"] != float64(http.StatusForbidden) ||
+		members["balance"] != float64(30) ||
+		members["instance"] != "/account/12345/msgs/abc" {
+		t.Fatalf("Unexpected problem JSON: %s", string(problemJSON))
+	}
+
+	var decoded Problem
+	decodeErr := json.Unmarshal(problemJSON, &decoded)
+	if decodeErr != nil {
+		t.Fatal(decodeErr)
+	}
+	if decoded.Status != http.StatusForbidden ||
+		decoded.Title != problem.Title ||
+		decoded.Extensions["balance"] != float64(30) {
+		t.Fatalf("Unexpected decoded problem: %#v", decoded)
+	}
+
+	response := problem.Response()
+	if response.Code != http.StatusForbidden ||
+		response.Headers["Content-Type"] != ContentTypeProblemJSON {
+		t.Fatalf("Unexpected problem response: %#v", response)
+	}
+	httpResponse := problem.HTTPResponse()
+	if httpResponse.StatusCode != http.StatusForbidden ||
+		httpResponse.Body != string(problemJSON) {
+		t.Fatalf("Unexpected problem HTTP response: %#v", httpResponse)
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	cborBytes, cborBytesErr := encodeCBOR(testEncodingBody)
+	if cborBytesErr != nil {
+		t.Fatal(cborBytesErr)
+	}
+	expectedCBOR := []byte{0xa4,
+		0x61, 'a', 0x01,
+		0x61, 'b', 0x83, 0xf5, 0xf6, 0x61, 'x',
+		0x61, 'c', 0x21,
+		0x61, 'd', 0xfb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}
+	if !bytes.Equal(cborBytes, expectedCBOR) {
+		t.Fatalf("Unexpected CBOR encoding: %x", cborBytes)
+	}
+
+	msgPackBytes, msgPackBytesErr := encodeMessagePack(testEncodingBody)
+	if msgPackBytesErr != nil {
+		t.Fatal(msgPackBytesErr)
+	}
+	expectedMsgPack := []byte{0x84,
+		0xa1, 'a', 0x01,
+		0xa1, 'b', 0x93, 0xc3, 0xc0, 0xa1, 'x',
+		0xa1, 'c', 0xfe,
+		0xa1, 'd', 0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}
+	if !bytes.Equal(msgPackBytes, expectedMsgPack) {
+		t.Fatalf("Unexpected MessagePack encoding: %x", msgPackBytes)
+	}
+
+	// Length headers
+	longString := strings.Repeat("x", 300)
+	cborBytes, _ = encodeCBOR(longString)
+	if !bytes.Equal(cborBytes[:3], []byte{0x79, 0x01, 0x2c}) {
+		t.Fatalf("Unexpected CBOR string header: %x", cborBytes[:3])
+	}
+	msgPackBytes, _ = encodeMessagePack(longString)
+	if !bytes.Equal(msgPackBytes[:3], []byte{0xda, 0x01, 0x2c}) {
+		t.Fatalf("Unexpected MessagePack string header: %x", msgPackBytes[:3])
+	}
+	msgPackBytes, _ = encodeMessagePack(-1000)
+	if !bytes.Equal(msgPackBytes, []byte{0xd1, 0xfc, 0x18}) {
+		t.Fatalf("Unexpected MessagePack integer: %x", msgPackBytes)
+	}
+}
+
+func TestNegotiateContentType(t *testing.T) {
+	mediaTypes := NewContentNegotiator().MediaTypes
+	testCases := map[string][]string{
+		"":                                    mediaTypes,
+		"application/cbor":                    {ContentTypeCBOR},
+		"text/*;q=0.5, application/msgpack":   {ContentTypeMessagePack, ContentTypeText},
+		"*/*;q=0.1, application/json;q=0.9":   {ContentTypeJSON, ContentTypeCBOR, ContentTypeMessagePack, ContentTypeMessagePackLegacy, ContentTypeText},
+		"application/*, application/cbor;q=0": {ContentTypeJSON, ContentTypeMessagePack, ContentTypeMessagePackLegacy},
+		"image/png":                           {},
+	}
+	for eachAccept, eachExpected := range testCases {
+		negotiated := NegotiateContentType(eachAccept, mediaTypes...)
+		if !reflect.DeepEqual(negotiated, eachExpected) {
+			t.Fatalf("Accept `%s`: expected %v, got %v", eachAccept, eachExpected, negotiated)
+		}
+	}
+}
+
+func TestContentNegotiator(t *testing.T) {
+	negotiator := NewContentNegotiator()
+
+	// CBOR is base64 encoded
+	response, responseErr := negotiator.NewHTTPResponse(http.StatusOK,
+		testEncodingBody,
+		map[string]string{"accept": "application/cbor"},
+		map[string]string{"X-Custom": "1"})
+	if responseErr != nil {
+		t.Fatal(responseErr)
+	}
+	if response.Headers["content-type"] != ContentTypeCBOR ||
+		response.Headers["x-custom"] != "1" ||
+		!response.IsBase64Encoded {
+		t.Fatalf("Unexpected CBOR response: %#v", response)
+	}
+
+	// Structs can't be encoded as text
+	response, responseErr = negotiator.NewHTTPResponse(http.StatusOK,
+		testEncodingBody,
+		map[string]string{"accept": "text/plain"})
+	if responseErr != nil {
+		t.Fatal(responseErr)
+	}
+	if response.StatusCode != http.StatusNotAcceptable ||
+		response.Headers["content-type"] != ContentTypeProblemJSON {
+		t.Fatalf("Unexpected not acceptable response: %#v", response)
+	}
+
+	// Large bodies are compressed
+	largeBody := strings.Repeat("Sparta ", DefaultCompressionThreshold)
+	proxyResponse, proxyResponseErr := negotiator.NewProxyResponse(http.StatusOK,
+		largeBody,
+		map[string]string{
+			"Accept":          "text/plain",
+			"Accept-Encoding": "br;q=1.0, gzip;q=0.8",
+		})
+	if proxyResponseErr != nil {
+		t.Fatal(proxyResponseErr)
+	}
+	if proxyResponse.Headers["content-encoding"] != "gzip" || !proxyResponse.IsBase64Encoded {
+		t.Fatalf("Unexpected compressed response: %#v", proxyResponse.Headers)
+	}
+	compressedBytes, _ := base64.StdEncoding.DecodeString(proxyResponse.Body)
+	gzipReader, gzipReaderErr := gzip.NewReader(bytes.NewReader(compressedBytes))
+	if gzipReaderErr != nil {
+		t.Fatal(gzipReaderErr)
+	}
+	decompressedBytes, _ := ioutil.ReadAll(gzipReader)
+	if string(decompressedBytes) != largeBody {
+		t.Fatalf("Unexpected decompressed body")
+	}
+
+	// Problems use their own status code and media type
+	response, responseErr = negotiator.NewHTTPResponse(http.StatusOK,
+		NewProblem(http.StatusConflict, "Version mismatch"),
+		map[string]string{
+			"accept":          "application/msgpack",
+			"accept-encoding": "gzip;q=0",
+		})
+	if responseErr != nil {
+		t.Fatal(responseErr)
+	}
+	if response.StatusCode != http.StatusConflict ||
+		response.IsBase64Encoded ||
+		response.Headers["content-type"] != ContentTypeProblemJSON {
+		t.Fatalf("Unexpected problem response: %#v", response)
+	}
+}
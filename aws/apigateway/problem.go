@@ -0,0 +1,169 @@
+package apigateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	// ContentTypeProblemJSON is the RFC 7807 problem details media type
+	ContentTypeProblemJSON = "application/problem+json"
+	// ProblemTypeBlank is the default problem type. It indicates that the
+	// problem has no semantics beyond the HTTP status code.
+	ProblemTypeBlank = "about:blank"
+)
+
+// problemMembers are the members defined by RFC 7807. Extension members
+// can't replace them.
+var problemMembers = map[string]bool{
+	"type":     true,
+	"title":    true,
+	"status":   true,
+	"detail":   true,
+	"instance": true,
+}
+
+// Problem is an RFC 7807 problem details object. See
+// https://datatracker.ietf.org/doc/html/rfc7807
+type Problem struct {
+	// Type is a URI reference that identifies the problem type
+	Type string `json:"type,omitempty"`
+	// Title is a short, human-readable summary of the problem type
+	Title string `json:"title,omitempty"`
+	// Status is the HTTP status code
+	Status int `json:"status,omitempty"`
+	// Detail is a human-readable explanation of this occurrence of the
+	// problem
+	Detail string `json:"detail,omitempty"`
+	// Instance is a URI reference that identifies this occurrence of the
+	// problem
+	Instance string `json:"instance,omitempty"`
+	// Extensions are additional members serialized alongside the
+	// standard members
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// NewProblem returns a Problem for the HTTP status code. The Type is
+// about:blank and the Title is the status code text.
+func NewProblem(statusCode int, detail string) *Problem {
+	if http.StatusText(statusCode) == "" {
+		statusCode = http.StatusInternalServerError
+	}
+	return &Problem{
+		Type:       ProblemTypeBlank,
+		Title:      http.StatusText(statusCode),
+		Status:     statusCode,
+		Detail:     detail,
+		Extensions: make(map[string]interface{}),
+	}
+}
+
+// WithType is a fluent builder that sets the problem type URI and title
+func (problem *Problem) WithType(typeURI string, title string) *Problem {
+	problem.Type = typeURI
+	problem.Title = title
+	return problem
+}
+
+// WithInstance is a fluent builder that sets the problem instance URI
+func (problem *Problem) WithInstance(instanceURI string) *Problem {
+	problem.Instance = instanceURI
+	return problem
+}
+
+// WithExtension is a fluent builder that adds an extension member
+func (problem *Problem) WithExtension(name string, value interface{}) *Problem {
+	if problem.Extensions == nil {
+		problem.Extensions = make(map[string]interface{})
+	}
+	problem.Extensions[name] = value
+	return problem
+}
+
+// StatusCode returns the HTTP status code
+func (problem *Problem) StatusCode() int {
+	if problem.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return problem.Status
+}
+
+// Error returns the JSONified version of this problem
+func (problem *Problem) Error() string {
+	bytes, bytesErr := json.Marshal(problem)
+	if bytesErr != nil {
+		return problem.Title
+	}
+	return string(bytes)
+}
+
+// MarshalJSON serializes the extension members alongside the standard
+// members
+func (problem *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{})
+	for eachKey, eachValue := range problem.Extensions {
+		if !problemMembers[eachKey] {
+			members[eachKey] = eachValue
+		}
+	}
+	standardMembers := map[string]interface{}{
+		"type":     problem.Type,
+		"title":    problem.Title,
+		"detail":   problem.Detail,
+		"instance": problem.Instance,
+	}
+	for eachKey, eachValue := range standardMembers {
+		if eachValue != "" {
+			members[eachKey] = eachValue
+		}
+	}
+	if problem.Status != 0 {
+		members["status"] = problem.Status
+	}
+	return json.Marshal(members)
+}
+
+// UnmarshalJSON deserializes the standard members and collects the
+// remaining members as extensions
+func (problem *Problem) UnmarshalJSON(data []byte) error {
+	type standardProblem Problem
+	var standard standardProblem
+	unmarshalErr := json.Unmarshal(data, &standard)
+	if unmarshalErr != nil {
+		return unmarshalErr
+	}
+	var members map[string]interface{}
+	unmarshalErr = json.Unmarshal(data, &members)
+	if unmarshalErr != nil {
+		return unmarshalErr
+	}
+	*problem = Problem(standard)
+	problem.Extensions = make(map[string]interface{})
+	for eachKey, eachValue := range members {
+		if !problemMembers[eachKey] {
+			problem.Extensions[eachKey] = eachValue
+		}
+	}
+	return nil
+}
+
+// Response returns the REST API response for the problem
+func (problem *Problem) Response() *Response {
+	return NewResponse(problem.StatusCode(), problem, map[string]string{
+		"Content-Type": ContentTypeProblemJSON,
+	})
+}
+
+// HTTPResponse returns the HTTP API (payload format 2.0) response for the
+// problem
+func (problem *Problem) HTTPResponse() *awsLambdaEvents.APIGatewayV2HTTPResponse {
+	return &awsLambdaEvents.APIGatewayV2HTTPResponse{
+		StatusCode: problem.StatusCode(),
+		Headers: map[string]string{
+			"content-type": ContentTypeProblemJSON,
+		},
+		Body: problem.Error(),
+	}
+}
@@ -0,0 +1,355 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsv2DynamoAttributeValue "github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	awsv2Dynamo "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	awsv2DynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultBroadcastConcurrency is the default number of concurrent
+	// PostToConnection requests
+	defaultBroadcastConcurrency = 16
+	// attrTopics is the connection topic subscriptions attribute
+	attrTopics = "topics"
+)
+
+// Connection is a WebSocket connection record
+type Connection struct {
+	// ConnectionID is the API Gateway connection ID. It's stored in the
+	// table key attribute.
+	ConnectionID string `dynamodbav:"-" json:"connectionId"`
+	// User is the optional user associated with the connection
+	User string `dynamodbav:"user,omitempty" json:"user,omitempty"`
+	// Topics are the connection topic subscriptions
+	Topics []string `dynamodbav:"topics,stringset,omitempty" json:"topics,omitempty"`
+	// Attributes are optional user defined connection attributes
+	Attributes map[string]string `dynamodbav:"attributes,omitempty" json:"attributes,omitempty"`
+	// ConnectedAt is the time the connection was registered
+	ConnectedAt time.Time `dynamodbav:"connectedAt" json:"connectedAt"`
+}
+
+// Subscribed returns true if the connection is subscribed to the topic
+func (conn *Connection) Subscribed(topic string) bool {
+	for _, eachTopic := range conn.Topics {
+		if eachTopic == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// ConnectionFilter returns true if the message should be sent to the
+// connection
+type ConnectionFilter func(conn *Connection) bool
+
+// TopicFilter returns a ConnectionFilter for connections subscribed to
+// the topic
+func TopicFilter(topic string) ConnectionFilter {
+	return func(conn *Connection) bool {
+		return conn.Subscribed(topic)
+	}
+}
+
+// UserFilter returns a ConnectionFilter for the user's connections
+func UserFilter(user string) ConnectionFilter {
+	return func(conn *Connection) bool {
+		return conn.User == user
+	}
+}
+
+// BroadcastResult summarizes a Broadcast
+type BroadcastResult struct {
+	// Sent is the number of connections that received the message
+	Sent int
+	// Gone are the stale connection IDs. Connections whose records
+	// couldn't be deleted also have an Errors entry.
+	Gone []string
+	// Errors are the errors keyed by connection ID
+	Errors map[string]error
+}
+
+// ConnectionManager registers WebSocket connections in a DynamoDB table
+// and sends messages to them
+type ConnectionManager struct {
+	tableName    string
+	keyAttribute string
+	dynamoClient *awsv2Dynamo.Client
+	management   *managementClient
+	// Concurrency is the maximum number of concurrent PostToConnection
+	// requests made by Broadcast
+	Concurrency int
+}
+
+// NewConnectionManager returns a ConnectionManager for the table whose
+// partition key is keyAttribute. These are the environment variable
+// value and propertyName supplied to APIV2.NewConnectionTableDecorator.
+// The management API endpoint defaults to the
+// SPARTA_WEBSOCKET_MANAGEMENT_ENDPOINT environment variable value.
+func NewConnectionManager(awsConfig awsv2.Config,
+	tableName string,
+	keyAttribute string) *ConnectionManager {
+	return &ConnectionManager{
+		tableName:    tableName,
+		keyAttribute: keyAttribute,
+		dynamoClient: awsv2Dynamo.NewFromConfig(awsConfig),
+		management: &managementClient{
+			awsConfig: awsConfig,
+			endpoint:  os.Getenv(EnvVarManagementEndpoint),
+		},
+		Concurrency: defaultBroadcastConcurrency,
+	}
+}
+
+// WithEndpoint sets the management API endpoint. See ManagementEndpoint
+// and RequestManagementEndpoint.
+func (manager *ConnectionManager) WithEndpoint(endpoint string) *ConnectionManager {
+	manager.management.endpoint = endpoint
+	return manager
+}
+
+func (manager *ConnectionManager) key(connectionID string) map[string]awsv2DynamoTypes.AttributeValue {
+	return map[string]awsv2DynamoTypes.AttributeValue{
+		manager.keyAttribute: &awsv2DynamoTypes.AttributeValueMemberS{
+			Value: connectionID,
+		},
+	}
+}
+
+// Register saves the connection record
+func (manager *ConnectionManager) Register(ctx context.Context, conn *Connection) error {
+	if conn.ConnectionID == "" {
+		return errors.Errorf("Connection ID must not be empty")
+	}
+	if conn.ConnectedAt.IsZero() {
+		conn.ConnectedAt = time.Now().UTC()
+	}
+	item, itemErr := awsv2DynamoAttributeValue.MarshalMap(conn)
+	if itemErr != nil {
+		return errors.Wrapf(itemErr, "Failed to marshal connection %s", conn.ConnectionID)
+	}
+	for eachKey, eachValue := range manager.key(conn.ConnectionID) {
+		item[eachKey] = eachValue
+	}
+	_, putErr := manager.dynamoClient.PutItem(ctx, &awsv2Dynamo.PutItemInput{
+		TableName: awsv2.String(manager.tableName),
+		Item:      item,
+	})
+	return errors.Wrapf(putErr, "Failed to register connection %s", conn.ConnectionID)
+}
+
+// Connect registers the connection for a $connect route request. The
+// optional conn value provides the user, topics and attributes.
+func (manager *ConnectionManager) Connect(ctx context.Context,
+	request *awsLambdaEvents.APIGatewayWebsocketProxyRequest,
+	conn *Connection) error {
+	if conn == nil {
+		conn = &Connection{}
+	}
+	conn.ConnectionID = request.RequestContext.ConnectionID
+	if request.RequestContext.ConnectedAt != 0 {
+		conn.ConnectedAt = time.Unix(0,
+			request.RequestContext.ConnectedAt*int64(time.Millisecond)).UTC()
+	}
+	return manager.Register(ctx, conn)
+}
+
+// Disconnect removes the connection for a $disconnect route request
+func (manager *ConnectionManager) Disconnect(ctx context.Context,
+	request *awsLambdaEvents.APIGatewayWebsocketProxyRequest) error {
+	return manager.Remove(ctx, request.RequestContext.ConnectionID)
+}
+
+// Remove deletes the connection record
+func (manager *ConnectionManager) Remove(ctx context.Context, connectionID string) error {
+	_, deleteErr := manager.dynamoClient.DeleteItem(ctx, &awsv2Dynamo.DeleteItemInput{
+		TableName: awsv2.String(manager.tableName),
+		Key:       manager.key(connectionID),
+	})
+	return errors.Wrapf(deleteErr, "Failed to remove connection %s", connectionID)
+}
+
+// updateTopics adds or deletes the topic subscriptions
+func (manager *ConnectionManager) updateTopics(ctx context.Context,
+	operation string,
+	connectionID string,
+	topics []string) error {
+	if len(topics) == 0 {
+		return nil
+	}
+	_, updateErr := manager.dynamoClient.UpdateItem(ctx, &awsv2Dynamo.UpdateItemInput{
+		TableName:        awsv2.String(manager.tableName),
+		Key:              manager.key(connectionID),
+		UpdateExpression: awsv2.String(operation + " #topics :topics"),
+		ExpressionAttributeNames: map[string]string{
+			"#topics": attrTopics,
+			"#key":    manager.keyAttribute,
+		},
+		ExpressionAttributeValues: map[string]awsv2DynamoTypes.AttributeValue{
+			":topics": &awsv2DynamoTypes.AttributeValueMemberSS{
+				Value: topics,
+			},
+		},
+		ConditionExpression: awsv2.String("attribute_exists(#key)"),
+	})
+	return errors.Wrapf(updateErr, "Failed to update connection %s topics", connectionID)
+}
+
+// Subscribe adds the topics to the connection subscriptions
+func (manager *ConnectionManager) Subscribe(ctx context.Context,
+	connectionID string,
+	topics ...string) error {
+	return manager.updateTopics(ctx, "ADD", connectionID, topics)
+}
+
+// Unsubscribe removes the topics from the connection subscriptions
+func (manager *ConnectionManager) Unsubscribe(ctx context.Context,
+	connectionID string,
+	topics ...string) error {
+	return manager.updateTopics(ctx, "DELETE", connectionID, topics)
+}
+
+// Connections returns the registered connections that satisfy the
+// optional filter
+func (manager *ConnectionManager) Connections(ctx context.Context,
+	filter ConnectionFilter) ([]*Connection, error) {
+
+	connections := make([]*Connection, 0)
+	paginator := awsv2Dynamo.NewScanPaginator(manager.dynamoClient, &awsv2Dynamo.ScanInput{
+		TableName:      awsv2.String(manager.tableName),
+		ConsistentRead: awsv2.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, pageErr := paginator.NextPage(ctx)
+		if pageErr != nil {
+			return nil, errors.Wrapf(pageErr, "Failed to scan connections")
+		}
+		for _, eachItem := range page.Items {
+			conn := &Connection{}
+			unmarshalErr := awsv2DynamoAttributeValue.UnmarshalMap(eachItem, conn)
+			if unmarshalErr != nil {
+				return nil, errors.Wrapf(unmarshalErr, "Failed to unmarshal connection")
+			}
+			keyValue, keyValueOk := eachItem[manager.keyAttribute].(*awsv2DynamoTypes.AttributeValueMemberS)
+			if keyValueOk {
+				conn.ConnectionID = keyValue.Value
+			}
+			if filter == nil || filter(conn) {
+				connections = append(connections, conn)
+			}
+		}
+	}
+	return connections, nil
+}
+
+// goneRemoveError is returned when a connection is gone and its record
+// couldn't be removed. It matches ErrGone with errors.Is and unwraps to
+// the Remove error.
+type goneRemoveError struct {
+	connectionID string
+	removeErr    error
+}
+
+func (gre *goneRemoveError) Error() string {
+	return fmt.Sprintf("%s. Failed to remove connection %s: %s",
+		ErrGone,
+		gre.connectionID,
+		gre.removeErr)
+}
+
+func (gre *goneRemoveError) Is(target error) bool {
+	return target == ErrGone
+}
+
+func (gre *goneRemoveError) Unwrap() error {
+	return gre.removeErr
+}
+
+// Send sends the data to the connection. Stale connections are removed
+// and return ErrGone. If the stale connection record can't be removed,
+// the returned error satisfies errors.Is(err, ErrGone) and wraps the
+// Remove error.
+func (manager *ConnectionManager) Send(ctx context.Context,
+	connectionID string,
+	data []byte) error {
+	postErr := manager.management.postToConnection(ctx, connectionID, data)
+	if postErr == ErrGone {
+		removeErr := manager.Remove(ctx, connectionID)
+		if removeErr != nil {
+			return &goneRemoveError{
+				connectionID: connectionID,
+				removeErr:    removeErr,
+			}
+		}
+	}
+	return postErr
+}
+
+// Close disconnects the connection. API Gateway invokes the $disconnect
+// route for the connection.
+func (manager *ConnectionManager) Close(ctx context.Context, connectionID string) error {
+	deleteErr := manager.management.deleteConnection(ctx, connectionID)
+	if deleteErr == ErrGone {
+		return manager.Remove(ctx, connectionID)
+	}
+	return deleteErr
+}
+
+// Broadcast concurrently sends the data to the connections that satisfy
+// the optional filter. Stale connections are removed and reported in
+// the result rather than as errors.
+func (manager *ConnectionManager) Broadcast(ctx context.Context,
+	data []byte,
+	filter ConnectionFilter) (*BroadcastResult, error) {
+
+	connections, connectionsErr := manager.Connections(ctx, filter)
+	if connectionsErr != nil {
+		return nil, connectionsErr
+	}
+	concurrency := manager.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBroadcastConcurrency
+	}
+	result := &BroadcastResult{
+		Gone:   make([]string, 0),
+		Errors: make(map[string]error),
+	}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, concurrency)
+	for _, eachConnection := range connections {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(connectionID string) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			sendErr := manager.Send(ctx, connectionID, data)
+			mutex.Lock()
+			defer mutex.Unlock()
+			switch {
+			case sendErr == nil:
+				result.Sent++
+			case sendErr == ErrGone:
+				result.Gone = append(result.Gone, connectionID)
+			case errors.Is(sendErr, ErrGone):
+				result.Gone = append(result.Gone, connectionID)
+				result.Errors[connectionID] = sendErr
+			default:
+				result.Errors[connectionID] = sendErr
+			}
+		}(eachConnection.ConnectionID)
+	}
+	wg.Wait()
+	return result, nil
+}
@@ -0,0 +1,111 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsv2Creds "github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestManagementClient(t *testing.T) {
+	received := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		connectionID := strings.TrimPrefix(r.URL.Path, "/v1/@connections/")
+		if connectionID == "gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		received[r.Method+" "+connectionID] = string(body)
+	}))
+	defer server.Close()
+
+	client := &managementClient{
+		awsConfig: awsv2.Config{
+			Region:      "us-east-1",
+			Credentials: awsv2Creds.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		},
+		endpoint: server.URL + "/v1",
+	}
+	ctx := context.Background()
+	postErr := client.postToConnection(ctx, "abc", []byte("hello"))
+	if postErr != nil {
+		t.Fatal(postErr)
+	}
+	if received["POST abc"] != "hello" {
+		t.Fatalf("Unexpected POST requests: %#v", received)
+	}
+	deleteErr := client.deleteConnection(ctx, "abc")
+	if deleteErr != nil {
+		t.Fatal(deleteErr)
+	}
+	if _, deleted := received["DELETE abc"]; !deleted {
+		t.Fatalf("Expected DELETE request: %#v", received)
+	}
+	goneErr := client.postToConnection(ctx, "gone", []byte("hello"))
+	if goneErr != ErrGone {
+		t.Fatalf("Expected ErrGone, got: %v", goneErr)
+	}
+}
+
+func TestConnectionFilters(t *testing.T) {
+	conn := &Connection{
+		ConnectionID: "abc",
+		User:         "user1",
+		Topics:       []string{"news", "sports"},
+	}
+	if !TopicFilter("news")(conn) || TopicFilter("weather")(conn) {
+		t.Fatalf("Unexpected TopicFilter result")
+	}
+	if !UserFilter("user1")(conn) || UserFilter("user2")(conn) {
+		t.Fatalf("Unexpected UserFilter result")
+	}
+	endpoint := ManagementEndpoint("api123", "us-west-2", "v1")
+	if endpoint != "https://api123.execute-api.us-west-2.amazonaws.com/v1" {
+		t.Fatalf("Unexpected management endpoint: %s", endpoint)
+	}
+}
+
+func TestSendGoneRemoveError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v1/@connections/") {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		// DynamoDB DeleteItem
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"__type":"com.amazonaws.dynamodb.v20120810#ResourceNotFoundException","message":"Requested resource not found"}`))
+	}))
+	defer server.Close()
+
+	awsConfig := awsv2.Config{
+		Region:      "us-east-1",
+		Credentials: awsv2Creds.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		EndpointResolverWithOptions: awsv2.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (awsv2.Endpoint, error) {
+			return awsv2.Endpoint{URL: server.URL}, nil
+		}),
+		Retryer: func() awsv2.Retryer {
+			return awsv2.NopRetryer{}
+		},
+	}
+	manager := NewConnectionManager(awsConfig, "connections", "connectionId").
+		WithEndpoint(server.URL + "/v1")
+	sendErr := manager.Send(context.Background(), "stale", []byte("hello"))
+	if !errors.Is(sendErr, ErrGone) {
+		t.Fatalf("Expected ErrGone, got: %v", sendErr)
+	}
+	if sendErr == ErrGone || !strings.Contains(sendErr.Error(), "ResourceNotFoundException") {
+		t.Fatalf("Expected the Remove error to be wrapped: %v", sendErr)
+	}
+}
@@ -0,0 +1,5 @@
+// Package websocket provides runtime helpers for API Gateway WebSocket
+// APIs. ConnectionManager records connections in the DynamoDB table
+// provisioned by APIV2.NewConnectionTableDecorator and sends messages to
+// them with the API Gateway management API.
+package websocket
@@ -0,0 +1,104 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	spartaSigV4 "github.com/mweagle/Sparta/v3/aws/internal/sigv4"
+	"github.com/pkg/errors"
+)
+
+const (
+	// EnvVarManagementEndpoint is the environment variable that stores
+	// the management API endpoint of the WebSocket API stage
+	EnvVarManagementEndpoint = "SPARTA_WEBSOCKET_MANAGEMENT_ENDPOINT"
+	// executeAPISigningName is the SigV4 service name for the
+	// management API
+	executeAPISigningName = "execute-api"
+)
+
+// ErrGone is returned when the connection is no longer available
+// (GoneException)
+var ErrGone = errors.New("GoneException: WebSocket connection is no longer available")
+
+// ManagementEndpoint returns the management API endpoint for the API
+// and stage (eg: https://API_ID.execute-api.REGION.amazonaws.com/STAGE)
+func ManagementEndpoint(apiID string, region string, stage string) string {
+	domain := "amazonaws.com"
+	if strings.HasPrefix(region, "cn-") {
+		domain = "amazonaws.com.cn"
+	}
+	return fmt.Sprintf("https://%s.execute-api.%s.%s/%s", apiID, region, domain, stage)
+}
+
+// RequestManagementEndpoint returns the management API endpoint for the
+// stage that delivered the request
+func RequestManagementEndpoint(request *awsLambdaEvents.APIGatewayWebsocketProxyRequest,
+	region string) string {
+	return ManagementEndpoint(request.RequestContext.APIID,
+		region,
+		request.RequestContext.Stage)
+}
+
+// managementClient calls the API Gateway management API. The SDK v2
+// apigatewaymanagementapi client isn't a module dependency, so requests
+// are signed directly.
+type managementClient struct {
+	awsConfig awsv2.Config
+	endpoint  string
+}
+
+// invoke sends the signed request for the connection and returns
+// ErrGone if the connection no longer exists
+func (client *managementClient) invoke(ctx context.Context,
+	httpMethod string,
+	connectionID string,
+	body []byte) error {
+
+	if client.endpoint == "" {
+		return errors.Errorf("WebSocket management endpoint is empty")
+	}
+	requestURL := fmt.Sprintf("%s/@connections/%s",
+		strings.TrimSuffix(client.endpoint, "/"),
+		url.PathEscape(connectionID))
+	statusCode, responseBody, sendErr := spartaSigV4.Send(ctx,
+		client.awsConfig,
+		&spartaSigV4.Request{
+			SigningName: executeAPISigningName,
+			Method:      httpMethod,
+			URL:         requestURL,
+			Body:        body,
+		})
+	if sendErr != nil {
+		return sendErr
+	}
+	switch {
+	case statusCode == http.StatusGone:
+		return ErrGone
+	case statusCode < http.StatusOK || statusCode >= http.StatusMultipleChoices:
+		return errors.Errorf("%s %s failed (HTTP %d): %s",
+			httpMethod,
+			requestURL,
+			statusCode,
+			string(responseBody))
+	}
+	return nil
+}
+
+// postToConnection sends the data to the connection
+func (client *managementClient) postToConnection(ctx context.Context,
+	connectionID string,
+	data []byte) error {
+	return client.invoke(ctx, http.MethodPost, connectionID, data)
+}
+
+// deleteConnection closes the connection
+func (client *managementClient) deleteConnection(ctx context.Context,
+	connectionID string) error {
+	return client.invoke(ctx, http.MethodDelete, connectionID, nil)
+}
@@ -7,9 +7,10 @@ import (
 	"encoding/json"
 	"io"
 	"io/ioutil"
-	"mime"
 	"os"
 	"path"
+	"regexp"
+	"sort"
 	"strings"
 
 	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
@@ -34,6 +35,55 @@ type ZipToS3BucketResourceRequest struct {
 	DestBucket   string
 	ManifestName string
 	Manifest     map[string]interface{}
+	// IndexDocument is the website index document suffix. Changes to an
+	// index document also invalidate the directory path.
+	IndexDocument string `json:",omitempty"`
+	// ContentRules set the Cache-Control and Content-Type metadata of
+	// matching objects. For each value, the first matching rule wins.
+	ContentRules []*ZipToS3BucketContentRule `json:",omitempty"`
+	// PrecompressedEncodings are the content encodings (`gzip`, `br`) of
+	// the variants published alongside compressible objects
+	PrecompressedEncodings []string `json:",omitempty"`
+	// ImmutableAssetPattern is a regular expression that matches
+	// content-hashed file names. See DefaultImmutableAssetPattern.
+	ImmutableAssetPattern string `json:",omitempty"`
+}
+
+// Validate ensures the content rules, encodings and immutable asset
+// pattern are well formed
+func (request *ZipToS3BucketResourceRequest) Validate() error {
+	for _, eachRule := range request.ContentRules {
+		if eachRule == nil || eachRule.Pattern == "" {
+			return errors.Errorf("ContentRules entries must define a Pattern")
+		}
+		for _, eachSegment := range strings.Split(eachRule.Pattern, "/") {
+			_, matchErr := path.Match(eachSegment, "")
+			if matchErr != nil {
+				return errors.Wrapf(matchErr, "Invalid ContentRules pattern: %s", eachRule.Pattern)
+			}
+		}
+	}
+	for _, eachEncoding := range request.PrecompressedEncodings {
+		_, exists := precompressedSuffixes[eachEncoding]
+		if !exists {
+			return errors.Errorf("Unsupported PrecompressedEncodings value: %s", eachEncoding)
+		}
+	}
+	if request.ImmutableAssetPattern != "" {
+		_, regexpErr := regexp.Compile(request.ImmutableAssetPattern)
+		if regexpErr != nil {
+			return errors.Wrapf(regexpErr, "Invalid ImmutableAssetPattern")
+		}
+	}
+	return nil
+}
+
+// manifestName returns the name of the manifest object
+func (request *ZipToS3BucketResourceRequest) manifestName() string {
+	if request.ManifestName == "" {
+		return DefaultManifestName
+	}
+	return request.ManifestName
 }
 
 // ZipToS3BucketResource manages populating an S3 bucket with the contents
@@ -42,6 +92,82 @@ type ZipToS3BucketResource struct {
 	gof.CustomResource
 }
 
+// publishedManifest returns the raw manifest and object hashes from
+// the previous publish. The hashes are empty if there is no manifest.
+func (command ZipToS3BucketResource) publishedManifest(ctx context.Context,
+	svc *awsv2S3.Client,
+	request *ZipToS3BucketResourceRequest,
+	logger *zerolog.Logger) ([]byte, map[string]string) {
+	objectHashes := make(map[string]string)
+	s3Object, s3ObjectErr := svc.GetObject(ctx, &awsv2S3.GetObjectInput{
+		Bucket: awsv2.String(request.DestBucket),
+		Key:    awsv2.String(request.manifestName()),
+	})
+	if s3ObjectErr != nil {
+		var noSuchKey *awsv2S3Types.NoSuchKey
+		if !errors.As(s3ObjectErr, &noSuchKey) {
+			logger.Warn().
+				Err(s3ObjectErr).
+				Msg("Failed to fetch existing manifest. Publishing all objects.")
+		}
+		return nil, objectHashes
+	}
+	defer s3Object.Body.Close()
+	manifestBytes, manifestBytesErr := ioutil.ReadAll(s3Object.Body)
+	if manifestBytesErr != nil {
+		logger.Warn().
+			Err(manifestBytesErr).
+			Msg("Failed to read existing manifest. Publishing all objects.")
+		return nil, objectHashes
+	}
+	var manifest struct {
+		Objects map[string]string `json:"objects"`
+	}
+	unmarshalErr := json.Unmarshal(manifestBytes, &manifest)
+	if unmarshalErr != nil {
+		logger.Warn().
+			Err(unmarshalErr).
+			Msg("Failed to parse existing manifest. Publishing all objects.")
+		return manifestBytes, objectHashes
+	}
+	for eachKey, eachHash := range manifest.Objects {
+		objectHashes[eachKey] = eachHash
+	}
+	return manifestBytes, objectHashes
+}
+
+func (command ZipToS3BucketResource) deleteObjects(ctx context.Context,
+	svc *awsv2S3.Client,
+	bucket string,
+	keys []string) error {
+	// DeleteObjects supports 1000 keys per request
+	for len(keys) != 0 {
+		batchSize := len(keys)
+		if batchSize > 1000 {
+			batchSize = 1000
+		}
+		params := &awsv2S3.DeleteObjectsInput{
+			Bucket: awsv2.String(bucket),
+			Delete: &awsv2S3Types.Delete{
+				Objects: []awsv2S3Types.ObjectIdentifier{},
+				Quiet:   true,
+			},
+		}
+		for _, eachKey := range keys[:batchSize] {
+			params.Delete.Objects = append(params.Delete.Objects,
+				awsv2S3Types.ObjectIdentifier{
+					Key: awsv2.String(eachKey),
+				})
+		}
+		_, deleteErr := svc.DeleteObjects(ctx, params)
+		if deleteErr != nil {
+			return deleteErr
+		}
+		keys = keys[batchSize:]
+	}
+	return nil
+}
+
 func (command ZipToS3BucketResource) unzip(ctx context.Context,
 	awsConfig awsv2.Config,
 	event *CloudFormationLambdaEvent,
@@ -56,6 +182,11 @@ func (command ZipToS3BucketResource) unzip(ctx context.Context,
 		Interface("CloudFormationEvent", *event).
 		Msg("Incoming unzip event")
 
+	planner, plannerErr := newSiteObjectPlanner(&request)
+	if plannerErr != nil {
+		return nil, plannerErr
+	}
+
 	// Fetch the ZIP contents and unpack them to the S3 bucket
 	svc := awsv2S3.NewFromConfig(awsConfig)
 	s3Object, s3ObjectErr := svc.GetObject(ctx, &awsv2S3.GetObjectInput{
@@ -81,11 +212,32 @@ func (command ZipToS3BucketResource) unzip(ctx context.Context,
 	if nil != zipErr {
 		return nil, zipErr
 	}
-	// Iterate through the files in the archive,
-	// printing some of their contents.
+	defer zipReader.Close()
+
+	// The existing manifest tracks the published objects. Objects
+	// are only tracked if there is a manifest.
+	var publishedManifestBytes []byte
+	publishedHashes := make(map[string]string)
+	if nil != request.Manifest {
+		publishedManifestBytes, publishedHashes = command.publishedManifest(ctx,
+			svc,
+			&request,
+			logger)
+	}
+
+	// Iterate through the files in the archive and publish
+	// the changed objects
 	// TODO - refactor to a worker pool
 	totalFiles := 0
+	objectHashes := make(map[string]string)
+	invalidatedKeys := []string{}
 	for _, eachFile := range zipReader.File {
+		normalizedName := strings.TrimPrefix(eachFile.Name, "/")
+		if eachFile.FileInfo().IsDir() ||
+			len(normalizedName) == 0 ||
+			normalizedName == request.manifestName() {
+			continue
+		}
 		totalFiles++
 
 		stream, streamErr := eachFile.Open()
@@ -96,26 +248,38 @@ func (command ZipToS3BucketResource) unzip(ctx context.Context,
 		if nil != bodySourceErr {
 			return nil, bodySourceErr
 		}
-		normalizedName := strings.TrimPrefix(eachFile.Name, "/")
+		errClose := stream.Close()
+		if errClose != nil {
+			return nil, errors.Wrapf(errClose, "Failed to close ZIP entry stream")
+		}
 
 		logger.Info().
 			Str("Name", eachFile.Name).
 			Int64("CompressedSize", int64(eachFile.CompressedSize64)).
 			Int64("UncompressedSize", int64(eachFile.UncompressedSize64)).
 			Msg("ZipEntry")
-		// Mime type?
-		fileExtension := path.Ext(eachFile.Name)
-		mimeType := mime.TypeByExtension(fileExtension)
-		if mimeType == "" {
-			mimeType = "application/octet-stream"
-		}
 
-		if len(normalizedName) > 0 {
+		for _, eachObject := range planner.objects(normalizedName, bodySource) {
+			objectHash := eachObject.hash()
+			objectHashes[eachObject.key] = objectHash
+			if publishedHashes[eachObject.key] == objectHash {
+				continue
+			}
+			objectBody, objectBodyErr := eachObject.body()
+			if objectBodyErr != nil {
+				return nil, objectBodyErr
+			}
 			s3PutObject := &awsv2S3.PutObjectInput{
-				Body:        bytes.NewReader(bodySource),
+				Body:        bytes.NewReader(objectBody),
 				Bucket:      awsv2.String(request.DestBucket),
-				Key:         awsv2.String(normalizedName),
-				ContentType: awsv2.String(mimeType),
+				Key:         awsv2.String(eachObject.key),
+				ContentType: awsv2.String(eachObject.contentType),
+			}
+			if eachObject.cacheControl != "" {
+				s3PutObject.CacheControl = awsv2.String(eachObject.cacheControl)
+			}
+			if eachObject.contentEncoding != "" {
+				s3PutObject.ContentEncoding = awsv2.String(eachObject.contentEncoding)
 			}
 			_, err := svc.PutObject(ctx, s3PutObject)
 			if err != nil {
@@ -125,42 +289,68 @@ func (command ZipToS3BucketResource) unzip(ctx context.Context,
 				Str("Bucket", request.DestBucket).
 				Str("Key", *s3PutObject.Key).
 				Msg("Unzipping object")
+			if !eachObject.immutable {
+				invalidatedKeys = append(invalidatedKeys, eachObject.key)
+			}
 		}
-		errClose := stream.Close()
-		if errClose != nil {
-			return nil, errors.Wrapf(errClose, "Failed to close S3 PutObject stream")
+	}
+
+	// Delete the previously published objects that are no longer
+	// in the archive
+	deletedKeys := []string{}
+	for eachKey := range publishedHashes {
+		if _, exists := objectHashes[eachKey]; !exists {
+			deletedKeys = append(deletedKeys, eachKey)
 		}
 	}
+	sort.Strings(deletedKeys)
+	deleteErr := command.deleteObjects(ctx, svc, request.DestBucket, deletedKeys)
+	if deleteErr != nil {
+		return nil, errors.Wrapf(deleteErr, "Failed to delete removed objects")
+	}
+	invalidatedKeys = append(invalidatedKeys, deletedKeys...)
+
 	// Need to add the manifest data iff defined
 	if nil != request.Manifest {
-		manifestBytes, manifestErr := json.Marshal(request.Manifest)
+		manifestData := make(map[string]interface{})
+		for eachKey, eachValue := range request.Manifest {
+			manifestData[eachKey] = eachValue
+		}
+		manifestData[ManifestObjectsKeyName] = objectHashes
+		manifestBytes, manifestErr := json.Marshal(manifestData)
 		if nil != manifestErr {
 			return nil, manifestErr
 		}
-		name := request.ManifestName
-		if name == "" {
-			name = DefaultManifestName
-		}
-		s3PutObject := &awsv2S3.PutObjectInput{
-			Body:        bytes.NewReader(manifestBytes),
-			Bucket:      awsv2.String(request.DestBucket),
-			Key:         awsv2.String(name),
-			ContentType: awsv2.String("application/json"),
-		}
-		_, err := svc.PutObject(context.Background(), s3PutObject)
-		if err != nil {
-			return nil, err
+		if !bytes.Equal(manifestBytes, publishedManifestBytes) {
+			s3PutObject := &awsv2S3.PutObjectInput{
+				Body:         bytes.NewReader(manifestBytes),
+				Bucket:       awsv2.String(request.DestBucket),
+				Key:          awsv2.String(request.manifestName()),
+				ContentType:  awsv2.String("application/json"),
+				CacheControl: awsv2.String("no-cache"),
+			}
+			_, err := svc.PutObject(ctx, s3PutObject)
+			if err != nil {
+				return nil, err
+			}
+			invalidatedKeys = append(invalidatedKeys, request.manifestName())
 		}
 	}
+	invalidationPaths := planner.invalidationPaths(invalidatedKeys)
+
 	// Log some information
 	logger.Info().
 		Int("TotalFileCount", totalFiles).
+		Int("DeletedObjectCount", len(deletedKeys)).
+		Strs("InvalidationPaths", invalidationPaths).
 		Int64("ArchiveSize", s3Object.ContentLength).
 		Interface("S3Bucket", request.DestBucket).
 		Msg("Expanded ZIP archive")
 
 	// All good
-	return nil, nil
+	return map[string]interface{}{
+		"InvalidationPaths": strings.Join(invalidationPaths, ","),
+	}, nil
 }
 
 // IAMPrivileges returns the IAM privs for this custom action
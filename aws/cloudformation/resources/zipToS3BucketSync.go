@@ -0,0 +1,278 @@
+package resources
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ManifestObjectsKeyName is the MANIFEST.json key that stores the
+	// content hash of every object published from the ZIP archive. The
+	// hashes are used to only upload changed objects and to delete
+	// removed objects.
+	ManifestObjectsKeyName = "objects"
+	// ImmutableCacheControl is the Cache-Control value for objects that
+	// match the immutable asset pattern
+	ImmutableCacheControl = "public, max-age=31536000, immutable"
+	// DefaultImmutableAssetPattern matches file names that include a
+	// content hash of at least eight hex characters (eg: main.3f2a9c1b.js,
+	// app-5d41402abc4b2a76.css)
+	DefaultImmutableAssetPattern = `[.-][0-9a-f]{8,}\.[a-zA-Z0-9]+(\.map)?$`
+	// ContentEncodingGzip is the gzip precompressed encoding. Objects are
+	// published with a `.gz` suffix.
+	ContentEncodingGzip = "gzip"
+	// ContentEncodingBrotli is the brotli precompressed encoding. Objects
+	// are published with a `.br` suffix.
+	ContentEncodingBrotli = "br"
+)
+
+// maxInvalidationPathsLength is the maximum length of the joined
+// invalidation paths. Custom resource responses are limited to 4096
+// bytes, so larger sets are replaced by a wildcard path.
+const maxInvalidationPathsLength = 2048
+
+// precompressedSuffixes are the object key suffixes for each supported
+// content encoding
+var precompressedSuffixes = map[string]string{
+	ContentEncodingGzip:   ".gz",
+	ContentEncodingBrotli: ".br",
+}
+
+// PrecompressedExtensions are the extensions of the objects that are
+// published with precompressed variants. Variants are selected by the
+// extension rather than the content type so that a CloudFront Function can
+// select them from the request URI.
+var PrecompressedExtensions = []string{
+	"css",
+	"htm",
+	"html",
+	"js",
+	"json",
+	"mjs",
+	"svg",
+	"wasm",
+	"xml",
+}
+
+// ZipToS3BucketContentRule is a glob-based rule that sets the metadata of
+// matching objects. Patterns use path.Match syntax with the addition of
+// `**`, which matches zero or more directories. Patterns without a `/`
+// are matched against the object's base name.
+type ZipToS3BucketContentRule struct {
+	Pattern      string
+	CacheControl string `json:",omitempty"`
+	ContentType  string `json:",omitempty"`
+}
+
+// matchGlob returns true if the slash separated name matches the pattern
+func matchGlob(pattern string, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(name))
+		return matched
+	}
+	return matchGlobSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"),
+		strings.Split(name, "/"))
+}
+
+func matchGlobSegments(patternSegments []string, nameSegments []string) bool {
+	for len(patternSegments) != 0 {
+		if patternSegments[0] == "**" {
+			// Consume as many name segments as necessary
+			for i := 0; i <= len(nameSegments); i++ {
+				if matchGlobSegments(patternSegments[1:], nameSegments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(nameSegments) == 0 {
+			return false
+		}
+		matched, _ := path.Match(patternSegments[0], nameSegments[0])
+		if !matched {
+			return false
+		}
+		patternSegments = patternSegments[1:]
+		nameSegments = nameSegments[1:]
+	}
+	return len(nameSegments) == 0
+}
+
+// hasPrecompressedExtension returns true if the name has one of the
+// PrecompressedExtensions
+func hasPrecompressedExtension(name string) bool {
+	extension := strings.TrimPrefix(path.Ext(name), ".")
+	for _, eachExtension := range PrecompressedExtensions {
+		if extension == eachExtension {
+			return true
+		}
+	}
+	return false
+}
+
+// siteObject is a single object published to the destination bucket
+type siteObject struct {
+	key             string
+	contentType     string
+	cacheControl    string
+	contentEncoding string
+	immutable       bool
+	source          []byte
+	sourceHash      string
+}
+
+// hash returns the object hash stored in the manifest. The hash includes
+// the object metadata so that rule changes republish the object.
+func (object *siteObject) hash() string {
+	hasher := sha256.New()
+	for _, eachValue := range []string{object.sourceHash,
+		object.contentType,
+		object.cacheControl,
+		object.contentEncoding} {
+		hasher.Write([]byte(eachValue))
+		hasher.Write([]byte{0})
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// body returns the object body, compressing the source if the object
+// is a precompressed variant
+func (object *siteObject) body() ([]byte, error) {
+	var buffer bytes.Buffer
+	switch object.contentEncoding {
+	case "":
+		return object.source, nil
+	case ContentEncodingGzip:
+		writer, writerErr := gzip.NewWriterLevel(&buffer, gzip.BestCompression)
+		if writerErr != nil {
+			return nil, writerErr
+		}
+		_, writeErr := writer.Write(object.source)
+		if writeErr != nil {
+			return nil, writeErr
+		}
+		closeErr := writer.Close()
+		if closeErr != nil {
+			return nil, closeErr
+		}
+	case ContentEncodingBrotli:
+		writer := brotli.NewWriterLevel(&buffer, brotli.BestCompression)
+		_, writeErr := writer.Write(object.source)
+		if writeErr != nil {
+			return nil, writeErr
+		}
+		closeErr := writer.Close()
+		if closeErr != nil {
+			return nil, closeErr
+		}
+	default:
+		return nil, errors.Errorf("Unsupported content encoding: %s", object.contentEncoding)
+	}
+	return buffer.Bytes(), nil
+}
+
+// siteObjectPlanner determines the objects and metadata to publish for
+// each ZIP archive entry
+type siteObjectPlanner struct {
+	rules          []*ZipToS3BucketContentRule
+	encodings      []string
+	immutableRegex *regexp.Regexp
+	indexDocument  string
+}
+
+func newSiteObjectPlanner(request *ZipToS3BucketResourceRequest) (*siteObjectPlanner, error) {
+	validateErr := request.Validate()
+	if validateErr != nil {
+		return nil, validateErr
+	}
+	planner := &siteObjectPlanner{
+		rules:         request.ContentRules,
+		encodings:     request.PrecompressedEncodings,
+		indexDocument: request.IndexDocument,
+	}
+	if request.ImmutableAssetPattern != "" {
+		planner.immutableRegex = regexp.MustCompile(request.ImmutableAssetPattern)
+	}
+	return planner, nil
+}
+
+// objects returns the object for the named archive entry, followed by
+// any precompressed variants
+func (planner *siteObjectPlanner) objects(name string, source []byte) []*siteObject {
+	sourceHash := sha256.Sum256(source)
+	object := &siteObject{
+		key:        name,
+		source:     source,
+		sourceHash: hex.EncodeToString(sourceHash[:]),
+	}
+	for _, eachRule := range planner.rules {
+		if !matchGlob(eachRule.Pattern, name) {
+			continue
+		}
+		if object.contentType == "" {
+			object.contentType = eachRule.ContentType
+		}
+		if object.cacheControl == "" {
+			object.cacheControl = eachRule.CacheControl
+		}
+	}
+	if object.contentType == "" {
+		object.contentType = mime.TypeByExtension(path.Ext(name))
+	}
+	if object.contentType == "" {
+		object.contentType = "application/octet-stream"
+	}
+	if object.cacheControl == "" &&
+		planner.immutableRegex != nil &&
+		planner.immutableRegex.MatchString(path.Base(name)) {
+		object.cacheControl = ImmutableCacheControl
+	}
+	object.immutable = strings.Contains(object.cacheControl, "immutable")
+
+	objects := []*siteObject{object}
+	if !hasPrecompressedExtension(name) {
+		return objects
+	}
+	for _, eachEncoding := range planner.encodings {
+		variant := *object
+		variant.key = name + precompressedSuffixes[eachEncoding]
+		variant.contentEncoding = eachEncoding
+		objects = append(objects, &variant)
+	}
+	return objects
+}
+
+// invalidationPaths returns the CloudFront invalidation paths for the
+// changed object keys. Index documents also invalidate their
+// directory path.
+func (planner *siteObjectPlanner) invalidationPaths(keys []string) []string {
+	uniquePaths := make(map[string]bool)
+	for _, eachKey := range keys {
+		uniquePaths["/"+eachKey] = true
+		if planner.indexDocument != "" &&
+			path.Base(eachKey) == planner.indexDocument {
+			uniquePaths["/"+strings.TrimSuffix(eachKey, planner.indexDocument)] = true
+		}
+	}
+	paths := make([]string, 0, len(uniquePaths))
+	pathsLength := 0
+	for eachPath := range uniquePaths {
+		paths = append(paths, eachPath)
+		pathsLength += len(eachPath) + 1
+	}
+	if pathsLength > maxInvalidationPathsLength {
+		return []string{"/*"}
+	}
+	sort.Strings(paths)
+	return paths
+}
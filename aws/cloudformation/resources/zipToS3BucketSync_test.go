@@ -0,0 +1,149 @@
+package resources
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestMatchGlob(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		name    string
+		matched bool
+	}{
+		{"*.js", "main.js", true},
+		{"*.js", "assets/js/main.js", true},
+		{"*.js", "main.css", false},
+		{"assets/*.js", "assets/main.js", true},
+		{"assets/*.js", "assets/js/main.js", false},
+		{"assets/**/*.js", "assets/main.js", true},
+		{"assets/**/*.js", "assets/js/vendor/main.js", true},
+		{"/assets/**", "assets/fonts/a.woff2", true},
+		{"**/index.html", "index.html", true},
+		{"**/index.html", "docs/index.html", true},
+		{"docs/**/index.html", "index.html", false},
+	}
+	for _, eachTestCase := range testCases {
+		matched := matchGlob(eachTestCase.pattern, eachTestCase.name)
+		if matched != eachTestCase.matched {
+			t.Fatalf("Pattern `%s` with name `%s`: expected %t",
+				eachTestCase.pattern,
+				eachTestCase.name,
+				eachTestCase.matched)
+		}
+	}
+}
+
+func TestSiteObjectPlanner(t *testing.T) {
+	request := &ZipToS3BucketResourceRequest{
+		IndexDocument: "index.html",
+		ContentRules: []*ZipToS3BucketContentRule{
+			{Pattern: "*.html", CacheControl: "no-cache"},
+			{Pattern: "data/**", ContentType: "application/json", CacheControl: "max-age=60"},
+		},
+		PrecompressedEncodings: []string{ContentEncodingGzip, ContentEncodingBrotli},
+		ImmutableAssetPattern:  DefaultImmutableAssetPattern,
+	}
+	planner, plannerErr := newSiteObjectPlanner(request)
+	if plannerErr != nil {
+		t.Fatal(plannerErr)
+	}
+	source := []byte(strings.Repeat("console.log('Sparta');\n", 100))
+
+	// Hashed assets are immutable and have precompressed variants
+	objects := planner.objects("assets/main.3f2a9c1b.js", source)
+	if len(objects) != 3 ||
+		objects[0].cacheControl != ImmutableCacheControl ||
+		!objects[0].immutable ||
+		objects[1].key != "assets/main.3f2a9c1b.js.gz" ||
+		objects[2].key != "assets/main.3f2a9c1b.js.br" {
+		t.Fatalf("Unexpected hashed asset objects: %#v", objects)
+	}
+	gzipBody, _ := objects[1].body()
+	gzipReader, gzipReaderErr := gzip.NewReader(bytes.NewReader(gzipBody))
+	if gzipReaderErr != nil {
+		t.Fatal(gzipReaderErr)
+	}
+	gzipSource, _ := ioutil.ReadAll(gzipReader)
+	brotliBody, _ := objects[2].body()
+	brotliSource, _ := ioutil.ReadAll(brotli.NewReader(bytes.NewReader(brotliBody)))
+	if !bytes.Equal(gzipSource, source) || !bytes.Equal(brotliSource, source) {
+		t.Fatalf("Unexpected precompressed variant bodies")
+	}
+
+	// Rules take precedence over the content type and immutable defaults
+	objects = planner.objects("data/results.deadbeef01.txt", source)
+	if objects[0].contentType != "application/json" ||
+		objects[0].cacheControl != "max-age=60" ||
+		objects[0].immutable {
+		t.Fatalf("Unexpected rule object: %#v", objects[0])
+	}
+
+	// Variants are published by extension so that they match the CloudFront
+	// Function rewrites, even if a rule overrides the content type
+	overridePlanner, _ := newSiteObjectPlanner(&ZipToS3BucketResourceRequest{
+		ContentRules: []*ZipToS3BucketContentRule{
+			{Pattern: "*.js", ContentType: "application/octet-stream"},
+		},
+		PrecompressedEncodings: []string{ContentEncodingGzip, ContentEncodingBrotli},
+	})
+	objects = overridePlanner.objects("vendor/app.js", source)
+	if len(objects) != 3 ||
+		objects[0].contentType != "application/octet-stream" ||
+		objects[1].key != "vendor/app.js.gz" {
+		t.Fatalf("Unexpected content type rule objects: %#v", objects)
+	}
+	objects = overridePlanner.objects("notes.txt", source)
+	if len(objects) != 1 {
+		t.Fatalf("Unexpected variants for an extension without a CloudFront rewrite: %#v", objects)
+	}
+
+	// Binary content isn't precompressed
+	objects = planner.objects("logo.png", []byte{0x89, 0x50, 0x4e, 0x47})
+	if len(objects) != 1 || objects[0].contentType != "image/png" {
+		t.Fatalf("Unexpected binary objects: %#v", objects)
+	}
+
+	// Metadata changes update the hash
+	htmlObject := planner.objects("index.html", source)[0]
+	request.ContentRules[0].CacheControl = "max-age=0"
+	updatedObject := planner.objects("index.html", source)[0]
+	if htmlObject.hash() == updatedObject.hash() ||
+		htmlObject.hash() != htmlObject.hash() {
+		t.Fatalf("Unexpected object hash")
+	}
+
+	paths := planner.invalidationPaths([]string{"index.html", "docs/index.html", "app.js"})
+	expectedPaths := []string{"/", "/app.js", "/docs/", "/docs/index.html", "/index.html"}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Fatalf("Unexpected invalidation paths: %v", paths)
+	}
+	manyKeys := make([]string, 0, 200)
+	for i := 0; i != 200; i++ {
+		manyKeys = append(manyKeys, strings.Repeat("x", i+1)+".html")
+	}
+	paths = planner.invalidationPaths(manyKeys)
+	if !reflect.DeepEqual(paths, []string{"/*"}) {
+		t.Fatalf("Expected wildcard invalidation path: %v", paths)
+	}
+}
+
+func TestZipToS3BucketRequestValidate(t *testing.T) {
+	invalidRequests := []*ZipToS3BucketResourceRequest{
+		{ContentRules: []*ZipToS3BucketContentRule{{CacheControl: "no-cache"}}},
+		{ContentRules: []*ZipToS3BucketContentRule{{Pattern: "assets/[*.js"}}},
+		{PrecompressedEncodings: []string{"deflate"}},
+		{ImmutableAssetPattern: "[0-9"},
+	}
+	for _, eachRequest := range invalidRequests {
+		if eachRequest.Validate() == nil {
+			t.Fatalf("Expected validation error for request: %#v", eachRequest)
+		}
+	}
+}
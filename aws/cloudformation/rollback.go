@@ -0,0 +1,159 @@
+package cloudformation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsv2ARN "github.com/aws/aws-sdk-go-v2/aws/arn"
+	awsv2CF "github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	awsv2CFTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	gofcloudwatch "github.com/awslabs/goformation/v5/cloudformation/cloudwatch"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// RollbackTriggerMetadataKey is the resource Metadata key that marks
+	// an AWS::CloudWatch::Alarm or AWS::CloudWatch::CompositeAlarm resource
+	// as a CloudFormation rollback trigger. The value is the number of
+	// minutes CloudFormation monitors the alarm after the stack update.
+	RollbackTriggerMetadataKey = "SpartaRollbackTrigger"
+	// MaxRollbackTriggers is the maximum number of rollback triggers
+	// supported by a stack operation
+	MaxRollbackTriggers = 5
+	// MaxRollbackMonitoringMinutes is the maximum rollback trigger monitoring
+	// period
+	MaxRollbackMonitoringMinutes = 180
+)
+
+// rollbackTriggerMinutes returns the monitoring time for the resource if
+// it's tagged as a rollback trigger
+func rollbackTriggerMinutes(resource gof.Resource) (int, bool) {
+	var metadata map[string]interface{}
+	switch typedResource := resource.(type) {
+	case *gofcloudwatch.Alarm:
+		metadata = typedResource.AWSCloudFormationMetadata
+	case *gofcloudwatch.CompositeAlarm:
+		metadata = typedResource.AWSCloudFormationMetadata
+	}
+	value, valueExists := metadata[RollbackTriggerMetadataKey]
+	if !valueExists {
+		return 0, false
+	}
+	// In memory templates store ints, templates read from disk store float64s
+	switch typedValue := value.(type) {
+	case int:
+		return typedValue, true
+	case float64:
+		return int(typedValue), true
+	}
+	return 0, false
+}
+
+// StackRollbackConfiguration returns the RollbackConfiguration for the
+// alarm resources in cfTemplate tagged with RollbackTriggerMetadataKey.
+// Only alarms that already exist in the stack can be used as triggers,
+// so the configuration is nil for new stacks and new alarms are
+// monitored starting with the next update. An error is returned if more
+// than MaxRollbackTriggers alarms are tagged.
+func StackRollbackConfiguration(ctx context.Context,
+	serviceName string,
+	cfTemplate *gof.Template,
+	awsCloudFormation *awsv2CF.Client,
+	logger *zerolog.Logger) (*awsv2CFTypes.RollbackConfiguration, error) {
+
+	triggerMinutes := make(map[string]int)
+	for eachName, eachResource := range cfTemplate.Resources {
+		minutes, isTrigger := rollbackTriggerMinutes(eachResource)
+		if isTrigger {
+			triggerMinutes[eachName] = minutes
+		}
+	}
+	if len(triggerMinutes) == 0 {
+		return nil, nil
+	}
+	if len(triggerMinutes) > MaxRollbackTriggers {
+		triggerNames := make([]string, 0, len(triggerMinutes))
+		for eachName := range triggerMinutes {
+			triggerNames = append(triggerNames, eachName)
+		}
+		sort.Strings(triggerNames)
+		return nil, errors.Errorf("%d alarms are tagged as rollback triggers (%s), but stack operations support at most %d. Use decorator.AlarmPolicy.WithCompositeAlarm to monitor the alarms with a single composite alarm",
+			len(triggerNames),
+			strings.Join(triggerNames, ", "),
+			MaxRollbackTriggers)
+	}
+
+	describeOutput, describeErr := awsCloudFormation.DescribeStacks(ctx,
+		&awsv2CF.DescribeStacksInput{
+			StackName: awsv2.String(serviceName),
+		})
+	if describeErr != nil && !strings.Contains(describeErr.Error(), "does not exist") {
+		return nil, errors.Wrapf(describeErr, "Failed to describe stack")
+	}
+	if describeErr != nil || len(describeOutput.Stacks) == 0 {
+		logger.Info().
+			Str("StackName", serviceName).
+			Msg("Rollback triggers will be enabled after the stack is created")
+		return nil, nil
+	}
+	stackARN, stackARNErr := awsv2ARN.Parse(awsv2.ToString(describeOutput.Stacks[0].StackId))
+	if stackARNErr != nil {
+		return nil, errors.Wrapf(stackARNErr, "Failed to parse stack ID")
+	}
+
+	// Find the alarm names of the existing triggers
+	rollbackConfig := &awsv2CFTypes.RollbackConfiguration{}
+	monitoringMinutes := 0
+	paginator := awsv2CF.NewListStackResourcesPaginator(awsCloudFormation,
+		&awsv2CF.ListStackResourcesInput{
+			StackName: awsv2.String(serviceName),
+		})
+	for paginator.HasMorePages() {
+		page, pageErr := paginator.NextPage(ctx)
+		if pageErr != nil {
+			return nil, errors.Wrapf(pageErr, "Failed to list stack resources")
+		}
+		for _, eachSummary := range page.StackResourceSummaries {
+			minutes, isTrigger := triggerMinutes[awsv2.ToString(eachSummary.LogicalResourceId)]
+			if !isTrigger || awsv2.ToString(eachSummary.PhysicalResourceId) == "" {
+				continue
+			}
+			alarmARN := awsv2ARN.ARN{
+				Partition: stackARN.Partition,
+				Service:   "cloudwatch",
+				Region:    stackARN.Region,
+				AccountID: stackARN.AccountID,
+				Resource:  fmt.Sprintf("alarm:%s", awsv2.ToString(eachSummary.PhysicalResourceId)),
+			}
+			rollbackConfig.RollbackTriggers = append(rollbackConfig.RollbackTriggers,
+				awsv2CFTypes.RollbackTrigger{
+					Arn:  awsv2.String(alarmARN.String()),
+					Type: eachSummary.ResourceType,
+				})
+			if minutes > monitoringMinutes {
+				monitoringMinutes = minutes
+			}
+		}
+	}
+	if len(rollbackConfig.RollbackTriggers) == 0 {
+		return nil, nil
+	}
+	sort.Slice(rollbackConfig.RollbackTriggers, func(i, j int) bool {
+		return awsv2.ToString(rollbackConfig.RollbackTriggers[i].Arn) <
+			awsv2.ToString(rollbackConfig.RollbackTriggers[j].Arn)
+	})
+	if monitoringMinutes > MaxRollbackMonitoringMinutes {
+		monitoringMinutes = MaxRollbackMonitoringMinutes
+	}
+	rollbackConfig.MonitoringTimeInMinutes = awsv2.Int32(int32(monitoringMinutes))
+	logger.Info().
+		Interface("RollbackTriggers", rollbackConfig.RollbackTriggers).
+		Int("MonitoringTimeInMinutes", monitoringMinutes).
+		Msg("Enabling rollback triggers")
+	return rollbackConfig, nil
+}
@@ -0,0 +1,36 @@
+package cloudformation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	gofcloudwatch "github.com/awslabs/goformation/v5/cloudformation/cloudwatch"
+	"github.com/rs/zerolog"
+)
+
+func TestStackRollbackConfigurationTooManyTriggers(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	template := gof.NewTemplate()
+	for i := 0; i <= MaxRollbackTriggers; i++ {
+		template.Resources[fmt.Sprintf("Alarm%d", i)] = &gofcloudwatch.Alarm{
+			AWSCloudFormationMetadata: map[string]interface{}{
+				RollbackTriggerMetadataKey: 10,
+			},
+		}
+	}
+	// The trigger count is checked before the stack is described
+	_, configErr := StackRollbackConfiguration(context.Background(),
+		"RollbackService",
+		template,
+		nil,
+		&logger)
+	if configErr == nil {
+		t.Fatalf("Expected error for %d rollback triggers", MaxRollbackTriggers+1)
+	}
+	if !strings.Contains(configErr.Error(), "WithCompositeAlarm") {
+		t.Fatalf("Expected error to reference WithCompositeAlarm: %s", configErr)
+	}
+}
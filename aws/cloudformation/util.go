@@ -700,6 +700,15 @@ func CreateStackChangeSet(ctx context.Context,
 		}
 		changeSetInput.Tags = awsTags
 	}
+	rollbackConfig, rollbackConfigErr := StackRollbackConfiguration(ctx,
+		serviceName,
+		cfTemplate,
+		awsCloudFormation,
+		logger)
+	if rollbackConfigErr != nil {
+		return nil, rollbackConfigErr
+	}
+	changeSetInput.RollbackConfiguration = rollbackConfig
 	_, changeSetError := awsCloudFormation.CreateChangeSet(ctx, changeSetInput)
 	if nil != changeSetError {
 		return nil, changeSetError
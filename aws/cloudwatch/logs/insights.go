@@ -0,0 +1,89 @@
+package cloudwatchlogs
+
+import (
+	"context"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsv2CWLogs "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	awsv2CWLogsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// MaxInsightsLogGroups is the maximum number of log groups that a single
+// CloudWatch Logs Insights query can search
+const MaxInsightsLogGroups = 20
+
+// insightsPollInterval is the interval between GetQueryResults calls
+const insightsPollInterval = time.Second
+
+// QueryWithContext runs the CloudWatch Logs Insights query across the
+// logGroupNames for the [startTime, endTime] window and returns the result
+// rows. Queries that span more than MaxInsightsLogGroups log groups are
+// split into multiple queries whose rows are concatenated.
+func QueryWithContext(reqContext context.Context,
+	awsConfig awsv2.Config,
+	logGroupNames []string,
+	query string,
+	startTime time.Time,
+	endTime time.Time,
+	logger *zerolog.Logger) ([][]awsv2CWLogsTypes.ResultField, error) {
+
+	cwlogsSvc := awsv2CWLogs.NewFromConfig(awsConfig)
+	results := make([][]awsv2CWLogsTypes.ResultField, 0)
+	for len(logGroupNames) != 0 {
+		batchSize := len(logGroupNames)
+		if batchSize > MaxInsightsLogGroups {
+			batchSize = MaxInsightsLogGroups
+		}
+		batch := logGroupNames[0:batchSize]
+		logGroupNames = logGroupNames[batchSize:]
+
+		startQueryInput := &awsv2CWLogs.StartQueryInput{
+			LogGroupNames: batch,
+			QueryString:   awsv2.String(query),
+			StartTime:     awsv2.Int64(startTime.Unix()),
+			EndTime:       awsv2.Int64(endTime.Unix()),
+		}
+		startQueryOutput, startQueryErr := cwlogsSvc.StartQuery(reqContext, startQueryInput)
+		if startQueryErr != nil {
+			return nil, errors.Wrapf(startQueryErr, "Failed to start Insights query")
+		}
+		logger.Debug().
+			Str("QueryID", *startQueryOutput.QueryId).
+			Strs("LogGroups", batch).
+			Msg("Started Insights query")
+
+		for {
+			select {
+			case <-reqContext.Done():
+				return nil, reqContext.Err()
+			case <-time.After(insightsPollInterval):
+			}
+			queryResults, queryResultsErr := cwlogsSvc.GetQueryResults(reqContext,
+				&awsv2CWLogs.GetQueryResultsInput{
+					QueryId: startQueryOutput.QueryId,
+				})
+			if queryResultsErr != nil {
+				return nil, errors.Wrapf(queryResultsErr, "Failed to get Insights query results")
+			}
+			logger.Debug().
+				Str("QueryID", *startQueryOutput.QueryId).
+				Str("Status", string(queryResults.Status)).
+				Msg("Insights query status")
+
+			if queryResults.Status == awsv2CWLogsTypes.QueryStatusComplete {
+				results = append(results, queryResults.Results...)
+				break
+			}
+			if queryResults.Status != awsv2CWLogsTypes.QueryStatusScheduled &&
+				queryResults.Status != awsv2CWLogsTypes.QueryStatusRunning {
+				return nil, errors.Errorf("Insights query %s did not complete: %s",
+					*startQueryOutput.QueryId,
+					queryResults.Status)
+			}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,110 @@
+package cloudwatchlogs
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsv2CWLogs "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	awsv2CWLogsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/rs/zerolog"
+)
+
+// LogGroupEvent is a log event together with the name of the log group
+// that includes it
+type LogGroupEvent struct {
+	LogGroupName string
+	awsv2CWLogsTypes.FilteredLogEvent
+}
+
+// TailGroupsWithContext is a utility function that supports tailing a set of
+// log groups using the optional filter, starting with the events logged at
+// startTime. The log groups are polled in turn so that the combined request
+// rate stays within the FilterLogEvents limit. After each log group has been
+// polled, the new events are sent to the returned channel in timestamp order.
+// Polling errors are sent as events with an EventId of "N/A".
+func TailGroupsWithContext(reqContext context.Context,
+	closeChan chan bool,
+	awsConfig awsv2.Config,
+	logGroupNames []string,
+	filter string,
+	startTime time.Time,
+	logger *zerolog.Logger) <-chan *LogGroupEvent {
+
+	outputChannel := make(chan *LogGroupEvent)
+	if len(logGroupNames) == 0 {
+		return outputChannel
+	}
+	// Milliseconds...
+	lastSeenTimestamps := make(map[string]int64)
+	for _, eachName := range logGroupNames {
+		lastSeenTimestamps[eachName] = startTime.Unix() * 1000
+	}
+	logger.Debug().
+		Strs("LogGroups", logGroupNames).
+		Time("StartTime", startTime).
+		Msg("Started polling")
+
+	cwlogsSvc := awsv2CWLogs.NewFromConfig(awsConfig)
+	go func() {
+		ticker := time.NewTicker(time.Millisecond * 333) //AWS cloudwatch logs limit is 5tx/sec
+		defer ticker.Stop()
+
+		pending := make([]*LogGroupEvent, 0)
+		groupIndex := 0
+		for {
+			select {
+			case <-closeChan:
+				logger.Debug().Msg("Exiting polling loop")
+				return
+			case <-ticker.C:
+				logGroupName := logGroupNames[groupIndex]
+				groupIndex = (groupIndex + 1) % len(logGroupNames)
+
+				logParam := tailParams(logGroupName, filter, lastSeenTimestamps[logGroupName])
+				paginator := awsv2CWLogs.NewFilterLogEventsPaginator(cwlogsSvc, logParam)
+				for paginator.HasMorePages() {
+					filterEvents, filterEventsErr := paginator.NextPage(reqContext)
+					if filterEventsErr != nil {
+						pending = append(pending, &LogGroupEvent{
+							LogGroupName: logGroupName,
+							FilteredLogEvent: awsv2CWLogsTypes.FilteredLogEvent{
+								EventId:   awsv2.String("N/A"),
+								Message:   awsv2.String(filterEventsErr.Error()),
+								Timestamp: awsv2.Int64(time.Now().Unix() * 1000),
+							},
+						})
+						break
+					}
+					for _, eachEvent := range filterEvents.Events {
+						if lastSeenTimestamps[logGroupName] <= *eachEvent.Timestamp {
+							lastSeenTimestamps[logGroupName] = *eachEvent.Timestamp + 1
+						}
+						pending = append(pending, &LogGroupEvent{
+							LogGroupName:     logGroupName,
+							FilteredLogEvent: eachEvent,
+						})
+					}
+				}
+				// Wait until every group has been polled
+				if groupIndex != 0 || len(pending) == 0 {
+					continue
+				}
+				sort.SliceStable(pending, func(i, j int) bool {
+					return *pending[i].Timestamp < *pending[j].Timestamp
+				})
+				for _, eachEvent := range pending {
+					select {
+					case outputChannel <- eachEvent:
+					case <-closeChan:
+						logger.Debug().Msg("Exiting polling loop")
+						return
+					}
+				}
+				pending = make([]*LogGroupEvent, 0)
+			}
+		}
+	}()
+	return outputChannel
+}
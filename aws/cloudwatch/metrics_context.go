@@ -0,0 +1,446 @@
+package cloudwatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// EMF limits.
+// Ref: https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/CloudWatch_Embedded_Metric_Format_Specification.html
+const (
+	// MaxMetricsPerDocument is the maximum number of metrics in a single
+	// embedded metric document. Additional metrics are written to
+	// additional documents.
+	MaxMetricsPerDocument = 100
+	// MaxValuesPerMetric is the maximum number of values for a single
+	// metric in a single embedded metric document. Additional values are
+	// written to additional documents.
+	MaxValuesPerMetric = 100
+	// MaxDimensionsPerSet is the maximum number of dimension keys in a
+	// single dimension set
+	MaxDimensionsPerSet = 30
+	// MaxDimensionsPerDocument is the maximum number of distinct dimension
+	// keys referenced by a single embedded metric document. Additional
+	// dimension sets are written to additional documents.
+	MaxDimensionsPerDocument = 30
+)
+
+// StorageResolution is the storage resolution of a metric in seconds
+type StorageResolution int
+
+const (
+	// ResolutionStandard is the default one minute storage resolution
+	ResolutionStandard StorageResolution = 60
+	// ResolutionHigh is the one second storage resolution
+	ResolutionHigh StorageResolution = 1
+)
+
+type contextKey int
+
+const (
+	contextKeyMetricsContext contextKey = iota
+)
+
+var validMetricUnits = map[MetricUnit]bool{
+	UnitSeconds:            true,
+	UnitMicroseconds:       true,
+	UnitMilliseconds:       true,
+	UnitBytes:              true,
+	UnitKilobytes:          true,
+	UnitMegabytes:          true,
+	UnitGigabytes:          true,
+	UnitTerabytes:          true,
+	UnitBits:               true,
+	UnitKilobits:           true,
+	UnitMegabits:           true,
+	UnitGigabits:           true,
+	UnitTerabits:           true,
+	UnitPercent:            true,
+	UnitCount:              true,
+	UnitBytesPerSecond:     true,
+	UnitKilobytesPerSecond: true,
+	UnitMegabytesPerSecond: true,
+	UnitGigabytesPerSecond: true,
+	UnitTerabytesPerSecond: true,
+	UnitBitsPerSecond:      true,
+	UnitKilobitsPerSecond:  true,
+	UnitMegabitsPerSecond:  true,
+	UnitGigabitsPerSecond:  true,
+	UnitTerabitsPerSecond:  true,
+	UnitCountPerSecond:     true,
+	UnitNone:               true,
+}
+
+// metricsContextValues are the values recorded for a single metric
+type metricsContextValues struct {
+	name       string
+	unit       MetricUnit
+	resolution StorageResolution
+	values     []float64
+}
+
+// MetricsContext collects the embedded metrics for a single invocation and
+// writes them as one or more CloudWatch Embedded Metric Format documents.
+// Use RegisterEmbeddedMetricsInterceptor in the interceptor package to
+// create a MetricsContext for each invocation and flush it when the
+// invocation completes. Handlers access it via MetricsContextFromContext.
+// MetricsContext is safe for concurrent use.
+type MetricsContext struct {
+	mu              sync.Mutex
+	namespace       string
+	timestamp       time.Time
+	dimensionSets   [][]string
+	dimensionValues map[string]string
+	metrics         []*metricsContextValues
+	metricIndex     map[string]*metricsContextValues
+	properties      map[string]interface{}
+	errors          []string
+	flushed         bool
+}
+
+// NewMetricsContext returns a new MetricsContext that publishes metrics
+// to the given namespace
+func NewMetricsContext(namespace string) *MetricsContext {
+	mc := &MetricsContext{
+		namespace:       namespace,
+		timestamp:       time.Now(),
+		dimensionSets:   make([][]string, 0),
+		dimensionValues: make(map[string]string),
+		metrics:         make([]*metricsContextValues, 0),
+		metricIndex:     make(map[string]*metricsContextValues),
+		properties:      make(map[string]interface{}),
+		errors:          make([]string, 0),
+	}
+	if namespace == "" || len(namespace) > 255 {
+		mc.errors = append(mc.errors,
+			fmt.Sprintf("Namespace must be 1-255 characters: %q", namespace))
+	}
+	return mc
+}
+
+// ContextWithMetrics returns a new context that includes the MetricsContext
+func ContextWithMetrics(ctx context.Context, mc *MetricsContext) context.Context {
+	return context.WithValue(ctx, contextKeyMetricsContext, mc)
+}
+
+// MetricsContextFromContext returns the invocation MetricsContext, or nil
+// if the context doesn't include one
+func MetricsContextFromContext(ctx context.Context) *MetricsContext {
+	mc, _ := ctx.Value(contextKeyMetricsContext).(*MetricsContext)
+	return mc
+}
+
+// WithTimestamp is a fluent builder to set the document timestamp. The
+// default timestamp is the MetricsContext creation time.
+func (mc *MetricsContext) WithTimestamp(timestamp time.Time) *MetricsContext {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.timestamp = timestamp
+	return mc
+}
+
+// WithDimensionSet is a fluent builder to add a dimension set. Every metric
+// is published for each dimension set. Dimension values are shared across
+// dimension sets, so a dimension key must have the same value in every set.
+func (mc *MetricsContext) WithDimensionSet(dimensions map[string]string) *MetricsContext {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if len(dimensions) > MaxDimensionsPerSet {
+		mc.errors = append(mc.errors,
+			fmt.Sprintf("Dimension set must not have more than %d dimensions. Count: %d",
+				MaxDimensionsPerSet,
+				len(dimensions)))
+		return mc
+	}
+	keys := make([]string, 0, len(dimensions))
+	for eachKey, eachValue := range dimensions {
+		existingValue, exists := mc.dimensionValues[eachKey]
+		if exists && existingValue != eachValue {
+			mc.errors = append(mc.errors,
+				fmt.Sprintf("Dimension %s has conflicting values: %q, %q",
+					eachKey,
+					existingValue,
+					eachValue))
+			continue
+		}
+		if _, isMetric := mc.metricIndex[eachKey]; isMetric {
+			mc.errors = append(mc.errors,
+				fmt.Sprintf("Dimension %s conflicts with a metric of the same name", eachKey))
+			continue
+		}
+		mc.dimensionValues[eachKey] = eachValue
+		keys = append(keys, eachKey)
+	}
+	sort.Strings(keys)
+	mc.dimensionSets = append(mc.dimensionSets, keys)
+	return mc
+}
+
+// WithProperty is a fluent builder to add a property to every document.
+// Properties should be used for high cardinality values that need to be
+// searchable, but not treated as independent metrics
+func (mc *MetricsContext) WithProperty(key string, value interface{}) *MetricsContext {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.properties[key] = value
+	return mc
+}
+
+func (mc *MetricsContext) putMetric(name string,
+	value float64,
+	unit MetricUnit,
+	resolution StorageResolution) *MetricsContext {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if name == "" || len(name) > 1024 {
+		mc.errors = append(mc.errors,
+			fmt.Sprintf("Metric name must be 1-1024 characters: %q", name))
+		return mc
+	}
+	if !validMetricUnits[unit] {
+		mc.errors = append(mc.errors,
+			fmt.Sprintf("Metric %s has an invalid unit: %q", name, unit))
+		return mc
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		mc.errors = append(mc.errors,
+			fmt.Sprintf("Metric %s value must be a finite number", name))
+		return mc
+	}
+	if _, isDimension := mc.dimensionValues[name]; isDimension {
+		mc.errors = append(mc.errors,
+			fmt.Sprintf("Metric %s conflicts with a dimension of the same name", name))
+		return mc
+	}
+	existing, exists := mc.metricIndex[name]
+	if !exists {
+		existing = &metricsContextValues{
+			name:       name,
+			unit:       unit,
+			resolution: resolution,
+			values:     make([]float64, 0, 1),
+		}
+		mc.metricIndex[name] = existing
+		mc.metrics = append(mc.metrics, existing)
+	} else if existing.unit != unit || existing.resolution != resolution {
+		mc.errors = append(mc.errors,
+			fmt.Sprintf("Metric %s must use the same unit and resolution for every value", name))
+		return mc
+	}
+	existing.values = append(existing.values, value)
+	return mc
+}
+
+// PutMetric is a fluent builder to record a standard resolution metric
+// value. Multiple values for the same metric are published as a value array.
+func (mc *MetricsContext) PutMetric(name string, value float64, unit MetricUnit) *MetricsContext {
+	return mc.putMetric(name, value, unit, ResolutionStandard)
+}
+
+// PutHighResolutionMetric is a fluent builder to record a one second
+// resolution metric value
+func (mc *MetricsContext) PutHighResolutionMetric(name string,
+	value float64,
+	unit MetricUnit) *MetricsContext {
+	return mc.putMetric(name, value, unit, ResolutionHigh)
+}
+
+// Validate returns an error describing every invalid namespace, dimension
+// and metric value provided to the MetricsContext
+func (mc *MetricsContext) Validate() error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.validate()
+}
+
+func (mc *MetricsContext) validate() error {
+	if len(mc.errors) != 0 {
+		return errors.Errorf("Invalid embedded metrics: %s", strings.Join(mc.errors, "; "))
+	}
+	return nil
+}
+
+// emfMetricDefinition is a MetricDefinition with the optional
+// StorageResolution value
+type emfMetricDefinition struct {
+	Name              string `json:"Name"`
+	Unit              string `json:"Unit"`
+	StorageResolution int    `json:"StorageResolution,omitempty"`
+}
+
+type emfMetricDirective struct {
+	Namespace  string                 `json:"Namespace"`
+	Dimensions [][]string             `json:"Dimensions"`
+	Metrics    []*emfMetricDefinition `json:"Metrics"`
+}
+
+type emfMetadata struct {
+	Timestamp         int64                `json:"Timestamp"`
+	CloudWatchMetrics []emfMetricDirective `json:"CloudWatchMetrics"`
+}
+
+// dimensionSetBatches groups the dimension sets so that every group
+// references at most MaxDimensionsPerDocument distinct keys
+func (mc *MetricsContext) dimensionSetBatches() [][][]string {
+	if len(mc.dimensionSets) == 0 {
+		return [][][]string{{{}}}
+	}
+	batches := make([][][]string, 0)
+	batchKeys := make(map[string]bool)
+	var batch [][]string
+	for _, eachSet := range mc.dimensionSets {
+		newKeys := 0
+		for _, eachKey := range eachSet {
+			if !batchKeys[eachKey] {
+				newKeys++
+			}
+		}
+		if batch != nil && len(batchKeys)+newKeys > MaxDimensionsPerDocument {
+			batches = append(batches, batch)
+			batch = nil
+			batchKeys = make(map[string]bool)
+		}
+		batch = append(batch, eachSet)
+		for _, eachKey := range eachSet {
+			batchKeys[eachKey] = true
+		}
+	}
+	return append(batches, batch)
+}
+
+// metricBatches groups the metric values so that every group has at most
+// MaxMetricsPerDocument metrics, each with at most MaxValuesPerMetric values
+func (mc *MetricsContext) metricBatches() [][]*metricsContextValues {
+	batches := make([][]*metricsContextValues, 0)
+	for _, eachMetric := range mc.metrics {
+		for valueIndex, batchIndex := 0, 0; valueIndex < len(eachMetric.values); batchIndex++ {
+			valueEnd := valueIndex + MaxValuesPerMetric
+			if valueEnd > len(eachMetric.values) {
+				valueEnd = len(eachMetric.values)
+			}
+			chunk := &metricsContextValues{
+				name:       eachMetric.name,
+				unit:       eachMetric.unit,
+				resolution: eachMetric.resolution,
+				values:     eachMetric.values[valueIndex:valueEnd],
+			}
+			valueIndex = valueEnd
+			// Metric names are document keys, so each chunk of a metric's values
+			// goes into a different document
+			for batchIndex < len(batches) && len(batches[batchIndex]) >= MaxMetricsPerDocument {
+				batchIndex++
+			}
+			if batchIndex == len(batches) {
+				batches = append(batches, make([]*metricsContextValues, 0))
+			}
+			batches[batchIndex] = append(batches[batchIndex], chunk)
+		}
+	}
+	return batches
+}
+
+// Documents returns the Embedded Metric Format JSON documents for the
+// recorded metrics. Metrics, metric values and dimension sets that exceed
+// the EMF limits are split across multiple documents. An error is returned
+// if any recorded value is invalid.
+func (mc *MetricsContext) Documents() ([][]byte, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.documents()
+}
+
+func (mc *MetricsContext) documents() ([][]byte, error) {
+	validateErr := mc.validate()
+	if validateErr != nil {
+		return nil, validateErr
+	}
+	documents := make([][]byte, 0)
+	if len(mc.metrics) == 0 {
+		return documents, nil
+	}
+	for _, eachDimensionBatch := range mc.dimensionSetBatches() {
+		for _, eachMetricBatch := range mc.metricBatches() {
+			document := make(map[string]interface{})
+			for eachKey, eachValue := range mc.properties {
+				document[eachKey] = eachValue
+			}
+			for _, eachSet := range eachDimensionBatch {
+				for _, eachKey := range eachSet {
+					document[eachKey] = mc.dimensionValues[eachKey]
+				}
+			}
+			directive := emfMetricDirective{
+				Namespace:  mc.namespace,
+				Dimensions: eachDimensionBatch,
+				Metrics:    make([]*emfMetricDefinition, 0, len(eachMetricBatch)),
+			}
+			for _, eachMetric := range eachMetricBatch {
+				definition := &emfMetricDefinition{
+					Name: eachMetric.name,
+					Unit: string(eachMetric.unit),
+				}
+				if eachMetric.resolution == ResolutionHigh {
+					definition.StorageResolution = int(ResolutionHigh)
+				}
+				directive.Metrics = append(directive.Metrics, definition)
+				if len(eachMetric.values) == 1 {
+					document[eachMetric.name] = eachMetric.values[0]
+				} else {
+					document[eachMetric.name] = eachMetric.values
+				}
+			}
+			document["_aws"] = &emfMetadata{
+				Timestamp:         mc.timestamp.UnixNano() / int64(time.Millisecond),
+				CloudWatchMetrics: []emfMetricDirective{directive},
+			}
+			jsonBytes, jsonBytesErr := json.Marshal(document)
+			if jsonBytesErr != nil {
+				return nil, errors.Wrapf(jsonBytesErr, "Failed to marshal embedded metric")
+			}
+			documents = append(documents, jsonBytes)
+		}
+	}
+	return documents, nil
+}
+
+// FlushToSink writes the Embedded Metric Format documents to the sink, one
+// document per line. Only the first call writes the documents. Subsequent
+// calls are no-ops so that metrics are published exactly once.
+func (mc *MetricsContext) FlushToSink(sink io.Writer) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if mc.flushed {
+		return nil
+	}
+	mc.flushed = true
+	documents, documentsErr := mc.documents()
+	if documentsErr != nil {
+		return documentsErr
+	}
+	for _, eachDocument := range documents {
+		_, writeErr := fmt.Fprintf(sink, "%s\n", string(eachDocument))
+		if writeErr != nil {
+			return writeErr
+		}
+	}
+	return nil
+}
+
+// Flush writes the Embedded Metric Format documents to stdout. See
+// FlushToSink.
+func (mc *MetricsContext) Flush() error {
+	return mc.FlushToSink(os.Stdout)
+}
@@ -0,0 +1,240 @@
+package cloudwatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+type testEMFDocument struct {
+	AWS emfMetadata `json:"_aws"`
+}
+
+// validateEmbeddedMetricDocument validates the document against the
+// emf.schema.json Embedded Metric Format schema
+func validateEmbeddedMetricDocument(document []byte) error {
+	schemaLoader := gojsonschema.NewReferenceLoader("file://./emf.schema.json")
+	documentLoader := gojsonschema.NewBytesLoader(document)
+	result, resultErr := gojsonschema.Validate(schemaLoader, documentLoader)
+	if resultErr != nil {
+		return errors.Wrapf(resultErr, "Failed to validate embedded metric document")
+	}
+	if !result.Valid() {
+		messages := make([]string, 0, len(result.Errors()))
+		for _, eachError := range result.Errors() {
+			messages = append(messages, eachError.String())
+		}
+		return errors.Errorf("Invalid embedded metric document: %s",
+			strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+func ensureValidDocuments(t *testing.T, mc *MetricsContext, expectedCount int) []testEMFDocument {
+	documents, documentsErr := mc.Documents()
+	if documentsErr != nil {
+		t.Fatalf("Failed to create documents: %s", documentsErr)
+	}
+	if len(documents) != expectedCount {
+		t.Fatalf("Expected %d documents. Found: %d", expectedCount, len(documents))
+	}
+	parsed := make([]testEMFDocument, 0, len(documents))
+	for _, eachDocument := range documents {
+		validateErr := validateEmbeddedMetricDocument(eachDocument)
+		if validateErr != nil {
+			t.Fatalf("Invalid document: %s\n%s", validateErr, string(eachDocument))
+		}
+		var document testEMFDocument
+		unmarshalErr := json.Unmarshal(eachDocument, &document)
+		if unmarshalErr != nil {
+			t.Fatal(unmarshalErr)
+		}
+		parsed = append(parsed, document)
+	}
+	return parsed
+}
+
+func TestMetricsContext(t *testing.T) {
+	mc := NewMetricsContext("SpecialNamespace").
+		WithDimensionSet(map[string]string{"service": "hello"}).
+		WithDimensionSet(map[string]string{"service": "hello", "stage": "prod"}).
+		WithProperty("requestId", "abc").
+		PutMetric("invocations", 1, UnitCount).
+		PutMetric("latency", 10, UnitMilliseconds).
+		PutMetric("latency", 12, UnitMilliseconds).
+		PutHighResolutionMetric("queueDepth", 3, UnitCount)
+
+	documents := ensureValidDocuments(t, mc, 1)
+	directive := documents[0].AWS.CloudWatchMetrics[0]
+	if len(directive.Dimensions) != 2 || len(directive.Metrics) != 3 {
+		t.Fatalf("Unexpected directive: %#v", directive)
+	}
+	for _, eachMetric := range directive.Metrics {
+		expectedResolution := 0
+		if eachMetric.Name == "queueDepth" {
+			expectedResolution = int(ResolutionHigh)
+		}
+		if eachMetric.StorageResolution != expectedResolution {
+			t.Fatalf("Unexpected storage resolution for %s: %d",
+				eachMetric.Name,
+				eachMetric.StorageResolution)
+		}
+	}
+	rawDocuments, _ := mc.Documents()
+	for _, eachFragment := range []string{
+		`"latency":[10,12]`,
+		`"invocations":1`,
+		`"requestId":"abc"`,
+		`"stage":"prod"`,
+	} {
+		if !strings.Contains(string(rawDocuments[0]), eachFragment) {
+			t.Fatalf("Failed to find %s in %s", eachFragment, string(rawDocuments[0]))
+		}
+	}
+}
+
+func TestMetricsContextNoDimensions(t *testing.T) {
+	mc := NewMetricsContext("SpecialNamespace").
+		PutMetric("invocations", 1, UnitCount)
+	documents := ensureValidDocuments(t, mc, 1)
+	if len(documents[0].AWS.CloudWatchMetrics[0].Dimensions) != 1 ||
+		len(documents[0].AWS.CloudWatchMetrics[0].Dimensions[0]) != 0 {
+		t.Fatalf("Expected a single empty dimension set")
+	}
+	// No metrics, no documents
+	ensureValidDocuments(t, NewMetricsContext("SpecialNamespace"), 0)
+}
+
+func TestMetricsContextSplitMetrics(t *testing.T) {
+	mc := NewMetricsContext("SpecialNamespace")
+	for i := 0; i != 150; i++ {
+		mc.PutMetric(fmt.Sprintf("metric%d", i), float64(i), UnitCount)
+	}
+	documents := ensureValidDocuments(t, mc, 2)
+	metricCount := 0
+	for _, eachDocument := range documents {
+		count := len(eachDocument.AWS.CloudWatchMetrics[0].Metrics)
+		if count > MaxMetricsPerDocument {
+			t.Fatalf("Document exceeds metric limit: %d", count)
+		}
+		metricCount += count
+	}
+	if metricCount != 150 {
+		t.Fatalf("Expected 150 metrics. Found: %d", metricCount)
+	}
+}
+
+func TestMetricsContextSplitValues(t *testing.T) {
+	mc := NewMetricsContext("SpecialNamespace").
+		PutMetric("single", 1, UnitCount)
+	for i := 0; i != 250; i++ {
+		mc.PutMetric("latency", float64(i), UnitMilliseconds)
+	}
+	documents, _ := mc.Documents()
+	ensureValidDocuments(t, mc, 3)
+	valueCount := 0
+	for _, eachDocument := range documents {
+		var values map[string]interface{}
+		_ = json.Unmarshal(eachDocument, &values)
+		latencyValues, _ := values["latency"].([]interface{})
+		if len(latencyValues) > MaxValuesPerMetric {
+			t.Fatalf("Document exceeds value limit: %d", len(latencyValues))
+		}
+		valueCount += len(latencyValues)
+	}
+	if valueCount != 250 {
+		t.Fatalf("Expected 250 values. Found: %d", valueCount)
+	}
+}
+
+func TestMetricsContextSplitDimensions(t *testing.T) {
+	mc := NewMetricsContext("SpecialNamespace").
+		PutMetric("invocations", 1, UnitCount)
+	for setIndex := 0; setIndex != 3; setIndex++ {
+		dimensions := make(map[string]string)
+		for dimIndex := 0; dimIndex != 15; dimIndex++ {
+			dimensions[fmt.Sprintf("dim%d_%d", setIndex, dimIndex)] = "value"
+		}
+		mc.WithDimensionSet(dimensions)
+	}
+	documents := ensureValidDocuments(t, mc, 2)
+	for _, eachDocument := range documents {
+		keys := make(map[string]bool)
+		for _, eachSet := range eachDocument.AWS.CloudWatchMetrics[0].Dimensions {
+			for _, eachKey := range eachSet {
+				keys[eachKey] = true
+			}
+		}
+		if len(keys) > MaxDimensionsPerDocument {
+			t.Fatalf("Document exceeds dimension limit: %d", len(keys))
+		}
+	}
+}
+
+func TestMetricsContextValidation(t *testing.T) {
+	tooManyDimensions := make(map[string]string)
+	for i := 0; i != MaxDimensionsPerSet+1; i++ {
+		tooManyDimensions[fmt.Sprintf("dim%d", i)] = "value"
+	}
+	invalidContexts := map[string]*MetricsContext{
+		"namespace": NewMetricsContext(""),
+		"unit": NewMetricsContext("SpecialNamespace").
+			PutMetric("invocations", 1, MetricUnit("Widgets")),
+		"dimensions": NewMetricsContext("SpecialNamespace").
+			WithDimensionSet(tooManyDimensions),
+		"conflict": NewMetricsContext("SpecialNamespace").
+			WithDimensionSet(map[string]string{"service": "hello"}).
+			WithDimensionSet(map[string]string{"service": "world"}),
+		"resolution": NewMetricsContext("SpecialNamespace").
+			PutMetric("invocations", 1, UnitCount).
+			PutHighResolutionMetric("invocations", 1, UnitCount),
+	}
+	for eachName, eachContext := range invalidContexts {
+		if eachContext.Validate() == nil {
+			t.Fatalf("Expected %s validation error", eachName)
+		}
+		if _, documentsErr := eachContext.Documents(); documentsErr == nil {
+			t.Fatalf("Expected %s documents error", eachName)
+		}
+	}
+}
+
+func TestMetricsContextFlushOnce(t *testing.T) {
+	mc := NewMetricsContext("SpecialNamespace").
+		PutMetric("invocations", 1, UnitCount)
+	ctx := ContextWithMetrics(context.Background(), mc)
+	if MetricsContextFromContext(ctx) != mc {
+		t.Fatalf("Failed to get MetricsContext from context")
+	}
+	if MetricsContextFromContext(context.Background()) != nil {
+		t.Fatalf("Expected nil MetricsContext for empty context")
+	}
+	sink := &bytes.Buffer{}
+	for i := 0; i != 3; i++ {
+		flushErr := MetricsContextFromContext(ctx).FlushToSink(sink)
+		if flushErr != nil {
+			t.Fatal(flushErr)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(sink.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected a single flushed document. Found: %d", len(lines))
+	}
+	validateErr := validateEmbeddedMetricDocument([]byte(lines[0]))
+	if validateErr != nil {
+		t.Fatal(validateErr)
+	}
+}
+
+func TestValidateEmbeddedMetricDocument(t *testing.T) {
+	invalidErr := validateEmbeddedMetricDocument([]byte(`{"_aws":{"Timestamp":1}}`))
+	if invalidErr == nil {
+		t.Fatalf("Expected error for document without CloudWatchMetrics")
+	}
+}
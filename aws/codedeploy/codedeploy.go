@@ -0,0 +1,142 @@
+package codedeploy
+
+import (
+	"context"
+	"encoding/json"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	spartaSigV4 "github.com/mweagle/Sparta/v3/aws/internal/sigv4"
+	"github.com/pkg/errors"
+)
+
+// Lifecycle event hook execution status values
+const (
+	// StatusSucceeded allows the deployment to continue
+	StatusSucceeded = "Succeeded"
+	// StatusFailed fails the deployment
+	StatusFailed = "Failed"
+)
+
+const (
+	codeDeployTargetPrefix = "CodeDeploy_20141006"
+	codeDeploySigningName  = "codedeploy"
+)
+
+// LifecycleEvent is the event delivered to BeforeAllowTraffic and
+// AfterAllowTraffic hook functions
+type LifecycleEvent struct {
+	DeploymentID                  string `json:"DeploymentId"`
+	LifecycleEventHookExecutionID string `json:"LifecycleEventHookExecutionId"`
+}
+
+// LambdaTarget is the AWS::Lambda::Function resource in a
+// deployment AppSpec
+type LambdaTarget struct {
+	Name           string `json:"Name"`
+	Alias          string `json:"Alias"`
+	CurrentVersion string `json:"CurrentVersion"`
+	TargetVersion  string `json:"TargetVersion"`
+}
+
+// appSpec is the subset of the Lambda AppSpec that identifies the
+// function versions. Ref: https://docs.aws.amazon.com/codedeploy/latest/userguide/reference-appspec-file-structure-resources.html
+type appSpec struct {
+	Resources []map[string]struct {
+		Type       string       `json:"Type"`
+		Properties LambdaTarget `json:"Properties"`
+	} `json:"Resources"`
+}
+
+type appSpecContent struct {
+	Content string `json:"content"`
+}
+
+type getDeploymentOutput struct {
+	DeploymentInfo struct {
+		Revision struct {
+			RevisionType   string          `json:"revisionType"`
+			AppSpecContent *appSpecContent `json:"appSpecContent"`
+			String         *appSpecContent `json:"string"`
+		} `json:"revision"`
+	} `json:"deploymentInfo"`
+}
+
+// invoke calls the CodeDeploy JSON API operation. The SDK v2 CodeDeploy
+// client isn't a module dependency, so requests are signed directly.
+func invoke(ctx context.Context,
+	awsConfig awsv2.Config,
+	operation string,
+	input interface{},
+	output interface{}) error {
+	return spartaSigV4.InvokeJSON(ctx,
+		awsConfig,
+		&spartaSigV4.JSONService{
+			Endpoint:     spartaSigV4.Endpoint(codeDeploySigningName, awsConfig.Region) + "/",
+			SigningName:  codeDeploySigningName,
+			TargetPrefix: codeDeployTargetPrefix,
+			Version:      "1.1",
+		},
+		operation,
+		input,
+		output)
+}
+
+// ParseLambdaTargets returns the Lambda function versions in a
+// JSON AppSpec
+func ParseLambdaTargets(appSpecJSON string) ([]*LambdaTarget, error) {
+	var spec appSpec
+	unmarshalErr := json.Unmarshal([]byte(appSpecJSON), &spec)
+	if unmarshalErr != nil {
+		return nil, errors.Wrapf(unmarshalErr, "Failed to unmarshal AppSpec")
+	}
+	targets := make([]*LambdaTarget, 0)
+	for _, eachResourceMap := range spec.Resources {
+		for _, eachResource := range eachResourceMap {
+			if eachResource.Type != "AWS::Lambda::Function" {
+				continue
+			}
+			target := eachResource.Properties
+			targets = append(targets, &target)
+		}
+	}
+	return targets, nil
+}
+
+// DeploymentLambdaTargets returns the Lambda function versions
+// updated by the deployment
+func DeploymentLambdaTargets(ctx context.Context,
+	awsConfig awsv2.Config,
+	deploymentID string) ([]*LambdaTarget, error) {
+
+	var output getDeploymentOutput
+	invokeErr := invoke(ctx, awsConfig, "GetDeployment", map[string]string{
+		"deploymentId": deploymentID,
+	}, &output)
+	if invokeErr != nil {
+		return nil, invokeErr
+	}
+	revision := output.DeploymentInfo.Revision
+	content := revision.AppSpecContent
+	if content == nil {
+		content = revision.String
+	}
+	if content == nil {
+		return nil, errors.Errorf("Deployment %s has unsupported revision type: %s",
+			deploymentID,
+			revision.RevisionType)
+	}
+	return ParseLambdaTargets(content.Content)
+}
+
+// PutLifecycleEventHookExecutionStatus reports the result of a lifecycle
+// event hook to CodeDeploy
+func PutLifecycleEventHookExecutionStatus(ctx context.Context,
+	awsConfig awsv2.Config,
+	event *LifecycleEvent,
+	status string) error {
+	return invoke(ctx, awsConfig, "PutLifecycleEventHookExecutionStatus", map[string]string{
+		"deploymentId":                  event.DeploymentID,
+		"lifecycleEventHookExecutionId": event.LifecycleEventHookExecutionID,
+		"status":                        status,
+	}, nil)
+}
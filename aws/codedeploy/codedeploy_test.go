@@ -0,0 +1,35 @@
+package codedeploy
+
+import "testing"
+
+func TestParseLambdaTargets(t *testing.T) {
+	appSpec := `{
+	"version": 0.0,
+	"Resources": [{
+		"MyFunction": {
+			"Type": "AWS::Lambda::Function",
+			"Properties": {
+				"Name": "MyService_Hello",
+				"Alias": "live",
+				"CurrentVersion": "3",
+				"TargetVersion": "4"
+			}
+		}
+	}],
+	"Hooks": [{"BeforeAllowTraffic": "MyService_CodeDeployPreTrafficHook"}]
+}`
+	targets, targetsErr := ParseLambdaTargets(appSpec)
+	if targetsErr != nil {
+		t.Fatal(targetsErr)
+	}
+	if len(targets) != 1 ||
+		targets[0].Name != "MyService_Hello" ||
+		targets[0].Alias != "live" ||
+		targets[0].TargetVersion != "4" {
+		t.Fatalf("Unexpected targets: %#v", targets)
+	}
+	_, invalidErr := ParseLambdaTargets("version: 0.0")
+	if invalidErr == nil {
+		t.Fatalf("Expected error for non-JSON AppSpec")
+	}
+}
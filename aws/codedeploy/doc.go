@@ -0,0 +1,3 @@
+// Package codedeploy scopes the CodeDeploy lifecycle hook operations used
+// by Sparta safe deployments
+package codedeploy
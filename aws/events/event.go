@@ -1,9 +1,14 @@
 package events
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"strings"
+	"time"
+
+	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
 )
 
 // APIGatewayIdentity is the API Gateway identity information
@@ -23,13 +28,14 @@ type APIGatewayIdentity struct {
 
 // APIGatewayContext is the API-Gateway context information
 type APIGatewayContext struct {
-	AppID        string             `json:"appId"`
-	Method       string             `json:"method"`
-	RequestID    string             `json:"requestId"`
-	ResourceID   string             `json:"resourceId"`
-	ResourcePath string             `json:"resourcePath"`
-	Stage        string             `json:"stage"`
-	Identity     APIGatewayIdentity `json:"identity"`
+	AppID        string                 `json:"appId"`
+	Method       string                 `json:"method"`
+	RequestID    string                 `json:"requestId"`
+	ResourceID   string                 `json:"resourceId"`
+	ResourcePath string                 `json:"resourcePath"`
+	Stage        string                 `json:"stage"`
+	Identity     APIGatewayIdentity     `json:"identity"`
+	Authorizer   map[string]interface{} `json:"authorizer,omitempty"`
 }
 
 // APIGatewayEnvelope is the type that maps to the VTL properties
@@ -112,3 +118,63 @@ func NewAPIGatewayMockRequest(lambdaName string,
 	}
 	return apiGatewayRequest, nil
 }
+
+// NewAPIGatewayV2HTTPMockRequest creates a mock HTTP API request that uses
+// the 2.0 Lambda proxy payload format. The path may include a query
+// string. String eventData values are used as the body; other values
+// are JSON encoded.
+func NewAPIGatewayV2HTTPMockRequest(routeKey string,
+	httpMethod string,
+	path string,
+	eventData interface{}) (*awsLambdaEvents.APIGatewayV2HTTPRequest, error) {
+
+	requestURL, requestURLErr := url.Parse(path)
+	if requestURLErr != nil {
+		return nil, requestURLErr
+	}
+	body := ""
+	switch typedData := eventData.(type) {
+	case nil:
+	case string:
+		body = typedData
+	default:
+		jsonData, jsonDataErr := json.Marshal(typedData)
+		if jsonDataErr != nil {
+			return nil, jsonDataErr
+		}
+		body = string(jsonData)
+	}
+	queryParams := make(map[string]string)
+	for eachKey, eachValues := range requestURL.Query() {
+		queryParams[eachKey] = strings.Join(eachValues, ",")
+	}
+	now := time.Now().UTC()
+	return &awsLambdaEvents.APIGatewayV2HTTPRequest{
+		Version:               "2.0",
+		RouteKey:              routeKey,
+		RawPath:               requestURL.Path,
+		RawQueryString:        requestURL.RawQuery,
+		Headers:               map[string]string{"content-type": "application/json"},
+		QueryStringParameters: queryParams,
+		PathParameters:        make(map[string]string),
+		Body:                  body,
+		RequestContext: awsLambdaEvents.APIGatewayV2HTTPRequestContext{
+			RouteKey:     routeKey,
+			AccountID:    "123412341234",
+			Stage:        "$default",
+			RequestID:    "12341234-1234-1234-1234-123412341234",
+			APIID:        fmt.Sprintf("spartaApp%d", os.Getpid()),
+			DomainName:   "mock.execute-api.us-east-1.amazonaws.com",
+			DomainPrefix: "mock",
+			Time:         now.Format("02/Jan/2006:15:04:05 -0700"),
+			TimeEpoch:    now.UnixNano() / int64(time.Millisecond),
+			HTTP: awsLambdaEvents.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    httpMethod,
+				Path:      requestURL.Path,
+				Protocol:  "HTTP/1.1",
+				SourceIP:  "127.0.0.1",
+				UserAgent: "Mozilla/Gecko",
+			},
+		},
+	}, nil
+}
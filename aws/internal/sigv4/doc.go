@@ -0,0 +1,17 @@
+/*
+Package sigv4 sends SigV4 signed JSON requests to the AWS services that
+Sparta's runtime helpers call but that don't have aws-sdk-go-v2 service
+modules in Sparta's go.mod: Step Functions (task token callbacks),
+CodeDeploy (lifecycle hook status) and the API Gateway management API
+(WebSocket connections).
+
+Sparta is imported by every user Lambda binary, so each service module
+added to go.mod is added to every consumer's module graph and must track
+the aws-sdk-go-v2 core version Sparta pins. The helpers above use five
+single-request operations in total, which doesn't justify three more
+service modules. Requests are signed with the SDK's own v4 signer and use
+the credentials, region and HTTP client from the caller's aws.Config, so
+switching a caller to the generated client later only changes that
+caller.
+*/
+package sigv4
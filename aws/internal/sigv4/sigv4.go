@@ -0,0 +1,177 @@
+package sigv4
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsv2Signer "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/pkg/errors"
+)
+
+// Endpoint returns the regional HTTPS endpoint for the service
+// (eg: https://states.us-west-2.amazonaws.com)
+func Endpoint(service string, region string) string {
+	dnsSuffix := "amazonaws.com"
+	if strings.HasPrefix(region, "cn-") {
+		dnsSuffix = "amazonaws.com.cn"
+	}
+	return fmt.Sprintf("https://%s.%s.%s", service, region, dnsSuffix)
+}
+
+// Request is an HTTP request to sign and send
+type Request struct {
+	// SigningName is the SigV4 service name (eg: states)
+	SigningName string
+	Method      string
+	URL         string
+	Header      http.Header
+	Body        []byte
+}
+
+// Send signs the request with the awsConfig credentials and sends it with
+// the awsConfig HTTP client. The response status code and body are
+// returned for every HTTP status.
+func Send(ctx context.Context,
+	awsConfig awsv2.Config,
+	request *Request) (int, []byte, error) {
+
+	httpRequest, httpRequestErr := http.NewRequestWithContext(ctx,
+		request.Method,
+		request.URL,
+		bytes.NewReader(request.Body))
+	if httpRequestErr != nil {
+		return 0, nil, errors.Wrapf(httpRequestErr, "Failed to create %s request", request.Method)
+	}
+	for eachKey, eachValues := range request.Header {
+		httpRequest.Header[eachKey] = eachValues
+	}
+	if awsConfig.Credentials == nil {
+		return 0, nil, errors.Errorf("Failed to sign %s request: no credentials", request.URL)
+	}
+	credentials, credentialsErr := awsConfig.Credentials.Retrieve(ctx)
+	if credentialsErr != nil {
+		return 0, nil, errors.Wrapf(credentialsErr, "Failed to retrieve credentials")
+	}
+	payloadHash := sha256.Sum256(request.Body)
+	signErr := awsv2Signer.NewSigner().SignHTTP(ctx,
+		credentials,
+		httpRequest,
+		hex.EncodeToString(payloadHash[:]),
+		request.SigningName,
+		awsConfig.Region,
+		time.Now())
+	if signErr != nil {
+		return 0, nil, errors.Wrapf(signErr, "Failed to sign %s request", request.URL)
+	}
+
+	var httpClient awsv2.HTTPClient = http.DefaultClient
+	if awsConfig.HTTPClient != nil {
+		httpClient = awsConfig.HTTPClient
+	}
+	response, responseErr := httpClient.Do(httpRequest)
+	if responseErr != nil {
+		return 0, nil, errors.Wrapf(responseErr, "Failed to call %s", request.URL)
+	}
+	defer response.Body.Close()
+	responseBody, responseBodyErr := ioutil.ReadAll(response.Body)
+	if responseBodyErr != nil {
+		return 0, nil, errors.Wrapf(responseBodyErr, "Failed to read %s response", request.URL)
+	}
+	return response.StatusCode, responseBody, nil
+}
+
+// JSONService is an AWS JSON protocol service
+type JSONService struct {
+	// Endpoint is the service URL
+	Endpoint string
+	// SigningName is the SigV4 service name
+	SigningName string
+	// TargetPrefix is the X-Amz-Target operation prefix
+	TargetPrefix string
+	// Version is the protocol version (1.0 or 1.1)
+	Version string
+}
+
+// ServiceError is a JSON protocol operation error
+type ServiceError struct {
+	Operation  string
+	StatusCode int
+	// Type is the unqualified error type (eg: TaskTimedOut), if known
+	Type    string
+	Message string
+}
+
+// Error returns the error description
+func (serviceErr *ServiceError) Error() string {
+	if serviceErr.Type != "" {
+		return fmt.Sprintf("%s failed (%s): %s",
+			serviceErr.Operation,
+			serviceErr.Type,
+			serviceErr.Message)
+	}
+	return fmt.Sprintf("%s failed (HTTP %d): %s",
+		serviceErr.Operation,
+		serviceErr.StatusCode,
+		serviceErr.Message)
+}
+
+// InvokeJSON calls the JSON protocol operation with the JSON marshalled
+// input and unmarshals the response into the optional output. Non 2xx
+// responses are returned as *ServiceError values.
+func InvokeJSON(ctx context.Context,
+	awsConfig awsv2.Config,
+	service *JSONService,
+	operation string,
+	input interface{},
+	output interface{}) error {
+
+	body, bodyErr := json.Marshal(input)
+	if bodyErr != nil {
+		return errors.Wrapf(bodyErr, "Failed to marshal %s input", operation)
+	}
+	header := http.Header{}
+	header.Set("Content-Type", fmt.Sprintf("application/x-amz-json-%s", service.Version))
+	header.Set("X-Amz-Target", fmt.Sprintf("%s.%s", service.TargetPrefix, operation))
+	statusCode, responseBody, sendErr := Send(ctx, awsConfig, &Request{
+		SigningName: service.SigningName,
+		Method:      http.MethodPost,
+		URL:         service.Endpoint,
+		Header:      header,
+		Body:        body,
+	})
+	if sendErr != nil {
+		return errors.Wrapf(sendErr, "Failed to call %s", operation)
+	}
+	if statusCode/100 != 2 {
+		serviceErr := &ServiceError{
+			Operation:  operation,
+			StatusCode: statusCode,
+			Message:    string(responseBody),
+		}
+		errorBody := struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}{}
+		if json.Unmarshal(responseBody, &errorBody) == nil && errorBody.Type != "" {
+			// The type may be namespace qualified: com.amazonaws.swf.base.model#TaskTimedOut
+			serviceErr.Type = errorBody.Type[strings.LastIndex(errorBody.Type, "#")+1:]
+			serviceErr.Message = errorBody.Message
+		}
+		return serviceErr
+	}
+	if output == nil {
+		return nil
+	}
+	return errors.Wrapf(json.Unmarshal(responseBody, output),
+		"Failed to unmarshal %s response",
+		operation)
+}
@@ -0,0 +1,97 @@
+package sigv4
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsv2Signer "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsv2Creds "github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestInvokeJSONSignature(t *testing.T) {
+	credentials := awsv2.Credentials{
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "application/x-amz-json-1.0" ||
+			r.Header.Get("X-Amz-Target") != "AWSStepFunctions.SendTaskHeartbeat" ||
+			string(body) != `{"taskToken":"token"}` {
+			t.Errorf("Unexpected request: %#v %s", r.Header, string(body))
+		}
+		// Sign the same request and compare the signature
+		signingTime, signingTimeErr := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+		if signingTimeErr != nil {
+			t.Errorf("Failed to parse X-Amz-Date: %s", signingTimeErr)
+		}
+		expected, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.Path, bytes.NewReader(body))
+		expected.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+		expected.Header.Set("X-Amz-Target", r.Header.Get("X-Amz-Target"))
+		payloadHash := sha256.Sum256(body)
+		signErr := awsv2Signer.NewSigner().SignHTTP(context.Background(),
+			credentials,
+			expected,
+			hex.EncodeToString(payloadHash[:]),
+			"states",
+			"us-west-2",
+			signingTime)
+		if signErr != nil {
+			t.Errorf("Failed to sign expected request: %s", signErr)
+		}
+		if r.Header.Get("Authorization") != expected.Header.Get("Authorization") {
+			t.Errorf("Unexpected signature:\n%s\n%s",
+				r.Header.Get("Authorization"),
+				expected.Header.Get("Authorization"))
+		}
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"com.amazonaws.swf.base.model#TaskTimedOut","message":"Task Timed Out"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	awsConfig := awsv2.Config{
+		Region:      "us-west-2",
+		Credentials: awsv2Creds.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+	}
+	service := &JSONService{
+		Endpoint:     server.URL + "/",
+		SigningName:  "states",
+		TargetPrefix: "AWSStepFunctions",
+		Version:      "1.0",
+	}
+	input := map[string]string{"taskToken": "token"}
+	var output struct {
+		OK bool `json:"ok"`
+	}
+	invokeErr := InvokeJSON(context.Background(), awsConfig, service, "SendTaskHeartbeat", input, &output)
+	if invokeErr != nil || !output.OK {
+		t.Fatalf("Failed to invoke operation: %v", invokeErr)
+	}
+
+	service.Endpoint = server.URL + "/error"
+	invokeErr = InvokeJSON(context.Background(), awsConfig, service, "SendTaskHeartbeat", input, nil)
+	var serviceErr *ServiceError
+	if !errors.As(invokeErr, &serviceErr) ||
+		serviceErr.Type != "TaskTimedOut" ||
+		serviceErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Unexpected service error: %v", invokeErr)
+	}
+
+	invokeErr = InvokeJSON(context.Background(), awsv2.Config{}, service, "SendTaskHeartbeat", input, nil)
+	if invokeErr == nil {
+		t.Fatalf("Failed to reject request without credentials")
+	}
+}
@@ -2,6 +2,8 @@ package step
 
 import (
 	"math/rand"
+
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
 )
 
 /*
@@ -49,8 +51,22 @@ type APIGatewayTaskState struct {
 // to turn into a stringified
 // Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connectors-sns.html
 func (sts *APIGatewayTaskState) MarshalJSON() ([]byte, error) {
-	return sts.BaseTask.marshalMergedParams("arn:aws:states:::apigateway:invoke",
-		&sts.parameters)
+	return sts.BaseTask.marshalIntegrationParams(&sts.parameters)
+}
+
+// policyStatements returns the privileges needed to invoke IAM_ROLE
+// authorized endpoints
+func (sts *APIGatewayTaskState) policyStatements() []spartaIAM.PolicyStatement {
+	if sts.parameters.AuthType != "IAM_ROLE" {
+		return nil
+	}
+	return []spartaIAM.PolicyStatement{
+		{
+			Effect:   "Allow",
+			Action:   []string{"execute-api:Invoke"},
+			Resource: "*",
+		},
+	}
 }
 
 // NewAPIGatewayTaskState returns an initialized APIGatewayTaskState
@@ -63,6 +79,11 @@ func NewAPIGatewayTaskState(stateName string,
 				name: stateName,
 				id:   rand.Int63(),
 			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::apigateway:invoke",
+				defaultPattern:    RequestResponse,
+				supportedPatterns: []IntegrationPattern{RequestResponse, WaitForTaskToken},
+			},
 		},
 		parameters: parameters,
 	}
@@ -0,0 +1,153 @@
+package step
+
+import (
+	"math/rand"
+	"strings"
+
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
+)
+
+// AthenaQueryExecutionContext is the database and catalog for the query
+type AthenaQueryExecutionContext struct {
+	Catalog  string `json:",omitempty"`
+	Database string `json:",omitempty"`
+}
+
+// AthenaResultConfiguration is the location and encryption for query results
+type AthenaResultConfiguration struct {
+	EncryptionConfiguration map[string]interface{} `json:",omitempty"`
+	OutputLocation          string                 `json:",omitempty"`
+}
+
+// AthenaStartQueryExecutionParameters represents params for the Athena
+// StartQueryExecution integration
+// Ref: https://docs.aws.amazon.com/athena/latest/APIReference/API_StartQueryExecution.html
+type AthenaStartQueryExecutionParameters struct {
+	QueryString           string                       `json:",omitempty"`
+	ClientRequestToken    string                       `json:",omitempty"`
+	QueryExecutionContext *AthenaQueryExecutionContext `json:",omitempty"`
+	ResultConfiguration   *AthenaResultConfiguration   `json:",omitempty"`
+	WorkGroup             string                       `json:",omitempty"`
+}
+
+// AthenaStartQueryExecutionState represents bindings for
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-athena.html
+type AthenaStartQueryExecutionState struct {
+	BaseTask
+	parameters AthenaStartQueryExecutionParameters
+}
+
+// MarshalJSON for custom marshalling, since this will be stringified and we need it
+// to turn into a stringified
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connect-athena.html
+func (aqs *AthenaStartQueryExecutionState) MarshalJSON() ([]byte, error) {
+	return aqs.BaseTask.marshalIntegrationParams(&aqs.parameters)
+}
+
+// policyStatements returns the privileges needed to run the query, read the
+// Glue Data Catalog and write the query results
+func (aqs *AthenaStartQueryExecutionState) policyStatements() []spartaIAM.PolicyStatement {
+	workGroup := aqs.parameters.WorkGroup
+	if workGroup == "" {
+		workGroup = "primary"
+	}
+	queryActions := []string{"athena:StartQueryExecution"}
+	if aqs.resolvedIntegrationPattern() == Sync {
+		queryActions = append(queryActions,
+			"athena:GetQueryExecution",
+			"athena:StopQueryExecution")
+	}
+	statements := []spartaIAM.PolicyStatement{
+		{
+			Effect:   "Allow",
+			Action:   queryActions,
+			Resource: regionalArn("athena", "workgroup/", workGroup),
+		},
+		{
+			Effect: "Allow",
+			Action: []string{
+				"glue:GetDatabase",
+				"glue:GetDatabases",
+				"glue:GetTable",
+				"glue:GetTables",
+				"glue:GetPartition",
+				"glue:GetPartitions",
+			},
+			Resource: "*",
+		},
+	}
+	outputBucket := ""
+	if aqs.parameters.ResultConfiguration != nil {
+		outputBucket = athenaOutputBucket(aqs.parameters.ResultConfiguration.OutputLocation)
+	}
+	s3Resource := "*"
+	s3KeysResource := "*"
+	if outputBucket != "" {
+		s3Resource = gof.Join("", []string{
+			"arn:",
+			gof.Ref("AWS::Partition"),
+			":s3:::",
+			outputBucket,
+		})
+		s3KeysResource = gof.Join("", []string{
+			"arn:",
+			gof.Ref("AWS::Partition"),
+			":s3:::",
+			outputBucket,
+			"/*",
+		})
+	}
+	statements = append(statements,
+		spartaIAM.PolicyStatement{
+			Effect: "Allow",
+			Action: []string{
+				"s3:GetBucketLocation",
+				"s3:ListBucket",
+				"s3:ListBucketMultipartUploads",
+			},
+			Resource: s3Resource,
+		},
+		spartaIAM.PolicyStatement{
+			Effect: "Allow",
+			Action: []string{
+				"s3:GetObject",
+				"s3:PutObject",
+				"s3:AbortMultipartUpload",
+				"s3:ListMultipartUploadParts",
+			},
+			Resource: s3KeysResource,
+		})
+	return statements
+}
+
+// athenaOutputBucket returns the bucket name from a literal
+// s3://bucket/prefix output location
+func athenaOutputBucket(outputLocation string) string {
+	if !strings.HasPrefix(outputLocation, "s3://") {
+		return ""
+	}
+	bucketPath := strings.TrimPrefix(outputLocation, "s3://")
+	return strings.SplitN(bucketPath, "/", 2)[0]
+}
+
+// NewAthenaStartQueryExecutionState returns an initialized
+// AthenaStartQueryExecutionState
+func NewAthenaStartQueryExecutionState(stateName string,
+	parameters AthenaStartQueryExecutionParameters) *AthenaStartQueryExecutionState {
+	aqs := &AthenaStartQueryExecutionState{
+		BaseTask: BaseTask{
+			baseInnerState: baseInnerState{
+				name: stateName,
+				id:   rand.Int63(),
+			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::athena:startQueryExecution",
+				defaultPattern:    Sync,
+				supportedPatterns: []IntegrationPattern{RequestResponse, Sync},
+			},
+		},
+		parameters: parameters,
+	}
+	return aqs
+}
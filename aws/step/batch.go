@@ -2,6 +2,8 @@ package step
 
 import (
 	"math/rand"
+
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
 )
 
 // BatchTaskParameters represents params for the Batch notification
@@ -29,8 +31,31 @@ type BatchTaskState struct {
 // to turn into a stringified
 // Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connectors-batch.html
 func (bts *BatchTaskState) MarshalJSON() ([]byte, error) {
-	return bts.BaseTask.marshalMergedParams("arn:aws:states:::batch:submitJob.sync",
-		&bts.parameters)
+	return bts.BaseTask.marshalIntegrationParams(&bts.parameters)
+}
+
+// policyStatements returns the privileges needed to submit the job
+func (bts *BatchTaskState) policyStatements() []spartaIAM.PolicyStatement {
+	statements := []spartaIAM.PolicyStatement{
+		{
+			Effect:   "Allow",
+			Action:   []string{"batch:SubmitJob"},
+			Resource: "*",
+		},
+	}
+	if bts.resolvedIntegrationPattern() == Sync {
+		statements = append(statements,
+			spartaIAM.PolicyStatement{
+				Effect: "Allow",
+				Action: []string{
+					"batch:DescribeJobs",
+					"batch:TerminateJob",
+				},
+				Resource: "*",
+			},
+			syncEventRuleStatement("StepFunctionsGetEventsForBatchJobsRule"))
+	}
+	return statements
 }
 
 // NewBatchTaskState returns an initialized BatchTaskState
@@ -43,6 +68,11 @@ func NewBatchTaskState(stateName string,
 				name: stateName,
 				id:   rand.Int63(),
 			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::batch:submitJob",
+				defaultPattern:    Sync,
+				supportedPatterns: []IntegrationPattern{RequestResponse, Sync, WaitForTaskToken},
+			},
 		},
 		parameters: parameters,
 	}
@@ -0,0 +1,129 @@
+package callback
+
+import (
+	"context"
+	"encoding/json"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	sparta "github.com/mweagle/Sparta/v3"
+	spartaAWS "github.com/mweagle/Sparta/v3/aws"
+	spartaSigV4 "github.com/mweagle/Sparta/v3/aws/internal/sigv4"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// Step Functions uses the AWS JSON 1.0 protocol
+	// Ref: https://docs.aws.amazon.com/step-functions/latest/apireference/CommonParameters.html
+	jsonProtocolVersion = "1.0"
+	targetPrefix        = "AWSStepFunctions"
+	signingName         = "states"
+)
+
+// TaskTokenPrivilege returns the IAM privilege a Lambda function needs to
+// report task token results. The SendTask* actions don't support resource
+// level permissions.
+func TaskTokenPrivilege() sparta.IAMRolePrivilege {
+	return sparta.IAMRolePrivilege{
+		Actions: []string{
+			"states:SendTaskSuccess",
+			"states:SendTaskFailure",
+			"states:SendTaskHeartbeat",
+		},
+		Resource: "*",
+	}
+}
+
+// Client sends task token results to AWS Step Functions
+type Client struct {
+	awsConfig awsv2.Config
+	// endpoint override. Defaults to the regional endpoint.
+	endpoint string
+}
+
+// NewClient returns a Client that uses the supplied AWS configuration
+func NewClient(awsConfig awsv2.Config) *Client {
+	return &Client{
+		awsConfig: awsConfig,
+	}
+}
+
+// NewClientFromContext returns a Client that uses the default AWS
+// configuration and the Sparta logger in the context, if present
+func NewClientFromContext(ctx context.Context) (*Client, error) {
+	logger, loggerOk := ctx.Value(sparta.ContextKeyLogger).(*zerolog.Logger)
+	if !loggerOk {
+		defaultLogger := zerolog.Nop()
+		logger = &defaultLogger
+	}
+	awsConfig, awsConfigErr := spartaAWS.NewConfig(ctx, logger)
+	if awsConfigErr != nil {
+		return nil, awsConfigErr
+	}
+	return NewClient(awsConfig), nil
+}
+
+// SendTaskSuccess reports that the task identified by the taskToken
+// completed successfully. The output value is JSON marshalled and becomes
+// the task's result.
+func (client *Client) SendTaskSuccess(ctx context.Context,
+	taskToken string,
+	output interface{}) error {
+	outputBytes, outputBytesErr := json.Marshal(output)
+	if outputBytesErr != nil {
+		return errors.Wrapf(outputBytesErr, "attempting to marshal task output")
+	}
+	return client.send(ctx, "SendTaskSuccess", map[string]interface{}{
+		"taskToken": taskToken,
+		"output":    string(outputBytes),
+	})
+}
+
+// SendTaskFailure reports that the task identified by the taskToken
+// failed. The errorCode is matched by Retry and Catch ErrorEquals values.
+func (client *Client) SendTaskFailure(ctx context.Context,
+	taskToken string,
+	errorCode string,
+	cause string) error {
+	params := map[string]interface{}{
+		"taskToken": taskToken,
+	}
+	if errorCode != "" {
+		params["error"] = errorCode
+	}
+	if cause != "" {
+		params["cause"] = cause
+	}
+	return client.send(ctx, "SendTaskFailure", params)
+}
+
+// SendTaskHeartbeat reports that the task identified by the taskToken
+// is still making progress. Heartbeats must be sent more frequently than
+// the task's HeartbeatSeconds value.
+func (client *Client) SendTaskHeartbeat(ctx context.Context,
+	taskToken string) error {
+	return client.send(ctx, "SendTaskHeartbeat", map[string]interface{}{
+		"taskToken": taskToken,
+	})
+}
+
+// send calls the Step Functions JSON protocol operation
+func (client *Client) send(ctx context.Context,
+	operation string,
+	params map[string]interface{}) error {
+	endpoint := client.endpoint
+	if endpoint == "" {
+		endpoint = spartaSigV4.Endpoint(signingName, client.awsConfig.Region) + "/"
+	}
+	return spartaSigV4.InvokeJSON(ctx,
+		client.awsConfig,
+		&spartaSigV4.JSONService{
+			Endpoint:     endpoint,
+			SigningName:  signingName,
+			TargetPrefix: targetPrefix,
+			Version:      jsonProtocolVersion,
+		},
+		operation,
+		params,
+		nil)
+}
@@ -0,0 +1,71 @@
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func testClient(handler http.HandlerFunc) (*Client, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	client := NewClient(awsv2.Config{
+		Region: "us-west-2",
+		Credentials: awsv2.CredentialsProviderFunc(func(ctx context.Context) (awsv2.Credentials, error) {
+			return awsv2.Credentials{
+				AccessKeyID:     "AKID",
+				SecretAccessKey: "SECRET",
+			}, nil
+		}),
+	})
+	client.endpoint = server.URL
+	return client, server
+}
+
+func TestSendTaskSuccess(t *testing.T) {
+	client, server := testClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "AWSStepFunctions.SendTaskSuccess" {
+			t.Errorf("Unexpected target: %s", r.Header.Get("X-Amz-Target"))
+		}
+		if r.Header.Get("Authorization") == "" {
+			t.Errorf("Failed to sign request")
+		}
+		var body map[string]string
+		decodeErr := json.NewDecoder(r.Body).Decode(&body)
+		if decodeErr != nil {
+			t.Errorf("Failed to decode request: %s", decodeErr)
+		}
+		if body["taskToken"] != "token" || body["output"] != `{"approved":true}` {
+			t.Errorf("Unexpected request body: %#v", body)
+		}
+		w.Write([]byte("{}"))
+	})
+	defer server.Close()
+
+	sendErr := client.SendTaskSuccess(context.Background(),
+		"token",
+		map[string]bool{"approved": true})
+	if sendErr != nil {
+		t.Fatalf("Failed to send task success: %s", sendErr)
+	}
+}
+
+func TestSendTaskFailureError(t *testing.T) {
+	client, server := testClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type":"com.amazonaws.swf.base.model#TaskTimedOut","message":"Task Timed Out"}`))
+	})
+	defer server.Close()
+
+	sendErr := client.SendTaskFailure(context.Background(),
+		"token",
+		"Rejected",
+		"Order rejected")
+	if sendErr == nil {
+		t.Fatalf("Failed to return service error")
+	}
+	t.Logf("Service error: %s", sendErr)
+}
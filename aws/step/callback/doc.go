@@ -0,0 +1,27 @@
+/*
+Package callback provides runtime helpers for Go Lambda functions that
+participate in AWS Step Functions callback (.waitForTaskToken) integrations.
+The task token is delivered by the integrated service (eg: SQS message
+attribute, SNS message attribute) and returned to Step Functions with
+SendTaskSuccess, SendTaskFailure or SendTaskHeartbeat:
+
+	func handler(ctx context.Context, event events.SQSEvent) error {
+	  client, clientErr := callback.NewClientFromContext(ctx)
+	  if clientErr != nil {
+	    return clientErr
+	  }
+	  for _, eachMessage := range event.Records {
+	    taskToken := *eachMessage.MessageAttributes["TaskToken"].StringValue
+	    sendErr := client.SendTaskSuccess(ctx, taskToken, map[string]string{
+	      "status": "approved",
+	    })
+	    if sendErr != nil {
+	      return sendErr
+	    }
+	  }
+	  return nil
+	}
+
+The Lambda function's IAM role must include the TaskTokenPrivilege privilege.
+*/
+package callback
@@ -0,0 +1,679 @@
+package step
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+/*******************************************************************************
+   ___ ___  _  _ ___ ___ _____ ___ ___  _  _ ___
+  / __/ _ \| \| |   \_ _|_   _|_ _/ _ \| \| / __|
+ | (_| (_) | .` | |) | |  | |  | | (_) | .` \__ \
+  \___\___/|_|\_|___/___| |_| |___\___/|_|\_|___/
+
+/******************************************************************************/
+
+// ParseCondition compiles a condition expression into the equivalent
+// Comparison value. The grammar is:
+//
+//	condition   := or
+//	or          := and ( "||" and )*
+//	and         := unary ( "&&" unary )*
+//	unary       := "!" unary | "(" condition ")" | comparison | typeCheck | path
+//	comparison  := operand ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "matches" ) operand
+//	operand     := path | number | 'string' | "string" | true | false | typeHint
+//	typeHint    := ( "number" | "string" | "boolean" | "timestamp" ) "(" path | 'string' ")"
+//	typeCheck   := ( "isPresent" | "isNull" | "isNumeric" | "isString" |
+//	                 "isBoolean" | "isTimestamp" ) "(" path ")"
+//
+// Paths are JSONPath expressions that begin with "$" (eg: $.order.total,
+// $$.Execution.StartTime). Comparisons between two paths compile to the
+// *Path comparison types and require a type hint on either operand,
+// for instance "$.order.total > number($.limits.max)". Timestamp literals
+// use the timestamp('2021-01-01T00:00:00Z') hint. A bare path is
+// compiled to a BooleanEquals true comparison. Example:
+//
+//	ParseCondition("$.order.total > 100 && ($.tier == 'gold' || isPresent($.coupon))")
+func ParseCondition(expression string) (Comparison, error) {
+	parser := &conditionParser{
+		expression: expression,
+	}
+	tokens, tokensErr := tokenizeCondition(expression)
+	if tokensErr != nil {
+		return nil, tokensErr
+	}
+	parser.tokens = tokens
+	condition, conditionErr := parser.parseOr()
+	if conditionErr != nil {
+		return nil, conditionErr
+	}
+	if parser.peek().kind != tokenEOF {
+		return nil, parser.errorf(parser.peek(), "unexpected %s after condition", parser.peek())
+	}
+	return condition, nil
+}
+
+// ParseChoiceBranch compiles the condition expression into a ChoiceBranch
+// that transitions to the nextState when the condition is true
+func ParseChoiceBranch(expression string, nextState MachineState) (ChoiceBranch, error) {
+	condition, conditionErr := ParseCondition(expression)
+	if conditionErr != nil {
+		return nil, conditionErr
+	}
+	switch typedCondition := condition.(type) {
+	case *And:
+		typedCondition.Next = nextState
+		return typedCondition, nil
+	case *Or:
+		typedCondition.Next = nextState
+		return typedCondition, nil
+	case *Not:
+		typedCondition.Next = nextState
+		return typedCondition, nil
+	default:
+		return &And{
+			Comparison: []Comparison{condition},
+			Next:       nextState,
+		}, nil
+	}
+}
+
+// ConditionError is returned for invalid condition expressions. Column
+// is the 1-based position of the offending input.
+type ConditionError struct {
+	Expression string
+	Column     int
+	Message    string
+}
+
+// Error returns the message with a caret pointing at the offending column
+func (ce *ConditionError) Error() string {
+	return fmt.Sprintf("invalid condition at column %d: %s\n\t%s\n\t%s^",
+		ce.Column,
+		ce.Message,
+		ce.Expression,
+		strings.Repeat(" ", ce.Column-1))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Tokenizer
+////////////////////////////////////////////////////////////////////////////////
+
+type conditionTokenKind int
+
+const (
+	tokenEOF conditionTokenKind = iota
+	tokenPath
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+type conditionToken struct {
+	kind  conditionTokenKind
+	value string
+	// 0-based offset into the expression
+	offset int
+}
+
+func (ct conditionToken) String() string {
+	switch ct.kind {
+	case tokenEOF:
+		return "end of condition"
+	case tokenString:
+		return fmt.Sprintf("string '%s'", ct.value)
+	default:
+		return fmt.Sprintf("%q", ct.value)
+	}
+}
+
+// isPathDelimiter returns true for the characters that terminate an
+// unbracketed JSONPath expression
+func isPathDelimiter(char rune) bool {
+	return unicode.IsSpace(char) || strings.ContainsRune("()!=<>&|,", char)
+}
+
+func tokenizeCondition(expression string) ([]conditionToken, error) {
+	tokens := make([]conditionToken, 0)
+	runes := []rune(expression)
+	newError := func(offset int, format string, args ...interface{}) error {
+		return &ConditionError{
+			Expression: expression,
+			Column:     offset + 1,
+			Message:    fmt.Sprintf(format, args...),
+		}
+	}
+	// Returns the offset of the closing quote
+	scanString := func(start int) (string, int, error) {
+		quote := runes[start]
+		var value strings.Builder
+		for index := start + 1; index < len(runes); index++ {
+			switch runes[index] {
+			case '\\':
+				if index+1 < len(runes) {
+					index++
+					value.WriteRune(runes[index])
+				}
+			case quote:
+				return value.String(), index, nil
+			default:
+				value.WriteRune(runes[index])
+			}
+		}
+		return "", 0, newError(start, "unterminated string literal")
+	}
+
+	for offset := 0; offset < len(runes); {
+		char := runes[offset]
+		twoChar := ""
+		if offset+1 < len(runes) {
+			twoChar = string(runes[offset : offset+2])
+		}
+		switch {
+		case unicode.IsSpace(char):
+			offset++
+		case twoChar == "&&":
+			tokens = append(tokens, conditionToken{tokenAnd, twoChar, offset})
+			offset += 2
+		case twoChar == "||":
+			tokens = append(tokens, conditionToken{tokenOr, twoChar, offset})
+			offset += 2
+		case twoChar == "==" || twoChar == "!=" || twoChar == "<=" || twoChar == ">=":
+			tokens = append(tokens, conditionToken{tokenOperator, twoChar, offset})
+			offset += 2
+		case char == '<' || char == '>':
+			tokens = append(tokens, conditionToken{tokenOperator, string(char), offset})
+			offset++
+		case char == '!':
+			tokens = append(tokens, conditionToken{tokenNot, "!", offset})
+			offset++
+		case char == '(':
+			tokens = append(tokens, conditionToken{tokenLParen, "(", offset})
+			offset++
+		case char == ')':
+			tokens = append(tokens, conditionToken{tokenRParen, ")", offset})
+			offset++
+		case char == '\'' || char == '"':
+			value, end, scanErr := scanString(offset)
+			if scanErr != nil {
+				return nil, scanErr
+			}
+			tokens = append(tokens, conditionToken{tokenString, value, offset})
+			offset = end + 1
+		case char == '$':
+			end := offset
+			for end < len(runes) && !isPathDelimiter(runes[end]) {
+				if runes[end] == '[' {
+					// Bracket notation may include quoted names with delimiters
+					closed := false
+					for end++; end < len(runes); end++ {
+						if runes[end] == '\'' || runes[end] == '"' {
+							_, quoteEnd, scanErr := scanString(end)
+							if scanErr != nil {
+								return nil, scanErr
+							}
+							end = quoteEnd
+						} else if runes[end] == ']' {
+							closed = true
+							break
+						}
+					}
+					if !closed {
+						return nil, newError(offset, "unterminated '[' in path")
+					}
+				}
+				end++
+			}
+			tokens = append(tokens, conditionToken{tokenPath, string(runes[offset:end]), offset})
+			offset = end
+		case unicode.IsDigit(char) || (char == '-' && offset+1 < len(runes) && unicode.IsDigit(runes[offset+1])):
+			end := offset + 1
+			for end < len(runes) && strings.ContainsRune("0123456789.eE+-", runes[end]) {
+				// Only allow a sign immediately after an exponent marker
+				if (runes[end] == '+' || runes[end] == '-') &&
+					runes[end-1] != 'e' && runes[end-1] != 'E' {
+					break
+				}
+				end++
+			}
+			tokens = append(tokens, conditionToken{tokenNumber, string(runes[offset:end]), offset})
+			offset = end
+		case unicode.IsLetter(char):
+			end := offset
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end])) {
+				end++
+			}
+			tokens = append(tokens, conditionToken{tokenIdent, string(runes[offset:end]), offset})
+			offset = end
+		default:
+			return nil, newError(offset, "unexpected character %q", char)
+		}
+	}
+	tokens = append(tokens, conditionToken{tokenEOF, "", len(runes)})
+	return tokens, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Parser
+////////////////////////////////////////////////////////////////////////////////
+
+// conditionValueType is the comparison type inferred from operands
+type conditionValueType string
+
+const (
+	conditionTypeUnknown   conditionValueType = ""
+	conditionTypeNumber    conditionValueType = "number"
+	conditionTypeString    conditionValueType = "string"
+	conditionTypeBoolean   conditionValueType = "boolean"
+	conditionTypeTimestamp conditionValueType = "timestamp"
+)
+
+// conditionOperand is a parsed comparison operand
+type conditionOperand struct {
+	token     conditionToken
+	isPath    bool
+	valueType conditionValueType
+	number    float64
+	text      string
+	boolean   bool
+	timestamp time.Time
+}
+
+type conditionParser struct {
+	expression string
+	tokens     []conditionToken
+	position   int
+}
+
+func (cp *conditionParser) peek() conditionToken {
+	return cp.tokens[cp.position]
+}
+
+func (cp *conditionParser) next() conditionToken {
+	token := cp.tokens[cp.position]
+	if token.kind != tokenEOF {
+		cp.position++
+	}
+	return token
+}
+
+func (cp *conditionParser) errorf(token conditionToken, format string, args ...interface{}) error {
+	return &ConditionError{
+		Expression: cp.expression,
+		Column:     token.offset + 1,
+		Message:    fmt.Sprintf(format, args...),
+	}
+}
+
+func (cp *conditionParser) expect(kind conditionTokenKind, description string) (conditionToken, error) {
+	token := cp.next()
+	if token.kind != kind {
+		return token, cp.errorf(token, "expected %s, found %s", description, token)
+	}
+	return token, nil
+}
+
+func (cp *conditionParser) parseOr() (Comparison, error) {
+	first, firstErr := cp.parseAnd()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	comparisons := []Comparison{first}
+	for cp.peek().kind == tokenOr {
+		cp.next()
+		eachComparison, eachComparisonErr := cp.parseAnd()
+		if eachComparisonErr != nil {
+			return nil, eachComparisonErr
+		}
+		comparisons = append(comparisons, eachComparison)
+	}
+	if len(comparisons) == 1 {
+		return first, nil
+	}
+	return &Or{Comparison: comparisons}, nil
+}
+
+func (cp *conditionParser) parseAnd() (Comparison, error) {
+	first, firstErr := cp.parseUnary()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	comparisons := []Comparison{first}
+	for cp.peek().kind == tokenAnd {
+		cp.next()
+		eachComparison, eachComparisonErr := cp.parseUnary()
+		if eachComparisonErr != nil {
+			return nil, eachComparisonErr
+		}
+		comparisons = append(comparisons, eachComparison)
+	}
+	if len(comparisons) == 1 {
+		return first, nil
+	}
+	return &And{Comparison: comparisons}, nil
+}
+
+func (cp *conditionParser) parseUnary() (Comparison, error) {
+	token := cp.peek()
+	switch token.kind {
+	case tokenNot:
+		cp.next()
+		negated, negatedErr := cp.parseUnary()
+		if negatedErr != nil {
+			return nil, negatedErr
+		}
+		// Type checks support a false value directly
+		if negatedTypeCheck := negateTypeCheck(negated); negatedTypeCheck != nil {
+			return negatedTypeCheck, nil
+		}
+		if notComparison, isNot := negated.(*Not); isNot {
+			return notComparison.Comparison, nil
+		}
+		return &Not{Comparison: negated}, nil
+	case tokenLParen:
+		cp.next()
+		nested, nestedErr := cp.parseOr()
+		if nestedErr != nil {
+			return nil, nestedErr
+		}
+		_, closeErr := cp.expect(tokenRParen, "')'")
+		if closeErr != nil {
+			return nil, closeErr
+		}
+		return nested, nil
+	case tokenIdent:
+		if typeCheck := typeCheckComparison(token.value, "", true); typeCheck != nil {
+			return cp.parseTypeCheck()
+		}
+	}
+	return cp.parseComparison()
+}
+
+func (cp *conditionParser) parseTypeCheck() (Comparison, error) {
+	nameToken := cp.next()
+	_, openErr := cp.expect(tokenLParen, "'('")
+	if openErr != nil {
+		return nil, openErr
+	}
+	pathToken, pathErr := cp.expect(tokenPath, "a path beginning with '$'")
+	if pathErr != nil {
+		return nil, pathErr
+	}
+	_, closeErr := cp.expect(tokenRParen, "')'")
+	if closeErr != nil {
+		return nil, closeErr
+	}
+	return typeCheckComparison(nameToken.value, pathToken.value, true), nil
+}
+
+func (cp *conditionParser) parseOperand() (*conditionOperand, error) {
+	token := cp.next()
+	operand := &conditionOperand{
+		token: token,
+	}
+	switch token.kind {
+	case tokenPath:
+		operand.isPath = true
+		operand.text = token.value
+	case tokenNumber:
+		number, numberErr := strconv.ParseFloat(token.value, 64)
+		if numberErr != nil {
+			return nil, cp.errorf(token, "invalid number %s", token.value)
+		}
+		operand.valueType = conditionTypeNumber
+		operand.number = number
+	case tokenString:
+		operand.valueType = conditionTypeString
+		operand.text = token.value
+	case tokenIdent:
+		switch token.value {
+		case "true", "false":
+			operand.valueType = conditionTypeBoolean
+			operand.boolean = token.value == "true"
+		case string(conditionTypeNumber),
+			string(conditionTypeString),
+			string(conditionTypeBoolean),
+			string(conditionTypeTimestamp):
+			return cp.parseTypeHint(token)
+		default:
+			return nil, cp.errorf(token, "unknown identifier %s", token)
+		}
+	default:
+		return nil, cp.errorf(token, "expected a path or value, found %s", token)
+	}
+	return operand, nil
+}
+
+func (cp *conditionParser) parseTypeHint(hintToken conditionToken) (*conditionOperand, error) {
+	_, openErr := cp.expect(tokenLParen, "'('")
+	if openErr != nil {
+		return nil, openErr
+	}
+	valueToken := cp.next()
+	operand := &conditionOperand{
+		token:     valueToken,
+		valueType: conditionValueType(hintToken.value),
+	}
+	switch {
+	case valueToken.kind == tokenPath:
+		operand.isPath = true
+		operand.text = valueToken.value
+	case valueToken.kind == tokenString && operand.valueType == conditionTypeTimestamp:
+		timestamp, timestampErr := time.Parse(time.RFC3339, valueToken.value)
+		if timestampErr != nil {
+			return nil, cp.errorf(valueToken, "invalid RFC3339 timestamp '%s'", valueToken.value)
+		}
+		operand.timestamp = timestamp
+	default:
+		return nil, cp.errorf(valueToken,
+			"%s() expects a path beginning with '$', found %s",
+			hintToken.value,
+			valueToken)
+	}
+	_, closeErr := cp.expect(tokenRParen, "')'")
+	if closeErr != nil {
+		return nil, closeErr
+	}
+	return operand, nil
+}
+
+func (cp *conditionParser) parseComparison() (Comparison, error) {
+	lhs, lhsErr := cp.parseOperand()
+	if lhsErr != nil {
+		return nil, lhsErr
+	}
+	operatorToken := cp.peek()
+	isMatches := operatorToken.kind == tokenIdent && operatorToken.value == "matches"
+	if operatorToken.kind != tokenOperator && !isMatches {
+		// A bare path is a boolean test
+		if lhs.isPath && (lhs.valueType == conditionTypeUnknown ||
+			lhs.valueType == conditionTypeBoolean) {
+			return &BooleanEquals{Variable: lhs.text, Value: true}, nil
+		}
+		return nil, cp.errorf(operatorToken, "expected a comparison operator, found %s", operatorToken)
+	}
+	cp.next()
+	rhs, rhsErr := cp.parseOperand()
+	if rhsErr != nil {
+		return nil, rhsErr
+	}
+	operator := operatorToken.value
+	// Normalize s.t. the Variable is always on the left
+	if !lhs.isPath {
+		if !rhs.isPath {
+			return nil, cp.errorf(lhs.token, "comparison requires a path beginning with '$'")
+		}
+		if isMatches {
+			return nil, cp.errorf(lhs.token, "matches requires a path on the left")
+		}
+		lhs, rhs = rhs, lhs
+		operator = map[string]string{
+			"==": "==",
+			"!=": "!=",
+			"<":  ">",
+			"<=": ">=",
+			">":  "<",
+			">=": "<=",
+		}[operator]
+	}
+
+	// Resolve the comparison type
+	valueType := rhs.valueType
+	if lhs.valueType != conditionTypeUnknown {
+		switch {
+		case valueType == conditionTypeUnknown:
+			valueType = lhs.valueType
+		case lhs.valueType == conditionTypeTimestamp && valueType == conditionTypeString:
+			timestamp, timestampErr := time.Parse(time.RFC3339, rhs.text)
+			if timestampErr != nil {
+				return nil, cp.errorf(rhs.token, "invalid RFC3339 timestamp '%s'", rhs.text)
+			}
+			rhs.timestamp = timestamp
+			valueType = conditionTypeTimestamp
+		case lhs.valueType != valueType:
+			return nil, cp.errorf(rhs.token,
+				"cannot compare %s to %s",
+				lhs.valueType,
+				valueType)
+		}
+	}
+	if valueType == conditionTypeUnknown {
+		return nil, cp.errorf(rhs.token,
+			"path comparisons require a type. Use number(), string(), boolean() or timestamp() on either operand")
+	}
+	if isMatches {
+		if rhs.isPath || valueType != conditionTypeString {
+			return nil, cp.errorf(rhs.token, "matches requires a string pattern")
+		}
+		return &StringMatches{Variable: lhs.text, Value: rhs.text}, nil
+	}
+	if valueType == conditionTypeBoolean && operator != "==" && operator != "!=" {
+		return nil, cp.errorf(operatorToken, "boolean values only support == and !=")
+	}
+	if operator == "!=" {
+		equals := newConditionComparison(valueType, "==", lhs.text, rhs)
+		return &Not{Comparison: equals}, nil
+	}
+	return newConditionComparison(valueType, operator, lhs.text, rhs), nil
+}
+
+// newConditionComparison returns the typed comparison for the operator
+func newConditionComparison(valueType conditionValueType,
+	operator string,
+	variable string,
+	rhs *conditionOperand) Comparison {
+	if rhs.isPath {
+		path := rhs.text
+		switch valueType {
+		case conditionTypeNumber:
+			return map[string]Comparison{
+				"==": &NumericEqualsPath{Variable: variable, Value: path},
+				"<":  &NumericLessThanPath{Variable: variable, Value: path},
+				"<=": &NumericLessThanEqualsPath{Variable: variable, Value: path},
+				">":  &NumericGreaterThanPath{Variable: variable, Value: path},
+				">=": &NumericGreaterThanEqualsPath{Variable: variable, Value: path},
+			}[operator]
+		case conditionTypeString:
+			return map[string]Comparison{
+				"==": &StringEqualsPath{Variable: variable, Value: path},
+				"<":  &StringLessThanPath{Variable: variable, Value: path},
+				"<=": &StringLessThanEqualsPath{Variable: variable, Value: path},
+				">":  &StringGreaterThanPath{Variable: variable, Value: path},
+				">=": &StringGreaterThanEqualsPath{Variable: variable, Value: path},
+			}[operator]
+		case conditionTypeTimestamp:
+			return map[string]Comparison{
+				"==": &TimestampEqualsPath{Variable: variable, Value: path},
+				"<":  &TimestampLessThanPath{Variable: variable, Value: path},
+				"<=": &TimestampLessThanEqualsPath{Variable: variable, Value: path},
+				">":  &TimestampGreaterThanPath{Variable: variable, Value: path},
+				">=": &TimestampGreaterThanEqualsPath{Variable: variable, Value: path},
+			}[operator]
+		default:
+			return &BooleanEqualsPath{Variable: variable, Value: path}
+		}
+	}
+	switch valueType {
+	case conditionTypeNumber:
+		value := rhs.number
+		return map[string]Comparison{
+			"==": &NumericEquals{Variable: variable, Value: value},
+			"<":  &NumericLessThan{Variable: variable, Value: value},
+			"<=": &NumericLessThanEquals{Variable: variable, Value: value},
+			">":  &NumericGreaterThan{Variable: variable, Value: value},
+			">=": &NumericGreaterThanEquals{Variable: variable, Value: value},
+		}[operator]
+	case conditionTypeString:
+		value := rhs.text
+		return map[string]Comparison{
+			"==": &StringEquals{Variable: variable, Value: value},
+			"<":  &StringLessThan{Variable: variable, Value: value},
+			"<=": &StringLessThanEquals{Variable: variable, Value: value},
+			">":  &StringGreaterThan{Variable: variable, Value: value},
+			">=": &StringGreaterThanEquals{Variable: variable, Value: value},
+		}[operator]
+	case conditionTypeTimestamp:
+		value := rhs.timestamp
+		return map[string]Comparison{
+			"==": &TimestampEquals{Variable: variable, Value: value},
+			"<":  &TimestampLessThan{Variable: variable, Value: value},
+			"<=": &TimestampLessThanEquals{Variable: variable, Value: value},
+			">":  &TimestampGreaterThan{Variable: variable, Value: value},
+			">=": &TimestampGreaterThanEquals{Variable: variable, Value: value},
+		}[operator]
+	default:
+		return &BooleanEquals{Variable: variable, Value: rhs.boolean}
+	}
+}
+
+// typeCheckComparison returns the type check comparison for the function
+// name, or nil if the name isn't a type check
+func typeCheckComparison(name string, variable string, value bool) Comparison {
+	switch name {
+	case "isPresent":
+		return &IsPresent{Variable: variable, Value: value}
+	case "isNull":
+		return &IsNull{Variable: variable, Value: value}
+	case "isNumeric":
+		return &IsNumeric{Variable: variable, Value: value}
+	case "isString":
+		return &IsString{Variable: variable, Value: value}
+	case "isBoolean":
+		return &IsBoolean{Variable: variable, Value: value}
+	case "isTimestamp":
+		return &IsTimestamp{Variable: variable, Value: value}
+	default:
+		return nil
+	}
+}
+
+// negateTypeCheck returns the inverted type check, or nil if the
+// comparison isn't a type check
+func negateTypeCheck(comparison Comparison) Comparison {
+	switch typedComparison := comparison.(type) {
+	case *IsPresent:
+		typedComparison.Value = !typedComparison.Value
+	case *IsNull:
+		typedComparison.Value = !typedComparison.Value
+	case *IsNumeric:
+		typedComparison.Value = !typedComparison.Value
+	case *IsString:
+		typedComparison.Value = !typedComparison.Value
+	case *IsBoolean:
+		typedComparison.Value = !typedComparison.Value
+	case *IsTimestamp:
+		typedComparison.Value = !typedComparison.Value
+	default:
+		return nil
+	}
+	return comparison
+}
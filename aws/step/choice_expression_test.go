@@ -0,0 +1,93 @@
+package step
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseCondition(t *testing.T) {
+	testCases := map[string]string{
+		"$.order.total > 100": `{"Variable":"$.order.total","NumericGreaterThan":100}`,
+		"$.order.total > 100 && ($.tier == 'gold' || isPresent($.coupon))": `{"And":[` +
+			`{"Variable":"$.order.total","NumericGreaterThan":100},` +
+			`{"Or":[{"Variable":"$.tier","StringEquals":"gold"},{"Variable":"$.coupon","IsPresent":true}]}]}`,
+		"100 <= $.total":                          `{"Variable":"$.total","NumericGreaterThanEquals":100}`,
+		"$.tier != \"silver\"":                    `{"Not":{"Variable":"$.tier","StringEquals":"silver"}}`,
+		"!isNull($.coupon)":                       `{"Variable":"$.coupon","IsNull":false}`,
+		"$.name matches 'log-*.txt'":              `{"Variable":"$.name","StringMatches":"log-*.txt"}`,
+		"$.total > number($.limits['max total'])": `{"Variable":"$.total","NumericGreaterThanPath":"$.limits['max total']"}`,
+		"timestamp($.created) < '2021-01-01T00:00:00Z'": `{"Variable":"$.created",` +
+			`"TimestampLessThan":"2021-01-01T00:00:00Z"}`,
+		"$.created >= timestamp($$.Execution.StartTime)": `{"Variable":"$.created",` +
+			`"TimestampGreaterThanEqualsPath":"$$.Execution.StartTime"}`,
+		"$.approved":           `{"Variable":"$.approved","BooleanEquals":true}`,
+		"$.approved == false":  `{"Variable":"$.approved","BooleanEquals":false}`,
+		"!($.total < -1.5e2)":  `{"Not":{"Variable":"$.total","NumericLessThan":-150}}`,
+		"!!($.tier == 'gold')": `{"Variable":"$.tier","StringEquals":"gold"}`,
+	}
+	for eachExpression, eachExpected := range testCases {
+		condition, conditionErr := ParseCondition(eachExpression)
+		if conditionErr != nil {
+			t.Fatalf("Failed to parse %s: %s", eachExpression, conditionErr)
+		}
+		jsonBytes, jsonBytesErr := json.Marshal(condition)
+		if jsonBytesErr != nil {
+			t.Fatalf("Failed to marshal %s: %s", eachExpression, jsonBytesErr)
+		}
+		if string(jsonBytes) != eachExpected {
+			t.Fatalf("Unexpected condition for %s.\nExpected: %s\nActual:   %s",
+				eachExpression,
+				eachExpected,
+				string(jsonBytes))
+		}
+	}
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	testCases := map[string]int{
+		"$.total >> 100":                 10,
+		"$.total > 100 &&":               17,
+		"$.a > $.b":                      7,
+		"$.tier == 'gold":                11,
+		"($.total > 100":                 15,
+		"$.approved < true":              12,
+		"$.created > timestamp('today')": 23,
+		"number($.a) == 'one'":           16,
+		"isPresent('coupon')":            11,
+		"$.total > 100 $.tier":           15,
+	}
+	for eachExpression, eachColumn := range testCases {
+		_, conditionErr := ParseCondition(eachExpression)
+		if conditionErr == nil {
+			t.Fatalf("Failed to reject invalid condition: %s", eachExpression)
+		}
+		var typedErr *ConditionError
+		if !errors.As(conditionErr, &typedErr) {
+			t.Fatalf("Failed to return ConditionError for %s: %#v", eachExpression, conditionErr)
+		}
+		if typedErr.Column != eachColumn {
+			t.Fatalf("Unexpected column for %s. Expected: %d, Actual: %d\n%s",
+				eachExpression,
+				eachColumn,
+				typedErr.Column,
+				typedErr)
+		}
+	}
+}
+
+func TestParseChoiceBranch(t *testing.T) {
+	successState := NewSuccessState("success")
+	branch, branchErr := ParseChoiceBranch("$.roll >= 3", successState)
+	if branchErr != nil {
+		t.Fatalf("Failed to parse choice branch: %s", branchErr)
+	}
+	jsonBytes, jsonBytesErr := json.Marshal(branch)
+	if jsonBytesErr != nil {
+		t.Fatalf("Failed to marshal choice branch: %s", jsonBytesErr)
+	}
+	expected := `{"And":[{"Variable":"$.roll","NumericGreaterThanEquals":3}],"Next":"success"}`
+	if string(jsonBytes) != expected {
+		t.Fatalf("Unexpected choice branch.\nExpected: %s\nActual:   %s", expected, string(jsonBytes))
+	}
+}
@@ -71,14 +71,14 @@ func (cmp *BooleanEqualsPath) MarshalJSON() ([]byte, error) {
 type IsBoolean struct {
 	Comparison
 	Variable string
-	Value    string
+	Value    bool
 }
 
 // MarshalJSON for custom marshalling
 func (cmp *IsBoolean) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Variable  string
-		IsBoolean string
+		IsBoolean bool
 	}{
 		Variable:  cmp.Variable,
 		IsBoolean: cmp.Value,
@@ -93,14 +93,14 @@ func (cmp *IsBoolean) MarshalJSON() ([]byte, error) {
 type IsNull struct {
 	Comparison
 	Variable string
-	Value    string
+	Value    bool
 }
 
 // MarshalJSON for custom marshalling
 func (cmp *IsNull) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Variable string
-		IsNull   string
+		IsNull   bool
 	}{
 		Variable: cmp.Variable,
 		IsNull:   cmp.Value,
@@ -115,14 +115,14 @@ func (cmp *IsNull) MarshalJSON() ([]byte, error) {
 type IsNumeric struct {
 	Comparison
 	Variable string
-	Value    string
+	Value    bool
 }
 
 // MarshalJSON for custom marshalling
 func (cmp *IsNumeric) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Variable  string
-		IsNumeric string
+		IsNumeric bool
 	}{
 		Variable:  cmp.Variable,
 		IsNumeric: cmp.Value,
@@ -137,14 +137,14 @@ func (cmp *IsNumeric) MarshalJSON() ([]byte, error) {
 type IsPresent struct {
 	Comparison
 	Variable string
-	Value    string
+	Value    bool
 }
 
 // MarshalJSON for custom marshalling
 func (cmp *IsPresent) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Variable  string
-		IsPresent string
+		IsPresent bool
 	}{
 		Variable:  cmp.Variable,
 		IsPresent: cmp.Value,
@@ -159,14 +159,14 @@ func (cmp *IsPresent) MarshalJSON() ([]byte, error) {
 type IsString struct {
 	Comparison
 	Variable string
-	Value    string
+	Value    bool
 }
 
 // MarshalJSON for custom marshalling
 func (cmp *IsString) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Variable string
-		IsString string
+		IsString bool
 	}{
 		Variable: cmp.Variable,
 		IsString: cmp.Value,
@@ -181,14 +181,14 @@ func (cmp *IsString) MarshalJSON() ([]byte, error) {
 type IsTimestamp struct {
 	Comparison
 	Variable string
-	Value    string
+	Value    bool
 }
 
 // MarshalJSON for custom marshalling
 func (cmp *IsTimestamp) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Variable    string
-		IsTimestamp string
+		IsTimestamp bool
 	}{
 		Variable:    cmp.Variable,
 		IsTimestamp: cmp.Value,
@@ -203,14 +203,14 @@ func (cmp *IsTimestamp) MarshalJSON() ([]byte, error) {
 type NumericEquals struct {
 	Comparison
 	Variable string
-	Value    int64
+	Value    float64
 }
 
 // MarshalJSON for custom marshalling
 func (cmp *NumericEquals) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Variable      string
-		NumericEquals int64
+		NumericEquals float64
 	}{
 		Variable:      cmp.Variable,
 		NumericEquals: cmp.Value,
@@ -247,14 +247,14 @@ func (cmp *NumericEqualsPath) MarshalJSON() ([]byte, error) {
 type NumericGreaterThan struct {
 	Comparison
 	Variable string
-	Value    int64
+	Value    float64
 }
 
 // MarshalJSON for custom marshalling
 func (cmp *NumericGreaterThan) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Variable           string
-		NumericGreaterThan int64
+		NumericGreaterThan float64
 	}{
 		Variable:           cmp.Variable,
 		NumericGreaterThan: cmp.Value,
@@ -269,14 +269,14 @@ func (cmp *NumericGreaterThan) MarshalJSON() ([]byte, error) {
 type NumericGreaterThanEquals struct {
 	Comparison
 	Variable string
-	Value    int64
+	Value    float64
 }
 
 // MarshalJSON for custom marshalling
 func (cmp *NumericGreaterThanEquals) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Variable                 string
-		NumericGreaterThanEquals int64
+		NumericGreaterThanEquals float64
 	}{
 		Variable:                 cmp.Variable,
 		NumericGreaterThanEquals: cmp.Value,
@@ -335,14 +335,14 @@ func (cmp *NumericGreaterThanPath) MarshalJSON() ([]byte, error) {
 type NumericLessThan struct {
 	Comparison
 	Variable string
-	Value    int64
+	Value    float64
 }
 
 // MarshalJSON for custom marshalling
 func (cmp *NumericLessThan) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Variable        string
-		NumericLessThan int64
+		NumericLessThan float64
 	}{
 		Variable:        cmp.Variable,
 		NumericLessThan: cmp.Value,
@@ -357,14 +357,14 @@ func (cmp *NumericLessThan) MarshalJSON() ([]byte, error) {
 type NumericLessThanEquals struct {
 	Comparison
 	Variable string
-	Value    int64
+	Value    float64
 }
 
 // MarshalJSON for custom marshalling
 func (cmp *NumericLessThanEquals) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Variable              string
-		NumericLessThanEquals int64
+		NumericLessThanEquals float64
 	}{
 		Variable:              cmp.Variable,
 		NumericLessThanEquals: cmp.Value,
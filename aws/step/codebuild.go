@@ -0,0 +1,82 @@
+package step
+
+import (
+	"math/rand"
+	"strings"
+
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
+)
+
+// CodeBuildStartBuildParameters represents params for the CodeBuild
+// StartBuild integration
+// Ref: https://docs.aws.amazon.com/codebuild/latest/APIReference/API_StartBuild.html
+type CodeBuildStartBuildParameters struct {
+	ProjectName                  string                   `json:",omitempty"`
+	BuildspecOverride            string                   `json:",omitempty"`
+	ComputeTypeOverride          string                   `json:",omitempty"`
+	EnvironmentVariablesOverride []map[string]interface{} `json:",omitempty"`
+	ImageOverride                string                   `json:",omitempty"`
+	SourceVersion                string                   `json:",omitempty"`
+	TimeoutInMinutesOverride     int                      `json:",omitempty"`
+}
+
+// CodeBuildStartBuildState represents bindings for
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-codebuild.html
+type CodeBuildStartBuildState struct {
+	BaseTask
+	parameters CodeBuildStartBuildParameters
+}
+
+// MarshalJSON for custom marshalling, since this will be stringified and we need it
+// to turn into a stringified
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connect-codebuild.html
+func (cbs *CodeBuildStartBuildState) MarshalJSON() ([]byte, error) {
+	return cbs.BaseTask.marshalIntegrationParams(&cbs.parameters)
+}
+
+// policyStatements returns the privileges needed to start the build
+func (cbs *CodeBuildStartBuildState) policyStatements() []spartaIAM.PolicyStatement {
+	projectArn := "*"
+	projectName := cbs.parameters.ProjectName
+	if strings.HasPrefix(projectName, "arn:") {
+		projectArn = projectName
+	} else if projectName != "" {
+		projectArn = regionalArn("codebuild", "project/", projectName)
+	}
+	statements := []spartaIAM.PolicyStatement{
+		{
+			Effect: "Allow",
+			Action: []string{
+				"codebuild:StartBuild",
+				"codebuild:StopBuild",
+				"codebuild:BatchGetBuilds",
+			},
+			Resource: projectArn,
+		},
+	}
+	if cbs.resolvedIntegrationPattern() == Sync {
+		statements = append(statements,
+			syncEventRuleStatement("StepFunctionsGetEventForCodeBuildStartBuildRule"))
+	}
+	return statements
+}
+
+// NewCodeBuildStartBuildState returns an initialized CodeBuildStartBuildState
+func NewCodeBuildStartBuildState(stateName string,
+	parameters CodeBuildStartBuildParameters) *CodeBuildStartBuildState {
+	cbs := &CodeBuildStartBuildState{
+		BaseTask: BaseTask{
+			baseInnerState: baseInnerState{
+				name: stateName,
+				id:   rand.Int63(),
+			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::codebuild:startBuild",
+				defaultPattern:    Sync,
+				supportedPatterns: []IntegrationPattern{RequestResponse, Sync},
+			},
+		},
+		parameters: parameters,
+	}
+	return cbs
+}
@@ -0,0 +1,120 @@
+package step
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	goflambda "github.com/awslabs/goformation/v5/cloudformation/lambda"
+	sparta "github.com/mweagle/Sparta/v3"
+	"github.com/rs/zerolog"
+)
+
+// DecorateService satisfies the sparta.ServiceDecoratorHookHandler interface
+// so that the StateMachine can be included directly in the
+// WorkflowHooks.ServiceDecorators slice. It's equivalent to
+// StateMachineDecorator, but also includes the state machine in the
+// `describe` output.
+func (sm *StateMachine) DecorateService(ctx context.Context,
+	serviceName string,
+	template *gof.Template,
+	lambdaFunctionCode *goflambda.Function_Code,
+	buildID string,
+	awsConfig awsv2.Config,
+	noop bool,
+	logger *zerolog.Logger) (context.Context, error) {
+	return sm.StateMachineDecorator()(ctx,
+		serviceName,
+		template,
+		lambdaFunctionCode,
+		buildID,
+		awsConfig,
+		noop,
+		logger)
+}
+
+// stateNodeName returns the describe node name for the state. State names
+// are only unique within a state machine.
+func (sm *StateMachine) stateNodeName(state MachineState) string {
+	return fmt.Sprintf("%s.%s", sm.name, state.Name())
+}
+
+// Describe satisfies the sparta.Describable interface
+func (sm *StateMachine) Describe(targetNodeName string) (*sparta.DescriptionInfo, error) {
+	descInfo := &sparta.DescriptionInfo{
+		Name:  fmt.Sprintf("StateMachine - %s", sm.name),
+		Nodes: make([]*sparta.DescriptionTriplet, 0),
+	}
+	descInfo.Nodes = append(descInfo.Nodes, &sparta.DescriptionTriplet{
+		SourceNodeName: sm.name,
+		DisplayInfo: &sparta.DescriptionDisplayInfo{
+			SourceIcon: &sparta.DescriptionIcon{
+				Category: "Arch_App-Integration",
+				Name:     "Arch_64/Arch_AWS-Step-Functions_64@5x.png",
+			},
+		},
+		TargetNodeName: targetNodeName,
+	})
+	if sm.startAt != nil {
+		descInfo.Nodes = append(descInfo.Nodes, &sparta.DescriptionTriplet{
+			SourceNodeName: sm.name,
+			ArcLabel:       "StartAt",
+			TargetNodeName: sm.stateNodeName(sm.startAt),
+		})
+	}
+	// Sorted for stable output
+	stateNames := make([]string, 0, len(sm.uniqueStates))
+	for eachName := range sm.uniqueStates {
+		stateNames = append(stateNames, eachName)
+	}
+	sort.Strings(stateNames)
+
+	for _, eachName := range stateNames {
+		eachState := sm.uniqueStates[eachName]
+		stateNodeName := sm.stateNodeName(eachState)
+		transition := func(nextState MachineState, label string) {
+			if nextState == nil {
+				return
+			}
+			descInfo.Nodes = append(descInfo.Nodes, &sparta.DescriptionTriplet{
+				SourceNodeName: stateNodeName,
+				ArcLabel:       label,
+				TargetNodeName: sm.stateNodeName(nextState),
+			})
+		}
+		// Every state is a node, even if it's terminal
+		descInfo.Nodes = append(descInfo.Nodes, &sparta.DescriptionTriplet{
+			SourceNodeName: stateNodeName,
+		})
+
+		switch typedState := eachState.(type) {
+		case *LambdaTaskState:
+			if typedState.lambdaFn != nil {
+				descInfo.Nodes = append(descInfo.Nodes,
+					typedState.lambdaFn.NewDescriptionTriplet(stateNodeName, true))
+			}
+			transition(typedState.next, "")
+			for _, eachCatcher := range typedState.Catchers {
+				transition(eachCatcher.next, "Catch")
+			}
+		case *ChoiceState:
+			for _, eachChoice := range typedState.Choices {
+				transition(eachChoice.nextState(), "Choice")
+			}
+			transition(typedState.Default, "Default")
+		case taskState:
+			task := typedState.baseTask()
+			transition(task.next, "")
+			for _, eachCatcher := range task.Catchers {
+				transition(eachCatcher.next, "Catch")
+			}
+		case TransitionState:
+			for _, eachAdjacent := range typedState.AdjacentStates() {
+				transition(eachAdjacent, "")
+			}
+		}
+	}
+	return descInfo, nil
+}
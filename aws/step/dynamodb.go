@@ -4,8 +4,23 @@ import (
 	"math/rand"
 
 	awsv2DynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
 )
 
+// dynamoDBTableStatement returns the statement that grants the
+// action on the named table
+func dynamoDBTableStatement(tableName string, action string) spartaIAM.PolicyStatement {
+	resource := "*"
+	if tableName != "" {
+		resource = regionalArn("dynamodb", "table/", tableName)
+	}
+	return spartaIAM.PolicyStatement{
+		Effect:   "Allow",
+		Action:   []string{action},
+		Resource: resource,
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 /*
    ___     _     ___ _
@@ -38,8 +53,14 @@ type DynamoDBGetItemState struct {
 // to turn into a stringified
 // Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connectors-sns.html
 func (dgis *DynamoDBGetItemState) MarshalJSON() ([]byte, error) {
-	return dgis.BaseTask.marshalMergedParams("arn:aws:states:::dynamodb:getItem",
-		&dgis.parameters)
+	return dgis.BaseTask.marshalIntegrationParams(&dgis.parameters)
+}
+
+// policyStatements returns the privileges needed to get the item
+func (dgis *DynamoDBGetItemState) policyStatements() []spartaIAM.PolicyStatement {
+	return []spartaIAM.PolicyStatement{
+		dynamoDBTableStatement(dgis.parameters.TableName, "dynamodb:GetItem"),
+	}
 }
 
 // NewDynamoDBGetItemState returns an initialized DynamoDB GetItem state
@@ -52,6 +73,11 @@ func NewDynamoDBGetItemState(stateName string,
 				name: stateName,
 				id:   rand.Int63(),
 			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::dynamodb:getItem",
+				defaultPattern:    RequestResponse,
+				supportedPatterns: []IntegrationPattern{RequestResponse},
+			},
 		},
 		parameters: parameters,
 	}
@@ -93,8 +119,14 @@ type DynamoDBPutItemState struct {
 // to turn into a stringified
 // Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connectors-sns.html
 func (dgis *DynamoDBPutItemState) MarshalJSON() ([]byte, error) {
-	return dgis.BaseTask.marshalMergedParams("arn:aws:states:::dynamodb:putItem",
-		&dgis.parameters)
+	return dgis.BaseTask.marshalIntegrationParams(&dgis.parameters)
+}
+
+// policyStatements returns the privileges needed to put the item
+func (dgis *DynamoDBPutItemState) policyStatements() []spartaIAM.PolicyStatement {
+	return []spartaIAM.PolicyStatement{
+		dynamoDBTableStatement(dgis.parameters.TableName, "dynamodb:PutItem"),
+	}
 }
 
 // NewDynamoDBPutItemState returns an initialized DynamoDB PutItem state
@@ -107,8 +139,224 @@ func NewDynamoDBPutItemState(stateName string,
 				name: stateName,
 				id:   rand.Int63(),
 			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::dynamodb:putItem",
+				defaultPattern:    RequestResponse,
+				supportedPatterns: []IntegrationPattern{RequestResponse},
+			},
 		},
 		parameters: parameters,
 	}
 	return dpis
 }
+
+////////////////////////////////////////////////////////////////////////////////
+/*
+  _   _          _      _         ___ _
+ | | | |_ __  __| |__ _| |_ ___  |_ _| |_ ___ _ __
+ | |_| | '_ \/ _` / _` |  _/ -_)  | ||  _/ -_) '  \
+  \___/| .__/\__,_\__,_|\__\___| |___|\__\___|_|_|_|
+       |_|
+*/
+////////////////////////////////////////////////////////////////////////////////
+
+// DynamoDBUpdateItemParameters represents params for the DynamoDB UpdateItem
+// integration. Key and attribute values use the DynamoDB JSON format
+// (eg: {"S": "value"}). Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connect-ddb.html
+type DynamoDBUpdateItemParameters struct {
+	Key                         map[string]interface{} `json:",omitempty"`
+	TableName                   string                 `json:",omitempty"`
+	ConditionExpression         string                 `json:",omitempty"`
+	ExpressionAttributeNames    map[string]string      `json:",omitempty"`
+	ExpressionAttributeValues   map[string]interface{} `json:",omitempty"`
+	ReturnConsumedCapacity      string                 `json:",omitempty"` // INDEXES | TOTAL | NONE
+	ReturnItemCollectionMetrics string                 `json:",omitempty"` // SIZE | NONE
+	ReturnValues                string                 `json:",omitempty"` // NONE | ALL_OLD | UPDATED_OLD | ALL_NEW | UPDATED_NEW
+	UpdateExpression            string                 `json:",omitempty"`
+}
+
+// DynamoDBUpdateItemState represents bindings for
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-ddb.html
+type DynamoDBUpdateItemState struct {
+	BaseTask
+	parameters DynamoDBUpdateItemParameters
+}
+
+// MarshalJSON for custom marshalling, since this will be stringified and we need it
+// to turn into a stringified
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connect-ddb.html
+func (duis *DynamoDBUpdateItemState) MarshalJSON() ([]byte, error) {
+	return duis.BaseTask.marshalIntegrationParams(&duis.parameters)
+}
+
+// policyStatements returns the privileges needed to update the item
+func (duis *DynamoDBUpdateItemState) policyStatements() []spartaIAM.PolicyStatement {
+	return []spartaIAM.PolicyStatement{
+		dynamoDBTableStatement(duis.parameters.TableName, "dynamodb:UpdateItem"),
+	}
+}
+
+// NewDynamoDBUpdateItemState returns an initialized DynamoDB UpdateItem state
+func NewDynamoDBUpdateItemState(stateName string,
+	parameters DynamoDBUpdateItemParameters) *DynamoDBUpdateItemState {
+
+	duis := &DynamoDBUpdateItemState{
+		BaseTask: BaseTask{
+			baseInnerState: baseInnerState{
+				name: stateName,
+				id:   rand.Int63(),
+			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::dynamodb:updateItem",
+				defaultPattern:    RequestResponse,
+				supportedPatterns: []IntegrationPattern{RequestResponse},
+			},
+		},
+		parameters: parameters,
+	}
+	return duis
+}
+
+////////////////////////////////////////////////////////////////////////////////
+/*
+  ___      _     _         ___ _
+ |   \ ___| |___| |_ ___  |_ _| |_ ___ _ __
+ | |) / -_) / -_)  _/ -_)  | ||  _/ -_) '  \
+ |___/\___|_\___|\__\___| |___|\__\___|_|_|_|
+*/
+////////////////////////////////////////////////////////////////////////////////
+
+// DynamoDBDeleteItemParameters represents params for the DynamoDB DeleteItem
+// integration. Key and attribute values use the DynamoDB JSON format
+// (eg: {"S": "value"}). Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connect-ddb.html
+type DynamoDBDeleteItemParameters struct {
+	Key                         map[string]interface{} `json:",omitempty"`
+	TableName                   string                 `json:",omitempty"`
+	ConditionExpression         string                 `json:",omitempty"`
+	ExpressionAttributeNames    map[string]string      `json:",omitempty"`
+	ExpressionAttributeValues   map[string]interface{} `json:",omitempty"`
+	ReturnConsumedCapacity      string                 `json:",omitempty"` // INDEXES | TOTAL | NONE
+	ReturnItemCollectionMetrics string                 `json:",omitempty"` // SIZE | NONE
+	ReturnValues                string                 `json:",omitempty"` // NONE | ALL_OLD
+}
+
+// DynamoDBDeleteItemState represents bindings for
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-ddb.html
+type DynamoDBDeleteItemState struct {
+	BaseTask
+	parameters DynamoDBDeleteItemParameters
+}
+
+// MarshalJSON for custom marshalling, since this will be stringified and we need it
+// to turn into a stringified
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connect-ddb.html
+func (ddis *DynamoDBDeleteItemState) MarshalJSON() ([]byte, error) {
+	return ddis.BaseTask.marshalIntegrationParams(&ddis.parameters)
+}
+
+// policyStatements returns the privileges needed to delete the item
+func (ddis *DynamoDBDeleteItemState) policyStatements() []spartaIAM.PolicyStatement {
+	return []spartaIAM.PolicyStatement{
+		dynamoDBTableStatement(ddis.parameters.TableName, "dynamodb:DeleteItem"),
+	}
+}
+
+// NewDynamoDBDeleteItemState returns an initialized DynamoDB DeleteItem state
+func NewDynamoDBDeleteItemState(stateName string,
+	parameters DynamoDBDeleteItemParameters) *DynamoDBDeleteItemState {
+
+	ddis := &DynamoDBDeleteItemState{
+		BaseTask: BaseTask{
+			baseInnerState: baseInnerState{
+				name: stateName,
+				id:   rand.Int63(),
+			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::dynamodb:deleteItem",
+				defaultPattern:    RequestResponse,
+				supportedPatterns: []IntegrationPattern{RequestResponse},
+			},
+		},
+		parameters: parameters,
+	}
+	return ddis
+}
+
+////////////////////////////////////////////////////////////////////////////////
+/*
+   ___
+  / _ \ _  _ ___ _ _ _  _
+ | (_) | || / -_) '_| || |
+  \__\_\\_,_\___|_|  \_, |
+                     |__/
+*/
+////////////////////////////////////////////////////////////////////////////////
+
+// DynamoDBQueryParameters represents params for the DynamoDB Query
+// AWS SDK integration. Attribute values use the DynamoDB JSON format
+// (eg: {"S": "value"}). Ref: https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_Query.html
+type DynamoDBQueryParameters struct {
+	TableName                 string                 `json:",omitempty"`
+	IndexName                 string                 `json:",omitempty"`
+	KeyConditionExpression    string                 `json:",omitempty"`
+	FilterExpression          string                 `json:",omitempty"`
+	ProjectionExpression      string                 `json:",omitempty"`
+	ExpressionAttributeNames  map[string]string      `json:",omitempty"`
+	ExpressionAttributeValues map[string]interface{} `json:",omitempty"`
+	ExclusiveStartKey         map[string]interface{} `json:",omitempty"`
+	ConsistentRead            bool                   `json:",omitempty"`
+	Limit                     int                    `json:",omitempty"`
+	ScanIndexForward          *bool                  `json:",omitempty"`
+	Select                    string                 `json:",omitempty"`
+	ReturnConsumedCapacity    string                 `json:",omitempty"` // INDEXES | TOTAL | NONE
+}
+
+// DynamoDBQueryState represents bindings for the DynamoDB Query
+// AWS SDK service integration. There is no optimized Query integration.
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/supported-services-awssdk.html
+type DynamoDBQueryState struct {
+	BaseTask
+	parameters DynamoDBQueryParameters
+}
+
+// MarshalJSON for custom marshalling, since this will be stringified and we need it
+// to turn into a stringified
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/supported-services-awssdk.html
+func (dqs *DynamoDBQueryState) MarshalJSON() ([]byte, error) {
+	return dqs.BaseTask.marshalIntegrationParams(&dqs.parameters)
+}
+
+// policyStatements returns the privileges needed to query the table and
+// any of its indexes
+func (dqs *DynamoDBQueryState) policyStatements() []spartaIAM.PolicyStatement {
+	tableStatement := dynamoDBTableStatement(dqs.parameters.TableName, "dynamodb:Query")
+	if dqs.parameters.IndexName != "" && dqs.parameters.TableName != "" {
+		tableStatement.Resource = regionalArn("dynamodb",
+			"table/",
+			dqs.parameters.TableName,
+			"/index/",
+			dqs.parameters.IndexName)
+	}
+	return []spartaIAM.PolicyStatement{tableStatement}
+}
+
+// NewDynamoDBQueryState returns an initialized DynamoDB Query state
+func NewDynamoDBQueryState(stateName string,
+	parameters DynamoDBQueryParameters) *DynamoDBQueryState {
+
+	dqs := &DynamoDBQueryState{
+		BaseTask: BaseTask{
+			baseInnerState: baseInnerState{
+				name: stateName,
+				id:   rand.Int63(),
+			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::aws-sdk:dynamodb:query",
+				defaultPattern:    RequestResponse,
+				supportedPatterns: []IntegrationPattern{RequestResponse},
+			},
+		},
+		parameters: parameters,
+	}
+	return dqs
+}
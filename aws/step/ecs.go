@@ -0,0 +1,127 @@
+package step
+
+import (
+	"encoding/base64"
+	"math/rand"
+	"regexp"
+	"strings"
+
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
+)
+
+// reECSTaskDefinitionFamily matches a task definition family name with an
+// optional revision (eg: myTask, myTask:3)
+var reECSTaskDefinitionFamily = regexp.MustCompile(`^[A-Za-z0-9_-]{1,255}(:[0-9]+)?$`)
+
+// ECSTaskParameters contains the information for an ECS task that
+// runs on EC2 container instances
+// Ref: https://docs.aws.amazon.com/AmazonECS/latest/APIReference/API_RunTask.html
+type ECSTaskParameters struct {
+	Cluster                  string                       `json:",omitempty"`
+	CapacityProviderStrategy []map[string]interface{}     `json:",omitempty"`
+	Count                    int                          `json:",omitempty"`
+	EnableECSManagedTags     bool                         `json:",omitempty"`
+	Group                    string                       `json:",omitempty"`
+	LaunchType               string                       `json:",omitempty"`
+	NetworkConfiguration     *FargateNetworkConfiguration `json:",omitempty"`
+	Overrides                map[string]interface{}       `json:",omitempty"`
+	PlacementConstraints     []map[string]string          `json:",omitempty"`
+	PlacementStrategy        []map[string]string          `json:",omitempty"`
+	PropagateTags            string                       `json:",omitempty"`
+	TaskDefinition           string                       `json:",omitempty"`
+}
+
+// ECSTaskState represents an ECS RunTask state
+type ECSTaskState struct {
+	BaseTask
+	parameters ECSTaskParameters
+}
+
+// MarshalJSON for custom marshalling, since this will be stringified and we need it
+// to turn into a stringified Ref:
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-ecs.html
+func (ets *ECSTaskState) MarshalJSON() ([]byte, error) {
+	return ets.BaseTask.marshalIntegrationParams(&ets.parameters)
+}
+
+// policyStatements returns the privileges needed to run the task
+func (ets *ECSTaskState) policyStatements() []spartaIAM.PolicyStatement {
+	return ecsPolicyStatements(ets.resolvedIntegrationPattern(),
+		ets.parameters.TaskDefinition)
+}
+
+// ecsTaskDefinitionResource returns the IAM resource for the RunTask
+// TaskDefinition value. ARNs and goformation references are used as is.
+// Family names are expanded to the task definition ARN for any revision,
+// and family:revision values to the ARN for that revision. Other values
+// return the wildcard resource.
+func ecsTaskDefinitionResource(taskDefinition string) string {
+	if taskDefinition == "" {
+		return "*"
+	}
+	if strings.HasPrefix(taskDefinition, "arn:") {
+		return taskDefinition
+	}
+	decoded, decodedErr := base64.StdEncoding.DecodeString(taskDefinition)
+	if decodedErr == nil && strings.HasPrefix(string(decoded), "{") {
+		return taskDefinition
+	}
+	if !reECSTaskDefinitionFamily.MatchString(taskDefinition) {
+		return "*"
+	}
+	if !strings.Contains(taskDefinition, ":") {
+		taskDefinition += ":*"
+	}
+	return regionalArn("ecs", "task-definition/", taskDefinition)
+}
+
+// ecsPolicyStatements returns the privileges needed to run an ECS task
+// with the given integration pattern
+func ecsPolicyStatements(pattern IntegrationPattern,
+	taskDefinition string) []spartaIAM.PolicyStatement {
+	statements := []spartaIAM.PolicyStatement{
+		{
+			Effect:   "Allow",
+			Action:   []string{"ecs:RunTask"},
+			Resource: ecsTaskDefinitionResource(taskDefinition),
+		},
+		passRoleStatement("ecs-tasks.amazonaws.com"),
+	}
+	if pattern == Sync {
+		statements = append(statements,
+			spartaIAM.PolicyStatement{
+				Effect: "Allow",
+				Action: []string{
+					"ecs:StopTask",
+					"ecs:DescribeTasks",
+				},
+				Resource: "*",
+			},
+			syncEventRuleStatement("StepFunctionsGetEventsForECSTaskRule"))
+	}
+	return statements
+}
+
+// NewECSTaskState returns an initialized ECSTaskState. The LaunchType
+// defaults to EC2 if neither a LaunchType nor a CapacityProviderStrategy
+// is provided.
+func NewECSTaskState(stateName string, parameters ECSTaskParameters) *ECSTaskState {
+	if parameters.LaunchType == "" && len(parameters.CapacityProviderStrategy) == 0 {
+		parameters.LaunchType = "EC2"
+	}
+	ets := &ECSTaskState{
+		BaseTask: BaseTask{
+			baseInnerState: baseInnerState{
+				name: stateName,
+				id:   rand.Int63(),
+			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::ecs:runTask",
+				defaultPattern:    Sync,
+				supportedPatterns: []IntegrationPattern{RequestResponse, Sync, WaitForTaskToken},
+			},
+		},
+		parameters: parameters,
+	}
+	return ets
+}
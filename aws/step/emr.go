@@ -0,0 +1,86 @@
+package step
+
+import (
+	"math/rand"
+
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
+)
+
+// EMRHadoopJarStep is the JAR file and arguments for an EMR step
+// Ref: https://docs.aws.amazon.com/emr/latest/APIReference/API_HadoopJarStepConfig.html
+type EMRHadoopJarStep struct {
+	Jar        string              `json:",omitempty"`
+	Args       []string            `json:",omitempty"`
+	MainClass  string              `json:",omitempty"`
+	Properties []map[string]string `json:",omitempty"`
+}
+
+// EMRStep is the step to add to the cluster
+// Ref: https://docs.aws.amazon.com/emr/latest/APIReference/API_StepConfig.html
+type EMRStep struct {
+	Name            string            `json:",omitempty"`
+	ActionOnFailure string            `json:",omitempty"` // TERMINATE_CLUSTER | CANCEL_AND_WAIT | CONTINUE
+	HadoopJarStep   *EMRHadoopJarStep `json:",omitempty"`
+}
+
+// EMRAddStepParameters represents params for the EMR AddStep integration
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connect-emr.html
+type EMRAddStepParameters struct {
+	ClusterID string   `json:"ClusterId,omitempty"`
+	Step      *EMRStep `json:",omitempty"`
+}
+
+// EMRAddStepState represents bindings for
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-emr.html
+type EMRAddStepState struct {
+	BaseTask
+	parameters EMRAddStepParameters
+}
+
+// MarshalJSON for custom marshalling, since this will be stringified and we need it
+// to turn into a stringified
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connect-emr.html
+func (eas *EMRAddStepState) MarshalJSON() ([]byte, error) {
+	return eas.BaseTask.marshalIntegrationParams(&eas.parameters)
+}
+
+// policyStatements returns the privileges needed to add the step
+func (eas *EMRAddStepState) policyStatements() []spartaIAM.PolicyStatement {
+	clusterArn := "*"
+	if eas.parameters.ClusterID != "" {
+		clusterArn = regionalArn("elasticmapreduce", "cluster/", eas.parameters.ClusterID)
+	}
+	actions := []string{"elasticmapreduce:AddJobFlowSteps"}
+	if eas.resolvedIntegrationPattern() == Sync {
+		actions = append(actions,
+			"elasticmapreduce:DescribeStep",
+			"elasticmapreduce:CancelSteps")
+	}
+	return []spartaIAM.PolicyStatement{
+		{
+			Effect:   "Allow",
+			Action:   actions,
+			Resource: clusterArn,
+		},
+	}
+}
+
+// NewEMRAddStepState returns an initialized EMRAddStepState
+func NewEMRAddStepState(stateName string,
+	parameters EMRAddStepParameters) *EMRAddStepState {
+	eas := &EMRAddStepState{
+		BaseTask: BaseTask{
+			baseInnerState: baseInnerState{
+				name: stateName,
+				id:   rand.Int63(),
+			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::elasticmapreduce:addStep",
+				defaultPattern:    Sync,
+				supportedPatterns: []IntegrationPattern{RequestResponse, Sync},
+			},
+		},
+		parameters: parameters,
+	}
+	return eas
+}
@@ -0,0 +1,87 @@
+package step
+
+import (
+	"math/rand"
+	"strings"
+
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
+)
+
+// EventBridgePutEventsEntry represents a single event to publish
+// Ref: https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_PutEventsRequestEntry.html
+type EventBridgePutEventsEntry struct {
+	Detail       interface{} `json:",omitempty"`
+	DetailType   string      `json:",omitempty"`
+	EventBusName string      `json:",omitempty"`
+	Resources    []string    `json:",omitempty"`
+	Source       string      `json:",omitempty"`
+	TraceHeader  string      `json:",omitempty"`
+}
+
+// EventBridgePutEventsParameters represents params for the EventBridge
+// PutEvents integration.
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connect-eventbridge.html
+type EventBridgePutEventsParameters struct {
+	Entries []EventBridgePutEventsEntry `json:",omitempty"`
+}
+
+// EventBridgePutEventsState represents bindings for
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-eventbridge.html
+type EventBridgePutEventsState struct {
+	BaseTask
+	parameters EventBridgePutEventsParameters
+}
+
+// MarshalJSON for custom marshalling, since this will be stringified and we need it
+// to turn into a stringified
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connect-eventbridge.html
+func (ebs *EventBridgePutEventsState) MarshalJSON() ([]byte, error) {
+	return ebs.BaseTask.marshalIntegrationParams(&ebs.parameters)
+}
+
+// policyStatements returns the privileges needed to publish to each event bus
+func (ebs *EventBridgePutEventsState) policyStatements() []spartaIAM.PolicyStatement {
+	uniqueBuses := make(map[string]bool)
+	statements := make([]spartaIAM.PolicyStatement, 0)
+	for _, eachEntry := range ebs.parameters.Entries {
+		busName := eachEntry.EventBusName
+		if busName == "" {
+			busName = "default"
+		}
+		if uniqueBuses[busName] {
+			continue
+		}
+		uniqueBuses[busName] = true
+
+		busArn := busName
+		if !strings.HasPrefix(busName, "arn:") {
+			busArn = regionalArn("events", "event-bus/", busName)
+		}
+		statements = append(statements, spartaIAM.PolicyStatement{
+			Effect:   "Allow",
+			Action:   []string{"events:PutEvents"},
+			Resource: busArn,
+		})
+	}
+	return statements
+}
+
+// NewEventBridgePutEventsState returns an initialized EventBridgePutEventsState
+func NewEventBridgePutEventsState(stateName string,
+	parameters EventBridgePutEventsParameters) *EventBridgePutEventsState {
+	ebs := &EventBridgePutEventsState{
+		BaseTask: BaseTask{
+			baseInnerState: baseInnerState{
+				name: stateName,
+				id:   rand.Int63(),
+			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::events:putEvents",
+				defaultPattern:    RequestResponse,
+				supportedPatterns: []IntegrationPattern{RequestResponse, WaitForTaskToken},
+			},
+		},
+		parameters: parameters,
+	}
+	return ebs
+}
@@ -4,6 +4,7 @@ import (
 	"math/rand"
 
 	gofecs "github.com/awslabs/goformation/v5/cloudformation/ecs"
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
 )
 
 // FargateNetworkConfiguration contains the AWSVPCConfiguration
@@ -36,8 +37,13 @@ type FargateTaskState struct {
 // to turn into a stringified Ref:
 // https://docs.aws.amazon.com/step-functions/latest/dg/connectors-ecs.html
 func (fts *FargateTaskState) MarshalJSON() ([]byte, error) {
-	return fts.BaseTask.marshalMergedParams("arn:aws:states:::ecs:runTask.sync",
-		&fts.parameters)
+	return fts.BaseTask.marshalIntegrationParams(&fts.parameters)
+}
+
+// policyStatements returns the privileges needed to run the task
+func (fts *FargateTaskState) policyStatements() []spartaIAM.PolicyStatement {
+	return ecsPolicyStatements(fts.resolvedIntegrationPattern(),
+		fts.parameters.TaskDefinition)
 }
 
 // NewFargateTaskState returns an initialized FargateTaskState
@@ -48,6 +54,11 @@ func NewFargateTaskState(stateName string, parameters FargateTaskParameters) *Fa
 				name: stateName,
 				id:   rand.Int63(),
 			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::ecs:runTask",
+				defaultPattern:    Sync,
+				supportedPatterns: []IntegrationPattern{RequestResponse, Sync, WaitForTaskToken},
+			},
 		},
 		parameters: parameters,
 	}
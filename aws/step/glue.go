@@ -2,6 +2,8 @@ package step
 
 import (
 	"math/rand"
+
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
 )
 
 // GlueParameters represents params for Glue step
@@ -27,8 +29,25 @@ type GlueState struct {
 // to turn into a stringified
 // Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connectors-sns.html
 func (gs *GlueState) MarshalJSON() ([]byte, error) {
-	return gs.BaseTask.marshalMergedParams("arn:aws:states:::glue:startJobRun.sync",
-		&gs.parameters)
+	return gs.BaseTask.marshalIntegrationParams(&gs.parameters)
+}
+
+// policyStatements returns the privileges needed to start the job run
+func (gs *GlueState) policyStatements() []spartaIAM.PolicyStatement {
+	actions := []string{"glue:StartJobRun"}
+	if gs.resolvedIntegrationPattern() == Sync {
+		actions = append(actions,
+			"glue:GetJobRun",
+			"glue:GetJobRuns",
+			"glue:BatchStopJobRun")
+	}
+	return []spartaIAM.PolicyStatement{
+		{
+			Effect:   "Allow",
+			Action:   actions,
+			Resource: "*",
+		},
+	}
 }
 
 // NewGlueState returns an initialized GlueState
@@ -41,6 +60,11 @@ func NewGlueState(stateName string,
 				name: stateName,
 				id:   rand.Int63(),
 			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::glue:startJobRun",
+				defaultPattern:    Sync,
+				supportedPatterns: []IntegrationPattern{RequestResponse, Sync},
+			},
 		},
 		parameters: parameters,
 	}
@@ -0,0 +1,221 @@
+package step
+
+import (
+	"fmt"
+	"strings"
+
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
+)
+
+// IntegrationPattern is the service integration pattern used by an optimized
+// service Task state.
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connect-to-resource.html
+type IntegrationPattern string
+
+const (
+	// RequestResponse calls the service and progresses to the next state as
+	// soon as the service returns an HTTP response.
+	RequestResponse IntegrationPattern = "RequestResponse"
+	// Sync (.sync) calls the service and waits for the started job to complete
+	// before progressing to the next state.
+	Sync IntegrationPattern = "Sync"
+	// WaitForTaskToken (.waitForTaskToken) calls the service with a task token
+	// and pauses until the token is returned with SendTaskSuccess or
+	// SendTaskFailure.
+	WaitForTaskToken IntegrationPattern = "WaitForTaskToken"
+)
+
+// TaskTokenContextPath is the context object path that must be passed to the
+// integrated service for WaitForTaskToken integrations. Example:
+//
+//	"MessageAttributes": {
+//	  "TaskToken": {
+//	    "DataType": "String",
+//	    "StringValue.$": "$$.Task.Token"
+//	  }
+//	}
+const TaskTokenContextPath = "$$.Task.Token"
+
+// resourceSuffix returns the Resource URI suffix for the pattern
+func (ip IntegrationPattern) resourceSuffix() string {
+	switch ip {
+	case Sync:
+		return ".sync"
+	case WaitForTaskToken:
+		return ".waitForTaskToken"
+	default:
+		return ""
+	}
+}
+
+// integrationPatternFromResource infers the pattern from an explicit
+// Resource URI (eg: TaskState, AWSSDKState)
+func integrationPatternFromResource(resourceURI string) IntegrationPattern {
+	switch {
+	case strings.HasSuffix(resourceURI, WaitForTaskToken.resourceSuffix()):
+		return WaitForTaskToken
+	case strings.HasSuffix(resourceURI, Sync.resourceSuffix()):
+		return Sync
+	default:
+		return RequestResponse
+	}
+}
+
+// serviceIntegration describes the optimized service integration
+// supplied by a typed Task state
+type serviceIntegration struct {
+	// Resource URI without the pattern suffix. Eg: arn:aws:states:::sns:publish
+	resourceARN       string
+	defaultPattern    IntegrationPattern
+	supportedPatterns []IntegrationPattern
+}
+
+func (si *serviceIntegration) supports(pattern IntegrationPattern) bool {
+	for _, eachPattern := range si.supportedPatterns {
+		if eachPattern == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// containsTaskToken returns true if the params include a dynamic
+// reference to the $$.Task.Token context object value
+func containsTaskToken(params interface{}) bool {
+	switch typedParams := params.(type) {
+	case map[string]interface{}:
+		for eachKey, eachValue := range typedParams {
+			stringValue, stringValueOk := eachValue.(string)
+			if stringValueOk &&
+				strings.HasSuffix(eachKey, ".$") &&
+				strings.Contains(stringValue, TaskTokenContextPath) {
+				return true
+			}
+			if containsTaskToken(eachValue) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, eachValue := range typedParams {
+			if containsTaskToken(eachValue) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// integrationResource returns the Resource URI for the integration
+// using the resolved integration pattern
+func (bt *BaseTask) integrationResource() (string, error) {
+	if bt.integration == nil {
+		return "", fmt.Errorf("state %s does not support service integration patterns",
+			bt.name)
+	}
+	pattern := bt.integrationPattern
+	if pattern == "" {
+		pattern = bt.integration.defaultPattern
+	}
+	if !bt.integration.supports(pattern) {
+		supported := make([]string, len(bt.integration.supportedPatterns))
+		for index, eachPattern := range bt.integration.supportedPatterns {
+			supported[index] = string(eachPattern)
+		}
+		return "", fmt.Errorf("state %s (%s) does not support the %s integration pattern. Supported patterns: %s",
+			bt.name,
+			bt.integration.resourceARN,
+			pattern,
+			strings.Join(supported, ", "))
+	}
+	return bt.integration.resourceARN + pattern.resourceSuffix(), nil
+}
+
+// marshalIntegrationParams marshals the typed service integration Task
+// using the configured integration pattern
+func (bt *BaseTask) marshalIntegrationParams(taskParams interface{}) ([]byte, error) {
+	resourceURI, resourceURIErr := bt.integrationResource()
+	if resourceURIErr != nil {
+		return nil, resourceURIErr
+	}
+	return bt.marshalMergedParams(resourceURI, taskParams)
+}
+
+// resolvedIntegrationPattern returns the integration pattern that will be
+// used when the state is serialized
+func (bt *BaseTask) resolvedIntegrationPattern() IntegrationPattern {
+	if bt.integrationPattern != "" {
+		return bt.integrationPattern
+	}
+	if bt.integration != nil {
+		return bt.integration.defaultPattern
+	}
+	return RequestResponse
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// IAM
+////////////////////////////////////////////////////////////////////////////////
+
+// policyStatementProvider is implemented by states that require
+// additional privileges for the state machine execution role
+type policyStatementProvider interface {
+	policyStatements() []spartaIAM.PolicyStatement
+}
+
+// regionalArn returns the Fn::Join expression for an account scoped
+// resource ARN in the current partition and region
+func regionalArn(service string, resourcePath ...string) string {
+	arnParts := []string{
+		"arn:",
+		gof.Ref("AWS::Partition"),
+		fmt.Sprintf(":%s:", service),
+		gof.Ref("AWS::Region"),
+		":",
+		gof.Ref("AWS::AccountId"),
+		":",
+	}
+	arnParts = append(arnParts, resourcePath...)
+	return gof.Join("", arnParts)
+}
+
+// syncEventRuleStatement returns the statement that allows Step Functions
+// to manage the EventBridge rule it uses to track .sync job completion
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/service-integration-iam-templates.html
+func syncEventRuleStatement(ruleName string) spartaIAM.PolicyStatement {
+	return spartaIAM.PolicyStatement{
+		Effect: "Allow",
+		Action: []string{
+			"events:PutTargets",
+			"events:PutRule",
+			"events:DescribeRule",
+		},
+		Resource: regionalArn("events", "rule/", ruleName),
+	}
+}
+
+// passRoleStatement returns the statement that allows the state machine to
+// pass a role to the given service principal
+func passRoleStatement(servicePrincipal string) spartaIAM.PolicyStatement {
+	return spartaIAM.PolicyStatement{
+		Effect:   "Allow",
+		Action:   []string{"iam:PassRole"},
+		Resource: "*",
+		Condition: map[string]interface{}{
+			"StringEquals": map[string]interface{}{
+				"iam:PassedToService": servicePrincipal,
+			},
+		},
+	}
+}
+
+// resourceOrWildcard returns the first non-empty resource value, or the
+// wildcard resource if none are set
+func resourceOrWildcard(resources ...string) string {
+	for _, eachResource := range resources {
+		if eachResource != "" {
+			return eachResource
+		}
+	}
+	return "*"
+}
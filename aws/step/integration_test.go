@@ -0,0 +1,73 @@
+package step
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	spartaCF "github.com/mweagle/Sparta/v3/aws/cloudformation"
+)
+
+func TestIntegrationPatternResource(t *testing.T) {
+	sqsState := NewSQSTaskState("sendMessage", SQSTaskParameters{
+		QueueURL:    "https://sqs.us-west-2.amazonaws.com/123412341234/MyQueue",
+		MessageBody: "Hello",
+		MessageAttributes: map[string]interface{}{
+			"TaskToken": map[string]interface{}{
+				"DataType":      "String",
+				"StringValue.$": TaskTokenContextPath,
+			},
+		},
+	})
+	sqsState.WithIntegrationPattern(WaitForTaskToken)
+	jsonBytes, jsonBytesErr := json.Marshal(sqsState)
+	if jsonBytesErr != nil {
+		t.Fatalf("Failed to marshal callback state: %s", jsonBytesErr)
+	}
+	if !strings.Contains(string(jsonBytes), "arn:aws:states:::sqs:sendMessage.waitForTaskToken") {
+		t.Fatalf("Failed to include integration pattern in Resource: %s", string(jsonBytes))
+	}
+	statements := sqsState.policyStatements()
+	if len(statements) != 1 ||
+		statements[0].Resource != "arn:aws:sqs:us-west-2:123412341234:MyQueue" {
+		t.Fatalf("Failed to scope SQS privilege to queue: %#v", statements)
+	}
+}
+
+func TestIntegrationPatternMissingToken(t *testing.T) {
+	snsState := NewSNSTaskState("publish", SNSTaskParameters{
+		TopicArn: "arn:aws:sns:us-west-2:123412341234:MyTopic",
+		Message:  "Hello",
+	})
+	snsState.WithIntegrationPattern(WaitForTaskToken)
+	_, jsonBytesErr := json.Marshal(snsState)
+	if jsonBytesErr == nil {
+		t.Fatalf("Failed to reject callback state without a task token")
+	}
+}
+
+func TestIntegrationPatternUnsupported(t *testing.T) {
+	ddbState := NewDynamoDBGetItemState("getItem", DynamoDBGetItemParameters{
+		TableName: "MyTable",
+	})
+	ddbState.WithIntegrationPattern(Sync)
+	_, jsonBytesErr := json.Marshal(ddbState)
+	if jsonBytesErr == nil {
+		t.Fatalf("Failed to reject unsupported .sync DynamoDB integration")
+	}
+}
+
+func TestIntegrationPatternRequestResponse(t *testing.T) {
+	glueState := NewGlueState("startJob", GlueParameters{
+		JobName: "MyJob",
+	})
+	glueState.WithIntegrationPattern(RequestResponse)
+	successState := NewSuccessState("success")
+	glueState.Next(successState)
+
+	stateMachineName := spartaCF.UserScopedStackName("TestIntegrationPatternMachine")
+	startMachine := NewStateMachine(stateMachineName, glueState)
+	testStepProvision(t,
+		nil,
+		startMachine)
+}
@@ -2,8 +2,30 @@ package step
 
 import (
 	"math/rand"
+
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
 )
 
+// sageMakerPolicyStatements returns the privileges needed to create
+// the SageMaker job type
+func sageMakerPolicyStatements(pattern IntegrationPattern,
+	jobType string,
+	jobActions []string,
+	syncEventRuleName string) []spartaIAM.PolicyStatement {
+	statements := []spartaIAM.PolicyStatement{
+		{
+			Effect:   "Allow",
+			Action:   append(jobActions, "sagemaker:AddTags"),
+			Resource: regionalArn("sagemaker", jobType, "/*"),
+		},
+		passRoleStatement("sagemaker.amazonaws.com"),
+	}
+	if pattern == Sync {
+		statements = append(statements, syncEventRuleStatement(syncEventRuleName))
+	}
+	return statements
+}
+
 // SageMakerTag represents a tag for a SageMaker task
 type SageMakerTag struct {
 	Key   string `json:",omitempty"`
@@ -46,8 +68,19 @@ type SageMakerTrainingJob struct {
 // to turn into a stringified
 // Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connectors-sqs.html
 func (smtj *SageMakerTrainingJob) MarshalJSON() ([]byte, error) {
-	return smtj.BaseTask.marshalMergedParams("arn:aws:states:::sagemaker:createTrainingJob.sync",
-		&smtj.parameters)
+	return smtj.BaseTask.marshalIntegrationParams(&smtj.parameters)
+}
+
+// policyStatements returns the privileges needed to create the training job
+func (smtj *SageMakerTrainingJob) policyStatements() []spartaIAM.PolicyStatement {
+	return sageMakerPolicyStatements(smtj.resolvedIntegrationPattern(),
+		"training-job",
+		[]string{
+			"sagemaker:CreateTrainingJob",
+			"sagemaker:DescribeTrainingJob",
+			"sagemaker:StopTrainingJob",
+		},
+		"StepFunctionsGetEventsForSageMakerTrainingJobsRule")
 }
 
 // NewSageMakerTrainingJob returns an initialized SQSTaskState
@@ -59,6 +92,11 @@ func NewSageMakerTrainingJob(stateName string,
 				name: stateName,
 				id:   rand.Int63(),
 			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::sagemaker:createTrainingJob",
+				defaultPattern:    Sync,
+				supportedPatterns: []IntegrationPattern{RequestResponse, Sync},
+			},
 		},
 		parameters: parameters,
 	}
@@ -100,8 +138,19 @@ type SageMakerTransformJob struct {
 // to turn into a stringified
 // Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connectors-sqs.html
 func (smtj *SageMakerTransformJob) MarshalJSON() ([]byte, error) {
-	return smtj.BaseTask.marshalMergedParams("arn:aws:states:::sagemaker:createTransformJob.sync",
-		&smtj.parameters)
+	return smtj.BaseTask.marshalIntegrationParams(&smtj.parameters)
+}
+
+// policyStatements returns the privileges needed to create the transform job
+func (smtj *SageMakerTransformJob) policyStatements() []spartaIAM.PolicyStatement {
+	return sageMakerPolicyStatements(smtj.resolvedIntegrationPattern(),
+		"transform-job",
+		[]string{
+			"sagemaker:CreateTransformJob",
+			"sagemaker:DescribeTransformJob",
+			"sagemaker:StopTransformJob",
+		},
+		"StepFunctionsGetEventsForSageMakerTransformJobsRule")
 }
 
 // NewSageMakerTransformJob returns an initialized SQSTaskState
@@ -113,6 +162,11 @@ func NewSageMakerTransformJob(stateName string,
 				name: stateName,
 				id:   rand.Int63(),
 			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::sagemaker:createTransformJob",
+				defaultPattern:    Sync,
+				supportedPatterns: []IntegrationPattern{RequestResponse, Sync},
+			},
 		},
 		parameters: parameters,
 	}
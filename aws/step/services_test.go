@@ -1,8 +1,12 @@
 package step
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 
+	gof "github.com/awslabs/goformation/v5/cloudformation"
 	gofecs "github.com/awslabs/goformation/v5/cloudformation/ecs"
 
 	spartaCF "github.com/mweagle/Sparta/v3/aws/cloudformation"
@@ -49,3 +53,160 @@ func TestFargateSNSServices(t *testing.T) {
 		nil,
 		startMachine)
 }
+
+func TestAdditionalServices(t *testing.T) {
+	putEventsState := NewEventBridgePutEventsState("Publish Event",
+		EventBridgePutEventsParameters{
+			Entries: []EventBridgePutEventsEntry{
+				{
+					Detail: map[string]interface{}{
+						"Message": "Order received",
+					},
+					DetailType: "OrderReceived",
+					Source:     "com.sparta.orders",
+				},
+			},
+		})
+	updateItemState := NewDynamoDBUpdateItemState("Update Order",
+		DynamoDBUpdateItemParameters{
+			TableName: "Orders",
+			Key: map[string]interface{}{
+				"OrderID": map[string]interface{}{
+					"S.$": "$.orderID",
+				},
+			},
+			UpdateExpression: "SET OrderStatus = :status",
+			ExpressionAttributeValues: map[string]interface{}{
+				":status": map[string]interface{}{
+					"S": "RECEIVED",
+				},
+			},
+		})
+	queryState := NewDynamoDBQueryState("Query Orders",
+		DynamoDBQueryParameters{
+			TableName:              "Orders",
+			IndexName:              "ByCustomer",
+			KeyConditionExpression: "CustomerID = :customer",
+			ExpressionAttributeValues: map[string]interface{}{
+				":customer": map[string]interface{}{
+					"S.$": "$.customerID",
+				},
+			},
+		})
+	athenaState := NewAthenaStartQueryExecutionState("Run Report",
+		AthenaStartQueryExecutionParameters{
+			QueryString: "SELECT * FROM orders",
+			ResultConfiguration: &AthenaResultConfiguration{
+				OutputLocation: "s3://sparta-reports/orders/",
+			},
+		})
+	codeBuildState := NewCodeBuildStartBuildState("Build Report",
+		CodeBuildStartBuildParameters{
+			ProjectName: "OrderReport",
+		})
+	emrState := NewEMRAddStepState("Process Report",
+		EMRAddStepParameters{
+			ClusterID: "j-1234T",
+			Step: &EMRStep{
+				Name:            "Process",
+				ActionOnFailure: "CONTINUE",
+				HadoopJarStep: &EMRHadoopJarStep{
+					Jar:  "command-runner.jar",
+					Args: []string{"spark-submit", "s3://sparta-reports/process.py"},
+				},
+			},
+		})
+	ecsState := NewECSTaskState("Archive Report",
+		ECSTaskParameters{
+			Cluster:        "arn:aws:ecs:us-west-2:123123123123:cluster/Reports",
+			TaskDefinition: "arn:aws:ecs:us-west-2:123123123123:task-definition/Archive:1",
+		})
+	nestedState := NewStartExecutionState("Notify",
+		StartExecutionParameters{
+			StateMachineArn: "arn:aws:states:us-west-2:123123123123:stateMachine:Notify",
+			Input: map[string]interface{}{
+				"AWS_STEP_FUNCTIONS_STARTED_BY_EXECUTION_ID.$": "$$.Execution.Id",
+			},
+		})
+	nestedState.WithIntegrationPattern(Sync)
+	deleteItemState := NewDynamoDBDeleteItemState("Delete Order",
+		DynamoDBDeleteItemParameters{
+			TableName: "Orders",
+			Key: map[string]interface{}{
+				"OrderID": map[string]interface{}{
+					"S.$": "$.orderID",
+				},
+			},
+		})
+
+	putEventsState.Next(updateItemState)
+	updateItemState.Next(queryState)
+	queryState.Next(athenaState)
+	athenaState.Next(codeBuildState)
+	codeBuildState.Next(emrState)
+	emrState.Next(ecsState)
+	ecsState.Next(nestedState)
+	nestedState.Next(deleteItemState)
+
+	if ecsState.parameters.LaunchType != "EC2" {
+		t.Fatalf("Failed to default ECS LaunchType to EC2")
+	}
+	expectedResources := map[TransitionState]string{
+		putEventsState:  "arn:aws:states:::events:putEvents",
+		updateItemState: "arn:aws:states:::dynamodb:updateItem",
+		queryState:      "arn:aws:states:::aws-sdk:dynamodb:query",
+		athenaState:     "arn:aws:states:::athena:startQueryExecution.sync",
+		codeBuildState:  "arn:aws:states:::codebuild:startBuild.sync",
+		emrState:        "arn:aws:states:::elasticmapreduce:addStep.sync",
+		ecsState:        "arn:aws:states:::ecs:runTask.sync",
+		nestedState:     "arn:aws:states:::states:startExecution.sync",
+		deleteItemState: "arn:aws:states:::dynamodb:deleteItem",
+	}
+	for eachState, eachResource := range expectedResources {
+		jsonBytes, jsonBytesErr := json.Marshal(eachState)
+		if jsonBytesErr != nil {
+			t.Fatalf("Failed to marshal %s: %s", eachState.Name(), jsonBytesErr)
+		}
+		if !strings.Contains(string(jsonBytes), fmt.Sprintf(`"Resource":"%s"`, eachResource)) {
+			t.Fatalf("Failed to find Resource %s for %s: %s",
+				eachResource,
+				eachState.Name(),
+				string(jsonBytes))
+		}
+		provider, providerOk := eachState.(policyStatementProvider)
+		if !providerOk || len(provider.policyStatements()) == 0 {
+			t.Fatalf("Failed to provide IAM statements for %s", eachState.Name())
+		}
+	}
+	stateMachineName := spartaCF.UserScopedStackName("TestAdditionalServicesMachine")
+	startMachine := NewStateMachine(stateMachineName, putEventsState)
+	testStepProvision(t,
+		nil,
+		startMachine)
+}
+
+func TestECSTaskDefinitionResource(t *testing.T) {
+	taskDefinitionARN := "arn:aws:ecs:us-west-2:123123123123:task-definition/myTask:3"
+	testCases := map[string]string{
+		"":                "*",
+		taskDefinitionARN: taskDefinitionARN,
+		gof.Ref("Task"):   gof.Ref("Task"),
+		"myTask":          regionalArn("ecs", "task-definition/", "myTask:*"),
+		"myTask:3":        regionalArn("ecs", "task-definition/", "myTask:3"),
+		"not a family!":   "*",
+	}
+	for eachTaskDefinition, eachExpected := range testCases {
+		resource := ecsTaskDefinitionResource(eachTaskDefinition)
+		if resource != eachExpected {
+			t.Fatalf("Unexpected resource for TaskDefinition `%s`: %s",
+				eachTaskDefinition,
+				resource)
+		}
+	}
+	ecsState := NewECSTaskState("Run ECS Task", ECSTaskParameters{
+		TaskDefinition: "myTask",
+	})
+	if ecsState.policyStatements()[0].Resource == "myTask" {
+		t.Fatalf("Expected task definition ARN resource")
+	}
+}
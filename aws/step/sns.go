@@ -2,6 +2,8 @@ package step
 
 import (
 	"math/rand"
+
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
 )
 
 // SNSTaskParameters represents params for the SNS notification
@@ -27,8 +29,18 @@ type SNSTaskState struct {
 // to turn into a stringified
 // Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connectors-sns.html
 func (sts *SNSTaskState) MarshalJSON() ([]byte, error) {
-	return sts.BaseTask.marshalMergedParams("arn:aws:states:::sns:publish",
-		&sts.parameters)
+	return sts.BaseTask.marshalIntegrationParams(&sts.parameters)
+}
+
+// policyStatements returns the privileges needed to publish the message
+func (sts *SNSTaskState) policyStatements() []spartaIAM.PolicyStatement {
+	return []spartaIAM.PolicyStatement{
+		{
+			Effect:   "Allow",
+			Action:   []string{"sns:Publish"},
+			Resource: resourceOrWildcard(sts.parameters.TopicArn, sts.parameters.TargetArn),
+		},
+	}
 }
 
 // NewSNSTaskState returns an initialized SNSTaskState
@@ -41,6 +53,11 @@ func NewSNSTaskState(stateName string,
 				name: stateName,
 				id:   rand.Int63(),
 			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::sns:publish",
+				defaultPattern:    RequestResponse,
+				supportedPatterns: []IntegrationPattern{RequestResponse, WaitForTaskToken},
+			},
 		},
 		parameters: parameters,
 	}
@@ -1,7 +1,12 @@
 package step
 
 import (
+	"fmt"
 	"math/rand"
+	"net/url"
+	"strings"
+
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
 )
 
 // SQSTaskParameters represents params for the SQS notification
@@ -26,8 +31,42 @@ type SQSTaskState struct {
 // to turn into a stringified
 // Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connectors-sqs.html
 func (sqs *SQSTaskState) MarshalJSON() ([]byte, error) {
-	return sqs.BaseTask.marshalMergedParams("arn:aws:states:::sqs:sendMessage",
-		&sqs.parameters)
+	return sqs.BaseTask.marshalIntegrationParams(&sqs.parameters)
+}
+
+// policyStatements returns the privileges needed to send the message
+func (sqs *SQSTaskState) policyStatements() []spartaIAM.PolicyStatement {
+	return []spartaIAM.PolicyStatement{
+		{
+			Effect:   "Allow",
+			Action:   []string{"sqs:SendMessage"},
+			Resource: resourceOrWildcard(sqsQueueArn(sqs.parameters.QueueURL)),
+		},
+	}
+}
+
+// sqsQueueArn returns the queue ARN for a literal queue URL of the form
+// https://sqs.us-east-1.amazonaws.com/123412341234/MyQueue. It returns
+// an empty string if the URL can't be parsed.
+func sqsQueueArn(queueURL string) string {
+	parsedURL, parsedURLErr := url.Parse(queueURL)
+	if parsedURLErr != nil {
+		return ""
+	}
+	hostParts := strings.Split(parsedURL.Host, ".")
+	pathParts := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	if len(hostParts) < 3 || hostParts[0] != "sqs" || len(pathParts) != 2 {
+		return ""
+	}
+	partition := "aws"
+	if strings.HasSuffix(parsedURL.Host, ".amazonaws.com.cn") {
+		partition = "aws-cn"
+	}
+	return fmt.Sprintf("arn:%s:sqs:%s:%s:%s",
+		partition,
+		hostParts[1],
+		pathParts[0],
+		pathParts[1])
 }
 
 // NewSQSTaskState returns an initialized SQSTaskState
@@ -39,6 +78,11 @@ func NewSQSTaskState(stateName string,
 				name: stateName,
 				id:   rand.Int63(),
 			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::sqs:sendMessage",
+				defaultPattern:    RequestResponse,
+				supportedPatterns: []IntegrationPattern{RequestResponse, WaitForTaskToken},
+			},
 		},
 		parameters: parameters,
 	}
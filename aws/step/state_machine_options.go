@@ -0,0 +1,261 @@
+package step
+
+import (
+	"encoding/json"
+	"fmt"
+
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	goflogs "github.com/awslabs/goformation/v5/cloudformation/logs"
+	"github.com/awslabs/goformation/v5/cloudformation/policies"
+	gofstep "github.com/awslabs/goformation/v5/cloudformation/stepfunctions"
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
+)
+
+// LogLevel is the execution history level sent to CloudWatch Logs
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/cw-logs.html#cloudwatch-log-level
+type LogLevel string
+
+const (
+	// LogLevelAll logs all execution history events
+	LogLevelAll LogLevel = "ALL"
+	// LogLevelError logs only ERROR events
+	LogLevelError LogLevel = "ERROR"
+	// LogLevelFatal logs only FATAL events
+	LogLevelFatal LogLevel = "FATAL"
+	// LogLevelOff disables logging
+	LogLevelOff LogLevel = "OFF"
+)
+
+// Alias deployment types
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/concepts-cfn-deployment.html
+const (
+	// AliasDeploymentAllAtOnce shifts all traffic to the new version
+	AliasDeploymentAllAtOnce = "ALL_AT_ONCE"
+	// AliasDeploymentCanary shifts Percentage traffic to the new version
+	// and then shifts the remainder after Interval minutes
+	AliasDeploymentCanary = "CANARY"
+	// AliasDeploymentLinear shifts Percentage traffic to the new version
+	// every Interval minutes
+	AliasDeploymentLinear = "LINEAR"
+)
+
+// AliasDeploymentPreference describes how traffic is shifted from the
+// previous version to the version published by the current build
+type AliasDeploymentPreference struct {
+	// Type is one of AliasDeploymentAllAtOnce, AliasDeploymentCanary or
+	// AliasDeploymentLinear
+	Type string `json:",omitempty"`
+	// Percentage of traffic to shift per increment
+	Percentage int `json:",omitempty"`
+	// Interval in minutes between traffic increments
+	Interval int `json:",omitempty"`
+	// Alarms are CloudWatch alarm names that roll back the deployment
+	// when they enter the ALARM state
+	Alarms []string `json:",omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// StateMachine builders
+////////////////////////////////////////////////////////////////////////////////
+
+// WithLogging enables CloudWatch Logs logging at the given level. Sparta
+// provisions a log group with the optional retentionInDays value (0 to never
+// expire) and adds the log delivery privileges to the state machine role.
+// User supplied roles (WithRoleArn) must include the privileges. WithLogging
+// replaces any NewExpressStateMachine loggingConfiguration.
+func (sm *StateMachine) WithLogging(level LogLevel,
+	includeExecutionData bool,
+	retentionInDays int) *StateMachine {
+	sm.logLevel = level
+	sm.logIncludeExecutionData = includeExecutionData
+	sm.logRetentionInDays = retentionInDays
+	return sm
+}
+
+// WithTracing enables AWS X-Ray tracing for the state machine and adds
+// the X-Ray privileges to the state machine role
+func (sm *StateMachine) WithTracing(enabled bool) *StateMachine {
+	sm.tracingEnabled = enabled
+	return sm
+}
+
+// WithVersioning publishes a new AWS::StepFunctions::StateMachineVersion
+// for every build. Previous versions are retained when the version is
+// replaced.
+func (sm *StateMachine) WithVersioning() *StateMachine {
+	sm.versioningEnabled = true
+	return sm
+}
+
+// WithAlias routes the named AWS::StepFunctions::StateMachineAlias to the
+// version published by the current build. A nil deploymentPreference
+// shifts all traffic at once. Aliases enable versioning.
+func (sm *StateMachine) WithAlias(aliasName string,
+	deploymentPreference *AliasDeploymentPreference) *StateMachine {
+	sm.versioningEnabled = true
+	sm.aliasName = aliasName
+	sm.aliasDeploymentPreference = deploymentPreference
+	return sm
+}
+
+// managedPolicyStatements returns the privileges required by the
+// logging and tracing options
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/cw-logs.html
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/xray-iam.html
+func (sm *StateMachine) managedPolicyStatements() []spartaIAM.PolicyStatement {
+	statements := make([]spartaIAM.PolicyStatement, 0)
+	if sm.logLevel != "" && sm.logLevel != LogLevelOff {
+		statements = append(statements, spartaIAM.PolicyStatement{
+			Effect: "Allow",
+			Action: []string{
+				"logs:CreateLogDelivery",
+				"logs:GetLogDelivery",
+				"logs:UpdateLogDelivery",
+				"logs:DeleteLogDelivery",
+				"logs:ListLogDeliveries",
+				"logs:PutResourcePolicy",
+				"logs:DescribeResourcePolicies",
+				"logs:DescribeLogGroups",
+			},
+			Resource: "*",
+		})
+	}
+	if sm.tracingEnabled {
+		statements = append(statements, spartaIAM.PolicyStatement{
+			Effect: "Allow",
+			Action: []string{
+				"xray:PutTraceSegments",
+				"xray:PutTelemetryRecords",
+				"xray:GetSamplingRules",
+				"xray:GetSamplingTargets",
+			},
+			Resource: "*",
+		})
+	}
+	return statements
+}
+
+// decorateManagedResources updates the state machine resource with the
+// logging and tracing configuration and adds the log group, version
+// and alias resources to the template
+func (sm *StateMachine) decorateManagedResources(stepFunctionResourceName string,
+	stepFunctionResource *gofstep.StateMachine,
+	buildID string,
+	template *gof.Template) {
+
+	if sm.logLevel != "" {
+		loggingConfiguration := &gofstep.StateMachine_LoggingConfiguration{
+			Level:                string(sm.logLevel),
+			IncludeExecutionData: sm.logIncludeExecutionData,
+		}
+		if sm.logLevel != LogLevelOff {
+			logGroupResourceName := stepFunctionResourceName + "LogGroup"
+			logGroupResource := &goflogs.LogGroup{}
+			if sm.logRetentionInDays != 0 {
+				logGroupResource.RetentionInDays = sm.logRetentionInDays
+			}
+			template.Resources[logGroupResourceName] = logGroupResource
+			loggingConfiguration.Destinations = []gofstep.StateMachine_LogDestination{
+				{
+					CloudWatchLogsLogGroup: &gofstep.StateMachine_CloudWatchLogsLogGroup{
+						LogGroupArn: gof.GetAtt(logGroupResourceName, "Arn"),
+					},
+				},
+			}
+		}
+		stepFunctionResource.LoggingConfiguration = loggingConfiguration
+	}
+	if sm.tracingEnabled {
+		stepFunctionResource.TracingConfiguration = &gofstep.StateMachine_TracingConfiguration{
+			Enabled: true,
+		}
+	}
+	if !sm.versioningEnabled {
+		return
+	}
+	// The revision ID changes with each definition update. The version
+	// is replaced (and the previous one retained) whenever it changes.
+	versionResourceName := stepFunctionResourceName + "Version"
+	template.Resources[versionResourceName] = &stateMachineVersion{
+		StateMachineArn:        gof.Ref(stepFunctionResourceName),
+		StateMachineRevisionID: gof.GetAtt(stepFunctionResourceName, "StateMachineRevisionId"),
+		Description:            fmt.Sprintf("Sparta build: %s", buildID),
+	}
+	if sm.aliasName == "" {
+		return
+	}
+	deploymentPreference := sm.aliasDeploymentPreference
+	if deploymentPreference == nil {
+		deploymentPreference = &AliasDeploymentPreference{
+			Type: AliasDeploymentAllAtOnce,
+		}
+	}
+	template.Resources[stepFunctionResourceName+"Alias"] = &stateMachineAlias{
+		Name: sm.aliasName,
+		DeploymentPreference: &stateMachineAliasDeploymentPreference{
+			AliasDeploymentPreference: *deploymentPreference,
+			StateMachineVersionArn:    gof.Ref(versionResourceName),
+		},
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CloudFormation resources
+////////////////////////////////////////////////////////////////////////////////
+
+// stateMachineVersion is the AWS::StepFunctions::StateMachineVersion resource
+// Ref: https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-stepfunctions-statemachineversion.html
+type stateMachineVersion struct {
+	StateMachineArn        string `json:",omitempty"`
+	StateMachineRevisionID string `json:"StateMachineRevisionId,omitempty"`
+	Description            string `json:",omitempty"`
+}
+
+// AWSCloudFormationType returns the AWS CloudFormation resource type
+func (smv *stateMachineVersion) AWSCloudFormationType() string {
+	return "AWS::StepFunctions::StateMachineVersion"
+}
+
+// MarshalJSON embeds the properties into a CloudFormation resource
+func (smv *stateMachineVersion) MarshalJSON() ([]byte, error) {
+	type Properties stateMachineVersion
+	return json.Marshal(&struct {
+		Type                string
+		Properties          *Properties
+		UpdateReplacePolicy policies.UpdateReplacePolicy
+	}{
+		Type:                smv.AWSCloudFormationType(),
+		Properties:          (*Properties)(smv),
+		UpdateReplacePolicy: policies.UpdateReplacePolicy("Retain"),
+	})
+}
+
+type stateMachineAliasDeploymentPreference struct {
+	AliasDeploymentPreference
+	StateMachineVersionArn string
+}
+
+// stateMachineAlias is the AWS::StepFunctions::StateMachineAlias resource
+// Ref: https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-stepfunctions-statemachinealias.html
+type stateMachineAlias struct {
+	Name                 string                                 `json:",omitempty"`
+	Description          string                                 `json:",omitempty"`
+	DeploymentPreference *stateMachineAliasDeploymentPreference `json:",omitempty"`
+}
+
+// AWSCloudFormationType returns the AWS CloudFormation resource type
+func (sma *stateMachineAlias) AWSCloudFormationType() string {
+	return "AWS::StepFunctions::StateMachineAlias"
+}
+
+// MarshalJSON embeds the properties into a CloudFormation resource
+func (sma *stateMachineAlias) MarshalJSON() ([]byte, error) {
+	type Properties stateMachineAlias
+	return json.Marshal(&struct {
+		Type       string
+		Properties *Properties
+	}{
+		Type:       sma.AWSCloudFormationType(),
+		Properties: (*Properties)(sma),
+	})
+}
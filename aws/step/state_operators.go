@@ -14,6 +14,15 @@ type ChoiceBranch interface {
 	nextState() MachineState
 }
 
+// nextStateName returns the name of the optional next state. Operators
+// nested inside another operator don't have a next state.
+func nextStateName(nextState MachineState) string {
+	if nextState == nil {
+		return ""
+	}
+	return nextState.Name()
+}
+
 /*******************************************************************************
    ___  ___ ___ ___    _ _____ ___  ___  ___
   / _ \| _ \ __| _ \  /_\_   _/ _ \| _ \/ __|
@@ -43,7 +52,7 @@ func (andOperation *And) MarshalJSON() ([]byte, error) {
 		Next       string       `json:",omitempty"`
 	}{
 		Comparison: andOperation.Comparison,
-		Next:       andOperation.Next.Name(),
+		Next:       nextStateName(andOperation.Next),
 	})
 }
 
@@ -69,7 +78,7 @@ func (orOperation *Or) MarshalJSON() ([]byte, error) {
 		Next       string       `json:",omitempty"`
 	}{
 		Comparison: orOperation.Comparison,
-		Next:       orOperation.Next.Name(),
+		Next:       nextStateName(orOperation.Next),
 	})
 }
 
@@ -92,9 +101,9 @@ func (notOperation *Not) nextState() MachineState {
 func (notOperation *Not) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Not  Comparison
-		Next string
+		Next string `json:",omitempty"`
 	}{
 		Not:  notOperation.Comparison,
-		Next: notOperation.Next.Name(),
+		Next: nextStateName(notOperation.Next),
 	})
 }
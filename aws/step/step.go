@@ -371,6 +371,9 @@ type BaseTask struct {
 	LambdaDecorator  sparta.TemplateDecorator
 	Retriers         []*TaskRetry
 	Catchers         []*TaskCatch
+	// Optimized service integration. Nil for non-service states.
+	integration        *serviceIntegration
+	integrationPattern IntegrationPattern
 }
 
 func (bt *BaseTask) marshalMergedParams(taskResourceType string,
@@ -390,6 +393,16 @@ func (bt *BaseTask) marshalMergedParams(taskResourceType string,
 	if !mapTypedErr {
 		return nil, errors.Errorf("attempting to type convert unmarshalled params to map[string]interface{}")
 	}
+	pattern := integrationPatternFromResource(taskResourceType)
+	if bt.integration != nil {
+		pattern = bt.resolvedIntegrationPattern()
+	}
+	if pattern == WaitForTaskToken && !containsTaskToken(mapTyped) {
+		return nil, errors.Errorf("state %s uses the %s integration pattern but doesn't pass %s in its parameters",
+			bt.name,
+			WaitForTaskToken,
+			TaskTokenContextPath)
+	}
 	additionalParams := bt.additionalParams()
 	additionalParams["Resource"] = taskResourceType
 	additionalParams["Parameters"] = mapTyped
@@ -485,6 +498,14 @@ func (bt *BaseTask) WithCatchers(catch ...*TaskCatch) *BaseTask {
 	return bt
 }
 
+// WithIntegrationPattern is the fluent builder for BaseTask. The pattern
+// must be supported by the service integration. WaitForTaskToken patterns must
+// include a TaskTokenContextPath reference in the task parameters.
+func (bt *BaseTask) WithIntegrationPattern(pattern IntegrationPattern) *BaseTask {
+	bt.integrationPattern = pattern
+	return bt
+}
+
 // WithComment returns the BaseTask comment
 func (bt *BaseTask) WithComment(comment string) TransitionState {
 	bt.comment = comment
@@ -573,6 +594,11 @@ func NewLambdaTaskState(stateName string, lambdaFn *sparta.LambdaAWSInfo) *Lambd
 // MarshalJSON for custom marshalling, since this will be stringified and we need it
 // to turn into a stringified Ref:
 func (ts *LambdaTaskState) MarshalJSON() ([]byte, error) {
+	if ts.integrationPattern != "" && ts.integrationPattern != RequestResponse {
+		return nil, errors.Errorf("state %s does not support the %s integration pattern",
+			ts.name,
+			ts.integrationPattern)
+	}
 	additionalParams := ts.BaseTask.additionalParams()
 	additionalParams["Resource"] = gof.GetAtt(ts.lambdaLogicalResourceName, "Arn")
 	return ts.marshalStateJSON("Task", additionalParams)
@@ -828,6 +854,15 @@ type StateMachine struct {
 	startAt              MachineState
 	uniqueStates         map[string]MachineState
 	roleArn              string
+	// managed logging, tracing and versioning options
+	logLevel                  LogLevel
+	logIncludeExecutionData   bool
+	logRetentionInDays        int
+	tracingEnabled            bool
+	versioningEnabled         bool
+	aliasName                 string
+	aliasDeploymentPreference *AliasDeploymentPreference
+	typeContractAnalysis      bool
 	// internal flag to suppress the automatic "End" property
 	// from being serialized for Map states
 	disableEndState bool
@@ -864,6 +899,26 @@ func (sm *StateMachine) validate() []error {
 	return validationErrors
 }
 
+// servicePolicyStatements returns the execution role privileges required
+// by the service integration states, including those nested in Map and
+// Parallel states
+func (sm *StateMachine) servicePolicyStatements() []spartaIAM.PolicyStatement {
+	statements := make([]spartaIAM.PolicyStatement, 0)
+	for _, eachState := range sm.uniqueStates {
+		switch typedState := eachState.(type) {
+		case *MapState:
+			statements = append(statements, typedState.States.servicePolicyStatements()...)
+		case *ParallelState:
+			for _, eachBranch := range typedState.Branches {
+				statements = append(statements, eachBranch.servicePolicyStatements()...)
+			}
+		case policyStatementProvider:
+			statements = append(statements, typedState.policyStatements()...)
+		}
+	}
+	return statements
+}
+
 // StateMachineDecorator is a decorator that returns a default
 // CloudFormationResource named decorator
 func (sm *StateMachine) StateMachineDecorator() sparta.ServiceDecoratorHookFunc {
@@ -892,6 +947,16 @@ func (sm *StateMachine) StateMachineNamedDecorator(stepFunctionResourceName stri
 			return ctx, errors.Errorf("Invalid state machine. Errors: %s",
 				strings.Join(errorText, ", "))
 		}
+		if sm.typeContractAnalysis {
+			for _, eachWarning := range sm.TypeContractWarnings() {
+				logger.Warn().
+					Str("State", eachWarning.StateName).
+					Str("Property", eachWarning.Property).
+					Str("Path", eachWarning.Path).
+					Str("UpstreamType", eachWarning.UpstreamType).
+					Msg(eachWarning.Message)
+			}
+		}
 
 		lambdaFunctionResourceNames := []string{}
 		for _, eachState := range sm.uniqueStates {
@@ -949,23 +1014,26 @@ func (sm *StateMachine) StateMachineNamedDecorator(stepFunctionResourceName stri
 				},
 			},
 		}
+		statements := make([]spartaIAM.PolicyStatement, 0)
+		for _, eachLambdaName := range lambdaFunctionResourceNames {
+			statements = append(statements,
+				spartaIAM.PolicyStatement{
+					Effect: "Allow",
+					Action: []string{
+						"lambda:InvokeFunction",
+					},
+					Resource: gof.GetAtt(eachLambdaName, "Arn"),
+				},
+			)
+		}
+		statements = append(statements, sm.servicePolicyStatements()...)
+		statements = append(statements, sm.managedPolicyStatements()...)
+
 		var iamRoleResourceName string
-		if len(lambdaFunctionResourceNames) != 0 && sm.roleArn == "" {
+		if len(statements) != 0 && sm.roleArn == "" {
 			statesIAMRole := &gofiam.Role{
 				AssumeRolePolicyDocument: AssumePolicyDocument,
 			}
-			statements := make([]spartaIAM.PolicyStatement, 0)
-			for _, eachLambdaName := range lambdaFunctionResourceNames {
-				statements = append(statements,
-					spartaIAM.PolicyStatement{
-						Effect: "Allow",
-						Action: []string{
-							"lambda:InvokeFunction",
-						},
-						Resource: gof.GetAtt(eachLambdaName, "Arn"),
-					},
-				)
-			}
 			iamPolicies := []gofiam.Role_Policy{}
 			iamPolicies = append(iamPolicies, gofiam.Role_Policy{
 				PolicyDocument: sparta.ArbitraryJSONObject{
@@ -1012,6 +1080,10 @@ func (sm *StateMachine) StateMachineNamedDecorator(stepFunctionResourceName stri
 		if sm.machineType != "" {
 			stepFunctionResource.StateMachineType = sm.machineType
 		}
+		sm.decorateManagedResources(stepFunctionResourceName,
+			stepFunctionResource,
+			buildID,
+			template)
 		template.Resources[stepFunctionResourceName] = stepFunctionResource
 		return ctx, nil
 	}
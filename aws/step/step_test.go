@@ -1,14 +1,20 @@
 package step
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	gof "github.com/awslabs/goformation/v5/cloudformation"
 	sparta "github.com/mweagle/Sparta/v3"
 	spartaCF "github.com/mweagle/Sparta/v3/aws/cloudformation"
 	spartaTesting "github.com/mweagle/Sparta/v3/testing"
+	"github.com/rs/zerolog"
 )
 
 func testStepProvisionAssertError(t *testing.T,
@@ -261,3 +267,100 @@ func TestParallelState(t *testing.T) {
 		[]*sparta.LambdaAWSInfo{lambdaMapFn, lambdaProducerFn},
 		stateMachine)
 }
+
+func TestStateMachineManagedOptions(t *testing.T) {
+	passState := NewPassState("pass", nil)
+	successState := NewSuccessState("success")
+	passState.Next(successState)
+	stateMachine := NewStateMachine("ManagedOptions", passState).
+		WithLogging(LogLevelError, true, 7).
+		WithTracing(true).
+		WithAlias("live", &AliasDeploymentPreference{
+			Type:       AliasDeploymentCanary,
+			Percentage: 10,
+			Interval:   5,
+		})
+
+	template := gof.NewTemplate()
+	logger := zerolog.Nop()
+	decorator := stateMachine.StateMachineNamedDecorator("Machine")
+	_, decorateErr := decorator(context.Background(),
+		"ManagedOptions",
+		template,
+		nil,
+		"build-1",
+		awsv2.Config{},
+		true,
+		&logger)
+	if decorateErr != nil {
+		t.Fatalf("Failed to decorate template: %s", decorateErr)
+	}
+	templateBytes, templateBytesErr := template.JSON()
+	if templateBytesErr != nil {
+		t.Fatalf("Failed to marshal template: %s", templateBytesErr)
+	}
+	compactJSON := bytes.Buffer{}
+	compactErr := json.Compact(&compactJSON, templateBytes)
+	if compactErr != nil {
+		t.Fatalf("Failed to compact template: %s", compactErr)
+	}
+	templateJSON := compactJSON.String()
+	for _, eachExpected := range []string{
+		`"MachineLogGroup":{"Properties":{"RetentionInDays":7},"Type":"AWS::Logs::LogGroup"}`,
+		`"Level":"ERROR"`,
+		`"TracingConfiguration":{"Enabled":true}`,
+		`"logs:CreateLogDelivery"`,
+		`"xray:PutTraceSegments"`,
+		`"Type":"AWS::StepFunctions::StateMachineVersion"`,
+		`"StateMachineRevisionId":{"Fn::GetAtt":["Machine","StateMachineRevisionId"]}`,
+		`"UpdateReplacePolicy":"Retain"`,
+		`"Type":"AWS::StepFunctions::StateMachineAlias"`,
+		`"StateMachineVersionArn":{"Ref":"MachineVersion"}`,
+		`"Type":"CANARY"`,
+	} {
+		if !strings.Contains(templateJSON, eachExpected) {
+			t.Fatalf("Failed to find %s in template: %s", eachExpected, templateJSON)
+		}
+	}
+}
+
+func TestStateMachineDescribe(t *testing.T) {
+	lambdaFn, _ := sparta.NewAWSLambda(sparta.LambdaName(helloWorld),
+		helloWorld,
+		sparta.IAMRoleDefinition{})
+	lambdaTaskState := NewLambdaTaskState("lambdaHelloWorld", lambdaFn)
+	successState := NewSuccessState("success")
+	failState := NewFailState("failed", "ErrorName", nil)
+	lambdaTaskState.WithCatchers(NewTaskCatch(failState, StatesAll))
+	lambdaTaskState.Next(successState)
+	stateMachine := NewStateMachine("DescribedMachine", lambdaTaskState)
+
+	// The StateMachine is a describable service decorator
+	var serviceDecorator sparta.ServiceDecoratorHookHandler = stateMachine
+	describable, isDescribable := serviceDecorator.(sparta.Describable)
+	if !isDescribable {
+		t.Fatalf("StateMachine doesn't implement sparta.Describable")
+	}
+	descInfo, descInfoErr := describable.Describe("service")
+	if descInfoErr != nil {
+		t.Fatalf("Failed to describe state machine: %s", descInfoErr)
+	}
+	arcs := make(map[string]bool)
+	for _, eachNode := range descInfo.Nodes {
+		if eachNode.TargetNodeName != "" {
+			arcs[eachNode.SourceNodeName+"->"+eachNode.TargetNodeName+":"+eachNode.ArcLabel] = true
+		}
+	}
+	expectedArcs := []string{
+		"DescribedMachine->service:",
+		"DescribedMachine->DescribedMachine.lambdaHelloWorld:StartAt",
+		"DescribedMachine.lambdaHelloWorld->DescribedMachine.success:",
+		"DescribedMachine.lambdaHelloWorld->DescribedMachine.failed:Catch",
+		"DescribedMachine.lambdaHelloWorld->" + lambdaFn.NewDescriptionTriplet("", true).TargetNodeName + ":",
+	}
+	for _, eachArc := range expectedArcs {
+		if !arcs[eachArc] {
+			t.Fatalf("Failed to find arc %s in %v", eachArc, arcs)
+		}
+	}
+}
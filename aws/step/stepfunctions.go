@@ -0,0 +1,76 @@
+package step
+
+import (
+	"math/rand"
+
+	spartaIAM "github.com/mweagle/Sparta/v3/aws/iam"
+)
+
+// StartExecutionParameters represents params for starting a nested
+// state machine execution.
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connect-stepfunctions.html
+type StartExecutionParameters struct {
+	StateMachineArn string      `json:",omitempty"`
+	Input           interface{} `json:",omitempty"`
+	Name            string      `json:",omitempty"`
+}
+
+// StartExecutionState represents bindings for
+// https://docs.aws.amazon.com/step-functions/latest/dg/connect-stepfunctions.html
+type StartExecutionState struct {
+	BaseTask
+	parameters StartExecutionParameters
+}
+
+// MarshalJSON for custom marshalling, since this will be stringified and we need it
+// to turn into a stringified
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connect-stepfunctions.html
+func (ses *StartExecutionState) MarshalJSON() ([]byte, error) {
+	return ses.BaseTask.marshalIntegrationParams(&ses.parameters)
+}
+
+// policyStatements returns the privileges needed to start the nested execution
+func (ses *StartExecutionState) policyStatements() []spartaIAM.PolicyStatement {
+	statements := []spartaIAM.PolicyStatement{
+		{
+			Effect:   "Allow",
+			Action:   []string{"states:StartExecution"},
+			Resource: resourceOrWildcard(ses.parameters.StateMachineArn),
+		},
+	}
+	if ses.resolvedIntegrationPattern() == Sync {
+		statements = append(statements,
+			spartaIAM.PolicyStatement{
+				Effect: "Allow",
+				Action: []string{
+					"states:DescribeExecution",
+					"states:StopExecution",
+				},
+				Resource: "*",
+			},
+			syncEventRuleStatement("StepFunctionsGetEventsForStepFunctionsExecutionRule"))
+	}
+	return statements
+}
+
+// NewStartExecutionState returns an initialized StartExecutionState. The
+// default RequestResponse pattern doesn't wait for the nested execution to
+// complete. Use WithIntegrationPattern(Sync) to wait for the result.
+func NewStartExecutionState(stateName string,
+	parameters StartExecutionParameters) *StartExecutionState {
+	ses := &StartExecutionState{
+		BaseTask: BaseTask{
+			baseInnerState: baseInnerState{
+				name: stateName,
+				id:   rand.Int63(),
+			},
+			integration: &serviceIntegration{
+				resourceARN:       "arn:aws:states:::states:startExecution",
+				defaultPattern:    RequestResponse,
+				supportedPatterns: []IntegrationPattern{RequestResponse, Sync, WaitForTaskToken},
+			},
+		},
+		parameters: parameters,
+	}
+	return ses
+}
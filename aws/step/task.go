@@ -35,7 +35,8 @@ func NewTaskState(stateName string,
 				id:   rand.Int63(),
 			},
 		},
-		parameters: parameters,
+		resourceURI: resourceURI,
+		parameters:  parameters,
 	}
 	return sns
 }
@@ -0,0 +1,634 @@
+package step
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TypeContractWarning describes a JSONPath expression that references a
+// field that doesn't exist in the Go type of the data flowing into a state.
+type TypeContractWarning struct {
+	// StateName is the name of the state that owns the path
+	StateName string
+	// Property is the state property that contains the path (eg: InputPath)
+	Property string
+	// Path is the JSONPath expression
+	Path string
+	// UpstreamType is the Go type the path was resolved against
+	UpstreamType string
+	// Message describes the missing field
+	Message string
+}
+
+// String returns a human readable description of the warning
+func (tcw TypeContractWarning) String() string {
+	return fmt.Sprintf("%s %s %s: %s",
+		tcw.StateName,
+		tcw.Property,
+		tcw.Path,
+		tcw.Message)
+}
+
+// WithTypeContractAnalysis enables the build-time analysis that propagates
+// the Go input and output types of LambdaTaskState handlers through the
+// state machine's InputPath, ResultPath and OutputPath values. Paths that
+// reference fields that don't exist in the upstream type, and handler
+// inputs that don't match the event type, are logged as warnings during
+// provisioning. See TypeContractWarnings.
+func (sm *StateMachine) WithTypeContractAnalysis() *StateMachine {
+	sm.typeContractAnalysis = true
+	return sm
+}
+
+// TypeContractWarnings returns the JSONPath expressions in the state machine
+// that reference fields which don't exist in the upstream Go type, and the
+// LambdaTaskState inputs that don't match the handler's event type. Data
+// produced by states other than LambdaTaskState and PassState is untyped
+// and its paths aren't checked. Each state is analyzed with the data of the
+// first transition that reaches it.
+func (sm *StateMachine) TypeContractWarnings() []TypeContractWarning {
+	analyzer := &typeContractAnalyzer{
+		visited:  make(map[string]bool),
+		warnings: make([]TypeContractWarning, 0),
+	}
+	analyzer.visitMachine(sm, nil)
+	return analyzer.warnings
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// JSON shapes
+////////////////////////////////////////////////////////////////////////////////
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// jsonShape is the statically known shape of a JSON value. A nil
+// shape is untyped and every path into it is valid.
+type jsonShape struct {
+	goType reflect.Type
+	// fields assigned by a ResultPath. They take precedence over
+	// the goType fields.
+	fields map[string]*jsonShape
+}
+
+func (shape *jsonShape) String() string {
+	if shape.goType != nil {
+		return shape.goType.String()
+	}
+	return "object"
+}
+
+// newJSONShape returns the shape for the Go type or nil if the
+// JSON representation can't be determined from the type
+func newJSONShape(goType reflect.Type) *jsonShape {
+	if goType == nil {
+		return nil
+	}
+	for goType.Kind() == reflect.Ptr {
+		goType = goType.Elem()
+	}
+	if goType.Kind() == reflect.Interface ||
+		goType.Implements(jsonMarshalerType) ||
+		reflect.PtrTo(goType).Implements(jsonMarshalerType) {
+		return nil
+	}
+	return &jsonShape{goType: goType}
+}
+
+// structFieldType returns the type of the struct field with the JSON
+// name, including fields promoted from embedded structs
+func structFieldType(structType reflect.Type, jsonName string) (reflect.Type, bool) {
+	for i := 0; i < structType.NumField(); i++ {
+		eachField := structType.Field(i)
+		tagName := strings.Split(eachField.Tag.Get("json"), ",")[0]
+		if tagName == "-" {
+			continue
+		}
+		fieldType := eachField.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if eachField.Anonymous && tagName == "" && fieldType.Kind() == reflect.Struct {
+			promotedType, promotedOk := structFieldType(fieldType, jsonName)
+			if promotedOk {
+				return promotedType, true
+			}
+			continue
+		}
+		if eachField.PkgPath != "" {
+			continue
+		}
+		if tagName == "" {
+			tagName = eachField.Name
+		}
+		if tagName == jsonName {
+			return eachField.Type, true
+		}
+	}
+	return nil, false
+}
+
+// child returns the shape of the named field or array index. The
+// boolean result is false if the field can't exist.
+func (shape *jsonShape) child(segment pathSegment) (*jsonShape, bool) {
+	if shape == nil {
+		return nil, true
+	}
+	if !segment.isIndex {
+		if fieldShape, fieldShapeExists := shape.fields[segment.name]; fieldShapeExists {
+			return fieldShape, true
+		}
+	}
+	if shape.goType == nil {
+		// Object created by a ResultPath
+		return nil, !segment.isIndex
+	}
+	switch shape.goType.Kind() {
+	case reflect.Struct:
+		if segment.isIndex {
+			return nil, false
+		}
+		fieldType, fieldTypeOk := structFieldType(shape.goType, segment.name)
+		if !fieldTypeOk {
+			return nil, false
+		}
+		return newJSONShape(fieldType), true
+	case reflect.Map:
+		if segment.isIndex || shape.goType.Key().Kind() != reflect.String {
+			return nil, false
+		}
+		return newJSONShape(shape.goType.Elem()), true
+	case reflect.Slice, reflect.Array:
+		if !segment.isIndex {
+			return nil, false
+		}
+		return newJSONShape(shape.goType.Elem()), true
+	default:
+		return nil, false
+	}
+}
+
+// withField returns a copy of the shape with the field at the
+// path set to the value shape
+func (shape *jsonShape) withField(segments []pathSegment, value *jsonShape) *jsonShape {
+	if len(segments) == 0 {
+		return value
+	}
+	updated := &jsonShape{
+		fields: make(map[string]*jsonShape),
+	}
+	if shape != nil {
+		updated.goType = shape.goType
+		for eachKey, eachValue := range shape.fields {
+			updated.fields[eachKey] = eachValue
+		}
+	}
+	existing, _ := shape.child(segments[0])
+	updated.fields[segments[0].name] = existing.withField(segments[1:], value)
+	return updated
+}
+
+// jsonKind returns the JSON value kind of the shape or the empty
+// string if it's unknown
+func (shape *jsonShape) jsonKind() string {
+	if shape == nil {
+		return ""
+	}
+	if shape.goType == nil || len(shape.fields) != 0 {
+		return "object"
+	}
+	switch shape.goType.Kind() {
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		if shape.goType.Elem().Kind() == reflect.Uint8 {
+			// []byte is base64 encoded
+			return "string"
+		}
+		return "array"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return ""
+	}
+}
+
+// structFieldNames returns the JSON names of the struct fields,
+// including fields promoted from embedded structs
+func structFieldNames(structType reflect.Type) []string {
+	names := make([]string, 0)
+	for i := 0; i < structType.NumField(); i++ {
+		eachField := structType.Field(i)
+		tagName := strings.Split(eachField.Tag.Get("json"), ",")[0]
+		if tagName == "-" {
+			continue
+		}
+		fieldType := eachField.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if eachField.Anonymous && tagName == "" && fieldType.Kind() == reflect.Struct {
+			names = append(names, structFieldNames(fieldType)...)
+			continue
+		}
+		if eachField.PkgPath != "" {
+			continue
+		}
+		if tagName == "" {
+			tagName = eachField.Name
+		}
+		names = append(names, tagName)
+	}
+	return names
+}
+
+// shapeMismatch returns a description of why a value with the input
+// shape can't be unmarshalled into the event shape, or the empty string
+// if it can. Event fields missing from the input are zero valued and
+// aren't mismatches unless none of the event's fields exist in the input.
+func shapeMismatch(event *jsonShape,
+	input *jsonShape,
+	compared map[[2]reflect.Type]bool) string {
+	eventKind := event.jsonKind()
+	inputKind := input.jsonKind()
+	if eventKind == "" || inputKind == "" {
+		return ""
+	}
+	if eventKind != inputKind {
+		return fmt.Sprintf("expected %s, found %s", eventKind, inputKind)
+	}
+	if len(input.fields) == 0 {
+		comparedKey := [2]reflect.Type{event.goType, input.goType}
+		if compared[comparedKey] {
+			return ""
+		}
+		compared[comparedKey] = true
+	}
+	switch eventKind {
+	case "array":
+		inputElem, _ := input.child(pathSegment{name: "0", isIndex: true})
+		eventElem, _ := event.child(pathSegment{name: "0", isIndex: true})
+		if mismatch := shapeMismatch(eventElem, inputElem, compared); mismatch != "" {
+			return fmt.Sprintf("[0]: %s", mismatch)
+		}
+	case "object":
+		if event.goType.Kind() != reflect.Struct {
+			return ""
+		}
+		fieldNames := structFieldNames(event.goType)
+		matched := 0
+		for _, eachName := range fieldNames {
+			segment := pathSegment{name: eachName}
+			inputField, inputFieldOk := input.child(segment)
+			if !inputFieldOk {
+				continue
+			}
+			matched++
+			eventField, _ := event.child(segment)
+			if mismatch := shapeMismatch(eventField, inputField, compared); mismatch != "" {
+				return fmt.Sprintf("%s: %s", eachName, mismatch)
+			}
+		}
+		// Only typed inputs have a closed set of fields
+		if matched == 0 && len(fieldNames) != 0 && input.goType != nil {
+			return fmt.Sprintf("none of the %s fields exist in %s", event, input)
+		}
+	}
+	return ""
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Paths
+////////////////////////////////////////////////////////////////////////////////
+
+type pathSegment struct {
+	name    string
+	isIndex bool
+}
+
+func (segment pathSegment) String() string {
+	if segment.isIndex {
+		return fmt.Sprintf("[%s]", segment.name)
+	}
+	return segment.name
+}
+
+// parseReferencePath splits a JSONPath into field and index segments.
+// The boolean result is false for context object paths and paths with
+// operators (wildcards, filters, slices) that aren't statically checked.
+func parseReferencePath(path string) ([]pathSegment, bool) {
+	if !strings.HasPrefix(path, "$") || strings.HasPrefix(path, "$$") {
+		return nil, false
+	}
+	segments := make([]pathSegment, 0)
+	remaining := path[1:]
+	for remaining != "" {
+		switch {
+		case strings.HasPrefix(remaining, ".."):
+			return nil, false
+		case strings.HasPrefix(remaining, "."):
+			end := strings.IndexAny(remaining[1:], ".[")
+			if end < 0 {
+				end = len(remaining) - 1
+			}
+			name := remaining[1 : end+1]
+			if name == "" || name == "*" {
+				return nil, false
+			}
+			segments = append(segments, pathSegment{name: name})
+			remaining = remaining[end+1:]
+		case strings.HasPrefix(remaining, "["):
+			end := strings.Index(remaining, "]")
+			if end < 0 {
+				return nil, false
+			}
+			selector := remaining[1:end]
+			remaining = remaining[end+1:]
+			if len(selector) >= 2 &&
+				(selector[0] == '\'' || selector[0] == '"') &&
+				selector[len(selector)-1] == selector[0] {
+				segments = append(segments, pathSegment{name: selector[1 : len(selector)-1]})
+			} else if _, indexErr := strconv.Atoi(selector); indexErr == nil {
+				segments = append(segments, pathSegment{name: selector, isIndex: true})
+			} else {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return segments, true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Analyzer
+////////////////////////////////////////////////////////////////////////////////
+
+// taskState is implemented by every state that embeds BaseTask
+type taskState interface {
+	baseTask() *BaseTask
+}
+
+func (bt *BaseTask) baseTask() *BaseTask {
+	return bt
+}
+
+// dataPathState is implemented by every state that embeds baseInnerState
+type dataPathState interface {
+	dataPaths() (inputPath string, outputPath string)
+}
+
+func (bis *baseInnerState) dataPaths() (string, string) {
+	return bis.inputPath, bis.outputPath
+}
+
+type typeContractAnalyzer struct {
+	visited  map[string]bool
+	warnings []TypeContractWarning
+}
+
+// resolve returns the shape selected by the path. Paths that can't
+// exist in the shape are recorded as warnings.
+func (tca *typeContractAnalyzer) resolve(stateName string,
+	property string,
+	shape *jsonShape,
+	path string) *jsonShape {
+	if path == "" {
+		return shape
+	}
+	segments, segmentsOk := parseReferencePath(path)
+	if !segmentsOk {
+		return nil
+	}
+	current := shape
+	for index, eachSegment := range segments {
+		next, nextOk := current.child(eachSegment)
+		if !nextOk {
+			message := fmt.Sprintf("field %s doesn't exist in %s", eachSegment, current)
+			if index != 0 {
+				message = fmt.Sprintf("%s (resolved from %s)", message, shape)
+			}
+			tca.warnings = append(tca.warnings, TypeContractWarning{
+				StateName:    stateName,
+				Property:     property,
+				Path:         path,
+				UpstreamType: shape.String(),
+				Message:      message,
+			})
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// assign returns the state output after the result is inserted
+// into the raw input at the ResultPath
+func (tca *typeContractAnalyzer) assign(input *jsonShape,
+	resultPath string,
+	result *jsonShape) *jsonShape {
+	if resultPath == "" || resultPath == "$" {
+		return result
+	}
+	segments, segmentsOk := parseReferencePath(resultPath)
+	if !segmentsOk {
+		return nil
+	}
+	return input.withField(segments, result)
+}
+
+// resolveReferences checks the `.$` suffixed keys in a Parameters object
+func (tca *typeContractAnalyzer) resolveReferences(stateName string,
+	property string,
+	shape *jsonShape,
+	value interface{}) {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for eachKey, eachValue := range typedValue {
+			pathValue, pathValueOk := eachValue.(string)
+			if strings.HasSuffix(eachKey, ".$") && pathValueOk {
+				tca.resolve(stateName, property, shape, pathValue)
+				continue
+			}
+			tca.resolveReferences(stateName, property, shape, eachValue)
+		}
+	case []interface{}:
+		for _, eachValue := range typedValue {
+			tca.resolveReferences(stateName, property, shape, eachValue)
+		}
+	}
+}
+
+// resolveChoicePaths checks the Variable and *Path operands of a
+// marshalled choice rule
+func (tca *typeContractAnalyzer) resolveChoicePaths(stateName string,
+	shape *jsonShape,
+	rule interface{}) {
+	switch typedRule := rule.(type) {
+	case map[string]interface{}:
+		for eachKey, eachValue := range typedRule {
+			pathValue, pathValueOk := eachValue.(string)
+			if pathValueOk && (eachKey == "Variable" || strings.HasSuffix(eachKey, "Path")) {
+				tca.resolve(stateName, eachKey, shape, pathValue)
+				continue
+			}
+			tca.resolveChoicePaths(stateName, shape, eachValue)
+		}
+	case []interface{}:
+		for _, eachValue := range typedRule {
+			tca.resolveChoicePaths(stateName, shape, eachValue)
+		}
+	}
+}
+
+func (tca *typeContractAnalyzer) visitMachine(sm *StateMachine, input *jsonShape) {
+	if sm == nil {
+		return
+	}
+	tca.visit(sm.startAt, input)
+}
+
+func (tca *typeContractAnalyzer) visit(state MachineState, input *jsonShape) {
+	if state == nil || tca.visited[state.nodeID()] {
+		return
+	}
+	tca.visited[state.nodeID()] = true
+
+	stateName := state.Name()
+	effectiveInput := input
+	outputPath := ""
+	if pathState, pathStateOk := state.(dataPathState); pathStateOk {
+		var inputPath string
+		inputPath, outputPath = pathState.dataPaths()
+		effectiveInput = tca.resolve(stateName, "InputPath", input, inputPath)
+	}
+
+	switch typedState := state.(type) {
+	case *LambdaTaskState:
+		var result *jsonShape
+		if typedState.lambdaFn != nil {
+			eventType, responseType := typedState.lambdaFn.HandlerTypes()
+			tca.checkEvent(stateName, effectiveInput, newJSONShape(eventType))
+			result = newJSONShape(responseType)
+		}
+		output := tca.assign(input, typedState.ResultPath, result)
+		output = tca.resolve(stateName, "OutputPath", output, outputPath)
+		tca.visit(typedState.next, output)
+		tca.visitCatchers(typedState.Catchers)
+
+	case *PassState:
+		result := effectiveInput
+		if typedState.Result != nil {
+			result = newJSONShape(reflect.TypeOf(typedState.Result))
+		}
+		output := tca.assign(input, typedState.ResultPath, result)
+		output = tca.resolve(stateName, "OutputPath", output, outputPath)
+		tca.visit(typedState.next, output)
+
+	case *ChoiceState:
+		output := tca.resolve(stateName, "OutputPath", effectiveInput, outputPath)
+		for _, eachChoice := range typedState.Choices {
+			var rule interface{}
+			ruleBytes, ruleBytesErr := json.Marshal(eachChoice)
+			if ruleBytesErr == nil && json.Unmarshal(ruleBytes, &rule) == nil {
+				tca.resolveChoicePaths(stateName, effectiveInput, rule)
+			}
+			tca.visit(eachChoice.nextState(), output)
+		}
+		if typedState.Default != nil {
+			tca.visit(typedState.Default, output)
+		}
+
+	case *WaitDynamicUntil:
+		tca.resolve(stateName, "TimestampPath", effectiveInput, typedState.TimestampPath)
+		tca.resolve(stateName, "SecondsPath", effectiveInput, typedState.SecondsPath)
+		output := tca.resolve(stateName, "OutputPath", effectiveInput, outputPath)
+		tca.visit(typedState.next, output)
+
+	case *WaitDelay, *WaitUntil, *SuccessState:
+		output := tca.resolve(stateName, "OutputPath", effectiveInput, outputPath)
+		if transitionState, transitionStateOk := state.(TransitionState); transitionStateOk {
+			for _, eachAdjacent := range transitionState.AdjacentStates() {
+				tca.visit(eachAdjacent, output)
+			}
+		}
+
+	case *MapState:
+		items := tca.resolve(stateName, "ItemsPath", effectiveInput, typedState.ItemsPath)
+		var iterationInput *jsonShape
+		if typedState.Parameters != nil {
+			tca.resolveReferences(stateName, "Parameters", effectiveInput, typedState.Parameters)
+		} else if items != nil {
+			iterationInput, _ = items.child(pathSegment{name: "0", isIndex: true})
+		}
+		tca.visitMachine(typedState.States, iterationInput)
+		output := tca.assign(input, typedState.ResultPath, nil)
+		output = tca.resolve(stateName, "OutputPath", output, outputPath)
+		tca.visit(typedState.next, output)
+		tca.visitCatchers(typedState.Catchers)
+
+	case *ParallelState:
+		branchInput := effectiveInput
+		if typedState.Parameters != nil {
+			tca.resolveReferences(stateName, "Parameters", effectiveInput, typedState.Parameters)
+			branchInput = nil
+		}
+		for _, eachBranch := range typedState.Branches {
+			tca.visitMachine(eachBranch, branchInput)
+		}
+		output := tca.assign(input, typedState.ResultPath, nil)
+		output = tca.resolve(stateName, "OutputPath", output, outputPath)
+		tca.visit(typedState.next, output)
+		tca.visitCatchers(typedState.Catchers)
+
+	case taskState:
+		// Service integration results are untyped
+		task := typedState.baseTask()
+		output := tca.assign(input, task.ResultPath, nil)
+		output = tca.resolve(stateName, "OutputPath", output, outputPath)
+		tca.visit(task.next, output)
+		tca.visitCatchers(task.Catchers)
+
+	case TransitionState:
+		for _, eachAdjacent := range typedState.AdjacentStates() {
+			tca.visit(eachAdjacent, nil)
+		}
+	}
+}
+
+// checkEvent records a warning if the effective input of a LambdaTaskState
+// can't be unmarshalled into the handler's event type
+func (tca *typeContractAnalyzer) checkEvent(stateName string,
+	input *jsonShape,
+	event *jsonShape) {
+	mismatch := shapeMismatch(event, input, make(map[[2]reflect.Type]bool))
+	if mismatch == "" {
+		return
+	}
+	tca.warnings = append(tca.warnings, TypeContractWarning{
+		StateName:    stateName,
+		Property:     "Input",
+		Path:         "$",
+		UpstreamType: input.String(),
+		Message: fmt.Sprintf("handler event type %s doesn't match %s: %s",
+			event,
+			input,
+			mismatch),
+	})
+}
+
+// visitCatchers visits the fallback states. Their input includes the
+// untyped error output.
+func (tca *typeContractAnalyzer) visitCatchers(catchers []*TaskCatch) {
+	for _, eachCatcher := range catchers {
+		tca.visit(eachCatcher.next, nil)
+	}
+}
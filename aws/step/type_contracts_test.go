@@ -0,0 +1,204 @@
+package step
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	sparta "github.com/mweagle/Sparta/v3"
+)
+
+type contractOrder struct {
+	OrderID  string `json:"orderId"`
+	Customer struct {
+		Email string `json:"email"`
+	} `json:"customer"`
+	Items []contractOrderItem `json:"items"`
+}
+
+type contractOrderItem struct {
+	SKU string `json:"sku"`
+}
+
+type contractReceipt struct {
+	contractAudit
+	ReceiptID string `json:"receiptId"`
+	Total     float64
+}
+
+type contractAudit struct {
+	RequestID string `json:"requestId"`
+}
+
+func loadOrder(ctx context.Context) (contractOrder, error) {
+	return contractOrder{}, nil
+}
+
+func chargeOrder(ctx context.Context, order contractOrder) (contractReceipt, error) {
+	return contractReceipt{}, nil
+}
+
+func notifyCustomer(ctx context.Context, receipt contractReceipt) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+func refundOrder(ctx context.Context, receipt contractReceipt) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+func packItems(ctx context.Context, items []struct {
+	SKU int `json:"sku"`
+}) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+func newContractLambda(t *testing.T, handler interface{}) *sparta.LambdaAWSInfo {
+	lambdaFn, lambdaFnErr := sparta.NewAWSLambda(sparta.LambdaName(handler),
+		handler,
+		sparta.IAMRoleDefinition{})
+	if lambdaFnErr != nil {
+		t.Fatalf("Failed to create lambda: %s", lambdaFnErr)
+	}
+	return lambdaFn
+}
+
+func TestTypeContractWarnings(t *testing.T) {
+	loadState := NewLambdaTaskState("load", newContractLambda(t, loadOrder))
+	chargeState := NewLambdaTaskState("charge", newContractLambda(t, chargeOrder))
+	chargeState.ResultPath = "$.receipt"
+	chargeState.WithInputPath("$.order")
+	notifyState := NewLambdaTaskState("notify", newContractLambda(t, notifyCustomer))
+	notifyState.WithInputPath("$.receipt")
+	choiceState := NewChoiceState("large",
+		&And{
+			Comparison: []Comparison{
+				&NumericGreaterThan{
+					Variable: "$.receipt.Total",
+					Value:    100,
+				},
+			},
+			Next: NewSuccessState("done"),
+		},
+		&And{
+			Comparison: []Comparison{
+				&StringEquals{
+					Variable: "$.receipt.requestId",
+					Value:    "",
+				},
+			},
+			Next: NewSuccessState("anonymous"),
+		},
+		&Not{
+			Comparison: &StringEquals{
+				Variable: "$.customer.name",
+				Value:    "",
+			},
+			Next: NewSuccessState("named"),
+		},
+	)
+	mapState := NewMapState("eachItem",
+		NewStateMachine("items",
+			NewPassState("sku", nil).WithInputPath("$.sku")))
+	mapState.ItemsPath = "$.items"
+
+	loadState.Next(chargeState)
+	chargeState.Next(choiceState)
+	choiceState.WithDefault(mapState)
+	mapState.Next(notifyState)
+	notifyState.WithOutputPath("$.anything")
+
+	sm := NewStateMachine("contracts", loadState)
+	warnings := sm.TypeContractWarnings()
+	if len(warnings) != 2 {
+		t.Fatalf("Expected 2 warnings, found %d: %v", len(warnings), warnings)
+	}
+	expected := map[string]string{
+		"charge": "$.order",
+		"large":  "$.customer.name",
+	}
+	for _, eachWarning := range warnings {
+		if expected[eachWarning.StateName] != eachWarning.Path {
+			t.Fatalf("Unexpected warning: %s", eachWarning)
+		}
+	}
+	if !strings.Contains(warnings[0].String(), "step.contractOrder") {
+		t.Fatalf("Unexpected warning text: %s", warnings[0])
+	}
+}
+
+func TestTypeContractPaths(t *testing.T) {
+	validPaths := []string{
+		"$",
+		"$.items[0].sku",
+		"$['customer'].email",
+		"$.customer[\"email\"]",
+	}
+	shape := newJSONShape(reflect.TypeOf(contractOrder{}))
+	for _, eachPath := range validPaths {
+		analyzer := &typeContractAnalyzer{}
+		analyzer.resolve("state", "InputPath", shape, eachPath)
+		if len(analyzer.warnings) != 0 {
+			t.Fatalf("Unexpected warning for %s: %s", eachPath, analyzer.warnings[0])
+		}
+	}
+	invalidPaths := []string{
+		"$.orderId.value",
+		"$.items.sku",
+		"$.customer[0]",
+		"$.Customer",
+	}
+	for _, eachPath := range invalidPaths {
+		analyzer := &typeContractAnalyzer{}
+		analyzer.resolve("state", "InputPath", shape, eachPath)
+		if len(analyzer.warnings) != 1 {
+			t.Fatalf("Failed to reject %s", eachPath)
+		}
+	}
+	uncheckedPaths := []string{
+		"$$.Execution.Id",
+		"$.items[*].sku",
+		"$..sku",
+	}
+	for _, eachPath := range uncheckedPaths {
+		analyzer := &typeContractAnalyzer{}
+		analyzer.resolve("state", "InputPath", shape, eachPath)
+		if len(analyzer.warnings) != 0 {
+			t.Fatalf("Unexpected warning for %s: %s", eachPath, analyzer.warnings[0])
+		}
+	}
+}
+
+func TestTypeContractEventMismatch(t *testing.T) {
+	loadState := NewLambdaTaskState("load", newContractLambda(t, loadOrder))
+	refundState := NewLambdaTaskState("refund", newContractLambda(t, refundOrder))
+	refundState.ResultPath = "$.refund"
+	packState := NewLambdaTaskState("pack", newContractLambda(t, packItems))
+	packState.WithInputPath("$.items")
+	packState.ResultPath = "$.packed"
+	chargeState := NewLambdaTaskState("charge", newContractLambda(t, chargeOrder))
+	chargeState.ResultPath = "$.receipt"
+	notifyState := NewLambdaTaskState("notify", newContractLambda(t, notifyCustomer))
+	notifyState.WithInputPath("$.receipt")
+
+	loadState.Next(refundState)
+	refundState.Next(packState)
+	packState.Next(chargeState)
+	chargeState.Next(notifyState)
+
+	sm := NewStateMachine("events", loadState)
+	warnings := sm.TypeContractWarnings()
+	if len(warnings) != 2 {
+		t.Fatalf("Expected 2 warnings, found %d: %v", len(warnings), warnings)
+	}
+	expected := map[string]string{
+		"refund": "none of the step.contractReceipt fields exist in step.contractOrder",
+		"pack":   "[0]: sku: expected number, found string",
+	}
+	for _, eachWarning := range warnings {
+		if !strings.HasSuffix(eachWarning.Message, expected[eachWarning.StateName]) ||
+			eachWarning.Property != "Input" {
+			t.Fatalf("Unexpected warning: %s", eachWarning)
+		}
+	}
+}
@@ -1271,6 +1271,9 @@ func Build(ctx context.Context,
 	buildContext.workflowHooksContext = context.WithValue(buildContext.workflowHooksContext,
 		ContextKeyBuildBinaryName,
 		SpartaBinaryName)
+	buildContext.workflowHooksContext = context.WithValue(buildContext.workflowHooksContext,
+		ContextKeyBuildTemplate,
+		buildContext.cfTemplate)
 
 	logger.Info().
 		Str("BuildID", buildID).
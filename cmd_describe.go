@@ -15,26 +15,6 @@ import (
 	"github.com/rs/zerolog"
 )
 
-// workflowHooksDescriptionNodes returns the set of []*DescriptionInfo
-// entries that summarizes the WorkflowNodes
-func workflowHooksDescriptionNodes(serviceName string, hooks *WorkflowHooks) ([]*DescriptionInfo, error) {
-	if hooks == nil {
-		return nil, nil
-	}
-	workflowDescInfo := make([]*DescriptionInfo, 0)
-	for _, eachServiceDecorator := range hooks.ServiceDecorators {
-		describable, isDescribable := eachServiceDecorator.(Describable)
-		if isDescribable {
-			descInfos, descInfosErr := describable.Describe(serviceName)
-			if descInfosErr != nil {
-				return nil, descInfosErr
-			}
-			workflowDescInfo = append(workflowDescInfo, descInfos)
-		}
-	}
-	return workflowDescInfo, nil
-}
-
 // Describe produces a graphical representation of a service's Lambda and data sources.  Typically
 // automatically called as part of a compiled golang binary via the `describe` command
 // line option.
@@ -49,6 +29,82 @@ func Describe(serviceName string,
 	outputWriter io.Writer,
 	workflowHooks *WorkflowHooks,
 	logger *zerolog.Logger) error {
+	return DescribeWithFormat(serviceName,
+		serviceDescription,
+		lambdaAWSInfos,
+		api,
+		site,
+		s3BucketName,
+		buildTags,
+		linkerFlags,
+		DescribeFormatHTML,
+		outputWriter,
+		workflowHooks,
+		logger)
+}
+
+// DescribeOpenAPI writes the OpenAPI 3.0 document for the service's
+// REST API to outputWriter. See API.OpenAPI
+func DescribeOpenAPI(api APIGateway,
+	outputWriter io.Writer,
+	logger *zerolog.Logger) error {
+	restAPI, restAPIOk := api.(*API)
+	if !restAPIOk || restAPI == nil {
+		return errors.Errorf("OpenAPI output requires a REST API created with NewAPIGateway")
+	}
+	doc, docErr := restAPI.OpenAPI()
+	if docErr != nil {
+		return docErr
+	}
+	encoder := json.NewEncoder(outputWriter)
+	encoder.SetIndent("", "  ")
+	encodeErr := encoder.Encode(doc)
+	if encodeErr != nil {
+		return errors.Wrapf(encodeErr, "Failed to write OpenAPI document")
+	}
+	logger.Info().
+		Int("PathCount", len(doc.Paths)).
+		Msg("Created OpenAPI document")
+	return nil
+}
+
+// DescribeWithFormat produces a representation of a service's Lambda and data
+// sources in the outputFormat (DescribeFormatHTML, DescribeFormatMermaid,
+// DescribeFormatDOT or DescribeFormatJSON). Only the HTML format includes the
+// CloudFormation template. The other formats are produced offline.
+func DescribeWithFormat(serviceName string,
+	serviceDescription string,
+	lambdaAWSInfos []*LambdaAWSInfo,
+	api APIGateway,
+	site *S3Site,
+	s3BucketName string,
+	buildTags string,
+	linkerFlags string,
+	outputFormat string,
+	outputWriter io.Writer,
+	workflowHooks *WorkflowHooks,
+	logger *zerolog.Logger) error {
+
+	graph, graphErr := newDescribeGraph(serviceName,
+		serviceDescription,
+		lambdaAWSInfos,
+		api,
+		workflowHooks)
+	if graphErr != nil {
+		return graphErr
+	}
+	switch outputFormat {
+	case DescribeFormatMermaid:
+		return graph.writeMermaid(outputWriter)
+	case DescribeFormatDOT:
+		return graph.writeDOT(outputWriter)
+	case DescribeFormatJSON:
+		return graph.writeJSON(outputWriter)
+	case DescribeFormatHTML, "":
+		// Handled below
+	default:
+		return errors.Errorf("Unsupported describe format: %s", outputFormat)
+	}
 
 	// Multiwriter
 	templateFile, templateFileErr := templateOutputFile(optionsProvision.OutputDir,
@@ -118,97 +174,37 @@ func Describe(serviceName string,
 			descriptionNode.Name)
 	}
 
-	// Setup the root object
-	writeErr := describer.writeNodeWithParent(serviceName,
-		nodeColorService,
-		fullIconPath(&DescriptionIcon{
-			Category: "Res_Management-Governance",
-			Name:     "Res_48_Light/Res_AWS-CloudFormation_Stack_48_Light.png",
-		}),
-		"",
-		labelWeightBold)
-	if writeErr != nil {
-		return writeErr
-	}
-
-	parentMap := make(map[string]bool)
-	writeNodes := func(parent string, descriptionNodes []*DescriptionTriplet) error {
-		if parent != "" {
-			_, exists := parentMap[parent]
-			if !exists {
-				writeErr = describer.writeNodeWithParent(parent,
-					"#FF0000",
-					fullIconPath(nil),
-					"",
-					labelWeightBold)
-				if writeErr != nil {
-					return writeErr
-				}
-				parentMap[parent] = true
-			}
-		}
-
-		for _, eachDescNode := range descriptionNodes {
-			descDisplayInfo := eachDescNode.DisplayInfo
-			if descDisplayInfo == nil {
-				descDisplayInfo = &DescriptionDisplayInfo{}
-			}
-			writeErr = describer.writeNodeWithParent(eachDescNode.SourceNodeName,
-				descDisplayInfo.SourceNodeColor,
-				fullIconPath(descDisplayInfo.SourceIcon),
-				parent,
-				labelWeightNormal)
-			if writeErr != nil {
-				return writeErr
-			}
-			writeErr = describer.writeEdge(eachDescNode.SourceNodeName,
-				eachDescNode.TargetNodeName,
-				eachDescNode.ArcLabel)
-			if writeErr != nil {
-				return writeErr
-			}
-		}
-		return nil
-	}
-
-	for _, eachLambda := range lambdaAWSInfos {
-		descriptionNodes, descriptionNodesErr := eachLambda.Description(serviceName)
-		if descriptionNodesErr != nil {
-			return descriptionNodesErr
-		}
-		writeErr := writeNodes("Lambdas", descriptionNodes)
+	// The groups are compound parent nodes
+	for _, eachGroup := range graph.Groups {
+		writeErr := describer.writeNodeWithParent(eachGroup,
+			"#FF0000",
+			fullIconPath(nil),
+			"",
+			labelWeightBold)
 		if writeErr != nil {
 			return writeErr
 		}
 	}
-	// The API needs to know how to describe itself. So for that it needs an object that
-	// encapsulates writing the nodes and links...so let's go ahead
-	// and make that object, then supply it to the Describe interface function
-
-	// API?
-	if nil != api {
-		descriptionInfo, descriptionInfoErr := api.Describe(serviceName)
-		if descriptionInfoErr != nil {
-			return descriptionInfoErr
+	for _, eachNode := range graph.Nodes {
+		labelWeight := labelWeightNormal
+		if eachNode.Name == serviceName {
+			labelWeight = labelWeightBold
 		}
-		writeErr := writeNodes(descriptionInfo.Name, descriptionInfo.Nodes)
+		writeErr := describer.writeNodeWithParent(eachNode.Name,
+			eachNode.Color,
+			fullIconPath(eachNode.Icon),
+			eachNode.Group,
+			labelWeight)
 		if writeErr != nil {
 			return writeErr
 		}
 	}
-	// What about everything else...
-	workflowDescription, workflowDescriptionErr := workflowHooksDescriptionNodes(serviceName, workflowHooks)
-	if workflowDescriptionErr != nil {
-		return workflowDescriptionErr
-	}
-	for _, eachWorkflowDesc := range workflowDescription {
-		groupName := eachWorkflowDesc.Name
-		if groupName == "" {
-			groupName = "WorkflowHooks"
-		}
-		workflowDescriptionErr = writeNodes(groupName, eachWorkflowDesc.Nodes)
-		if workflowDescriptionErr != nil {
-			return workflowDescriptionErr
+	for _, eachEdge := range graph.Edges {
+		writeErr := describer.writeEdge(eachEdge.Source,
+			eachEdge.Target,
+			eachEdge.Label)
+		if writeErr != nil {
+			return writeErr
 		}
 	}
 
@@ -0,0 +1,344 @@
+//go:build !lambdabinary
+// +build !lambdabinary
+
+package sparta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// describeGraphNode is a vertex in the service graph
+type describeGraphNode struct {
+	Name  string           `json:"name"`
+	Group string           `json:"group,omitempty"`
+	Color string           `json:"color,omitempty"`
+	Icon  *DescriptionIcon `json:"icon,omitempty"`
+}
+
+// describeGraphEdge is a directed arc in the service graph
+type describeGraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Label  string `json:"label,omitempty"`
+}
+
+// describeGraph is the format independent service graph produced from
+// the DescriptionTriplet values of each describable resource
+type describeGraph struct {
+	Service     string               `json:"service"`
+	Description string               `json:"description,omitempty"`
+	Groups      []string             `json:"groups"`
+	Nodes       []*describeGraphNode `json:"nodes"`
+	Edges       []*describeGraphEdge `json:"edges"`
+	nodeIndex   map[string]*describeGraphNode
+	edgeIndex   map[string]bool
+}
+
+func (graph *describeGraph) addNode(name string,
+	group string,
+	displayInfo *DescriptionDisplayInfo) {
+	if displayInfo == nil {
+		displayInfo = &DescriptionDisplayInfo{}
+	}
+	existingNode, exists := graph.nodeIndex[name]
+	if exists {
+		// Prefer the first grouped declaration
+		if existingNode.Group == "" && group != "" {
+			existingNode.Group = group
+			existingNode.Color = displayInfo.SourceNodeColor
+			existingNode.Icon = displayInfo.SourceIcon
+		}
+		return
+	}
+	node := &describeGraphNode{
+		Name:  name,
+		Group: group,
+		Color: displayInfo.SourceNodeColor,
+		Icon:  displayInfo.SourceIcon,
+	}
+	graph.nodeIndex[name] = node
+	graph.Nodes = append(graph.Nodes, node)
+}
+
+func (graph *describeGraph) addEdge(source string, target string, label string) {
+	if source == "" || target == "" {
+		return
+	}
+	edgeKey := strings.Join([]string{source, target, label}, "\x00")
+	if graph.edgeIndex[edgeKey] {
+		return
+	}
+	graph.edgeIndex[edgeKey] = true
+	graph.Edges = append(graph.Edges, &describeGraphEdge{
+		Source: source,
+		Target: target,
+		Label:  label,
+	})
+}
+
+// addTriplets adds the source nodes and arcs to the named group
+func (graph *describeGraph) addTriplets(group string, triplets []*DescriptionTriplet) {
+	if group != "" {
+		groupExists := false
+		for _, eachGroup := range graph.Groups {
+			groupExists = groupExists || eachGroup == group
+		}
+		if !groupExists {
+			graph.Groups = append(graph.Groups, group)
+		}
+	}
+	for _, eachTriplet := range triplets {
+		graph.addNode(eachTriplet.SourceNodeName, group, eachTriplet.DisplayInfo)
+		graph.addEdge(eachTriplet.SourceNodeName,
+			eachTriplet.TargetNodeName,
+			eachTriplet.ArcLabel)
+	}
+}
+
+// finalize adds the nodes that are only referenced as arc targets and
+// sorts the graph so that the output is stable across builds
+func (graph *describeGraph) finalize() {
+	for _, eachEdge := range graph.Edges {
+		graph.addNode(eachEdge.Target, "", nil)
+	}
+	groupOrder := map[string]int{
+		"": -1,
+	}
+	for index, eachGroup := range graph.Groups {
+		groupOrder[eachGroup] = index
+	}
+	sort.SliceStable(graph.Nodes, func(i, j int) bool {
+		lhs, rhs := graph.Nodes[i], graph.Nodes[j]
+		if lhs.Group != rhs.Group {
+			return groupOrder[lhs.Group] < groupOrder[rhs.Group]
+		}
+		return lhs.Name < rhs.Name
+	})
+	sort.SliceStable(graph.Edges, func(i, j int) bool {
+		lhs, rhs := graph.Edges[i], graph.Edges[j]
+		if lhs.Source != rhs.Source {
+			return lhs.Source < rhs.Source
+		}
+		if lhs.Target != rhs.Target {
+			return lhs.Target < rhs.Target
+		}
+		return lhs.Label < rhs.Label
+	})
+}
+
+// newDescribeGraph collects the description information for the service
+// without calling any AWS APIs
+func newDescribeGraph(serviceName string,
+	serviceDescription string,
+	lambdaAWSInfos []*LambdaAWSInfo,
+	api APIGateway,
+	workflowHooks *WorkflowHooks) (*describeGraph, error) {
+
+	graph := &describeGraph{
+		Service:     serviceName,
+		Description: serviceDescription,
+		Groups:      make([]string, 0),
+		Nodes:       make([]*describeGraphNode, 0),
+		Edges:       make([]*describeGraphEdge, 0),
+		nodeIndex:   make(map[string]*describeGraphNode),
+		edgeIndex:   make(map[string]bool),
+	}
+	graph.addNode(serviceName, "", &DescriptionDisplayInfo{
+		SourceNodeColor: nodeColorService,
+		SourceIcon: &DescriptionIcon{
+			Category: "Res_Management-Governance",
+			Name:     "Res_48_Light/Res_AWS-CloudFormation_Stack_48_Light.png",
+		},
+	})
+
+	addDescribable := func(defaultGroupName string, describable Describable) error {
+		descriptionInfo, descriptionInfoErr := describable.Describe(serviceName)
+		if descriptionInfoErr != nil {
+			return descriptionInfoErr
+		}
+		if descriptionInfo == nil {
+			return nil
+		}
+		groupName := descriptionInfo.Name
+		if groupName == "" {
+			groupName = defaultGroupName
+		}
+		graph.addTriplets(groupName, descriptionInfo.Nodes)
+		return nil
+	}
+
+	for _, eachLambda := range lambdaAWSInfos {
+		descriptionNodes, descriptionNodesErr := eachLambda.Description(serviceName)
+		if descriptionNodesErr != nil {
+			return nil, descriptionNodesErr
+		}
+		graph.addTriplets("Lambdas", descriptionNodes)
+	}
+	// Decorators that create resources can describe them
+	for _, eachLambda := range lambdaAWSInfos {
+		for _, eachDecorator := range eachLambda.Decorators {
+			describable, isDescribable := eachDecorator.(Describable)
+			if isDescribable {
+				describeErr := addDescribable("Decorators", describable)
+				if describeErr != nil {
+					return nil, describeErr
+				}
+			}
+		}
+	}
+	if api != nil {
+		describeErr := addDescribable("APIGateway", api)
+		if describeErr != nil {
+			return nil, describeErr
+		}
+	}
+	if workflowHooks != nil {
+		for _, eachServiceDecorator := range workflowHooks.ServiceDecorators {
+			describable, isDescribable := eachServiceDecorator.(Describable)
+			if isDescribable {
+				describeErr := addDescribable("WorkflowHooks", describable)
+				if describeErr != nil {
+					return nil, describeErr
+				}
+			}
+		}
+	}
+	graph.finalize()
+	return graph, nil
+}
+
+// graphNodeID returns the stable identifier for the node
+func graphNodeID(nodeName string) string {
+	nodeID, nodeIDErr := cytoscapeNodeID(nodeName)
+	if nodeIDErr != nil {
+		nodeID = fmt.Sprintf("%x", nodeName)
+	}
+	return fmt.Sprintf("n%s", nodeID[0:12])
+}
+
+// graphNodeLabel returns the display label for the node
+func graphNodeLabel(nodeName string) string {
+	return strings.TrimSpace(strings.Trim(nodeName, "\""))
+}
+
+// nodesByGroup returns the nodes in each group, including the ungrouped nodes
+func (graph *describeGraph) nodesByGroup() map[string][]*describeGraphNode {
+	grouped := make(map[string][]*describeGraphNode)
+	for _, eachNode := range graph.Nodes {
+		grouped[eachNode.Group] = append(grouped[eachNode.Group], eachNode)
+	}
+	return grouped
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Writers
+////////////////////////////////////////////////////////////////////////////////
+
+func (graph *describeGraph) writeJSON(outputWriter io.Writer) error {
+	jsonBytes, jsonBytesErr := json.MarshalIndent(graph, "", "  ")
+	if jsonBytesErr != nil {
+		return errors.Wrapf(jsonBytesErr, "Failed to marshal service graph")
+	}
+	_, writeErr := fmt.Fprintf(outputWriter, "%s\n", string(jsonBytes))
+	return writeErr
+}
+
+func mermaidEscape(value string) string {
+	replacer := strings.NewReplacer("\"", "#quot;",
+		"\r", "",
+		"\n", "<br/>")
+	return replacer.Replace(strings.TrimSpace(value))
+}
+
+func (graph *describeGraph) writeMermaid(outputWriter io.Writer) error {
+	var builder strings.Builder
+	writeNode := func(indent string, node *describeGraphNode) {
+		fmt.Fprintf(&builder, "%s%s[\"%s\"]\n",
+			indent,
+			graphNodeID(node.Name),
+			mermaidEscape(graphNodeLabel(node.Name)))
+	}
+	builder.WriteString("flowchart LR\n")
+	grouped := graph.nodesByGroup()
+	for _, eachNode := range grouped[""] {
+		writeNode("  ", eachNode)
+	}
+	for groupIndex, eachGroup := range graph.Groups {
+		fmt.Fprintf(&builder, "  subgraph g%d[\"%s\"]\n", groupIndex, mermaidEscape(eachGroup))
+		for _, eachNode := range grouped[eachGroup] {
+			writeNode("    ", eachNode)
+		}
+		builder.WriteString("  end\n")
+	}
+	for _, eachEdge := range graph.Edges {
+		label := mermaidEscape(eachEdge.Label)
+		if label != "" {
+			fmt.Fprintf(&builder, "  %s -->|\"%s\"| %s\n",
+				graphNodeID(eachEdge.Source),
+				label,
+				graphNodeID(eachEdge.Target))
+		} else {
+			fmt.Fprintf(&builder, "  %s --> %s\n",
+				graphNodeID(eachEdge.Source),
+				graphNodeID(eachEdge.Target))
+		}
+	}
+	_, writeErr := io.WriteString(outputWriter, builder.String())
+	return writeErr
+}
+
+func dotEscape(value string) string {
+	replacer := strings.NewReplacer("\\", "\\\\",
+		"\"", "\\\"",
+		"\r", "",
+		"\n", "\\n")
+	return replacer.Replace(strings.TrimSpace(value))
+}
+
+func (graph *describeGraph) writeDOT(outputWriter io.Writer) error {
+	var builder strings.Builder
+	writeNode := func(indent string, node *describeGraphNode) {
+		attributes := fmt.Sprintf("label=\"%s\"", dotEscape(graphNodeLabel(node.Name)))
+		if node.Color != "" {
+			attributes = fmt.Sprintf("%s, color=\"%s\"", attributes, node.Color)
+		}
+		fmt.Fprintf(&builder, "%s%s [%s];\n",
+			indent,
+			graphNodeID(node.Name),
+			attributes)
+	}
+	fmt.Fprintf(&builder, "digraph \"%s\" {\n", dotEscape(graph.Service))
+	builder.WriteString("  rankdir=LR;\n")
+	builder.WriteString("  node [shape=box];\n")
+	grouped := graph.nodesByGroup()
+	for _, eachNode := range grouped[""] {
+		writeNode("  ", eachNode)
+	}
+	for groupIndex, eachGroup := range graph.Groups {
+		fmt.Fprintf(&builder, "  subgraph cluster_%d {\n", groupIndex)
+		fmt.Fprintf(&builder, "    label=\"%s\";\n", dotEscape(eachGroup))
+		for _, eachNode := range grouped[eachGroup] {
+			writeNode("    ", eachNode)
+		}
+		builder.WriteString("  }\n")
+	}
+	for _, eachEdge := range graph.Edges {
+		attributes := ""
+		if eachEdge.Label != "" {
+			attributes = fmt.Sprintf(" [label=\"%s\"]", dotEscape(eachEdge.Label))
+		}
+		fmt.Fprintf(&builder, "  %s -> %s%s;\n",
+			graphNodeID(eachEdge.Source),
+			graphNodeID(eachEdge.Target),
+			attributes)
+	}
+	builder.WriteString("}\n")
+	_, writeErr := io.WriteString(outputWriter, builder.String())
+	return writeErr
+}
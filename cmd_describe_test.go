@@ -1,7 +1,10 @@
 package sparta
 
 import (
+	"bytes"
+	"net/http"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/rs/zerolog"
@@ -30,3 +33,74 @@ func TestDescribe(t *testing.T) {
 		t.Errorf("Failed to describe: %s", err)
 	}
 }
+
+func TestDescribeFormats(t *testing.T) {
+	logger, _ := NewLogger(zerolog.InfoLevel.String())
+	lambdaFunctions := testLambdaData()
+	apiGateway := NewAPIGateway("SpartaAPIGateway", NewStage("v1"))
+	apiGatewayResource, _ := apiGateway.NewResource("/test", lambdaFunctions[0])
+	_, methodErr := apiGatewayResource.NewMethod("GET", http.StatusOK)
+	if methodErr != nil {
+		t.Fatalf("Failed to create method: %s", methodErr)
+	}
+	expectedContent := map[string][]string{
+		DescribeFormatMermaid: {
+			"flowchart LR",
+			"subgraph g0[\"Lambdas\"]",
+			"[\"GET - /test\"]",
+			"-->|\"s3:ObjectRemoved:*<br/>s3:ObjectCreated:*\"|",
+		},
+		DescribeFormatDOT: {
+			"digraph \"SampleService\" {",
+			"subgraph cluster_1 {",
+			"label=\"APIGateway\";",
+			"[label=\"GET - /test\", color=\"#06B5F5\"];",
+		},
+		DescribeFormatJSON: {
+			"\"service\": \"SampleService\"",
+			"\"name\": \"GET - /test\"",
+			"\"group\": \"APIGateway\"",
+		},
+	}
+	for eachFormat, eachExpected := range expectedContent {
+		var output bytes.Buffer
+		describeErr := DescribeWithFormat("SampleService",
+			"SampleService Description",
+			lambdaFunctions,
+			apiGateway,
+			nil,
+			"",
+			"",
+			"",
+			eachFormat,
+			&output,
+			nil,
+			logger)
+		if describeErr != nil {
+			t.Fatalf("Failed to describe %s: %s", eachFormat, describeErr)
+		}
+		for _, eachContent := range eachExpected {
+			if !strings.Contains(output.String(), eachContent) {
+				t.Fatalf("Failed to find %s in %s output:\n%s",
+					eachContent,
+					eachFormat,
+					output.String())
+			}
+		}
+	}
+	invalidErr := DescribeWithFormat("SampleService",
+		"",
+		lambdaFunctions,
+		nil,
+		nil,
+		"",
+		"",
+		"",
+		"svg",
+		&bytes.Buffer{},
+		nil,
+		logger)
+	if invalidErr == nil {
+		t.Fatalf("Failed to reject unsupported format")
+	}
+}
@@ -27,6 +27,19 @@ const (
 	defaultImagePath  = "/resources/describe/AWS-Architecture-Assets/General@4x.png"
 )
 
+const (
+	// DescribeFormatHTML is the interactive HTML report that includes
+	// the CloudFormation template
+	DescribeFormatHTML = "html"
+	// DescribeFormatMermaid is a Mermaid (https://mermaid-js.github.io)
+	// flowchart that can be embedded in Markdown documents
+	DescribeFormatMermaid = "mermaid"
+	// DescribeFormatDOT is a Graphviz DOT (https://graphviz.org) digraph
+	DescribeFormatDOT = "dot"
+	// DescribeFormatJSON is the JSON representation of the service graph
+	DescribeFormatJSON = "json"
+)
+
 // This is the `go` type that's shuttled through the JSON data
 // and parsed by the sparta.js script that's executed in the browser
 type cytoscapeData struct {
@@ -48,16 +48,6 @@ func awsLambdaFunctionName(internalFunctionName string) string {
 		sanitizedName)
 }
 
-func takesContext(handler reflect.Type) bool {
-	handlerTakesContext := false
-	if handler.NumIn() > 0 {
-		contextType := reflect.TypeOf((*context.Context)(nil)).Elem()
-		argumentType := handler.In(0)
-		handlerTakesContext = argumentType.Implements(contextType)
-	}
-	return handlerTakesContext
-}
-
 // tappedHandler is the handler that represents this binary's mode
 func tappedHandler(handlerSymbol interface{},
 	interceptors *LambdaEventInterceptors,
@@ -0,0 +1,232 @@
+//go:build !lambdabinary
+// +build !lambdabinary
+
+package sparta
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsv2Middleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awsv2CWLogsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	awsv2Lambda "github.com/aws/aws-sdk-go-v2/service/lambda"
+	awsv2LambdaTypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	spartaAWS "github.com/mweagle/Sparta/v3/aws"
+	spartaCWLogs "github.com/mweagle/Sparta/v3/aws/cloudwatch/logs"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// resolveFunctionName returns the physical name of the provisioned Lambda
+// function. The functionName can be the Sparta function name, the
+// CloudFormation logical resource name, the physical name or an ARN.
+func resolveFunctionName(ctx context.Context,
+	awsConfig awsv2.Config,
+	serviceName string,
+	lambdaAWSInfos []*LambdaAWSInfo,
+	functionName string) (string, error) {
+
+	if strings.HasPrefix(functionName, "arn:") {
+		return functionName, nil
+	}
+	functions, functionsErr := provisionedFunctions(ctx,
+		awsConfig,
+		serviceName,
+		lambdaAWSInfos)
+	if functionsErr != nil {
+		return "", functionsErr
+	}
+	provisionedNames := []string{}
+	for _, eachFunction := range functions {
+		if eachFunction.FunctionName == functionName ||
+			eachFunction.LogicalName == functionName ||
+			eachFunction.PhysicalName == functionName {
+			return eachFunction.PhysicalName, nil
+		}
+		provisionedNames = append(provisionedNames, eachFunction.FunctionName)
+	}
+	return "", errors.Errorf("Failed to find function %s in stack %s. Provisioned functions: %s",
+		functionName,
+		serviceName,
+		strings.Join(provisionedNames, ", "))
+}
+
+// unqualifiedFunctionName returns the function name from a name, partial
+// ARN or ARN, without any version or alias qualifier. The log group is
+// shared by all qualifiers.
+func unqualifiedFunctionName(functionName string) string {
+	if functionIndex := strings.Index(functionName, "function:"); functionIndex >= 0 {
+		functionName = functionName[functionIndex+len("function:"):]
+	}
+	return strings.Split(functionName, ":")[0]
+}
+
+// writeInvokePayload writes the indented JSON payload, or the raw
+// payload if it's not JSON
+func writeInvokePayload(outputWriter io.Writer, payload []byte) error {
+	if len(payload) == 0 {
+		return nil
+	}
+	var indented bytes.Buffer
+	indentErr := json.Indent(&indented, payload, "", "  ")
+	if indentErr == nil {
+		payload = indented.Bytes()
+	}
+	_, writeErr := fmt.Fprintf(outputWriter, "%s\n", strings.TrimSpace(string(payload)))
+	return writeErr
+}
+
+// followInvocationLogs writes the CloudWatch log messages for the function
+// to the outputWriter until the REPORT line for the requestID is logged
+func followInvocationLogs(ctx context.Context,
+	messages <-chan *awsv2CWLogsTypes.FilteredLogEvent,
+	requestID string,
+	outputWriter io.Writer,
+	logger *zerolog.Logger) error {
+	reportPrefix := fmt.Sprintf("REPORT RequestId: %s", requestID)
+	lastErrorMessage := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(),
+				"Failed to find log completion for request %s",
+				requestID)
+		case event := <-messages:
+			if event == nil || event.Message == nil {
+				continue
+			}
+			message := strings.TrimRight(*event.Message, "\n")
+			// Polling errors are returned as synthetic events
+			if event.EventId != nil && *event.EventId == "N/A" {
+				if message != lastErrorMessage {
+					logger.Warn().
+						Str("Error", message).
+						Msg("Waiting for CloudWatch Logs")
+					lastErrorMessage = message
+				}
+				continue
+			}
+			_, writeErr := fmt.Fprintln(outputWriter, message)
+			if writeErr != nil {
+				return writeErr
+			}
+			if strings.HasPrefix(message, reportPrefix) {
+				return nil
+			}
+		}
+	}
+}
+
+// Invoke calls the provisioned Lambda function with the eventPayload. The
+// response payload is written to the outputWriter. Synchronous invocations
+// also write the decoded tail of the execution log. If tail is true, the
+// function's CloudWatch Logs messages are written until the invocation
+// completes or the timeout expires. An error is returned if the function
+// returned an error.
+func Invoke(ctx context.Context,
+	serviceName string,
+	lambdaAWSInfos []*LambdaAWSInfo,
+	functionName string,
+	eventPayload []byte,
+	tail bool,
+	async bool,
+	timeout time.Duration,
+	outputWriter io.Writer,
+	logger *zerolog.Logger) error {
+
+	awsConfig, awsConfigErr := spartaAWS.NewConfig(ctx, logger)
+	if awsConfigErr != nil {
+		return awsConfigErr
+	}
+	physicalName, physicalNameErr := resolveFunctionName(ctx,
+		awsConfig,
+		serviceName,
+		lambdaAWSInfos,
+		functionName)
+	if physicalNameErr != nil {
+		return physicalNameErr
+	}
+	logger.Info().
+		Str("Function", physicalName).
+		Bool("Async", async).
+		Msg("Invoking function")
+
+	// Start tailing before the invocation so that no messages are missed
+	var messages <-chan *awsv2CWLogsTypes.FilteredLogEvent
+	if tail {
+		closeChan := make(chan bool, 1)
+		defer func() {
+			closeChan <- true
+		}()
+		logGroupName := fmt.Sprintf("/aws/lambda/%s", unqualifiedFunctionName(physicalName))
+		messages = spartaCWLogs.TailWithContext(ctx,
+			closeChan,
+			awsConfig,
+			logGroupName,
+			"",
+			logger)
+	}
+	lambdaInput := &awsv2Lambda.InvokeInput{
+		FunctionName: awsv2.String(physicalName),
+		Payload:      eventPayload,
+	}
+	if async {
+		lambdaInput.InvocationType = awsv2LambdaTypes.InvocationTypeEvent
+	} else {
+		lambdaInput.LogType = awsv2LambdaTypes.LogTypeTail
+	}
+	lambdaSvc := awsv2Lambda.NewFromConfig(awsConfig)
+	invokeOutput, invokeOutputErr := lambdaSvc.Invoke(ctx, lambdaInput)
+	if invokeOutputErr != nil {
+		return errors.Wrapf(invokeOutputErr, "Failed to invoke %s", physicalName)
+	}
+	requestID, _ := awsv2Middleware.GetRequestIDMetadata(invokeOutput.ResultMetadata)
+	logger.Info().
+		Str("RequestID", requestID).
+		Int32("StatusCode", invokeOutput.StatusCode).
+		Msg("Function invoked")
+
+	if tail {
+		logSectionHeader("CloudWatch Logs", dividerLength, logger)
+		followCtx, followCancel := context.WithTimeout(ctx, timeout)
+		defer followCancel()
+		followErr := followInvocationLogs(followCtx,
+			messages,
+			requestID,
+			outputWriter,
+			logger)
+		if followErr != nil {
+			return followErr
+		}
+	} else if invokeOutput.LogResult != nil {
+		logBytes, logBytesErr := base64.StdEncoding.DecodeString(*invokeOutput.LogResult)
+		if logBytesErr != nil {
+			return errors.Wrapf(logBytesErr, "Failed to decode log result")
+		}
+		logSectionHeader("Log Tail", dividerLength, logger)
+		_, writeErr := io.WriteString(outputWriter, string(logBytes))
+		if writeErr != nil {
+			return writeErr
+		}
+	}
+	if !async {
+		logSectionHeader("Response", dividerLength, logger)
+		writeErr := writeInvokePayload(outputWriter, invokeOutput.Payload)
+		if writeErr != nil {
+			return writeErr
+		}
+	}
+	if invokeOutput.FunctionError != nil {
+		return errors.Errorf("Function %s returned an error: %s",
+			physicalName,
+			*invokeOutput.FunctionError)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+//go:build !lambdabinary
+// +build !lambdabinary
+
+package sparta
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsv2CWLogsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/rs/zerolog"
+)
+
+func TestInvokeFollowLogs(t *testing.T) {
+	logger, _ := NewLogger(zerolog.InfoLevel.String())
+	requestID := "6bc28136-xmpl-4365-b021-0ce6b2e64ab0"
+	messages := make(chan *awsv2CWLogsTypes.FilteredLogEvent, 8)
+	for _, eachMessage := range []string{
+		"START RequestId: " + requestID + " Version: $LATEST\n",
+		"Hello World\n",
+		"END RequestId: " + requestID + "\n",
+		"REPORT RequestId: " + requestID + "\tDuration: 1.02 ms\n",
+		"Unreachable\n",
+	} {
+		messages <- &awsv2CWLogsTypes.FilteredLogEvent{
+			EventId: awsv2.String("1"),
+			Message: awsv2.String(eachMessage),
+		}
+	}
+	// Errors are logged, not written
+	messages <- &awsv2CWLogsTypes.FilteredLogEvent{
+		EventId: awsv2.String("N/A"),
+		Message: awsv2.String("ResourceNotFoundException"),
+	}
+	var output bytes.Buffer
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	followErr := followInvocationLogs(ctx, messages, requestID, &output, logger)
+	if followErr != nil {
+		t.Fatalf("Failed to follow logs: %s", followErr)
+	}
+	if !strings.Contains(output.String(), "Hello World\n") ||
+		strings.Contains(output.String(), "Unreachable") {
+		t.Fatalf("Unexpected log output: %s", output.String())
+	}
+	// No REPORT line should time out
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer shortCancel()
+	followErr = followInvocationLogs(shortCtx, messages, "missing", &output, logger)
+	if followErr == nil {
+		t.Fatalf("Expected timeout error for missing request")
+	}
+}
+
+func TestInvokePayload(t *testing.T) {
+	var output bytes.Buffer
+	writeErr := writeInvokePayload(&output, []byte(`{"hello":"world"}`))
+	if writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	if output.String() != "{\n  \"hello\": \"world\"\n}\n" {
+		t.Fatalf("Unexpected JSON payload output: %q", output.String())
+	}
+	output.Reset()
+	writeErr = writeInvokePayload(&output, []byte("not json"))
+	if writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	if output.String() != "not json\n" {
+		t.Fatalf("Unexpected raw payload output: %q", output.String())
+	}
+}
+
+func TestInvokeUnqualifiedFunctionName(t *testing.T) {
+	testCases := map[string]string{
+		"myFunction":                       "myFunction",
+		"myFunction:live":                  "myFunction",
+		"123456789012:function:myFunction": "myFunction",
+		"arn:aws:lambda:us-west-2:123456789012:function:myFunction":      "myFunction",
+		"arn:aws:lambda:us-west-2:123456789012:function:myFunction:live": "myFunction",
+		"arn:aws:lambda:us-west-2:123456789012:function:myFunction:3":    "myFunction",
+	}
+	for eachName, eachExpected := range testCases {
+		unqualified := unqualifiedFunctionName(eachName)
+		if unqualified != eachExpected {
+			t.Fatalf("Unexpected function name for %s: %s", eachName, unqualified)
+		}
+	}
+}
+
+func TestInvokeLoggerOutput(t *testing.T) {
+	if commandLoggerOutput(CommandLineOptions.Invoke) != os.Stderr {
+		t.Fatalf("Expected invoke log output to be written to stderr")
+	}
+}
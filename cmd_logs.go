@@ -0,0 +1,316 @@
+//go:build !lambdabinary
+// +build !lambdabinary
+
+package sparta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	awsv2CWLogsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	spartaAWS "github.com/mweagle/Sparta/v3/aws"
+	spartaCWLogs "github.com/mweagle/Sparta/v3/aws/cloudwatch/logs"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// logsTimestampFormat is the format for the log event timestamp prefix
+	logsTimestampFormat = "2006-01-02T15:04:05.000Z07:00"
+	// logsInsightsTimestampFormat is the format of the @timestamp field
+	// in Insights results
+	logsInsightsTimestampFormat = "2006-01-02 15:04:05.000"
+	// logsInsightsDefaultWindow is the query window if --since isn't provided
+	logsInsightsDefaultWindow = time.Hour
+)
+
+// logsExcludedFields are the context-scoped logger fields that are
+// repeated in every message and omitted from the pretty-printed output
+var logsExcludedFields = []string{
+	LogFieldARN,
+	LogFieldBuildID,
+	LogFieldInstanceID,
+}
+
+// logsFilter selects the log messages written by the logs command
+type logsFilter struct {
+	minLevel  zerolog.Level
+	requestID string
+	pattern   *regexp.Regexp
+}
+
+func newLogsFilter(minLevel string, requestID string, pattern string) (*logsFilter, error) {
+	filter := &logsFilter{
+		minLevel:  zerolog.NoLevel,
+		requestID: requestID,
+	}
+	if minLevel != "" {
+		level, levelErr := zerolog.ParseLevel(minLevel)
+		if levelErr != nil {
+			return nil, errors.Wrapf(levelErr, "Invalid log level")
+		}
+		filter.minLevel = level
+	}
+	if pattern != "" {
+		regex, regexErr := regexp.Compile(pattern)
+		if regexErr != nil {
+			return nil, errors.Wrapf(regexErr, "Invalid log filter expression")
+		}
+		filter.pattern = regex
+	}
+	return filter, nil
+}
+
+// matches returns true if the message should be included. Messages that
+// aren't zerolog JSON don't have a level and are excluded by a level filter.
+func (filter *logsFilter) matches(message string, fields map[string]interface{}) bool {
+	if filter.minLevel != zerolog.NoLevel {
+		levelValue, levelValueOk := fields[zerolog.LevelFieldName].(string)
+		if !levelValueOk {
+			return false
+		}
+		level, levelErr := zerolog.ParseLevel(levelValue)
+		if levelErr != nil || level < filter.minLevel {
+			return false
+		}
+	}
+	if filter.requestID != "" && !strings.Contains(message, filter.requestID) {
+		return false
+	}
+	if filter.pattern != nil && !filter.pattern.MatchString(message) {
+		return false
+	}
+	return true
+}
+
+// parseLogMessage returns the trimmed message and the zerolog fields if
+// the message is a JSON object
+func parseLogMessage(message string) (string, map[string]interface{}) {
+	message = strings.TrimRight(message, "\r\n")
+	if !strings.HasPrefix(message, "{") {
+		return message, nil
+	}
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(message))
+	decoder.UseNumber()
+	if decoder.Decode(&fields) != nil {
+		return message, nil
+	}
+	return message, fields
+}
+
+// logsFormatter writes the log messages prefixed with the function name and
+// the event timestamp
+type logsFormatter struct {
+	outputWriter io.Writer
+	nameWidth    int
+}
+
+func (formatter *logsFormatter) write(functionName string,
+	timestamp time.Time,
+	message string,
+	fields map[string]interface{}) error {
+
+	if fields != nil {
+		for _, eachField := range logsExcludedFields {
+			delete(fields, eachField)
+		}
+		jsonBytes, jsonBytesErr := json.Marshal(fields)
+		if jsonBytesErr == nil {
+			var pretty bytes.Buffer
+			consoleWriter := zerolog.ConsoleWriter{
+				Out:          &pretty,
+				NoColor:      true,
+				PartsExclude: []string{zerolog.TimestampFieldName},
+			}
+			_, writeErr := consoleWriter.Write(jsonBytes)
+			if writeErr == nil {
+				message = strings.TrimSpace(pretty.String())
+			}
+		}
+	}
+	_, writeErr := fmt.Fprintf(formatter.outputWriter,
+		"%-*s %s %s\n",
+		formatter.nameWidth,
+		functionName,
+		timestamp.Format(logsTimestampFormat),
+		message)
+	return writeErr
+}
+
+// Logs tails the CloudWatch Logs for every function in the service. Log
+// events are interleaved by timestamp and prefixed with the function name.
+// zerolog JSON messages are pretty printed. Messages can be filtered by
+// minimum level, request ID and a regular expression. If since is non-zero,
+// the messages logged over that duration are included. If insightsQuery
+// is non-empty, the CloudWatch Logs Insights query is run across all of
+// the service's log groups instead of tailing the logs.
+func Logs(ctx context.Context,
+	serviceName string,
+	lambdaAWSInfos []*LambdaAWSInfo,
+	minLevel string,
+	requestID string,
+	pattern string,
+	since time.Duration,
+	insightsQuery string,
+	outputWriter io.Writer,
+	logger *zerolog.Logger) error {
+
+	filter, filterErr := newLogsFilter(minLevel, requestID, pattern)
+	if filterErr != nil {
+		return filterErr
+	}
+	awsConfig, awsConfigErr := spartaAWS.NewConfig(ctx, logger)
+	if awsConfigErr != nil {
+		return awsConfigErr
+	}
+	functions, functionsErr := provisionedFunctions(ctx,
+		awsConfig,
+		serviceName,
+		lambdaAWSInfos)
+	if functionsErr != nil {
+		return functionsErr
+	}
+	if len(functions) == 0 {
+		return errors.Errorf("Stack %s does not include any functions", serviceName)
+	}
+	formatter := &logsFormatter{
+		outputWriter: outputWriter,
+	}
+	logGroupNames := make([]string, 0, len(functions))
+	functionNames := make(map[string]string)
+	for _, eachFunction := range functions {
+		logGroupNames = append(logGroupNames, eachFunction.logGroupName())
+		functionNames[eachFunction.logGroupName()] = eachFunction.FunctionName
+		if len(eachFunction.FunctionName) > formatter.nameWidth {
+			formatter.nameWidth = len(eachFunction.FunctionName)
+		}
+	}
+
+	if insightsQuery != "" {
+		if since == 0 {
+			since = logsInsightsDefaultWindow
+		}
+		endTime := time.Now()
+		logger.Info().
+			Str("Query", insightsQuery).
+			Time("StartTime", endTime.Add(-since)).
+			Int("LogGroupCount", len(logGroupNames)).
+			Msg("Running CloudWatch Logs Insights query")
+		results, resultsErr := spartaCWLogs.QueryWithContext(ctx,
+			awsConfig,
+			logGroupNames,
+			insightsQuery,
+			endTime.Add(-since),
+			endTime,
+			logger)
+		if resultsErr != nil {
+			return resultsErr
+		}
+		return writeInsightsResults(results, functionNames, filter, formatter)
+	}
+
+	closeChan := make(chan bool, 1)
+	defer func() {
+		closeChan <- true
+	}()
+	messages := spartaCWLogs.TailGroupsWithContext(ctx,
+		closeChan,
+		awsConfig,
+		logGroupNames,
+		"",
+		time.Now().Add(-since),
+		logger)
+	logger.Info().
+		Int("FunctionCount", len(functions)).
+		Msg("Tailing function logs. Press Ctrl+C to exit.")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-messages:
+			if event.Message == nil || event.Timestamp == nil {
+				continue
+			}
+			// Polling errors are returned as synthetic events
+			if event.EventId != nil && *event.EventId == "N/A" {
+				logger.Warn().
+					Str("LogGroup", event.LogGroupName).
+					Str("Error", *event.Message).
+					Msg("Failed to poll CloudWatch Logs")
+				continue
+			}
+			message, fields := parseLogMessage(*event.Message)
+			if !filter.matches(message, fields) {
+				continue
+			}
+			writeErr := formatter.write(functionNames[event.LogGroupName],
+				time.Unix(0, *event.Timestamp*int64(time.Millisecond)),
+				message,
+				fields)
+			if writeErr != nil {
+				return writeErr
+			}
+		}
+	}
+}
+
+// writeInsightsResults writes the Insights result rows. Rows with a @message
+// field are filtered and formatted like tailed log events. Other rows are
+// written as tab-separated field=value pairs.
+func writeInsightsResults(results [][]awsv2CWLogsTypes.ResultField,
+	functionNames map[string]string,
+	filter *logsFilter,
+	formatter *logsFormatter) error {
+
+	for _, eachRow := range results {
+		rowFields := make(map[string]string)
+		rowFieldNames := make([]string, 0, len(eachRow))
+		for _, eachField := range eachRow {
+			if eachField.Field == nil || eachField.Value == nil ||
+				*eachField.Field == "@ptr" {
+				continue
+			}
+			rowFields[*eachField.Field] = *eachField.Value
+			rowFieldNames = append(rowFieldNames, *eachField.Field)
+		}
+		rawMessage, hasMessage := rowFields["@message"]
+		if !hasMessage {
+			pairs := make([]string, 0, len(rowFieldNames))
+			for _, eachName := range rowFieldNames {
+				pairs = append(pairs, fmt.Sprintf("%s=%s", eachName, rowFields[eachName]))
+			}
+			_, writeErr := fmt.Fprintln(formatter.outputWriter, strings.Join(pairs, "\t"))
+			if writeErr != nil {
+				return writeErr
+			}
+			continue
+		}
+		message, fields := parseLogMessage(rawMessage)
+		if !filter.matches(message, fields) {
+			continue
+		}
+		// @log is "<accountID>:<logGroupName>"
+		logParts := strings.SplitN(rowFields["@log"], ":", 2)
+		functionName := functionNames[logParts[len(logParts)-1]]
+		if functionName == "" {
+			functionName = "-"
+		}
+		timestamp, timestampErr := time.Parse(logsInsightsTimestampFormat, rowFields["@timestamp"])
+		if timestampErr != nil {
+			timestamp = time.Time{}
+		}
+		writeErr := formatter.write(functionName, timestamp, message, fields)
+		if writeErr != nil {
+			return writeErr
+		}
+	}
+	return nil
+}
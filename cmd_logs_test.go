@@ -0,0 +1,163 @@
+//go:build !lambdabinary
+// +build !lambdabinary
+
+package sparta
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsv2CWLogsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+const testLogsRequestID = "6bc28136-xmpl-4365-b021-0ce6b2e64ab0"
+
+var testLogsMessages = []string{
+	"START RequestId: " + testLogsRequestID + " Version: $LATEST\n",
+	`{"level":"debug","reqID":"` + testLogsRequestID + `","arn":"arn:aws:lambda:us-west-2:123412341234:function:Hello","message":"Debug details"}` + "\n",
+	`{"level":"error","reqID":"` + testLogsRequestID + `","error":"boom","message":"Request failed"}` + "\n",
+	`{"level":"info","reqID":"other","message":"Other request"}` + "\n",
+}
+
+func testLogsFilterMatches(filter *logsFilter) []string {
+	matched := []string{}
+	for _, eachMessage := range testLogsMessages {
+		message, fields := parseLogMessage(eachMessage)
+		if filter.matches(message, fields) {
+			matched = append(matched, message)
+		}
+	}
+	return matched
+}
+
+func TestLogsFilter(t *testing.T) {
+	type testCase struct {
+		minLevel  string
+		requestID string
+		pattern   string
+		expected  int
+	}
+	testCases := []testCase{
+		{expected: 4},
+		{minLevel: "info", expected: 2},
+		{minLevel: "error", expected: 1},
+		{requestID: testLogsRequestID, expected: 3},
+		{minLevel: "debug", requestID: testLogsRequestID, expected: 2},
+		{pattern: `Request\s+failed`, expected: 1},
+	}
+	for _, eachTestCase := range testCases {
+		filter, filterErr := newLogsFilter(eachTestCase.minLevel,
+			eachTestCase.requestID,
+			eachTestCase.pattern)
+		if filterErr != nil {
+			t.Fatal(filterErr)
+		}
+		matched := testLogsFilterMatches(filter)
+		if len(matched) != eachTestCase.expected {
+			t.Fatalf("Expected %d matches for %#v. Found: %#v",
+				eachTestCase.expected,
+				eachTestCase,
+				matched)
+		}
+	}
+	_, filterErr := newLogsFilter("", "", "(unclosed")
+	if filterErr == nil {
+		t.Fatalf("Expected error for invalid regular expression")
+	}
+	_, filterErr = newLogsFilter("loud", "", "")
+	if filterErr == nil {
+		t.Fatalf("Expected error for invalid log level")
+	}
+}
+
+func TestLogsFormatter(t *testing.T) {
+	var output bytes.Buffer
+	formatter := &logsFormatter{
+		outputWriter: &output,
+		nameWidth:    8,
+	}
+	timestamp := time.Date(2021, 10, 1, 12, 30, 0, 0, time.UTC)
+	for _, eachMessage := range testLogsMessages[0:3] {
+		message, fields := parseLogMessage(eachMessage)
+		writeErr := formatter.write("Hello", timestamp, message, fields)
+		if writeErr != nil {
+			t.Fatal(writeErr)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Unexpected output: %s", output.String())
+	}
+	if !strings.HasPrefix(lines[0], "Hello    2021-10-01T12:30:00.000Z START RequestId:") {
+		t.Fatalf("Unexpected raw output: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "DBG Debug details") ||
+		strings.Contains(lines[1], "arn:aws:lambda") {
+		t.Fatalf("Unexpected zerolog output: %s", lines[1])
+	}
+	if !strings.Contains(lines[2], "ERR Request failed error=boom") {
+		t.Fatalf("Unexpected zerolog error output: %s", lines[2])
+	}
+}
+
+func TestLogsInsightsResults(t *testing.T) {
+	field := func(name string, value string) awsv2CWLogsTypes.ResultField {
+		return awsv2CWLogsTypes.ResultField{
+			Field: awsv2.String(name),
+			Value: awsv2.String(value),
+		}
+	}
+	results := [][]awsv2CWLogsTypes.ResultField{
+		{
+			field("@timestamp", "2021-10-01 12:30:00.000"),
+			field("@log", "123412341234:/aws/lambda/Hello-ABCD"),
+			field("@message", testLogsMessages[2]),
+			field("@ptr", "ignored"),
+		},
+		{
+			field("bin(5m)", "2021-10-01 12:30:00.000"),
+			field("count()", "42"),
+		},
+	}
+	var output bytes.Buffer
+	filter, _ := newLogsFilter("", "", "")
+	writeErr := writeInsightsResults(results,
+		map[string]string{"/aws/lambda/Hello-ABCD": "Hello"},
+		filter,
+		&logsFormatter{outputWriter: &output})
+	if writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 ||
+		!strings.HasPrefix(lines[0], "Hello 2021-10-01T12:30:00.000Z ERR Request failed") ||
+		lines[1] != "bin(5m)=2021-10-01 12:30:00.000\tcount()=42" {
+		t.Fatalf("Unexpected Insights output: %s", output.String())
+	}
+}
+
+func TestLogsOptionsValidation(t *testing.T) {
+	validate := validator.New()
+	validOptions := []optionsLogsStruct{
+		{},
+		{MinLevel: "warn", Since: time.Hour},
+	}
+	for _, eachOptions := range validOptions {
+		if validateErr := validate.Struct(eachOptions); validateErr != nil {
+			t.Fatalf("Expected valid options %#v: %s", eachOptions, validateErr)
+		}
+	}
+	invalidOptions := []optionsLogsStruct{
+		{MinLevel: "loud"},
+		{Since: -time.Hour},
+	}
+	for _, eachOptions := range invalidOptions {
+		if validate.Struct(eachOptions) == nil {
+			t.Fatalf("Expected invalid options %#v", eachOptions)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+//go:build !lambdabinary
+// +build !lambdabinary
+
+package sparta
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsv2CF "github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	awsv2CFTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// provisionedFunction is a Lambda function provisioned by the service stack
+type provisionedFunction struct {
+	// The Sparta function name, or the physical name if the function isn't
+	// in the set of LambdaAWSInfo values
+	FunctionName string
+	LogicalName  string
+	PhysicalName string
+}
+
+// logGroupName returns the CloudWatch Logs group for the function
+func (pf *provisionedFunction) logGroupName() string {
+	return fmt.Sprintf("/aws/lambda/%s", pf.PhysicalName)
+}
+
+// stackResourceSummaries returns all the resources in the service stack
+func stackResourceSummaries(ctx context.Context,
+	awsConfig awsv2.Config,
+	serviceName string) ([]awsv2CFTypes.StackResourceSummary, error) {
+
+	cfSvc := awsv2CF.NewFromConfig(awsConfig)
+	input := &awsv2CF.ListStackResourcesInput{
+		StackName: awsv2.String(serviceName),
+	}
+	summaries := make([]awsv2CFTypes.StackResourceSummary, 0)
+	paginator := awsv2CF.NewListStackResourcesPaginator(cfSvc, input)
+	for paginator.HasMorePages() {
+		page, pageErr := paginator.NextPage(ctx)
+		if pageErr != nil {
+			return nil, pageErr
+		}
+		summaries = append(summaries, page.StackResourceSummaries...)
+	}
+	return summaries, nil
+}
+
+// stackResourcesOfType returns the logical to physical name mapping
+// for the provisioned resources of the given type
+func stackResourcesOfType(summaries []awsv2CFTypes.StackResourceSummary,
+	resourceType string) map[string]string {
+	resources := make(map[string]string)
+	for _, eachSummary := range summaries {
+		if eachSummary.ResourceType == nil ||
+			*eachSummary.ResourceType != resourceType ||
+			eachSummary.PhysicalResourceId == nil {
+			continue
+		}
+		resources[*eachSummary.LogicalResourceId] = *eachSummary.PhysicalResourceId
+	}
+	return resources
+}
+
+// newProvisionedFunctions returns the Lambda functions in the set of stack
+// resources, sorted by function name
+func newProvisionedFunctions(summaries []awsv2CFTypes.StackResourceSummary,
+	lambdaAWSInfos []*LambdaAWSInfo) []*provisionedFunction {
+
+	functionNames := make(map[string]string)
+	for _, eachInfo := range lambdaAWSInfos {
+		functionNames[eachInfo.LogicalResourceName()] = eachInfo.lambdaFunctionName()
+	}
+	functions := make([]*provisionedFunction, 0)
+	for eachLogicalName, eachPhysicalName := range stackResourcesOfType(summaries,
+		"AWS::Lambda::Function") {
+		function := &provisionedFunction{
+			FunctionName: functionNames[eachLogicalName],
+			LogicalName:  eachLogicalName,
+			PhysicalName: eachPhysicalName,
+		}
+		if function.FunctionName == "" {
+			function.FunctionName = function.PhysicalName
+		}
+		functions = append(functions, function)
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].FunctionName < functions[j].FunctionName
+	})
+	return functions
+}
+
+// provisionedFunctions returns the Lambda functions in the service stack,
+// sorted by function name
+func provisionedFunctions(ctx context.Context,
+	awsConfig awsv2.Config,
+	serviceName string,
+	lambdaAWSInfos []*LambdaAWSInfo) ([]*provisionedFunction, error) {
+
+	summaries, summariesErr := stackResourceSummaries(ctx, awsConfig, serviceName)
+	if summariesErr != nil {
+		return nil, summariesErr
+	}
+	return newProvisionedFunctions(summaries, lambdaAWSInfos), nil
+}
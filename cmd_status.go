@@ -5,17 +5,156 @@ package sparta
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsv2CF "github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	awsv2CFTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	awsv2CW "github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	awsv2CWTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	awsv2Lambda "github.com/aws/aws-sdk-go-v2/service/lambda"
+	awsv2LambdaTypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
 	awsv2STS "github.com/aws/aws-sdk-go-v2/service/sts"
 	spartaAWS "github.com/mweagle/Sparta/v3/aws"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
+// statusMetricsWindow is the window for the function invocation and
+// error counts
+const statusMetricsWindow = time.Hour
+
+// statusMaxMetricQueries is the maximum number of GetMetricData queries
+// per request
+const statusMaxMetricQueries = 500
+
+// statusSLOPeriod is the GetMetricData period used to compute the bad
+// event ratio over an SLO window
+const statusSLOPeriod = time.Hour
+
+// statusMaxAlarmNames is the maximum number of DescribeAlarms names
+// per request
+const statusMaxAlarmNames = 100
+
+// StatusOutput is a stack output in the StatusReport
+type StatusOutput struct {
+	Key        string `json:"key"`
+	Value      string `json:"value"`
+	ExportName string `json:"exportName,omitempty"`
+}
+
+// StatusFunction is the configuration and recent activity of a function
+// in the StatusReport
+type StatusFunction struct {
+	Name             string `json:"name"`
+	LogicalName      string `json:"logicalName"`
+	PhysicalName     string `json:"physicalName"`
+	Runtime          string `json:"runtime,omitempty"`
+	MemorySize       int32  `json:"memorySize,omitempty"`
+	Timeout          int32  `json:"timeout,omitempty"`
+	Version          string `json:"version,omitempty"`
+	LastModified     string `json:"lastModified,omitempty"`
+	CodeSha256       string `json:"codeSha256,omitempty"`
+	State            string `json:"state,omitempty"`
+	LastUpdateStatus string `json:"lastUpdateStatus,omitempty"`
+	Invocations      int64  `json:"invocations"`
+	Errors           int64  `json:"errors"`
+}
+
+// StatusAlarm is the state of a CloudWatch alarm provisioned by the stack,
+// including those created by decorator.CloudWatchErrorAlarmDecorator
+type StatusAlarm struct {
+	Name         string     `json:"name"`
+	LogicalName  string     `json:"logicalName"`
+	State        string     `json:"state"`
+	StateReason  string     `json:"stateReason,omitempty"`
+	StateUpdated *time.Time `json:"stateUpdated,omitempty"`
+}
+
+// StatusSLO is the remaining error budget of a service level objective
+// created by decorator.ServiceLevelObjective
+type StatusSLO struct {
+	Name      string  `json:"name"`
+	Objective float64 `json:"objective"`
+	Window    string  `json:"window"`
+	// Events is false if there were no events in the window
+	Events bool `json:"events"`
+	// SLI is the percentage of good events in the window
+	SLI float64 `json:"sli"`
+	// ErrorBudgetRemaining is the unused fraction of the error budget. It
+	// is negative if the budget is exhausted.
+	ErrorBudgetRemaining float64 `json:"errorBudgetRemaining"`
+}
+
+// StatusReport is the machine readable status of a provisioned service
+type StatusReport struct {
+	ServiceName       string            `json:"serviceName"`
+	Region            string            `json:"region"`
+	Exists            bool              `json:"exists"`
+	StackID           string            `json:"stackId,omitempty"`
+	Description       string            `json:"description,omitempty"`
+	StackStatus       string            `json:"stackStatus,omitempty"`
+	StackStatusReason string            `json:"stackStatusReason,omitempty"`
+	CreationTime      *time.Time        `json:"creationTime,omitempty"`
+	LastUpdatedTime   *time.Time        `json:"lastUpdatedTime,omitempty"`
+	BuildID           string            `json:"buildId,omitempty"`
+	Parameters        map[string]string `json:"parameters,omitempty"`
+	Tags              map[string]string `json:"tags,omitempty"`
+	Outputs           []*StatusOutput   `json:"outputs,omitempty"`
+	Functions         []*StatusFunction `json:"functions,omitempty"`
+	Alarms            []*StatusAlarm    `json:"alarms,omitempty"`
+	SLOs              []*StatusSLO      `json:"slos,omitempty"`
+	// MetricsWindow is the window for the function Invocations and Errors
+	MetricsWindow string `json:"metricsWindow"`
+	// Healthy is true if the stack is stable, every function is active and
+	// no alarm is in the ALARM state
+	Healthy       bool     `json:"healthy"`
+	HealthReasons []string `json:"healthReasons,omitempty"`
+}
+
+// evaluateHealth sets the report's health from the stack, function and
+// alarm states
+func (report *StatusReport) evaluateHealth() {
+	reasons := make([]string, 0)
+	if !report.Exists {
+		reasons = append(reasons, "Stack does not exist")
+	} else {
+		switch awsv2CFTypes.StackStatus(report.StackStatus) {
+		case awsv2CFTypes.StackStatusCreateComplete,
+			awsv2CFTypes.StackStatusUpdateComplete,
+			awsv2CFTypes.StackStatusImportComplete:
+			// NOP
+		default:
+			reasons = append(reasons, fmt.Sprintf("Stack status is %s", report.StackStatus))
+		}
+	}
+	for _, eachFunction := range report.Functions {
+		if eachFunction.State != "" &&
+			eachFunction.State != string(awsv2LambdaTypes.StateActive) {
+			reasons = append(reasons, fmt.Sprintf("Function %s state is %s",
+				eachFunction.Name,
+				eachFunction.State))
+		}
+		if eachFunction.LastUpdateStatus == string(awsv2LambdaTypes.LastUpdateStatusFailed) {
+			reasons = append(reasons, fmt.Sprintf("Function %s last update failed",
+				eachFunction.Name))
+		}
+	}
+	for _, eachAlarm := range report.Alarms {
+		if eachAlarm.State == string(awsv2CWTypes.StateValueAlarm) {
+			reasons = append(reasons, fmt.Sprintf("Alarm %s is in the ALARM state",
+				eachAlarm.Name))
+		}
+	}
+	report.HealthReasons = reasons
+	report.Healthy = len(reasons) == 0
+}
+
 func logSectionHeader(text string,
 	dividerWidth int,
 	logger *zerolog.Logger) {
@@ -25,16 +164,282 @@ func logSectionHeader(text string,
 	logger.Info().Msgf("%s%s", outputHeader, suffix)
 }
 
-// Status produces a status report for the given stack
-func Status(ctx context.Context,
+// statusFunctions returns the configuration and recent invocation and
+// error counts for the provisioned functions
+func statusFunctions(ctx context.Context,
+	awsConfig aws.Config,
+	functions []*provisionedFunction,
+	logger *zerolog.Logger) ([]*StatusFunction, error) {
+
+	lambdaSvc := awsv2Lambda.NewFromConfig(awsConfig)
+	statusFunctions := make([]*StatusFunction, 0, len(functions))
+	for _, eachFunction := range functions {
+		statusFunction := &StatusFunction{
+			Name:         eachFunction.FunctionName,
+			LogicalName:  eachFunction.LogicalName,
+			PhysicalName: eachFunction.PhysicalName,
+		}
+		config, configErr := lambdaSvc.GetFunctionConfiguration(ctx,
+			&awsv2Lambda.GetFunctionConfigurationInput{
+				FunctionName: aws.String(eachFunction.PhysicalName),
+			})
+		if configErr != nil {
+			return nil, errors.Wrapf(configErr,
+				"Failed to get configuration for %s",
+				eachFunction.PhysicalName)
+		}
+		statusFunction.Runtime = string(config.Runtime)
+		statusFunction.MemorySize = aws.ToInt32(config.MemorySize)
+		statusFunction.Timeout = aws.ToInt32(config.Timeout)
+		statusFunction.Version = aws.ToString(config.Version)
+		statusFunction.LastModified = aws.ToString(config.LastModified)
+		statusFunction.CodeSha256 = aws.ToString(config.CodeSha256)
+		statusFunction.State = string(config.State)
+		statusFunction.LastUpdateStatus = string(config.LastUpdateStatus)
+		statusFunctions = append(statusFunctions, statusFunction)
+	}
+
+	// Then the recent activity
+	cwSvc := awsv2CW.NewFromConfig(awsConfig)
+	endTime := time.Now()
+	startTime := endTime.Add(-statusMetricsWindow)
+	period := int32(statusMetricsWindow.Seconds())
+	counters := make(map[string]*int64)
+	queries := make([]awsv2CWTypes.MetricDataQuery, 0)
+	for index, eachFunction := range statusFunctions {
+		for metricName, counter := range map[string]*int64{
+			"Invocations": &eachFunction.Invocations,
+			"Errors":      &eachFunction.Errors,
+		} {
+			queryID := fmt.Sprintf("%s%d", strings.ToLower(metricName), index)
+			counters[queryID] = counter
+			queries = append(queries, awsv2CWTypes.MetricDataQuery{
+				Id: aws.String(queryID),
+				MetricStat: &awsv2CWTypes.MetricStat{
+					Metric: &awsv2CWTypes.Metric{
+						Namespace:  aws.String("AWS/Lambda"),
+						MetricName: aws.String(metricName),
+						Dimensions: []awsv2CWTypes.Dimension{
+							{
+								Name:  aws.String("FunctionName"),
+								Value: aws.String(eachFunction.PhysicalName),
+							},
+						},
+					},
+					Period: aws.Int32(period),
+					Stat:   aws.String("Sum"),
+				},
+			})
+		}
+	}
+	for len(queries) != 0 {
+		batchSize := len(queries)
+		if batchSize > statusMaxMetricQueries {
+			batchSize = statusMaxMetricQueries
+		}
+		paginator := awsv2CW.NewGetMetricDataPaginator(cwSvc, &awsv2CW.GetMetricDataInput{
+			StartTime:         aws.Time(startTime),
+			EndTime:           aws.Time(endTime),
+			MetricDataQueries: queries[0:batchSize],
+		})
+		queries = queries[batchSize:]
+		for paginator.HasMorePages() {
+			page, pageErr := paginator.NextPage(ctx)
+			if pageErr != nil {
+				return nil, errors.Wrapf(pageErr, "Failed to get function metrics")
+			}
+			for _, eachResult := range page.MetricDataResults {
+				counter, counterExists := counters[aws.ToString(eachResult.Id)]
+				if !counterExists {
+					continue
+				}
+				for _, eachValue := range eachResult.Values {
+					*counter += int64(eachValue)
+				}
+			}
+		}
+	}
+	logger.Debug().
+		Int("FunctionCount", len(statusFunctions)).
+		Msg("Function status")
+	return statusFunctions, nil
+}
+
+// statusAlarms returns the state of the CloudWatch alarms in the stack
+func statusAlarms(ctx context.Context,
+	awsConfig aws.Config,
+	alarmResources map[string]string) ([]*StatusAlarm, error) {
+
+	logicalNames := make(map[string]string)
+	alarmNames := make([]string, 0, len(alarmResources))
+	for eachLogicalName, eachAlarmName := range alarmResources {
+		logicalNames[eachAlarmName] = eachLogicalName
+		alarmNames = append(alarmNames, eachAlarmName)
+	}
+	sort.Strings(alarmNames)
+
+	cwSvc := awsv2CW.NewFromConfig(awsConfig)
+	alarms := make([]*StatusAlarm, 0, len(alarmNames))
+	appendAlarm := func(name *string,
+		state awsv2CWTypes.StateValue,
+		reason *string,
+		updated *time.Time) {
+		alarms = append(alarms, &StatusAlarm{
+			Name:         aws.ToString(name),
+			LogicalName:  logicalNames[aws.ToString(name)],
+			State:        string(state),
+			StateReason:  aws.ToString(reason),
+			StateUpdated: updated,
+		})
+	}
+	for len(alarmNames) != 0 {
+		batchSize := len(alarmNames)
+		if batchSize > statusMaxAlarmNames {
+			batchSize = statusMaxAlarmNames
+		}
+		paginator := awsv2CW.NewDescribeAlarmsPaginator(cwSvc, &awsv2CW.DescribeAlarmsInput{
+			AlarmNames: alarmNames[0:batchSize],
+			AlarmTypes: []awsv2CWTypes.AlarmType{
+				awsv2CWTypes.AlarmTypeMetricAlarm,
+				awsv2CWTypes.AlarmTypeCompositeAlarm,
+			},
+		})
+		alarmNames = alarmNames[batchSize:]
+		for paginator.HasMorePages() {
+			page, pageErr := paginator.NextPage(ctx)
+			if pageErr != nil {
+				return nil, errors.Wrapf(pageErr, "Failed to describe alarms")
+			}
+			for _, eachAlarm := range page.MetricAlarms {
+				appendAlarm(eachAlarm.AlarmName,
+					eachAlarm.StateValue,
+					eachAlarm.StateReason,
+					eachAlarm.StateUpdatedTimestamp)
+			}
+			for _, eachAlarm := range page.CompositeAlarms {
+				appendAlarm(eachAlarm.AlarmName,
+					eachAlarm.StateValue,
+					eachAlarm.StateReason,
+					eachAlarm.StateUpdatedTimestamp)
+			}
+		}
+	}
+	sort.Slice(alarms, func(i, j int) bool {
+		return alarms[i].Name < alarms[j].Name
+	})
+	return alarms, nil
+}
+
+// statusSLOs returns the error budget of the SLOs defined in the stack outputs
+func statusSLOs(ctx context.Context,
+	awsConfig aws.Config,
+	outputs []awsv2CFTypes.Output,
+	logger *zerolog.Logger) ([]*StatusSLO, error) {
+
+	statusSLOs := make([]*StatusSLO, 0)
+	cwSvc := awsv2CW.NewFromConfig(awsConfig)
+	endTime := time.Now().Truncate(statusSLOPeriod)
+	for _, eachOutput := range outputs {
+		if !strings.HasPrefix(aws.ToString(eachOutput.OutputKey), SLOOutputPrefix) {
+			continue
+		}
+		var definition SLODefinition
+		unmarshalErr := json.Unmarshal([]byte(aws.ToString(eachOutput.OutputValue)), &definition)
+		if unmarshalErr != nil {
+			return nil, errors.Wrapf(unmarshalErr,
+				"Failed to unmarshal SLO output %s",
+				aws.ToString(eachOutput.OutputKey))
+		}
+		dimensions := make([]awsv2CWTypes.Dimension, 0, len(definition.Dimensions))
+		for eachName, eachValue := range definition.Dimensions {
+			dimensions = append(dimensions, awsv2CWTypes.Dimension{
+				Name:  aws.String(eachName),
+				Value: aws.String(eachValue),
+			})
+		}
+		metricQueries, _ := definition.MetricQueries()
+		queries := make([]awsv2CWTypes.MetricDataQuery, 0, len(metricQueries))
+		for _, eachQuery := range metricQueries {
+			queries = append(queries, awsv2CWTypes.MetricDataQuery{
+				Id: aws.String(eachQuery.ID),
+				MetricStat: &awsv2CWTypes.MetricStat{
+					Metric: &awsv2CWTypes.Metric{
+						Namespace:  aws.String(definition.Namespace),
+						MetricName: aws.String(eachQuery.MetricName),
+						Dimensions: dimensions,
+					},
+					Period: aws.Int32(int32(statusSLOPeriod.Seconds())),
+					Stat:   aws.String(eachQuery.Stat),
+				},
+			})
+		}
+		queryValues := make(map[string]map[time.Time]float64)
+		paginator := awsv2CW.NewGetMetricDataPaginator(cwSvc, &awsv2CW.GetMetricDataInput{
+			StartTime:         aws.Time(endTime.Add(-definition.Window())),
+			EndTime:           aws.Time(endTime),
+			MetricDataQueries: queries,
+		})
+		for paginator.HasMorePages() {
+			page, pageErr := paginator.NextPage(ctx)
+			if pageErr != nil {
+				return nil, errors.Wrapf(pageErr, "Failed to get SLO %s metrics", definition.Name)
+			}
+			for _, eachResult := range page.MetricDataResults {
+				queryID := aws.ToString(eachResult.Id)
+				if queryValues[queryID] == nil {
+					queryValues[queryID] = make(map[time.Time]float64)
+				}
+				for index, eachTimestamp := range eachResult.Timestamps {
+					if index < len(eachResult.Values) {
+						queryValues[queryID][eachTimestamp] = eachResult.Values[index]
+					}
+				}
+			}
+		}
+		statusSLOs = append(statusSLOs, newStatusSLO(&definition, queryValues))
+	}
+	logger.Debug().
+		Int("SLOCount", len(statusSLOs)).
+		Msg("SLO status")
+	return statusSLOs, nil
+}
+
+// newStatusSLO returns the StatusSLO for the metric values
+func newStatusSLO(definition *SLODefinition,
+	queryValues map[string]map[time.Time]float64) *StatusSLO {
+	statusSLO := &StatusSLO{
+		Name:                 definition.Name,
+		Objective:            definition.Objective,
+		Window:               definition.Window().String(),
+		SLI:                  100,
+		ErrorBudgetRemaining: 1,
+	}
+	badRatio, hasEvents := definition.BadEventRatio(queryValues)
+	if hasEvents {
+		statusSLO.Events = true
+		statusSLO.SLI = 100 * (1 - badRatio)
+		statusSLO.ErrorBudgetRemaining = 1 - (badRatio / definition.ErrorBudget())
+	}
+	return statusSLO
+}
+
+// NewStatusReport returns the StatusReport for the given stack. The report
+// for a stack that doesn't exist has Exists set to false. If redact is true,
+// the AWS account ID is replaced in the report values.
+func NewStatusReport(ctx context.Context,
 	serviceName string,
-	serviceDescription string,
+	lambdaAWSInfos []*LambdaAWSInfo,
 	redact bool,
-	logger *zerolog.Logger) error {
+	logger *zerolog.Logger) (*StatusReport, error) {
 
 	awsConfig, awsConfigErr := spartaAWS.NewConfig(ctx, logger)
 	if awsConfigErr != nil {
-		return awsConfigErr
+		return nil, awsConfigErr
+	}
+	report := &StatusReport{
+		ServiceName:   serviceName,
+		Region:        awsConfig.Region,
+		MetricsWindow: statusMetricsWindow.String(),
 	}
 	cfSvc := awsv2CF.NewFromConfig(awsConfig)
 
@@ -45,13 +450,13 @@ func Status(ctx context.Context,
 
 	if describeStacksResponseErr != nil {
 		if strings.Contains(describeStacksResponseErr.Error(), "does not exist") {
-			logger.Info().Str("Region", awsConfig.Region).Msg("Stack does not exist")
-			return nil
+			report.evaluateHealth()
+			return report, nil
 		}
-		return describeStacksResponseErr
+		return nil, describeStacksResponseErr
 	}
 	if len(describeStacksResponse.Stacks) > 1 {
-		return errors.Errorf("More than 1 stack returned for %s. Count: %d",
+		return nil, errors.Errorf("More than 1 stack returned for %s. Count: %d",
 			serviceName,
 			len(describeStacksResponse.Stacks))
 	}
@@ -66,7 +471,7 @@ func Status(ctx context.Context,
 		stsSvc := awsv2STS.NewFromConfig(awsConfig)
 		identityResponse, identityResponseErr := stsSvc.GetCallerIdentity(ctx, input)
 		if identityResponseErr != nil {
-			return identityResponseErr
+			return nil, identityResponseErr
 		}
 		redactedValue := strings.Repeat("*", len(*identityResponse.Account))
 		redactor = func(stringValue string) string {
@@ -77,51 +482,236 @@ func Status(ctx context.Context,
 		}
 	}
 
-	// Report on what's up with the stack...
-	logSectionHeader("Stack Summary", dividerLength, logger)
 	stackInfo := describeStacksResponse.Stacks[0]
-	logger.Info().Str("Id", redactor(*stackInfo.StackId)).Msg("StackId")
-	logger.Info().Str("Description", redactor(*stackInfo.Description)).Msg("Description")
-	logger.Info().Str("State", string(stackInfo.StackStatus)).Msg("Status")
-	if stackInfo.StackStatusReason != nil {
-		logger.Info().Str("Reason", *stackInfo.StackStatusReason).Msg("Reason")
+	report.Exists = true
+	report.StackID = redactor(aws.ToString(stackInfo.StackId))
+	report.Description = redactor(aws.ToString(stackInfo.Description))
+	report.StackStatus = string(stackInfo.StackStatus)
+	report.StackStatusReason = aws.ToString(stackInfo.StackStatusReason)
+	report.CreationTime = stackInfo.CreationTime
+	report.LastUpdatedTime = stackInfo.LastUpdatedTime
+	if len(stackInfo.Parameters) != 0 {
+		report.Parameters = make(map[string]string)
+		for _, eachParam := range stackInfo.Parameters {
+			report.Parameters[aws.ToString(eachParam.ParameterKey)] =
+				redactor(aws.ToString(eachParam.ParameterValue))
+		}
+	}
+	if len(stackInfo.Tags) != 0 {
+		report.Tags = make(map[string]string)
+		for _, eachTag := range stackInfo.Tags {
+			report.Tags[aws.ToString(eachTag.Key)] = redactor(aws.ToString(eachTag.Value))
+		}
+		report.BuildID = report.Tags[SpartaTagBuildIDKey]
+	}
+	for _, eachOutput := range stackInfo.Outputs {
+		// SLO definitions are reported separately
+		if strings.HasPrefix(aws.ToString(eachOutput.OutputKey), SLOOutputPrefix) {
+			continue
+		}
+		report.Outputs = append(report.Outputs, &StatusOutput{
+			Key:        aws.ToString(eachOutput.OutputKey),
+			Value:      redactor(aws.ToString(eachOutput.OutputValue)),
+			ExportName: aws.ToString(eachOutput.ExportName),
+		})
+	}
+
+	// Functions and alarms
+	summaries, summariesErr := stackResourceSummaries(ctx, awsConfig, serviceName)
+	if summariesErr != nil {
+		return nil, summariesErr
 	}
-	logger.Info().Str("Time", stackInfo.CreationTime.UTC().String()).Msg("Created")
-	if stackInfo.LastUpdatedTime != nil {
-		logger.Info().Str("Time", stackInfo.LastUpdatedTime.UTC().String()).Msg("Last Update")
+	functions, functionsErr := statusFunctions(ctx,
+		awsConfig,
+		newProvisionedFunctions(summaries, lambdaAWSInfos),
+		logger)
+	if functionsErr != nil {
+		return nil, functionsErr
 	}
-	if stackInfo.DeletionTime != nil {
-		logger.Info().Str("Time", stackInfo.DeletionTime.UTC().String()).Msg("Deleted")
+	report.Functions = functions
+
+	alarms, alarmsErr := statusAlarms(ctx,
+		awsConfig,
+		stackResourcesOfType(summaries, "AWS::CloudWatch::Alarm"))
+	if alarmsErr != nil {
+		return nil, alarmsErr
 	}
+	report.Alarms = alarms
+
+	slos, slosErr := statusSLOs(ctx, awsConfig, stackInfo.Outputs, logger)
+	if slosErr != nil {
+		return nil, slosErr
+	}
+	report.SLOs = slos
+	report.evaluateHealth()
+	return report, nil
+}
 
+// logStatusReport logs the human readable status report
+func logStatusReport(report *StatusReport, logger *zerolog.Logger) {
+	if !report.Exists {
+		logger.Info().Str("Region", report.Region).Msg("Stack does not exist")
+		return
+	}
+	// Report on what's up with the stack...
+	logSectionHeader("Stack Summary", dividerLength, logger)
+	logger.Info().Str("Id", report.StackID).Msg("StackId")
+	logger.Info().Str("Description", report.Description).Msg("Description")
+	logger.Info().Str("State", report.StackStatus).Msg("Status")
+	if report.StackStatusReason != "" {
+		logger.Info().Str("Reason", report.StackStatusReason).Msg("Reason")
+	}
+	if report.CreationTime != nil {
+		logger.Info().Str("Time", report.CreationTime.UTC().String()).Msg("Created")
+	}
+	if report.LastUpdatedTime != nil {
+		logger.Info().Str("Time", report.LastUpdatedTime.UTC().String()).Msg("Last Update")
+	}
+	if report.BuildID != "" {
+		logger.Info().Str("BuildID", report.BuildID).Msg("Build")
+	}
 	logger.Info()
-	if len(stackInfo.Parameters) != 0 {
-		logSectionHeader("Parameters", dividerLength, logger)
-		for _, eachParam := range stackInfo.Parameters {
-			logger.Info().Str("Value",
-				redactor(*eachParam.ParameterValue)).Msg(*eachParam.ParameterKey)
+
+	logSortedValues := func(values map[string]string) {
+		keys := make([]string, 0, len(values))
+		for eachKey := range values {
+			keys = append(keys, eachKey)
+		}
+		sort.Strings(keys)
+		for _, eachKey := range keys {
+			logger.Info().Str("Value", values[eachKey]).Msg(eachKey)
 		}
 		logger.Info().Msg("")
 	}
-	if len(stackInfo.Tags) != 0 {
+	if len(report.Parameters) != 0 {
+		logSectionHeader("Parameters", dividerLength, logger)
+		logSortedValues(report.Parameters)
+	}
+	if len(report.Tags) != 0 {
 		logSectionHeader("Tags", dividerLength, logger)
-		for _, eachTag := range stackInfo.Tags {
-			logger.Info().Str("Value",
-				redactor(*eachTag.Value)).Msg(*eachTag.Key)
-		}
-		logger.Info().Msg("")
+		logSortedValues(report.Tags)
 	}
-	if len(stackInfo.Outputs) != 0 {
+	if len(report.Outputs) != 0 {
 		logSectionHeader("Outputs", dividerLength, logger)
-		for _, eachOutput := range stackInfo.Outputs {
-			statement := logger.Info().Str("Value",
-				redactor(*eachOutput.OutputValue))
-			if eachOutput.ExportName != nil {
-				statement.Str("ExportName", *eachOutput.ExportName)
+		for _, eachOutput := range report.Outputs {
+			statement := logger.Info().Str("Value", eachOutput.Value)
+			if eachOutput.ExportName != "" {
+				statement.Str("ExportName", eachOutput.ExportName)
 			}
-			statement.Msg(*eachOutput.OutputKey)
+			statement.Msg(eachOutput.Key)
+		}
+		logger.Info().Msg("")
+	}
+	if len(report.Functions) != 0 {
+		logSectionHeader("Functions", dividerLength, logger)
+		for _, eachFunction := range report.Functions {
+			logger.Info().
+				Str("Runtime", eachFunction.Runtime).
+				Int32("MemorySize", eachFunction.MemorySize).
+				Str("Version", eachFunction.Version).
+				Str("LastModified", eachFunction.LastModified).
+				Str("CodeSha256", eachFunction.CodeSha256).
+				Str("State", eachFunction.State).
+				Int64("Invocations", eachFunction.Invocations).
+				Int64("Errors", eachFunction.Errors).
+				Msg(eachFunction.Name)
+		}
+		logger.Info().Msg("")
+	}
+	if len(report.Alarms) != 0 {
+		logSectionHeader("Alarms", dividerLength, logger)
+		for _, eachAlarm := range report.Alarms {
+			logger.Info().
+				Str("State", eachAlarm.State).
+				Str("Reason", eachAlarm.StateReason).
+				Msg(eachAlarm.Name)
+		}
+		logger.Info().Msg("")
+	}
+	if len(report.SLOs) != 0 {
+		logSectionHeader("Service Level Objectives", dividerLength, logger)
+		for _, eachSLO := range report.SLOs {
+			logger.Info().
+				Float64("Objective", eachSLO.Objective).
+				Str("Window", eachSLO.Window).
+				Str("SLI", fmt.Sprintf("%.3f%%", eachSLO.SLI)).
+				Str("ErrorBudgetRemaining", fmt.Sprintf("%.1f%%", 100*eachSLO.ErrorBudgetRemaining)).
+				Msg(eachSLO.Name)
+		}
+		logger.Info().Msg("")
+	}
+	logSectionHeader("Health", dividerLength, logger)
+	logger.Info().Bool("Healthy", report.Healthy).Msg("Health")
+	for _, eachReason := range report.HealthReasons {
+		logger.Warn().Msg(eachReason)
+	}
+}
+
+// Status produces a status report for the given stack
+func Status(ctx context.Context,
+	serviceName string,
+	serviceDescription string,
+	redact bool,
+	logger *zerolog.Logger) error {
+
+	report, reportErr := NewStatusReport(ctx, serviceName, nil, redact, logger)
+	if reportErr != nil {
+		return reportErr
+	}
+	logStatusReport(report, logger)
+	return nil
+}
+
+// StatusWithFormat produces a status report for the given stack in the
+// StatusFormatText or StatusFormatJSON format. The JSON report is written
+// to the outputWriter. An error is returned if the service is unhealthy
+// so that the process exit code reflects the service health. The status
+// command logs to stderr in JSON mode so that stdout only contains the report.
+func StatusWithFormat(ctx context.Context,
+	serviceName string,
+	serviceDescription string,
+	lambdaAWSInfos []*LambdaAWSInfo,
+	redact bool,
+	outputFormat string,
+	outputWriter io.Writer,
+	logger *zerolog.Logger) error {
+
+	report, reportErr := NewStatusReport(ctx, serviceName, lambdaAWSInfos, redact, logger)
+	if reportErr != nil {
+		return reportErr
+	}
+	writeErr := writeStatusReport(report, outputFormat, outputWriter, logger)
+	if writeErr != nil {
+		return writeErr
+	}
+	if !report.Healthy {
+		return errors.Errorf("Service %s is unhealthy: %s",
+			serviceName,
+			strings.Join(report.HealthReasons, "; "))
+	}
+	return nil
+}
+
+// writeStatusReport logs the text report or writes the JSON report
+// to the outputWriter
+func writeStatusReport(report *StatusReport,
+	outputFormat string,
+	outputWriter io.Writer,
+	logger *zerolog.Logger) error {
+	switch outputFormat {
+	case StatusFormatText, "":
+		logStatusReport(report, logger)
+	case StatusFormatJSON:
+		jsonBytes, jsonBytesErr := json.MarshalIndent(report, "", "  ")
+		if jsonBytesErr != nil {
+			return errors.Wrapf(jsonBytesErr, "Failed to marshal status report")
+		}
+		_, writeErr := fmt.Fprintf(outputWriter, "%s\n", string(jsonBytes))
+		if writeErr != nil {
+			return writeErr
 		}
-		logger.Info()
+	default:
+		return errors.Errorf("Unsupported status format: %s", outputFormat)
 	}
 	return nil
 }
@@ -1,8 +1,16 @@
+//go:build !lambdabinary
+// +build !lambdabinary
+
 package sparta
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -17,3 +25,192 @@ func TestStatus(t *testing.T) {
 		t.Fatalf("Failed to error for non-existent stack")
 	}
 }
+
+func TestStatusReportHealth(t *testing.T) {
+	type testCase struct {
+		report  StatusReport
+		healthy bool
+	}
+	testCases := []testCase{
+		{
+			report:  StatusReport{},
+			healthy: false,
+		},
+		{
+			report: StatusReport{
+				Exists:      true,
+				StackStatus: "UPDATE_COMPLETE",
+				Functions: []*StatusFunction{
+					{Name: "Hello", State: "Active", LastUpdateStatus: "Successful"},
+				},
+				Alarms: []*StatusAlarm{
+					{Name: "ERROR Alarm for Hello", State: "OK"},
+				},
+			},
+			healthy: true,
+		},
+		{
+			report: StatusReport{
+				Exists:      true,
+				StackStatus: "UPDATE_ROLLBACK_COMPLETE",
+			},
+			healthy: false,
+		},
+		{
+			report: StatusReport{
+				Exists:      true,
+				StackStatus: "CREATE_COMPLETE",
+				Functions: []*StatusFunction{
+					{Name: "Hello", State: "Active", LastUpdateStatus: "Failed"},
+				},
+			},
+			healthy: false,
+		},
+		{
+			report: StatusReport{
+				Exists:      true,
+				StackStatus: "CREATE_COMPLETE",
+				Alarms: []*StatusAlarm{
+					{Name: "ERROR Alarm for Hello", State: "ALARM"},
+				},
+			},
+			healthy: false,
+		},
+	}
+	for index, eachTestCase := range testCases {
+		report := eachTestCase.report
+		report.evaluateHealth()
+		if report.Healthy != eachTestCase.healthy {
+			t.Fatalf("Test case %d: expected healthy=%t. Reasons: %v",
+				index,
+				eachTestCase.healthy,
+				report.HealthReasons)
+		}
+		if report.Healthy != (len(report.HealthReasons) == 0) {
+			t.Fatalf("Test case %d: inconsistent health reasons: %v",
+				index,
+				report.HealthReasons)
+		}
+	}
+}
+
+func TestStatusReportJSON(t *testing.T) {
+	report := &StatusReport{
+		ServiceName: "MyService",
+		Exists:      true,
+		StackStatus: "CREATE_COMPLETE",
+		BuildID:     "abc123",
+		Functions: []*StatusFunction{
+			{Name: "Hello", CodeSha256: "sha", Errors: 2},
+		},
+	}
+	report.evaluateHealth()
+	jsonBytes, jsonBytesErr := json.Marshal(report)
+	if jsonBytesErr != nil {
+		t.Fatal(jsonBytesErr)
+	}
+	for _, eachFragment := range []string{
+		`"stackStatus":"CREATE_COMPLETE"`,
+		`"buildId":"abc123"`,
+		`"codeSha256":"sha","invocations":0,"errors":2`,
+		`"healthy":true`,
+	} {
+		if !strings.Contains(string(jsonBytes), eachFragment) {
+			t.Fatalf("Failed to find %s in status JSON: %s", eachFragment, string(jsonBytes))
+		}
+	}
+}
+
+func TestStatusReportJSONStdout(t *testing.T) {
+	savedFormat := optionsStatus.Format
+	savedStdout := os.Stdout
+	savedHeaderDisplayed := headerDisplayed
+	defer func() {
+		optionsStatus.Format = savedFormat
+		os.Stdout = savedStdout
+		headerDisplayed = savedHeaderDisplayed
+	}()
+	stdoutReader, stdoutWriter, pipeErr := os.Pipe()
+	if pipeErr != nil {
+		t.Fatal(pipeErr)
+	}
+	os.Stdout = stdoutWriter
+	optionsStatus.Format = StatusFormatJSON
+	headerDisplayed = false
+
+	// Same logger as the status command
+	logger, loggerErr := newLoggerForFile(zerolog.InfoLevel.String(),
+		"text",
+		true,
+		commandLoggerOutput(CommandLineOptions.Status))
+	if loggerErr != nil {
+		t.Fatal(loggerErr)
+	}
+	displayPrettyHeader(headerDivider, true, logger)
+	logger.Info().Str("Option", "status").Msg("Welcome to MyService")
+	report := &StatusReport{
+		ServiceName: "MyService",
+		Exists:      true,
+		StackStatus: "CREATE_COMPLETE",
+	}
+	report.evaluateHealth()
+	writeErr := writeStatusReport(report, StatusFormatJSON, os.Stdout, logger)
+	if writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	stdoutWriter.Close()
+	stdoutBytes, stdoutBytesErr := io.ReadAll(stdoutReader)
+	if stdoutBytesErr != nil {
+		t.Fatal(stdoutBytesErr)
+	}
+	var parsedReport StatusReport
+	parseErr := json.Unmarshal(stdoutBytes, &parsedReport)
+	if parseErr != nil {
+		t.Fatalf("Failed to parse stdout as JSON: %s\n%s", parseErr, string(stdoutBytes))
+	}
+	if parsedReport.ServiceName != "MyService" {
+		t.Fatalf("Unexpected status report: %s", string(stdoutBytes))
+	}
+}
+
+func TestStatusSLO(t *testing.T) {
+	now := time.Now().Truncate(time.Hour)
+	availability := &SLODefinition{
+		Name:          "Availability",
+		Objective:     99,
+		WindowSeconds: 86400,
+		ErrorMetric:   "Errors",
+		TotalMetric:   "Invocations",
+	}
+	statusSLO := newStatusSLO(availability, map[string]map[time.Time]float64{
+		SLOQueryBad:   {now: 1, now.Add(-time.Hour): 1},
+		SLOQueryTotal: {now: 300, now.Add(-time.Hour): 100},
+	})
+	if !statusSLO.Events ||
+		math.Abs(statusSLO.SLI-99.5) > 1e-9 ||
+		math.Abs(statusSLO.ErrorBudgetRemaining-0.5) > 1e-9 {
+		t.Fatalf("Unexpected availability status: %#v", statusSLO)
+	}
+
+	// 90% of 100 events and 50% of 300 events are within the threshold
+	latency := &SLODefinition{
+		Name:               "Latency",
+		Objective:          90,
+		WindowSeconds:      86400,
+		LatencyMetric:      "Duration",
+		LatencyThresholdMs: 100,
+	}
+	statusSLO = newStatusSLO(latency, map[string]map[time.Time]float64{
+		SLOQueryGoodPercent: {now: 50, now.Add(-time.Hour): 90},
+		SLOQueryTotal:       {now: 300, now.Add(-time.Hour): 100},
+	})
+	if math.Abs(statusSLO.SLI-60) > 1e-9 ||
+		math.Abs(statusSLO.ErrorBudgetRemaining+3) > 1e-9 {
+		t.Fatalf("Unexpected latency status: %#v", statusSLO)
+	}
+
+	statusSLO = newStatusSLO(availability, map[string]map[time.Time]float64{})
+	if statusSLO.Events || statusSLO.ErrorBudgetRemaining != 1 {
+		t.Fatalf("Unexpected status without events: %#v", statusSLO)
+	}
+}
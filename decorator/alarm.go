@@ -17,7 +17,7 @@ import (
 // the strict lower bound value, and the SNS topic to which alerts should be
 // sent. See the CloudWatch alarm resource type in the official
 // AWS documentation at https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-properties-cw-alarm.html
-// for more information
+// for more information. See NewAlarmPolicy for additional alarm types.
 func CloudWatchErrorAlarmDecorator(periodWindow int,
 	minutesPerPeriod int,
 	thresholdGreaterThanOrEqualToValue float64,
@@ -0,0 +1,531 @@
+package decorator
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsv2ARN "github.com/aws/aws-sdk-go-v2/aws/arn"
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	gofcloudwatch "github.com/awslabs/goformation/v5/cloudformation/cloudwatch"
+	gofevents "github.com/awslabs/goformation/v5/cloudformation/events"
+	goflambda "github.com/awslabs/goformation/v5/cloudformation/lambda"
+	sparta "github.com/mweagle/Sparta/v3"
+	spartaCF "github.com/mweagle/Sparta/v3/aws/cloudformation"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// defaultLambdaTimeout is the timeout used when the function doesn't
+	// define one
+	defaultLambdaTimeout = 3
+	// defaultAlarmPeriod is the default alarm period in seconds
+	defaultAlarmPeriod = 60
+	// defaultAlarmEvaluationPeriods is the default number of periods
+	// evaluated by each alarm
+	defaultAlarmEvaluationPeriods = 5
+)
+
+// alarmPolicyRule creates the alarm for a single function. It returns nil
+// if the rule doesn't apply to the function.
+type alarmPolicyRule struct {
+	name  string
+	alarm func(lambdaResourceName string,
+		lambdaResource *goflambda.Function,
+		template *gof.Template) *gofcloudwatch.Alarm
+}
+
+// AlarmPolicy is a set of CloudWatch alarm rules that are applied to every
+// Lambda function in the service. It satisfies sparta.ServiceDecoratorHookHandler
+// and should be included in the WorkflowHooks.ServiceDecorators slice.
+// See CloudWatchErrorAlarmDecorator for a single function error alarm.
+type AlarmPolicy struct {
+	name                    string
+	period                  int
+	evaluationPeriods       int
+	datapointsToAlarm       int
+	rules                   []*alarmPolicyRule
+	functions               map[string]bool
+	alarmActions            []string
+	notifyOK                bool
+	eventBridgeTargets      []string
+	composite               bool
+	rollbackMonitoringTime  time.Duration
+	rollbackTriggersEnabled bool
+}
+
+// NewAlarmPolicy returns an AlarmPolicy with the given name. The name is
+// used to identify the service's composite alarm. Alarms evaluate five one
+// minute periods by default.
+func NewAlarmPolicy(name string) *AlarmPolicy {
+	return &AlarmPolicy{
+		name:              name,
+		period:            defaultAlarmPeriod,
+		evaluationPeriods: defaultAlarmEvaluationPeriods,
+	}
+}
+
+// WithEvaluation sets the period, number of evaluation periods and number
+// of breaching datapoints required to trigger each alarm
+func (ap *AlarmPolicy) WithEvaluation(period time.Duration,
+	evaluationPeriods int,
+	datapointsToAlarm int) *AlarmPolicy {
+	ap.period = int(period.Seconds())
+	ap.evaluationPeriods = evaluationPeriods
+	ap.datapointsToAlarm = datapointsToAlarm
+	return ap
+}
+
+// WithFunctions limits the policy to the given functions. By default the
+// policy applies to every function in the service.
+func (ap *AlarmPolicy) WithFunctions(lambdaAWSInfos ...*sparta.LambdaAWSInfo) *AlarmPolicy {
+	if ap.functions == nil {
+		ap.functions = make(map[string]bool)
+	}
+	for _, eachLambda := range lambdaAWSInfos {
+		ap.functions[eachLambda.LogicalResourceName()] = true
+	}
+	return ap
+}
+
+// WithErrors alarms when the function's error count is greater than or
+// equal to the threshold
+func (ap *AlarmPolicy) WithErrors(threshold float64) *AlarmPolicy {
+	return ap.withLambdaMetricRule("Errors", "Sum", threshold)
+}
+
+// WithThrottles alarms when the function's throttle count is greater than
+// or equal to the threshold
+func (ap *AlarmPolicy) WithThrottles(threshold float64) *AlarmPolicy {
+	return ap.withLambdaMetricRule("Throttles", "Sum", threshold)
+}
+
+// WithDuration alarms when the function's duration percentile (eg: 95, 99)
+// is greater than or equal to the fraction of the configured timeout
+func (ap *AlarmPolicy) WithDuration(percentile float64, fractionOfTimeout float64) *AlarmPolicy {
+	extendedStatistic := fmt.Sprintf("p%g", percentile)
+	ap.rules = append(ap.rules, &alarmPolicyRule{
+		name: fmt.Sprintf("Duration%s", strings.ReplaceAll(extendedStatistic, ".", "_")),
+		alarm: func(lambdaResourceName string,
+			lambdaResource *goflambda.Function,
+			template *gof.Template) *gofcloudwatch.Alarm {
+			timeout := lambdaResource.Timeout
+			if timeout <= 0 {
+				timeout = defaultLambdaTimeout
+			}
+			threshold := fractionOfTimeout * float64(timeout*1000)
+			alarm := ap.newLambdaAlarm(lambdaResourceName, "Duration", "", threshold)
+			alarm.ExtendedStatistic = extendedStatistic
+			alarm.Unit = "Milliseconds"
+			return alarm
+		},
+	})
+	return ap
+}
+
+// WithIteratorAge alarms when the age of the last record processed by a
+// stream event source exceeds maxAge. It only applies to functions with
+// Kinesis, DynamoDB or Kafka event source mappings.
+func (ap *AlarmPolicy) WithIteratorAge(maxAge time.Duration) *AlarmPolicy {
+	ap.rules = append(ap.rules, &alarmPolicyRule{
+		name: "IteratorAge",
+		alarm: func(lambdaResourceName string,
+			lambdaResource *goflambda.Function,
+			template *gof.Template) *gofcloudwatch.Alarm {
+			if !hasStreamEventSource(lambdaResourceName, template) {
+				return nil
+			}
+			return ap.newLambdaAlarm(lambdaResourceName,
+				"IteratorAge",
+				"Maximum",
+				float64(maxAge.Milliseconds()))
+		},
+	})
+	return ap
+}
+
+// WithDLQDepth alarms when the number of messages in the function's SQS
+// dead letter queue is greater than or equal to the threshold. It only
+// applies to functions with an SQS DeadLetterConfig.
+func (ap *AlarmPolicy) WithDLQDepth(threshold float64) *AlarmPolicy {
+	ap.rules = append(ap.rules, &alarmPolicyRule{
+		name: "DLQDepth",
+		alarm: func(lambdaResourceName string,
+			lambdaResource *goflambda.Function,
+			template *gof.Template) *gofcloudwatch.Alarm {
+			if lambdaResource.DeadLetterConfig == nil {
+				return nil
+			}
+			queueName := sqsQueueName(lambdaResource.DeadLetterConfig.TargetArn)
+			if queueName == "" {
+				return nil
+			}
+			alarm := ap.newAlarm(fmt.Sprintf("dead letter queue depth for AWS Lambda function %s",
+				lambdaResourceName),
+				"AWS/SQS",
+				"ApproximateNumberOfMessagesVisible",
+				"Maximum",
+				threshold)
+			alarm.Dimensions = []gofcloudwatch.Alarm_Dimension{
+				{
+					Name:  "QueueName",
+					Value: queueName,
+				},
+			}
+			return alarm
+		},
+	})
+	return ap
+}
+
+// WithConcurrency alarms when the function's concurrent executions are
+// greater than or equal to the fraction of its reserved concurrency. It
+// only applies to functions with ReservedConcurrentExecutions.
+func (ap *AlarmPolicy) WithConcurrency(fractionOfReserved float64) *AlarmPolicy {
+	ap.rules = append(ap.rules, &alarmPolicyRule{
+		name: "Concurrency",
+		alarm: func(lambdaResourceName string,
+			lambdaResource *goflambda.Function,
+			template *gof.Template) *gofcloudwatch.Alarm {
+			if lambdaResource.ReservedConcurrentExecutions <= 0 {
+				return nil
+			}
+			return ap.newLambdaAlarm(lambdaResourceName,
+				"ConcurrentExecutions",
+				"Maximum",
+				fractionOfReserved*float64(lambdaResource.ReservedConcurrentExecutions))
+		},
+	})
+	return ap
+}
+
+// WithAnomalyDetection alarms when the function's AWS/Lambda metric is
+// above the anomaly detection band. The bandWidth is the number of standard
+// deviations in the band (eg: 2).
+func (ap *AlarmPolicy) WithAnomalyDetection(metricName string,
+	stat string,
+	bandWidth float64) *AlarmPolicy {
+	ap.rules = append(ap.rules, &alarmPolicyRule{
+		name: fmt.Sprintf("%sAnomaly", metricName),
+		alarm: func(lambdaResourceName string,
+			lambdaResource *goflambda.Function,
+			template *gof.Template) *gofcloudwatch.Alarm {
+			alarm := ap.newLambdaAlarm(lambdaResourceName, metricName, stat, 0)
+			alarm.AlarmDescription = gof.Join(" ", []string{
+				metricName,
+				"for AWS Lambda function",
+				gof.Ref(lambdaResourceName),
+				"is above the expected band",
+			})
+			alarm.Metrics = []gofcloudwatch.Alarm_MetricDataQuery{
+				{
+					Id: "m1",
+					MetricStat: &gofcloudwatch.Alarm_MetricStat{
+						Metric: &gofcloudwatch.Alarm_Metric{
+							Namespace:  alarm.Namespace,
+							MetricName: alarm.MetricName,
+							Dimensions: alarm.Dimensions,
+						},
+						Period: alarm.Period,
+						Stat:   alarm.Statistic,
+					},
+					ReturnData: true,
+				},
+				{
+					Id:         "ad1",
+					Expression: fmt.Sprintf("ANOMALY_DETECTION_BAND(m1, %g)", bandWidth),
+					Label:      fmt.Sprintf("%s (expected)", metricName),
+					ReturnData: true,
+				},
+			}
+			// Metric math alarms can't also define the single metric properties
+			alarm.Namespace = ""
+			alarm.MetricName = ""
+			alarm.Statistic = ""
+			alarm.Period = 0
+			alarm.Dimensions = nil
+			alarm.ThresholdMetricId = "ad1"
+			alarm.ComparisonOperator = "GreaterThanUpperThreshold"
+			return alarm
+		},
+	})
+	return ap
+}
+
+// WithSNSTopics sends alarm notifications to the given SNS topic ARNs
+func (ap *AlarmPolicy) WithSNSTopics(topicARNs ...string) *AlarmPolicy {
+	ap.alarmActions = append(ap.alarmActions, topicARNs...)
+	return ap
+}
+
+// WithOKNotifications also notifies the SNS topics and EventBridge targets
+// when alarms return to the OK state
+func (ap *AlarmPolicy) WithOKNotifications() *AlarmPolicy {
+	ap.notifyOK = true
+	return ap
+}
+
+// WithEventBridgeTargets creates an EventBridge rule that forwards the
+// policy's alarm state changes to the given target ARNs (eg: an event bus,
+// queue or function). The target's resource policy must allow
+// events.amazonaws.com to deliver the event.
+func (ap *AlarmPolicy) WithEventBridgeTargets(targetARNs ...string) *AlarmPolicy {
+	ap.eventBridgeTargets = append(ap.eventBridgeTargets, targetARNs...)
+	return ap
+}
+
+// WithCompositeAlarm creates a single composite alarm for the service that
+// is in ALARM when any policy alarm is in ALARM. Notifications and rollback
+// triggers are attached to the composite alarm rather than to each alarm.
+func (ap *AlarmPolicy) WithCompositeAlarm() *AlarmPolicy {
+	ap.composite = true
+	return ap
+}
+
+// WithRollbackTriggers registers the policy alarms as CloudFormation
+// RollbackConfiguration triggers. CloudFormation rolls back a stack update
+// if an alarm enters the ALARM state during the update or the monitoring
+// time that follows it. Stack operations support at most five triggers and
+// provisioning fails if there are more, so services with many alarms must
+// also use WithCompositeAlarm.
+func (ap *AlarmPolicy) WithRollbackTriggers(monitoringTime time.Duration) *AlarmPolicy {
+	ap.rollbackTriggersEnabled = true
+	ap.rollbackMonitoringTime = monitoringTime
+	return ap
+}
+
+func (ap *AlarmPolicy) withLambdaMetricRule(metricName string,
+	stat string,
+	threshold float64) *AlarmPolicy {
+	ap.rules = append(ap.rules, &alarmPolicyRule{
+		name: metricName,
+		alarm: func(lambdaResourceName string,
+			lambdaResource *goflambda.Function,
+			template *gof.Template) *gofcloudwatch.Alarm {
+			return ap.newLambdaAlarm(lambdaResourceName, metricName, stat, threshold)
+		},
+	})
+	return ap
+}
+
+func (ap *AlarmPolicy) newAlarm(description string,
+	namespace string,
+	metricName string,
+	stat string,
+	threshold float64) *gofcloudwatch.Alarm {
+	return &gofcloudwatch.Alarm{
+		AlarmDescription: gof.Join(" ", []string{
+			metricName,
+			"alarm for",
+			description,
+			"( Stack:",
+			gof.Ref("AWS::StackName"),
+			")",
+		}),
+		Namespace:          namespace,
+		MetricName:         metricName,
+		Statistic:          stat,
+		Period:             ap.period,
+		EvaluationPeriods:  ap.evaluationPeriods,
+		DatapointsToAlarm:  ap.datapointsToAlarm,
+		Threshold:          threshold,
+		ComparisonOperator: "GreaterThanOrEqualToThreshold",
+		TreatMissingData:   "notBreaching",
+	}
+}
+
+func (ap *AlarmPolicy) newLambdaAlarm(lambdaResourceName string,
+	metricName string,
+	stat string,
+	threshold float64) *gofcloudwatch.Alarm {
+	alarm := ap.newAlarm(fmt.Sprintf("AWS Lambda function %s", lambdaResourceName),
+		"AWS/Lambda",
+		metricName,
+		stat,
+		threshold)
+	alarm.Dimensions = []gofcloudwatch.Alarm_Dimension{
+		{
+			Name:  "FunctionName",
+			Value: gof.Ref(lambdaResourceName),
+		},
+	}
+	return alarm
+}
+
+func (ap *AlarmPolicy) rollbackMetadata() map[string]interface{} {
+	return map[string]interface{}{
+		spartaCF.RollbackTriggerMetadataKey: int(ap.rollbackMonitoringTime.Minutes()),
+	}
+}
+
+func (ap *AlarmPolicy) okActions() []string {
+	if ap.notifyOK {
+		return ap.alarmActions
+	}
+	return nil
+}
+
+// DecorateService satisfies the sparta.ServiceDecoratorHookHandler interface
+func (ap *AlarmPolicy) DecorateService(ctx context.Context,
+	serviceName string,
+	cfTemplate *gof.Template,
+	lambdaFunctionCode *goflambda.Function_Code,
+	buildID string,
+	awsConfig awsv2.Config,
+	noop bool,
+	logger *zerolog.Logger) (context.Context, error) {
+
+	serviceTemplate, _ := ctx.Value(sparta.ContextKeyBuildTemplate).(*gof.Template)
+	if serviceTemplate == nil {
+		return ctx, errors.Errorf("AlarmPolicy %s requires the service template", ap.name)
+	}
+	lambdaResourceNames := []string{}
+	for eachName, eachResource := range serviceTemplate.Resources {
+		_, isFunction := eachResource.(*goflambda.Function)
+		if isFunction && (ap.functions == nil || ap.functions[eachName]) {
+			lambdaResourceNames = append(lambdaResourceNames, eachName)
+		}
+	}
+	sort.Strings(lambdaResourceNames)
+
+	alarmResourceNames := []string{}
+	for _, eachLambdaName := range lambdaResourceNames {
+		lambdaResource := serviceTemplate.Resources[eachLambdaName].(*goflambda.Function)
+		for _, eachRule := range ap.rules {
+			alarm := eachRule.alarm(eachLambdaName, lambdaResource, serviceTemplate)
+			if alarm == nil {
+				continue
+			}
+			if !ap.composite {
+				alarm.AlarmActions = ap.alarmActions
+				alarm.OKActions = ap.okActions()
+				if ap.rollbackTriggersEnabled {
+					alarm.AWSCloudFormationMetadata = ap.rollbackMetadata()
+				}
+			}
+			alarmResourceName := sparta.CloudFormationResourceName("Alarm",
+				ap.name,
+				eachLambdaName,
+				eachRule.name)
+			cfTemplate.Resources[alarmResourceName] = alarm
+			alarmResourceNames = append(alarmResourceNames, alarmResourceName)
+		}
+	}
+	logger.Debug().
+		Str("AlarmPolicy", ap.name).
+		Int("FunctionCount", len(lambdaResourceNames)).
+		Int("AlarmCount", len(alarmResourceNames)).
+		Msg("Created CloudWatch alarms")
+	if len(alarmResourceNames) == 0 {
+		return ctx, nil
+	}
+
+	// The alarms that notify and trigger rollbacks
+	notifyingAlarmNames := alarmResourceNames
+	if ap.composite {
+		alarmRules := make([]string, len(alarmResourceNames))
+		for index, eachName := range alarmResourceNames {
+			// Ref returns the alarm name
+			alarmRules[index] = fmt.Sprintf(`ALARM("${%s}")`, eachName)
+		}
+		compositeAlarm := &gofcloudwatch.CompositeAlarm{
+			AlarmName: fmt.Sprintf("%s-%s", serviceName, ap.name),
+			AlarmDescription: gof.Join(" ", []string{
+				"Composite",
+				ap.name,
+				"alarm for stack",
+				gof.Ref("AWS::StackName"),
+			}),
+			AlarmRule:    gof.Sub(strings.Join(alarmRules, " OR ")),
+			AlarmActions: ap.alarmActions,
+			OKActions:    ap.okActions(),
+		}
+		if ap.rollbackTriggersEnabled {
+			compositeAlarm.AWSCloudFormationMetadata = ap.rollbackMetadata()
+		}
+		compositeAlarmName := sparta.CloudFormationResourceName("CompositeAlarm", ap.name)
+		cfTemplate.Resources[compositeAlarmName] = compositeAlarm
+		notifyingAlarmNames = []string{compositeAlarmName}
+	}
+
+	if len(ap.eventBridgeTargets) != 0 {
+		alarmARNs := make([]string, len(notifyingAlarmNames))
+		for index, eachName := range notifyingAlarmNames {
+			alarmARNs[index] = gof.GetAtt(eachName, "Arn")
+		}
+		states := []string{"ALARM"}
+		if ap.notifyOK {
+			states = append(states, "OK")
+		}
+		rule := &gofevents.Rule{
+			Description: fmt.Sprintf("%s alarm state changes", ap.name),
+			State:       "ENABLED",
+			EventPattern: map[string]interface{}{
+				"source":      []string{"aws.cloudwatch"},
+				"detail-type": []string{"CloudWatch Alarm State Change"},
+				"resources":   alarmARNs,
+				"detail": map[string]interface{}{
+					"state": map[string]interface{}{
+						"value": states,
+					},
+				},
+			},
+		}
+		for index, eachTarget := range ap.eventBridgeTargets {
+			rule.Targets = append(rule.Targets, gofevents.Rule_Target{
+				Arn: eachTarget,
+				Id:  fmt.Sprintf("AlarmTarget%d", index),
+			})
+		}
+		cfTemplate.Resources[sparta.CloudFormationResourceName("AlarmRule", ap.name)] = rule
+	}
+	return ctx, nil
+}
+
+// hasStreamEventSource returns true if the function has an event source
+// mapping with a StartingPosition, which is only valid for stream sources
+func hasStreamEventSource(lambdaResourceName string, template *gof.Template) bool {
+	functionArn := gof.GetAtt(lambdaResourceName, "Arn")
+	for _, eachResource := range template.Resources {
+		mapping, isMapping := eachResource.(*goflambda.EventSourceMapping)
+		if isMapping &&
+			mapping.StartingPosition != "" &&
+			(mapping.FunctionName == functionArn || mapping.FunctionName == gof.Ref(lambdaResourceName)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sqsQueueName returns the QueueName dimension value for an SQS queue ARN.
+// The ARN may be a literal value or a Fn::GetAtt reference to a queue
+// in the template. Other ARNs return an empty string.
+func sqsQueueName(queueARN string) string {
+	parsedARN, parsedARNErr := awsv2ARN.Parse(queueARN)
+	if parsedARNErr == nil {
+		if parsedARN.Service != "sqs" {
+			return ""
+		}
+		return parsedARN.Resource
+	}
+	decoded, decodedErr := base64.StdEncoding.DecodeString(queueARN)
+	if decodedErr != nil {
+		return ""
+	}
+	var getAtt map[string][]string
+	unmarshalErr := json.Unmarshal(decoded, &getAtt)
+	if unmarshalErr != nil {
+		return ""
+	}
+	attParts := getAtt["Fn::GetAtt"]
+	if len(attParts) != 2 || attParts[1] != "Arn" {
+		return ""
+	}
+	return gof.GetAtt(attParts[0], "QueueName")
+}
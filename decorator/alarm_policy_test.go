@@ -0,0 +1,119 @@
+package decorator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	gofcloudwatch "github.com/awslabs/goformation/v5/cloudformation/cloudwatch"
+	gofevents "github.com/awslabs/goformation/v5/cloudformation/events"
+	spartaCF "github.com/mweagle/Sparta/v3/aws/cloudformation"
+)
+
+func testDecorateAlarmPolicy(t *testing.T, policy *AlarmPolicy) *gof.Template {
+	template, decorateErr := testDecorateService(policy, testAlarmPolicyServiceTemplate())
+	if decorateErr != nil {
+		t.Fatal(decorateErr)
+	}
+	return template
+}
+
+func TestAlarmPolicy(t *testing.T) {
+	policy := NewAlarmPolicy("Health").
+		WithErrors(1).
+		WithThrottles(1).
+		WithDuration(99, 0.8).
+		WithIteratorAge(time.Minute).
+		WithDLQDepth(1).
+		WithConcurrency(0.9).
+		WithAnomalyDetection("Invocations", "Sum", 2).
+		WithSNSTopics("arn:aws:sns:us-west-2:123412341234:alerts").
+		WithRollbackTriggers(10 * time.Minute)
+	template := testDecorateAlarmPolicy(t, policy)
+
+	// PlainLambda: errors, throttles, duration, anomaly
+	// StreamLambda: errors, throttles, duration, iterator age, DLQ, concurrency, anomaly
+	alarms := testResourcesOfType(template, "AWS::CloudWatch::Alarm")
+	if len(alarms) != 11 {
+		t.Fatalf("Expected 11 alarms. Found: %d", len(alarms))
+	}
+	thresholds := map[string]float64{}
+	for _, eachResource := range alarms {
+		alarm := eachResource.(*gofcloudwatch.Alarm)
+		if len(alarm.AlarmActions) != 1 {
+			t.Fatalf("Expected SNS alarm action: %#v", alarm)
+		}
+		if alarm.AWSCloudFormationMetadata[spartaCF.RollbackTriggerMetadataKey] != 10 {
+			t.Fatalf("Expected rollback trigger metadata: %#v", alarm.AWSCloudFormationMetadata)
+		}
+		key := alarm.MetricName + alarm.ExtendedStatistic
+		if len(alarm.Dimensions) == 1 && alarm.Dimensions[0].Value == gof.Ref("StreamLambda") {
+			thresholds[key] = alarm.Threshold
+		}
+	}
+	expectedThresholds := map[string]float64{
+		"Durationp99":          8000,
+		"IteratorAge":          60000,
+		"ConcurrentExecutions": 18,
+	}
+	for eachKey, eachThreshold := range expectedThresholds {
+		if thresholds[eachKey] != eachThreshold {
+			t.Fatalf("Unexpected %s threshold: %f", eachKey, thresholds[eachKey])
+		}
+	}
+	jsonBytes, _ := template.JSON()
+	for _, eachFragment := range []string{
+		`"ANOMALY_DETECTION_BAND(m1, 2)"`,
+		`"DeadLetterQueue",`,
+		`"QueueName"`,
+	} {
+		if !strings.Contains(string(jsonBytes), eachFragment) {
+			t.Fatalf("Failed to find %s in template: %s", eachFragment, string(jsonBytes))
+		}
+	}
+}
+
+func TestAlarmPolicyComposite(t *testing.T) {
+	policy := NewAlarmPolicy("Health").
+		WithErrors(1).
+		WithSNSTopics("arn:aws:sns:us-west-2:123412341234:alerts").
+		WithEventBridgeTargets("arn:aws:events:us-west-2:123412341234:event-bus/ops").
+		WithOKNotifications().
+		WithCompositeAlarm().
+		WithRollbackTriggers(5 * time.Minute)
+	template := testDecorateAlarmPolicy(t, policy)
+
+	for _, eachResource := range testResourcesOfType(template, "AWS::CloudWatch::Alarm") {
+		alarm := eachResource.(*gofcloudwatch.Alarm)
+		if len(alarm.AlarmActions) != 0 || alarm.AWSCloudFormationMetadata != nil {
+			t.Fatalf("Expected composite alarm to own actions and triggers: %#v", alarm)
+		}
+	}
+	composites := testResourcesOfType(template, "AWS::CloudWatch::CompositeAlarm")
+	if len(composites) != 1 {
+		t.Fatalf("Expected a single composite alarm. Found: %d", len(composites))
+	}
+	composite := composites[0].(*gofcloudwatch.CompositeAlarm)
+	if composite.AlarmName != "DecoratorService-Health" ||
+		len(composite.OKActions) != 1 ||
+		composite.AWSCloudFormationMetadata[spartaCF.RollbackTriggerMetadataKey] != 5 {
+		t.Fatalf("Unexpected composite alarm: %#v", composite)
+	}
+	rules := testResourcesOfType(template, "AWS::Events::Rule")
+	if len(rules) != 1 || len(rules[0].(*gofevents.Rule).Targets) != 1 {
+		t.Fatalf("Expected EventBridge rule with a single target")
+	}
+	jsonBytes, _ := template.JSON()
+	if !strings.Contains(string(jsonBytes), `ALARM(\"${`) ||
+		!strings.Contains(string(jsonBytes), `") OR ALARM(\"`) {
+		t.Fatalf("Unexpected composite alarm rule: %s", string(jsonBytes))
+	}
+}
+
+func TestAlarmPolicyRequiresTemplate(t *testing.T) {
+	_, decorateErr := testDecorateService(NewAlarmPolicy("Health").WithErrors(1), nil)
+	if decorateErr == nil {
+		t.Fatalf("Expected error without service template")
+	}
+}
@@ -130,7 +130,8 @@ var templateFuncMap = template.FuncMap{
 }
 
 // DashboardDecorator returns a ServiceDecoratorHook function that
-// can be attached the workflow to create a dashboard
+// can be attached the workflow to create a dashboard. See NewDashboardBuilder
+// for a configurable dashboard that discovers the service's resources.
 func DashboardDecorator(lambdaAWSInfo []*sparta.LambdaAWSInfo,
 	timeSeriesPeriod int) sparta.ServiceDecoratorHookFunc {
 	return func(ctx context.Context,
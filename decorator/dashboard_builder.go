@@ -0,0 +1,629 @@
+package decorator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	gofapigateway "github.com/awslabs/goformation/v5/cloudformation/apigateway"
+	gofcloudwatch "github.com/awslabs/goformation/v5/cloudformation/cloudwatch"
+	goflambda "github.com/awslabs/goformation/v5/cloudformation/lambda"
+	sparta "github.com/mweagle/Sparta/v3"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// CloudWatch dashboard widget types. See
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/CloudWatch-Dashboard-Body-Structure.html
+const (
+	// WidgetTypeMetric is a metric graph widget
+	WidgetTypeMetric = "metric"
+	// WidgetTypeLog is a CloudWatch Logs Insights query widget
+	WidgetTypeLog = "log"
+	// WidgetTypeAlarm is an alarm status widget
+	WidgetTypeAlarm = "alarm"
+	// WidgetTypeText is a markdown text widget
+	WidgetTypeText = "text"
+)
+
+const (
+	// DashboardGridWidth is the number of grid units in a dashboard row
+	DashboardGridWidth = 24
+	// maxAlarmStatusAlarms is the number of alarms an alarm status widget
+	// supports
+	maxAlarmStatusAlarms = 100
+	// maxLogWidgetLogGroups is the number of log groups a log widget
+	// can query
+	maxLogWidgetLogGroups  = 20
+	defaultWidgetWidth     = 8
+	defaultWidgetHeight    = 6
+	defaultDashboardPeriod = 60
+)
+
+// DashboardMetric is a single metric displayed by a metric or
+// single value widget. Dimension values may be literal values or
+// Fn::Sub expressions (eg: "${MyQueue.QueueName}"). Metrics with
+// an Expression are metric math expressions that reference other
+// metrics by ID.
+type DashboardMetric struct {
+	Namespace  string
+	MetricName string
+	Dimensions map[string]string
+	Stat       string
+	Label      string
+	ID         string
+	Expression string
+	Hidden     bool
+}
+
+// MarshalJSON returns the dashboard body array representation of the metric
+func (dm *DashboardMetric) MarshalJSON() ([]byte, error) {
+	if dm.Expression != "" {
+		expression := map[string]interface{}{
+			"expression": dm.Expression,
+		}
+		if dm.Label != "" {
+			expression["label"] = dm.Label
+		}
+		if dm.ID != "" {
+			expression["id"] = dm.ID
+		}
+		return json.Marshal([]interface{}{expression})
+	}
+	metric := []interface{}{
+		dm.Namespace,
+		dm.MetricName,
+	}
+	dimensionNames := make([]string, 0, len(dm.Dimensions))
+	for eachName := range dm.Dimensions {
+		dimensionNames = append(dimensionNames, eachName)
+	}
+	sort.Strings(dimensionNames)
+	for _, eachName := range dimensionNames {
+		metric = append(metric, eachName, dm.Dimensions[eachName])
+	}
+	options := map[string]interface{}{}
+	if dm.Stat != "" {
+		options["stat"] = dm.Stat
+	}
+	if dm.Label != "" {
+		options["label"] = dm.Label
+	}
+	if dm.ID != "" {
+		options["id"] = dm.ID
+	}
+	if dm.Hidden {
+		options["visible"] = false
+	}
+	if len(options) != 0 {
+		metric = append(metric, options)
+	}
+	return json.Marshal(metric)
+}
+
+// DashboardWidget is a single widget in a CloudWatch dashboard. The
+// dashboard body is an Fn::Sub expression, so metric dimensions, log
+// group names, alarm ARNs and WithProperty values may reference resources
+// and pseudo parameters (eg: "${AWS::Region}"). Titles and markdown passed
+// to the widget constructors are literal text.
+type DashboardWidget struct {
+	Type       string                 `json:"type"`
+	X          int                    `json:"x"`
+	Y          int                    `json:"y"`
+	Width      int                    `json:"width"`
+	Height     int                    `json:"height"`
+	Properties map[string]interface{} `json:"properties"`
+	positioned bool
+}
+
+// WithSize sets the widget size in grid units
+func (dw *DashboardWidget) WithSize(width int, height int) *DashboardWidget {
+	dw.Width = width
+	dw.Height = height
+	return dw
+}
+
+// WithPosition pins the widget to the given grid location. Pinned widgets
+// are not moved by the DashboardLayout.
+func (dw *DashboardWidget) WithPosition(x int, y int) *DashboardWidget {
+	dw.X = x
+	dw.Y = y
+	dw.positioned = true
+	return dw
+}
+
+// WithProperty sets a widget property
+func (dw *DashboardWidget) WithProperty(name string, value interface{}) *DashboardWidget {
+	dw.Properties[name] = value
+	return dw
+}
+
+// clone returns a copy of the widget that can be laid out without
+// modifying the original
+func (dw *DashboardWidget) clone() *DashboardWidget {
+	widget := *dw
+	widget.Properties = make(map[string]interface{}, len(dw.Properties))
+	for eachKey, eachValue := range dw.Properties {
+		widget.Properties[eachKey] = eachValue
+	}
+	return &widget
+}
+
+// literalSubText escapes the Fn::Sub variable syntax in text that should
+// be displayed as is
+func literalSubText(text string) string {
+	return strings.Replace(text, "${", "${!", -1)
+}
+
+func newDashboardWidget(widgetType string, properties map[string]interface{}) *DashboardWidget {
+	return &DashboardWidget{
+		Type:       widgetType,
+		Width:      defaultWidgetWidth,
+		Height:     defaultWidgetHeight,
+		Properties: properties,
+	}
+}
+
+// NewMetricWidget returns a time series graph of the given metrics
+func NewMetricWidget(title string, metrics ...*DashboardMetric) *DashboardWidget {
+	return newMetricWidget(literalSubText(title), metrics...)
+}
+
+// newMetricWidget returns a time series graph whose title is an Fn::Sub
+// expression
+func newMetricWidget(title string, metrics ...*DashboardMetric) *DashboardWidget {
+	return newDashboardWidget(WidgetTypeMetric, map[string]interface{}{
+		"title":   title,
+		"view":    "timeSeries",
+		"stacked": false,
+		"metrics": metrics,
+	})
+}
+
+// NewSingleValueWidget returns a widget that displays the most recent
+// value of the given metrics
+func NewSingleValueWidget(title string, metrics ...*DashboardMetric) *DashboardWidget {
+	return newDashboardWidget(WidgetTypeMetric, map[string]interface{}{
+		"title":   literalSubText(title),
+		"view":    "singleValue",
+		"metrics": metrics,
+	})
+}
+
+// NewLogInsightsWidget returns a widget that displays the results of the
+// CloudWatch Logs Insights query over the given log groups
+func NewLogInsightsWidget(title string, query string, logGroupNames ...string) *DashboardWidget {
+	sources := make([]string, 0, len(logGroupNames)+1)
+	for _, eachName := range logGroupNames {
+		sources = append(sources, fmt.Sprintf("SOURCE '%s'", eachName))
+	}
+	sources = append(sources, query)
+	return newDashboardWidget(WidgetTypeLog, map[string]interface{}{
+		"title": literalSubText(title),
+		"view":  "table",
+		"query": strings.Join(sources, " | "),
+	})
+}
+
+// NewAlarmStatusWidget returns a widget that displays the state of the
+// given alarm ARNs
+func NewAlarmStatusWidget(title string, alarmARNs ...string) *DashboardWidget {
+	return newDashboardWidget(WidgetTypeAlarm, map[string]interface{}{
+		"title":  literalSubText(title),
+		"alarms": alarmARNs,
+	})
+}
+
+// NewTextWidget returns a markdown text widget
+func NewTextWidget(markdown string) *DashboardWidget {
+	return newTextWidget(literalSubText(markdown))
+}
+
+// newTextWidget returns a markdown text widget whose markdown is an Fn::Sub
+// expression
+func newTextWidget(markdown string) *DashboardWidget {
+	return newDashboardWidget(WidgetTypeText, map[string]interface{}{
+		"markdown": markdown,
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Presets
+////////////////////////////////////////////////////////////////////////////////
+
+// NewLambdaFunctionWidget returns the invocations, errors and throttles
+// graph for the given function name
+func NewLambdaFunctionWidget(functionName string) *DashboardWidget {
+	dimensions := map[string]string{"FunctionName": functionName}
+	return newMetricWidget(fmt.Sprintf("λ: %s", functionName),
+		&DashboardMetric{Namespace: "AWS/Lambda", MetricName: "Invocations", Dimensions: dimensions, Stat: "Sum"},
+		&DashboardMetric{Namespace: "AWS/Lambda", MetricName: "Errors", Dimensions: dimensions, Stat: "Sum"},
+		&DashboardMetric{Namespace: "AWS/Lambda", MetricName: "Throttles", Dimensions: dimensions, Stat: "Sum"})
+}
+
+// NewAPIGatewayLatencyWidget returns the p50, p90 and p99 latency graph
+// for the given REST API name
+func NewAPIGatewayLatencyWidget(apiName string) *DashboardWidget {
+	dimensions := map[string]string{"ApiName": apiName}
+	return newMetricWidget(fmt.Sprintf("API Latency: %s", apiName),
+		&DashboardMetric{Namespace: "AWS/ApiGateway", MetricName: "Latency", Dimensions: dimensions, Stat: "p50", Label: "p50"},
+		&DashboardMetric{Namespace: "AWS/ApiGateway", MetricName: "Latency", Dimensions: dimensions, Stat: "p90", Label: "p90"},
+		&DashboardMetric{Namespace: "AWS/ApiGateway", MetricName: "Latency", Dimensions: dimensions, Stat: "p99", Label: "p99"})
+}
+
+// NewAPIGatewayErrorsWidget returns the 4XX and 5XX count graph for the
+// given REST API name
+func NewAPIGatewayErrorsWidget(apiName string) *DashboardWidget {
+	dimensions := map[string]string{"ApiName": apiName}
+	return newMetricWidget(fmt.Sprintf("API Errors: %s", apiName),
+		&DashboardMetric{Namespace: "AWS/ApiGateway", MetricName: "4XXError", Dimensions: dimensions, Stat: "Sum"},
+		&DashboardMetric{Namespace: "AWS/ApiGateway", MetricName: "5XXError", Dimensions: dimensions, Stat: "Sum"})
+}
+
+// NewSQSAgeOfOldestMessageWidget returns the age of the oldest message
+// graph for the given queue name
+func NewSQSAgeOfOldestMessageWidget(queueName string) *DashboardWidget {
+	return newMetricWidget(fmt.Sprintf("SQS Age: %s", queueName),
+		&DashboardMetric{
+			Namespace:  "AWS/SQS",
+			MetricName: "ApproximateAgeOfOldestMessage",
+			Dimensions: map[string]string{"QueueName": queueName},
+			Stat:       "Maximum",
+		})
+}
+
+// NewDynamoDBThrottlesWidget returns the read and write throttle graph
+// for the given table name
+func NewDynamoDBThrottlesWidget(tableName string) *DashboardWidget {
+	dimensions := map[string]string{"TableName": tableName}
+	return newMetricWidget(fmt.Sprintf("DynamoDB Throttles: %s", tableName),
+		&DashboardMetric{Namespace: "AWS/DynamoDB", MetricName: "ReadThrottleEvents", Dimensions: dimensions, Stat: "Sum"},
+		&DashboardMetric{Namespace: "AWS/DynamoDB", MetricName: "WriteThrottleEvents", Dimensions: dimensions, Stat: "Sum"})
+}
+
+// NewStepFunctionsFailuresWidget returns the failed, timed out and aborted
+// execution graph for the given state machine ARN
+func NewStepFunctionsFailuresWidget(stateMachineArn string) *DashboardWidget {
+	dimensions := map[string]string{"StateMachineArn": stateMachineArn}
+	return NewMetricWidget("Step Functions Failures",
+		&DashboardMetric{Namespace: "AWS/States", MetricName: "ExecutionsFailed", Dimensions: dimensions, Stat: "Sum"},
+		&DashboardMetric{Namespace: "AWS/States", MetricName: "ExecutionsTimedOut", Dimensions: dimensions, Stat: "Sum"},
+		&DashboardMetric{Namespace: "AWS/States", MetricName: "ExecutionsAborted", Dimensions: dimensions, Stat: "Sum"})
+}
+
+// NewKinesisIteratorAgeWidget returns the GetRecords iterator age graph
+// for the given stream name
+func NewKinesisIteratorAgeWidget(streamName string) *DashboardWidget {
+	return newMetricWidget(fmt.Sprintf("Kinesis Iterator Age: %s", streamName),
+		&DashboardMetric{
+			Namespace:  "AWS/Kinesis",
+			MetricName: "GetRecords.IteratorAgeMilliseconds",
+			Dimensions: map[string]string{"StreamName": streamName},
+			Stat:       "Maximum",
+		})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Layout
+////////////////////////////////////////////////////////////////////////////////
+
+// DashboardLayout assigns grid positions to the widgets that
+// were not pinned with WithPosition
+type DashboardLayout func(widgets []*DashboardWidget)
+
+// FlowLayout places widgets left to right in rows of DashboardGridWidth
+// units, starting a new row when the next widget doesn't fit
+func FlowLayout() DashboardLayout {
+	return func(widgets []*DashboardWidget) {
+		x, y, rowHeight := 0, 0, 0
+		for _, eachWidget := range widgets {
+			if eachWidget.positioned {
+				continue
+			}
+			if x != 0 && x+eachWidget.Width > DashboardGridWidth {
+				x = 0
+				y += rowHeight
+				rowHeight = 0
+			}
+			eachWidget.X = x
+			eachWidget.Y = y
+			x += eachWidget.Width
+			if eachWidget.Height > rowHeight {
+				rowHeight = eachWidget.Height
+			}
+		}
+	}
+}
+
+// ColumnLayout places widgets in a grid with the given number of equally
+// sized columns
+func ColumnLayout(columns int, height int) DashboardLayout {
+	if columns <= 0 {
+		columns = 1
+	}
+	return func(widgets []*DashboardWidget) {
+		width := DashboardGridWidth / columns
+		index := 0
+		for _, eachWidget := range widgets {
+			if eachWidget.positioned {
+				continue
+			}
+			eachWidget.Width = width
+			eachWidget.Height = height
+			eachWidget.X = (index % columns) * width
+			eachWidget.Y = (index / columns) * height
+			index++
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Builder
+////////////////////////////////////////////////////////////////////////////////
+
+// DashboardBuilder assembles a CloudWatch dashboard from user supplied
+// widgets and widgets discovered from the resources in the service's
+// CloudFormation template. It satisfies sparta.ServiceDecoratorHookHandler.
+type DashboardBuilder struct {
+	name           string
+	period         int
+	header         bool
+	discover       bool
+	widgets        []*DashboardWidget
+	layout         DashboardLayout
+	discoveryTypes map[string]bool
+}
+
+// NewDashboardBuilder returns a DashboardBuilder that includes the
+// Sparta summary header and discovered resource widgets laid out with
+// a FlowLayout
+func NewDashboardBuilder() *DashboardBuilder {
+	return &DashboardBuilder{
+		period:   defaultDashboardPeriod,
+		header:   true,
+		discover: true,
+		layout:   FlowLayout(),
+	}
+}
+
+// WithName sets the dashboard name. The default is the service name.
+func (db *DashboardBuilder) WithName(name string) *DashboardBuilder {
+	db.name = name
+	return db
+}
+
+// WithPeriod sets the default period, in seconds, for metric widgets
+func (db *DashboardBuilder) WithPeriod(seconds int) *DashboardBuilder {
+	db.period = seconds
+	return db
+}
+
+// WithWidgets appends widgets to the dashboard. User widgets are
+// placed after the header and before any discovered widgets.
+func (db *DashboardBuilder) WithWidgets(widgets ...*DashboardWidget) *DashboardBuilder {
+	db.widgets = append(db.widgets, widgets...)
+	return db
+}
+
+// WithLayout replaces the default FlowLayout
+func (db *DashboardBuilder) WithLayout(layout DashboardLayout) *DashboardBuilder {
+	db.layout = layout
+	return db
+}
+
+// WithoutHeader excludes the Sparta summary header
+func (db *DashboardBuilder) WithoutHeader() *DashboardBuilder {
+	db.header = false
+	return db
+}
+
+// WithoutDiscovery excludes widgets for resources discovered in
+// the template
+func (db *DashboardBuilder) WithoutDiscovery() *DashboardBuilder {
+	db.discover = false
+	return db
+}
+
+// WithDiscoveryTypes limits discovery to the given CloudFormation
+// resource types (eg: "AWS::SQS::Queue")
+func (db *DashboardBuilder) WithDiscoveryTypes(resourceTypes ...string) *DashboardBuilder {
+	db.discoveryTypes = make(map[string]bool)
+	for _, eachType := range resourceTypes {
+		db.discoveryTypes[eachType] = true
+	}
+	return db
+}
+
+func (db *DashboardBuilder) headerWidget() *DashboardWidget {
+	markdown := strings.Join([]string{
+		"## ![Sparta](https://mweagle.github.io/SpartaPublicResources/sparta/SpartaHelmet32.png) ${AWS::StackName} Summary",
+		"* ☁️ [CloudFormation Stack](https://${AWS::Region}.console.aws.amazon.com/cloudformation/home?region=${AWS::Region}#/stack/detail?stackId=${AWS::StackId})",
+		"* ☢️ [XRay](https://${AWS::Region}.console.aws.amazon.com/xray/home?region=${AWS::Region}#/service-map)",
+		fmt.Sprintf("* **Sparta Version** : %s ( [%s](https://github.com/mweagle/Sparta/commit/%s) )",
+			sparta.SpartaVersion,
+			sparta.SpartaGitHash,
+			sparta.SpartaGitHash),
+		"  * 🔗 [Sparta Documentation](https://gosparta.io)",
+	}, "\n")
+	return newTextWidget(markdown).WithSize(DashboardGridWidth, defaultWidgetHeight)
+}
+
+// discoveredWidgets returns the preset widgets for the supported resources
+// in the template, ordered by resource type and logical name
+func (db *DashboardBuilder) discoveredWidgets(template *gof.Template) []*DashboardWidget {
+	logicalNames := make([]string, 0, len(template.Resources))
+	for eachName := range template.Resources {
+		logicalNames = append(logicalNames, eachName)
+	}
+	sort.Strings(logicalNames)
+
+	typeOrder := []string{
+		"AWS::Lambda::Function",
+		"AWS::ApiGateway::RestApi",
+		"AWS::SQS::Queue",
+		"AWS::DynamoDB::Table",
+		"AWS::StepFunctions::StateMachine",
+		"AWS::Kinesis::Stream",
+	}
+	widgets := []*DashboardWidget{}
+	functionLogGroups := []string{}
+	alarmARNs := []string{}
+	for _, eachType := range typeOrder {
+		if db.discoveryTypes != nil && !db.discoveryTypes[eachType] {
+			continue
+		}
+		for _, eachName := range logicalNames {
+			resource := template.Resources[eachName]
+			if resource.AWSCloudFormationType() != eachType {
+				continue
+			}
+			switch eachType {
+			case "AWS::Lambda::Function":
+				widgets = append(widgets,
+					NewLambdaFunctionWidget(fmt.Sprintf("${%s}", eachName)))
+				functionLogGroups = append(functionLogGroups,
+					fmt.Sprintf("/aws/lambda/${%s}", eachName))
+			case "AWS::ApiGateway::RestApi":
+				// The ApiName dimension is the API name, not the Ref value
+				restAPI, restAPIOk := resource.(*gofapigateway.RestApi)
+				if restAPIOk && restAPI.Name != "" {
+					widgets = append(widgets,
+						NewAPIGatewayLatencyWidget(restAPI.Name),
+						NewAPIGatewayErrorsWidget(restAPI.Name))
+				}
+			case "AWS::SQS::Queue":
+				widgets = append(widgets,
+					NewSQSAgeOfOldestMessageWidget(fmt.Sprintf("${%s.QueueName}", eachName)))
+			case "AWS::DynamoDB::Table":
+				widgets = append(widgets,
+					NewDynamoDBThrottlesWidget(fmt.Sprintf("${%s}", eachName)))
+			case "AWS::StepFunctions::StateMachine":
+				widgets = append(widgets,
+					NewStepFunctionsFailuresWidget(fmt.Sprintf("${%s}", eachName)).
+						WithProperty("title", fmt.Sprintf("Step Functions Failures: ${%s.Name}", eachName)))
+			case "AWS::Kinesis::Stream":
+				widgets = append(widgets,
+					NewKinesisIteratorAgeWidget(fmt.Sprintf("${%s}", eachName)))
+			}
+		}
+	}
+	if db.discoveryTypes == nil || db.discoveryTypes["AWS::CloudWatch::Alarm"] {
+		for _, eachName := range logicalNames {
+			if template.Resources[eachName].AWSCloudFormationType() == "AWS::CloudWatch::Alarm" &&
+				len(alarmARNs) < maxAlarmStatusAlarms {
+				alarmARNs = append(alarmARNs, fmt.Sprintf("${%s.Arn}", eachName))
+			}
+		}
+	}
+	if len(alarmARNs) != 0 {
+		widgets = append(widgets,
+			NewAlarmStatusWidget("Alarms", alarmARNs...).WithSize(DashboardGridWidth, 3))
+	}
+	if len(functionLogGroups) != 0 {
+		if len(functionLogGroups) > maxLogWidgetLogGroups {
+			functionLogGroups = functionLogGroups[0:maxLogWidgetLogGroups]
+		}
+		widgets = append(widgets,
+			NewLogInsightsWidget("Recent Errors",
+				`fields @timestamp, @message | filter level = "error" or @message like /ERROR/ | sort @timestamp desc | limit 50`,
+				functionLogGroups...).WithSize(DashboardGridWidth, defaultWidgetHeight))
+	}
+	return widgets
+}
+
+// Widgets returns the laid out widgets for the given template
+func (db *DashboardBuilder) Widgets(template *gof.Template) []*DashboardWidget {
+	widgets := []*DashboardWidget{}
+	if db.header {
+		widgets = append(widgets, db.headerWidget())
+	}
+	// Copy the user supplied widgets so that building doesn't change them
+	for _, eachWidget := range db.widgets {
+		widgets = append(widgets, eachWidget.clone())
+	}
+	if db.discover && template != nil {
+		widgets = append(widgets, db.discoveredWidgets(template)...)
+	}
+	for _, eachWidget := range widgets {
+		if eachWidget.Type == WidgetTypeText || eachWidget.Type == WidgetTypeAlarm {
+			continue
+		}
+		if _, exists := eachWidget.Properties["region"]; !exists {
+			eachWidget.Properties["region"] = "${AWS::Region}"
+		}
+		_, exists := eachWidget.Properties["period"]
+		if eachWidget.Type == WidgetTypeMetric && !exists && db.period > 0 {
+			eachWidget.Properties["period"] = db.period
+		}
+	}
+	if db.layout != nil {
+		db.layout(widgets)
+	}
+	return widgets
+}
+
+// Body returns the Fn::Sub encoded dashboard body for the given template
+func (db *DashboardBuilder) Body(template *gof.Template) (string, error) {
+	widgets := db.Widgets(template)
+	for _, eachWidget := range widgets {
+		if eachWidget.X < 0 ||
+			eachWidget.Width <= 0 ||
+			eachWidget.X+eachWidget.Width > DashboardGridWidth {
+			return "", errors.Errorf("Invalid dashboard widget extents (x: %d, width: %d)",
+				eachWidget.X,
+				eachWidget.Width)
+		}
+	}
+	bodyJSON, bodyJSONErr := json.Marshal(map[string]interface{}{
+		"widgets": widgets,
+	})
+	if bodyJSONErr != nil {
+		return "", errors.Wrapf(bodyJSONErr, "Failed to marshal dashboard body")
+	}
+	return gof.Sub(string(bodyJSON)), nil
+}
+
+// DecorateService satisfies the sparta.ServiceDecoratorHookHandler interface
+func (db *DashboardBuilder) DecorateService(ctx context.Context,
+	serviceName string,
+	cfTemplate *gof.Template,
+	lambdaFunctionCode *goflambda.Function_Code,
+	buildID string,
+	awsConfig awsv2.Config,
+	noop bool,
+	logger *zerolog.Logger) (context.Context, error) {
+
+	serviceTemplate, _ := ctx.Value(sparta.ContextKeyBuildTemplate).(*gof.Template)
+	if serviceTemplate == nil && db.discover {
+		logger.Warn().Msg("Service template unavailable. Dashboard widgets will not be discovered")
+	}
+	dashboardBody, dashboardBodyErr := db.Body(serviceTemplate)
+	if dashboardBodyErr != nil {
+		return ctx, dashboardBodyErr
+	}
+	dashboardName := db.name
+	if dashboardName == "" {
+		dashboardName = serviceName
+	}
+	dashboardResourceName := sparta.CloudFormationResourceName("Dashboard", "Dashboard")
+	cfTemplate.Resources[dashboardResourceName] = &gofcloudwatch.Dashboard{
+		DashboardName: dashboardName,
+		DashboardBody: dashboardBody,
+	}
+	cfTemplate.Outputs[OutputDashboardURL] = gof.Output{
+		Description: "CloudWatch Dashboard URL",
+		Value: gof.Join("", []string{
+			"https://",
+			gof.Ref("AWS::Region"),
+			".console.aws.amazon.com/cloudwatch/home?region=",
+			gof.Ref("AWS::Region"),
+			"#dashboards:name=",
+			gof.Ref(dashboardResourceName),
+		}),
+	}
+	return ctx, nil
+}
@@ -0,0 +1,153 @@
+package decorator
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	sparta "github.com/mweagle/Sparta/v3"
+)
+
+type testDashboardBody struct {
+	Widgets []*DashboardWidget `json:"widgets"`
+}
+
+func testDashboardBodyWidgets(t *testing.T, template *gof.Template) (string, *testDashboardBody) {
+	jsonBytes, jsonBytesErr := template.JSON()
+	if jsonBytesErr != nil {
+		t.Fatal(jsonBytesErr)
+	}
+	var parsedTemplate map[string]interface{}
+	_ = json.Unmarshal(jsonBytes, &parsedTemplate)
+	resources := parsedTemplate["Resources"].(map[string]interface{})
+	dashboardName := sparta.CloudFormationResourceName("Dashboard", "Dashboard")
+	dashboard, dashboardOk := resources[dashboardName].(map[string]interface{})
+	if !dashboardOk {
+		t.Fatalf("Failed to find dashboard resource in: %s", string(jsonBytes))
+	}
+	properties := dashboard["Properties"].(map[string]interface{})
+	body := properties["DashboardBody"].(map[string]interface{})["Fn::Sub"].(string)
+	var parsedBody testDashboardBody
+	unmarshalErr := json.Unmarshal([]byte(body), &parsedBody)
+	if unmarshalErr != nil {
+		t.Fatalf("Invalid dashboard body: %s\n%s", unmarshalErr, body)
+	}
+	return body, &parsedBody
+}
+
+func TestDashboardBuilderDiscovery(t *testing.T) {
+	customWidget := NewSingleValueWidget("Orders",
+		&DashboardMetric{Namespace: "Orders", MetricName: "Placed", Stat: "Sum"})
+
+	template, decorateErr := testDecorateService(NewDashboardBuilder().WithWidgets(customWidget),
+		testDashboardServiceTemplate())
+	if decorateErr != nil {
+		t.Fatal(decorateErr)
+	}
+	body, parsedBody := testDashboardBodyWidgets(t, template)
+	for _, eachFragment := range []string{
+		`["AWS/Lambda","Invocations","FunctionName","${HelloLambda}",{"stat":"Sum"}]`,
+		`"ApiName","OrdersAPI"`,
+		`"QueueName","${OrdersQueue.QueueName}"`,
+		`"TableName","${OrdersTable}"`,
+		`"${HelloAlarm.Arn}"`,
+		`SOURCE '/aws/lambda/${HelloLambda}'`,
+	} {
+		if !strings.Contains(body, eachFragment) {
+			t.Fatalf("Failed to find %s in dashboard body: %s", eachFragment, body)
+		}
+	}
+	// Header, custom, lambda, API latency & errors, queue, table, alarms, logs
+	if len(parsedBody.Widgets) != 9 {
+		t.Fatalf("Unexpected widget count: %d", len(parsedBody.Widgets))
+	}
+	if parsedBody.Widgets[1].Properties["view"] != "singleValue" {
+		t.Fatalf("Expected user widget to follow the header")
+	}
+	for _, eachWidget := range parsedBody.Widgets {
+		if eachWidget.X+eachWidget.Width > DashboardGridWidth {
+			t.Fatalf("Widget exceeds grid width: %#v", eachWidget)
+		}
+	}
+	if _, exists := template.Outputs[OutputDashboardURL]; !exists {
+		t.Fatalf("Expected dashboard URL output")
+	}
+}
+
+func TestDashboardBuilderLayout(t *testing.T) {
+	pinned := NewTextWidget("pinned").WithPosition(0, 100)
+	builder := NewDashboardBuilder().
+		WithoutHeader().
+		WithDiscoveryTypes("AWS::SQS::Queue", "AWS::DynamoDB::Table").
+		WithWidgets(pinned).
+		WithLayout(ColumnLayout(2, 4))
+	widgets := builder.Widgets(testDashboardServiceTemplate())
+	if len(widgets) != 3 {
+		t.Fatalf("Unexpected widget count: %d", len(widgets))
+	}
+	if widgets[0].X != 0 || widgets[0].Y != 100 {
+		t.Fatalf("Pinned widget was moved: %#v", widgets[0])
+	}
+	if widgets[1].X != 0 || widgets[2].X != 12 || widgets[2].Width != 12 || widgets[2].Height != 4 {
+		t.Fatalf("Unexpected column layout: %#v, %#v", widgets[1], widgets[2])
+	}
+	_, bodyErr := NewDashboardBuilder().
+		WithWidgets(NewTextWidget("too wide").WithSize(DashboardGridWidth+1, 2)).
+		Body(nil)
+	if bodyErr == nil {
+		t.Fatalf("Expected error for widget wider than the grid")
+	}
+}
+
+func TestDashboardBuilderWidgetsCopied(t *testing.T) {
+	userWidget := NewMetricWidget("Orders",
+		&DashboardMetric{Namespace: "Orders", MetricName: "Placed", Stat: "Sum"})
+	builder := NewDashboardBuilder().
+		WithoutDiscovery().
+		WithPeriod(300).
+		WithWidgets(userWidget).
+		WithLayout(ColumnLayout(1, 4))
+	for i := 0; i < 2; i++ {
+		widgets := builder.Widgets(nil)
+		if len(widgets) != 2 || widgets[1].Y != 4 {
+			t.Fatalf("Unexpected layout: %#v", widgets)
+		}
+	}
+	if userWidget.Width != defaultWidgetWidth ||
+		userWidget.Height != defaultWidgetHeight ||
+		userWidget.Y != 0 {
+		t.Fatalf("User widget was laid out in place: %#v", userWidget)
+	}
+	for _, eachProperty := range []string{"region", "period"} {
+		if _, exists := userWidget.Properties[eachProperty]; exists {
+			t.Fatalf("User widget property %s was set in place", eachProperty)
+		}
+	}
+}
+
+func TestDashboardBuilderLiteralText(t *testing.T) {
+	widgets := NewDashboardBuilder().
+		WithoutHeader().
+		WithoutDiscovery().
+		WithWidgets(NewTextWidget("Cost: ${total}"),
+			NewMetricWidget("Orders ${region}",
+				&DashboardMetric{Namespace: "Orders",
+					MetricName: "Placed",
+					Dimensions: map[string]string{"Stage": "${AWS::StackName}"}})).
+		Widgets(nil)
+	widgetsJSON, widgetsJSONErr := json.Marshal(widgets)
+	if widgetsJSONErr != nil {
+		t.Fatal(widgetsJSONErr)
+	}
+	for _, eachFragment := range []string{
+		`"markdown":"Cost: ${!total}"`,
+		`"title":"Orders ${!region}"`,
+		`"Stage","${AWS::StackName}"`,
+		`"region":"${AWS::Region}"`,
+	} {
+		if !strings.Contains(string(widgetsJSON), eachFragment) {
+			t.Fatalf("Failed to find %s in dashboard widgets: %s", eachFragment, string(widgetsJSON))
+		}
+	}
+}
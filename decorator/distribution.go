@@ -2,7 +2,9 @@ package decorator
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
 	gof "github.com/awslabs/goformation/v5/cloudformation"
@@ -10,10 +12,180 @@ import (
 	goflambda "github.com/awslabs/goformation/v5/cloudformation/lambda"
 	gofRoute53 "github.com/awslabs/goformation/v5/cloudformation/route53"
 	sparta "github.com/mweagle/Sparta/v3"
+	cfCustomResources "github.com/mweagle/Sparta/v3/aws/cloudformation/resources"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
+const (
+	// DefaultCloudFrontAPIPathPattern is the default path pattern routed
+	// to the CloudFrontSiteDistributionOptions API origin
+	DefaultCloudFrontAPIPathPattern = "/api/*"
+
+	// OutputCloudFrontDistributionID is the keyname used in the CloudFormation
+	// Output that stores the CloudFront distribution ID
+	// @enum OutputKey
+	OutputCloudFrontDistributionID = "CloudFrontDistributionID"
+)
+
+const (
+	cloudFrontS3OriginID  = "S3Origin"
+	cloudFrontAPIOriginID = "APIOrigin"
+	// Resource type that isn't supported by goformation
+	cloudFrontOriginAccessControlType = "AWS::CloudFront::OriginAccessControl"
+)
+
+// cloudFrontAPIForwardedHeaders are the viewer headers forwarded to
+// the API origin. The Host header isn't forwarded as API Gateway
+// uses it to route the request.
+var cloudFrontAPIForwardedHeaders = []string{
+	"Accept",
+	"Accept-Language",
+	"Content-Type",
+	"Origin",
+	"X-API-Key",
+}
+
+// cloudFrontSiteRouterTemplate is the viewer request CloudFront Function
+// that rewrites directory requests to the index document, routes single
+// page application requests and selects precompressed variants
+const cloudFrontSiteRouterTemplate = `var INDEX_DOCUMENT = %s;
+var MANIFEST = %s;
+var SPA_ROUTING = %t;
+var ENCODINGS = %s;
+var PRECOMPRESSED = /\.(%s)$/;
+
+function handler(event) {
+  var request = event.request;
+  var uri = request.uri;
+  if (uri.charAt(uri.length - 1) === '/') {
+    uri += INDEX_DOCUMENT;
+  } else if (SPA_ROUTING && uri.lastIndexOf('.') < uri.lastIndexOf('/')) {
+    uri = '/' + INDEX_DOCUMENT;
+  }
+  var acceptEncoding = request.headers['accept-encoding'];
+  if (acceptEncoding && uri !== MANIFEST && PRECOMPRESSED.test(uri)) {
+    for (var i = 0; i < ENCODINGS.length; i++) {
+      if (acceptEncoding.value.indexOf(ENCODINGS[i][0]) >= 0) {
+        uri += ENCODINGS[i][1];
+        break;
+      }
+    }
+  }
+  request.uri = uri;
+  return request;
+}
+`
+
+// CloudFrontSiteDistributionOptions are the optional settings for the
+// CloudFront distribution provisioned by
+// CloudFrontSiteDistributionDecoratorWithOptions
+type CloudFrontSiteDistributionOptions struct {
+	// SinglePageApplication routes requests for missing objects to the
+	// index document with a 200 status. Without an API origin, 403 and
+	// 404 responses are rewritten. With an API origin, requests for paths
+	// without a file extension are rewritten by a CloudFront Function so
+	// that API error responses are preserved.
+	SinglePageApplication bool
+	// SecurityHeaders are the response headers (eg: Content-Security-Policy,
+	// Strict-Transport-Security) added to every response. Defaults to
+	// DefaultCloudFrontSecurityHeaders("").
+	SecurityHeaders *gofCloudFront.ResponseHeadersPolicy_SecurityHeadersConfig
+	// API is an optional REST API whose stage is routed for the
+	// APIPathPattern requests. The request path is forwarded unchanged, so
+	// the API resources must include the path prefix (eg: /api/hello).
+	API *sparta.API
+	// APIPathPattern is the path pattern for the API origin. Defaults to
+	// DefaultCloudFrontAPIPathPattern.
+	APIPathPattern string
+	// WebACLArn is the optional ARN of a WAFv2 web ACL with the CLOUDFRONT
+	// scope to associate with the distribution
+	WebACLArn string
+}
+
+// DefaultCloudFrontSecurityHeaders returns the security headers that enable
+// HSTS for two years, disable MIME sniffing and framing and limit the
+// referrer. The Content-Security-Policy header is only included if
+// contentSecurityPolicy is non-empty.
+func DefaultCloudFrontSecurityHeaders(contentSecurityPolicy string) *gofCloudFront.ResponseHeadersPolicy_SecurityHeadersConfig {
+	securityHeaders := &gofCloudFront.ResponseHeadersPolicy_SecurityHeadersConfig{
+		StrictTransportSecurity: &gofCloudFront.ResponseHeadersPolicy_StrictTransportSecurity{
+			AccessControlMaxAgeSec: 63072000,
+			IncludeSubdomains:      true,
+			Override:               true,
+		},
+		ContentTypeOptions: &gofCloudFront.ResponseHeadersPolicy_ContentTypeOptions{
+			Override: true,
+		},
+		FrameOptions: &gofCloudFront.ResponseHeadersPolicy_FrameOptions{
+			FrameOption: "DENY",
+			Override:    true,
+		},
+		ReferrerPolicy: &gofCloudFront.ResponseHeadersPolicy_ReferrerPolicy{
+			ReferrerPolicy: "strict-origin-when-cross-origin",
+			Override:       true,
+		},
+	}
+	if contentSecurityPolicy != "" {
+		securityHeaders.ContentSecurityPolicy = &gofCloudFront.ResponseHeadersPolicy_ContentSecurityPolicy{
+			ContentSecurityPolicy: contentSecurityPolicy,
+			Override:              true,
+		}
+	}
+	return securityHeaders
+}
+
+// cloudFrontSiteRouterCode returns the CloudFront Function source
+func cloudFrontSiteRouterCode(indexDocument string,
+	singlePageApplication bool,
+	precompressedEncodings []string) (string, error) {
+	suffixes := map[string]string{
+		cfCustomResources.ContentEncodingGzip:   ".gz",
+		cfCustomResources.ContentEncodingBrotli: ".br",
+	}
+	encodings := [][]string{}
+	for _, eachEncoding := range precompressedEncodings {
+		suffix, suffixExists := suffixes[eachEncoding]
+		if !suffixExists {
+			return "", errors.Errorf("Unsupported S3Site precompressed encoding: %s", eachEncoding)
+		}
+		encodings = append(encodings, []string{eachEncoding, suffix})
+	}
+	jsonValues := []interface{}{indexDocument,
+		"/" + cfCustomResources.DefaultManifestName,
+		encodings}
+	jsonLiterals := make([]interface{}, len(jsonValues))
+	for eachIndex, eachValue := range jsonValues {
+		jsonBytes, jsonBytesErr := json.Marshal(eachValue)
+		if jsonBytesErr != nil {
+			return "", jsonBytesErr
+		}
+		jsonLiterals[eachIndex] = string(jsonBytes)
+	}
+	return fmt.Sprintf(cloudFrontSiteRouterTemplate,
+		jsonLiterals[0],
+		jsonLiterals[1],
+		singlePageApplication,
+		jsonLiterals[2],
+		strings.Join(cfCustomResources.PrecompressedExtensions, "|")), nil
+}
+
+// cloudFormationProperties returns the JSON object representation of the
+// typed goformation properties so that properties goformation doesn't
+// support can be added
+func cloudFormationProperties(properties interface{}) (map[string]interface{}, error) {
+	jsonBytes, jsonBytesErr := json.Marshal(properties)
+	if jsonBytesErr != nil {
+		return nil, jsonBytesErr
+	}
+	var jsonProperties map[string]interface{}
+	unmarshalErr := json.Unmarshal(jsonBytes, &jsonProperties)
+	if unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+	return jsonProperties, nil
+}
+
 // CloudFrontSiteDistributionDecorator returns a CloudFrontSiteDecorator with
 // the default VIP certificate.
 // NOTE: The default VIP certificate is expensive. Consider using SNI to
@@ -47,6 +219,26 @@ func CloudFrontSiteDistributionDecoratorWithCert(s3Site *sparta.S3Site,
 	subdomain string,
 	domainName string,
 	cert *gofCloudFront.Distribution_ViewerCertificate) sparta.ServiceDecoratorHookHandler {
+	return CloudFrontSiteDistributionDecoratorWithOptions(s3Site,
+		subdomain,
+		domainName,
+		cert,
+		nil)
+}
+
+// CloudFrontSiteDistributionDecoratorWithOptions returns a
+// ServiceDecoratorHookHandler function that provisions a CloudFront
+// distribution whose origin is the supplied S3Site bucket. The bucket is
+// private and is read with Origin Access Control. A viewer request
+// CloudFront Function serves the index document for directory paths and
+// selects the S3Site precompressed variants. The optional settings
+// configure single page application routing, security headers, an API
+// origin and a WAF web ACL.
+func CloudFrontSiteDistributionDecoratorWithOptions(s3Site *sparta.S3Site,
+	subdomain string,
+	domainName string,
+	cert *gofCloudFront.Distribution_ViewerCertificate,
+	options *CloudFrontSiteDistributionOptions) sparta.ServiceDecoratorHookHandler {
 
 	// Setup the CF distro
 	distroDecorator := func(ctx context.Context,
@@ -58,6 +250,10 @@ func CloudFrontSiteDistributionDecoratorWithCert(s3Site *sparta.S3Site,
 		noop bool,
 		logger *zerolog.Logger) (context.Context, error) {
 
+		distroOptions := options
+		if distroOptions == nil {
+			distroOptions = &CloudFrontSiteDistributionOptions{}
+		}
 		// Computed name
 		bucketName := domainName
 		if subdomain != "" {
@@ -100,31 +296,226 @@ func CloudFrontSiteDistributionDecoratorWithCert(s3Site *sparta.S3Site,
 			s3Site.WebsiteConfiguration.IndexDocument.Suffix != nil {
 			indexDocument = *s3Site.WebsiteConfiguration.IndexDocument.Suffix
 		}
+
+		// The bucket only allows the distribution to read objects
+		s3Site.CloudFrontDistributionARN = gof.Join("", []string{
+			"arn:aws:cloudfront::",
+			gof.Ref("AWS::AccountId"),
+			":distribution/",
+			gof.Ref(cloudFrontDistroResourceName),
+		})
+		originAccessControlResourceName := sparta.CloudFormationResourceName("CloudFrontOAC",
+			"CloudFrontOAC")
+		template.Resources[originAccessControlResourceName] = &gof.CustomResource{
+			Type: cloudFrontOriginAccessControlType,
+			Properties: map[string]interface{}{
+				"OriginAccessControlConfig": map[string]interface{}{
+					"Name": sparta.CloudFormationResourceName("SiteOAC",
+						serviceName,
+						bucketName),
+					"Description":                   fmt.Sprintf("%s S3 site", serviceName),
+					"OriginAccessControlOriginType": "s3",
+					"SigningBehavior":               "always",
+					"SigningProtocol":               "sigv4",
+				},
+			},
+		}
+
+		// The function that rewrites the viewer request
+		siteRouterCode, siteRouterCodeErr := cloudFrontSiteRouterCode(indexDocument,
+			distroOptions.SinglePageApplication && distroOptions.API != nil,
+			s3Site.PrecompressedEncodings)
+		if siteRouterCodeErr != nil {
+			return ctx, siteRouterCodeErr
+		}
+		siteRouterResourceName := sparta.CloudFormationResourceName("CloudFrontSiteRouter",
+			"CloudFrontSiteRouter")
+		template.Resources[siteRouterResourceName] = &gofCloudFront.Function{
+			AutoPublish:  true,
+			FunctionCode: siteRouterCode,
+			FunctionConfig: &gofCloudFront.Function_FunctionConfig{
+				Comment: fmt.Sprintf("%s S3 site viewer request router", serviceName),
+				Runtime: "cloudfront-js-1.0",
+			},
+			Name: sparta.CloudFormationResourceName("SiteRouter",
+				serviceName,
+				bucketName),
+		}
+
+		// The security headers
+		securityHeaders := distroOptions.SecurityHeaders
+		if securityHeaders == nil {
+			securityHeaders = DefaultCloudFrontSecurityHeaders("")
+		}
+		securityHeadersResourceName := sparta.CloudFormationResourceName("CloudFrontSecurityHeaders",
+			"CloudFrontSecurityHeaders")
+		template.Resources[securityHeadersResourceName] = &gofCloudFront.ResponseHeadersPolicy{
+			ResponseHeadersPolicyConfig: &gofCloudFront.ResponseHeadersPolicy_ResponseHeadersPolicyConfig{
+				Comment: fmt.Sprintf("%s S3 site security headers", serviceName),
+				Name: sparta.CloudFormationResourceName("SiteSecurityHeaders",
+					serviceName,
+					bucketName),
+				SecurityHeadersConfig: securityHeaders,
+			},
+		}
+
 		// Add the distro...
 		distroConfig := &gofCloudFront.Distribution_DistributionConfig{
 			Aliases:           []string{s3Site.BucketName},
 			DefaultRootObject: indexDocument,
 			Origins: []gofCloudFront.Distribution_Origin{
 				{
-					DomainName:     gof.GetAtt(s3Site.CloudFormationS3ResourceName(), "DomainName"),
-					Id:             "S3Origin",
+					DomainName:     gof.GetAtt(s3Site.CloudFormationS3ResourceName(), "RegionalDomainName"),
+					Id:             cloudFrontS3OriginID,
 					S3OriginConfig: &gofCloudFront.Distribution_S3OriginConfig{},
 				},
 			},
 			Enabled: true,
 			DefaultCacheBehavior: &gofCloudFront.Distribution_DefaultCacheBehavior{
+				Compress: true,
 				ForwardedValues: &gofCloudFront.Distribution_ForwardedValues{
 					QueryString: false,
 				},
-				TargetOriginId:       "S3Origin",
-				ViewerProtocolPolicy: "allow-all",
+				FunctionAssociations: []gofCloudFront.Distribution_FunctionAssociation{
+					{
+						EventType:   "viewer-request",
+						FunctionARN: gof.GetAtt(siteRouterResourceName, "FunctionMetadata.FunctionARN"),
+					},
+				},
+				ResponseHeadersPolicyId: gof.Ref(securityHeadersResourceName),
+				TargetOriginId:          cloudFrontS3OriginID,
+				ViewerProtocolPolicy:    "redirect-to-https",
 			},
+			WebACLId: distroOptions.WebACLArn,
+		}
+		// Without an API origin, single page application routes are
+		// handled by rewriting the S3 error responses
+		if distroOptions.SinglePageApplication && distroOptions.API == nil {
+			for _, eachErrorCode := range []int{403, 404} {
+				distroConfig.CustomErrorResponses = append(distroConfig.CustomErrorResponses,
+					gofCloudFront.Distribution_CustomErrorResponse{
+						ErrorCode:        eachErrorCode,
+						ResponseCode:     200,
+						ResponsePagePath: "/" + indexDocument,
+					})
+			}
+		}
+
+		// The API origin doesn't cache responses and forwards the
+		// viewer request
+		if distroOptions.API != nil {
+			if distroOptions.API.StageName() == "" {
+				return ctx, errors.Errorf("CloudFrontDistribution API origin (%s) requires a Stage",
+					distroOptions.API.Name())
+			}
+			apiPathPattern := distroOptions.APIPathPattern
+			if apiPathPattern == "" {
+				apiPathPattern = DefaultCloudFrontAPIPathPattern
+			}
+			distroConfig.Origins = append(distroConfig.Origins,
+				gofCloudFront.Distribution_Origin{
+					DomainName: gof.Join("", []string{
+						gof.Ref(distroOptions.API.LogicalResourceName()),
+						".execute-api.",
+						gof.Ref("AWS::Region"),
+						".amazonaws.com",
+					}),
+					Id:         cloudFrontAPIOriginID,
+					OriginPath: "/" + distroOptions.API.StageName(),
+					CustomOriginConfig: &gofCloudFront.Distribution_CustomOriginConfig{
+						OriginProtocolPolicy: "https-only",
+						OriginSSLProtocols:   []string{"TLSv1.2"},
+					},
+				})
+
+			// The Authorization header can only be forwarded if it's
+			// part of the cache key
+			apiCachePolicyResourceName := sparta.CloudFormationResourceName("CloudFrontAPICachePolicy",
+				"CloudFrontAPICachePolicy")
+			template.Resources[apiCachePolicyResourceName] = &gofCloudFront.CachePolicy{
+				CachePolicyConfig: &gofCloudFront.CachePolicy_CachePolicyConfig{
+					Comment: fmt.Sprintf("%s API cache policy", serviceName),
+					Name: sparta.CloudFormationResourceName("SiteAPICache",
+						serviceName,
+						bucketName),
+					DefaultTTL: 0,
+					MinTTL:     0,
+					MaxTTL:     1,
+					ParametersInCacheKeyAndForwardedToOrigin: &gofCloudFront.CachePolicy_ParametersInCacheKeyAndForwardedToOrigin{
+						CookiesConfig: &gofCloudFront.CachePolicy_CookiesConfig{
+							CookieBehavior: "all",
+						},
+						HeadersConfig: &gofCloudFront.CachePolicy_HeadersConfig{
+							HeaderBehavior: "whitelist",
+							Headers:        []string{"Authorization"},
+						},
+						QueryStringsConfig: &gofCloudFront.CachePolicy_QueryStringsConfig{
+							QueryStringBehavior: "all",
+						},
+					},
+				},
+			}
+			apiOriginRequestPolicyResourceName := sparta.CloudFormationResourceName("CloudFrontAPIOriginRequestPolicy",
+				"CloudFrontAPIOriginRequestPolicy")
+			template.Resources[apiOriginRequestPolicyResourceName] = &gofCloudFront.OriginRequestPolicy{
+				OriginRequestPolicyConfig: &gofCloudFront.OriginRequestPolicy_OriginRequestPolicyConfig{
+					Comment: fmt.Sprintf("%s API origin request policy", serviceName),
+					Name: sparta.CloudFormationResourceName("SiteAPIOriginRequest",
+						serviceName,
+						bucketName),
+					CookiesConfig: &gofCloudFront.OriginRequestPolicy_CookiesConfig{
+						CookieBehavior: "all",
+					},
+					HeadersConfig: &gofCloudFront.OriginRequestPolicy_HeadersConfig{
+						HeaderBehavior: "whitelist",
+						Headers:        cloudFrontAPIForwardedHeaders,
+					},
+					QueryStringsConfig: &gofCloudFront.OriginRequestPolicy_QueryStringsConfig{
+						QueryStringBehavior: "all",
+					},
+				},
+			}
+			distroConfig.CacheBehaviors = append(distroConfig.CacheBehaviors,
+				gofCloudFront.Distribution_CacheBehavior{
+					AllowedMethods: []string{"GET",
+						"HEAD",
+						"OPTIONS",
+						"PUT",
+						"PATCH",
+						"POST",
+						"DELETE"},
+					CachedMethods:           []string{"GET", "HEAD"},
+					CachePolicyId:           gof.Ref(apiCachePolicyResourceName),
+					Compress:                true,
+					OriginRequestPolicyId:   gof.Ref(apiOriginRequestPolicyResourceName),
+					PathPattern:             apiPathPattern,
+					ResponseHeadersPolicyId: gof.Ref(securityHeadersResourceName),
+					TargetOriginId:          cloudFrontAPIOriginID,
+					ViewerProtocolPolicy:    "redirect-to-https",
+				})
 		}
 		// Update the cert...
 		distroConfig.ViewerCertificate = cert
 
-		cloudfrontDistro := &gofCloudFront.Distribution{
-			DistributionConfig: distroConfig,
+		// goformation doesn't support the OriginAccessControlId origin
+		// property, so add it to the JSON representation
+		distroConfigProperties, distroConfigPropertiesErr := cloudFormationProperties(distroConfig)
+		if distroConfigPropertiesErr != nil {
+			return ctx, errors.Wrapf(distroConfigPropertiesErr,
+				"Failed to marshal CloudFront distribution configuration")
+		}
+		distroOrigins, _ := distroConfigProperties["Origins"].([]interface{})
+		for _, eachOrigin := range distroOrigins {
+			originProperties, _ := eachOrigin.(map[string]interface{})
+			if originProperties != nil && originProperties["Id"] == cloudFrontS3OriginID {
+				originProperties["OriginAccessControlId"] = gof.GetAtt(originAccessControlResourceName, "Id")
+			}
+		}
+		cloudfrontDistro := &gof.CustomResource{
+			Type: (&gofCloudFront.Distribution{}).AWSCloudFormationType(),
+			Properties: map[string]interface{}{
+				"DistributionConfig": distroConfigProperties,
+			},
 		}
 		template.Resources[cloudFrontDistroResourceName] = cloudfrontDistro
 
@@ -133,6 +524,10 @@ func CloudFrontSiteDistributionDecoratorWithCert(s3Site *sparta.S3Site,
 			Description: "CloudFront Distribution Route53 entry",
 			Value:       s3Site.BucketName,
 		}
+		template.Outputs[OutputCloudFrontDistributionID] = gof.Output{
+			Description: "CloudFront Distribution ID",
+			Value:       gof.Ref(cloudFrontDistroResourceName),
+		}
 		return ctx, nil
 	}
 	return sparta.ServiceDecoratorHookFunc(distroDecorator)
@@ -0,0 +1,132 @@
+package decorator
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	gofCloudFront "github.com/awslabs/goformation/v5/cloudformation/cloudfront"
+	sparta "github.com/mweagle/Sparta/v3"
+	cfCustomResources "github.com/mweagle/Sparta/v3/aws/cloudformation/resources"
+)
+
+func decorateSiteDistribution(t *testing.T,
+	s3Site *sparta.S3Site,
+	options *CloudFrontSiteDistributionOptions) *gof.Template {
+	decorator := CloudFrontSiteDistributionDecoratorWithOptions(s3Site,
+		"www",
+		"spartademo.net",
+		&gofCloudFront.Distribution_ViewerCertificate{
+			AcmCertificateArn: "arn:aws:acm:us-east-1:123412341234:certificate/6486C3FF-A3B7-46B6-83A0-9AE329FEC4E3",
+			SslSupportMethod:  "sni-only",
+		},
+		options)
+	template, decorateErr := testDecorateService(decorator, nil)
+	if decorateErr != nil {
+		t.Fatal(decorateErr)
+	}
+	return template
+}
+
+func TestCloudFrontSiteDistribution(t *testing.T) {
+	s3Site, _ := sparta.NewS3Site("./site")
+	s3Site.BucketName = "www.spartademo.net"
+	s3Site.PrecompressedEncodings = []string{"br", "gzip"}
+	s3Site.ContentRules = []*sparta.S3SiteContentRule{
+		{Pattern: "*.js", ContentType: "application/octet-stream"},
+	}
+
+	template := decorateSiteDistribution(t, s3Site, &CloudFrontSiteDistributionOptions{
+		SinglePageApplication: true,
+		WebACLArn:             "arn:aws:wafv2:us-east-1:123412341234:global/webacl/site/abc",
+	})
+	if s3Site.CloudFrontDistributionARN == "" {
+		t.Fatalf("Expected S3Site to use Origin Access Control")
+	}
+	if len(testResourcePropertiesOfType(t, template, cloudFrontOriginAccessControlType)) != 1 ||
+		len(testResourcePropertiesOfType(t, template, "AWS::CloudFront::ResponseHeadersPolicy")) != 1 {
+		t.Fatalf("Expected Origin Access Control and response headers policy resources")
+	}
+	distros := testResourcePropertiesOfType(t, template, "AWS::CloudFront::Distribution")
+	if len(distros) != 1 {
+		t.Fatalf("Expected a single distribution: %#v", template.Resources)
+	}
+	distroConfig := distros[0]["DistributionConfig"].(map[string]interface{})
+	s3Origin := distroConfig["Origins"].([]interface{})[0].(map[string]interface{})
+	if s3Origin["OriginAccessControlId"] == nil ||
+		distroConfig["WebACLId"] == nil ||
+		len(distroConfig["CustomErrorResponses"].([]interface{})) != 2 {
+		t.Fatalf("Unexpected distribution configuration: %#v", distroConfig)
+	}
+	functions := testResourcePropertiesOfType(t, template, "AWS::CloudFront::Function")
+	functionCode := functions[0]["FunctionCode"].(string)
+	if !strings.Contains(functionCode, `var SPA_ROUTING = false;`) ||
+		!strings.Contains(functionCode, `var ENCODINGS = [["br",".br"],["gzip",".gz"]];`) {
+		t.Fatalf("Unexpected function code: %s", functionCode)
+	}
+	// The function selects variants for the same extensions that the
+	// S3Site publishes them for, regardless of the content type rules
+	precompressedMatch := regexp.MustCompile(`var PRECOMPRESSED = /(.+)/;`).FindStringSubmatch(functionCode)
+	if len(precompressedMatch) != 2 {
+		t.Fatalf("Failed to find PRECOMPRESSED pattern: %s", functionCode)
+	}
+	precompressed := regexp.MustCompile(precompressedMatch[1])
+	for _, eachExtension := range cfCustomResources.PrecompressedExtensions {
+		if !precompressed.MatchString("/assets/app." + eachExtension) {
+			t.Fatalf("Expected precompressed variant for %s", eachExtension)
+		}
+	}
+	for _, eachURI := range []string{"/notes.txt", "/logo.png", "/app.js.map"} {
+		if precompressed.MatchString(eachURI) {
+			t.Fatalf("Unexpected precompressed variant for %s", eachURI)
+		}
+	}
+}
+
+func TestCloudFrontSiteDistributionAPIOrigin(t *testing.T) {
+	s3Site, _ := sparta.NewS3Site("./site")
+	s3Site.BucketName = "www.spartademo.net"
+	api := sparta.NewAPIGateway("SpartaSiteAPI", sparta.NewStage("v1"))
+
+	template := decorateSiteDistribution(t, s3Site, &CloudFrontSiteDistributionOptions{
+		SinglePageApplication: true,
+		SecurityHeaders:       DefaultCloudFrontSecurityHeaders("default-src 'self'"),
+		API:                   api,
+	})
+	distroConfig := testResourcePropertiesOfType(t, template, "AWS::CloudFront::Distribution")[0]["DistributionConfig"].(map[string]interface{})
+	if len(distroConfig["Origins"].([]interface{})) != 2 ||
+		distroConfig["CustomErrorResponses"] != nil {
+		t.Fatalf("Unexpected distribution configuration: %#v", distroConfig)
+	}
+	apiBehavior := distroConfig["CacheBehaviors"].([]interface{})[0].(map[string]interface{})
+	if apiBehavior["PathPattern"] != DefaultCloudFrontAPIPathPattern ||
+		apiBehavior["TargetOriginId"] != cloudFrontAPIOriginID {
+		t.Fatalf("Unexpected API cache behavior: %#v", apiBehavior)
+	}
+	functionCode := testResourcePropertiesOfType(t, template, "AWS::CloudFront::Function")[0]["FunctionCode"].(string)
+	if !strings.Contains(functionCode, `var SPA_ROUTING = true;`) {
+		t.Fatalf("Expected function SPA routing: %s", functionCode)
+	}
+	headersPolicy := testResourcePropertiesOfType(t, template, "AWS::CloudFront::ResponseHeadersPolicy")[0]
+	headersPolicyJSON, _ := json.Marshal(headersPolicy)
+	if !strings.Contains(string(headersPolicyJSON), "default-src 'self'") {
+		t.Fatalf("Expected Content-Security-Policy header: %s", string(headersPolicyJSON))
+	}
+
+	// APIs must be deployed to a stage
+	s3Site, _ = sparta.NewS3Site("./site")
+	s3Site.BucketName = "www.spartademo.net"
+	decorator := CloudFrontSiteDistributionDecoratorWithOptions(s3Site,
+		"www",
+		"spartademo.net",
+		nil,
+		&CloudFrontSiteDistributionOptions{
+			API: sparta.NewAPIGateway("SpartaSiteAPI", nil),
+		})
+	_, decorateErr := testDecorateService(decorator, nil)
+	if decorateErr == nil {
+		t.Fatalf("Expected error for API without a stage")
+	}
+}
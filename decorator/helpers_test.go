@@ -0,0 +1,111 @@
+package decorator
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	gofapigateway "github.com/awslabs/goformation/v5/cloudformation/apigateway"
+	gofcloudwatch "github.com/awslabs/goformation/v5/cloudformation/cloudwatch"
+	gofdynamodb "github.com/awslabs/goformation/v5/cloudformation/dynamodb"
+	goflambda "github.com/awslabs/goformation/v5/cloudformation/lambda"
+	gofsqs "github.com/awslabs/goformation/v5/cloudformation/sqs"
+	sparta "github.com/mweagle/Sparta/v3"
+	"github.com/rs/zerolog"
+)
+
+// testDecorateService applies the decorator to an empty template and
+// returns it. A non-nil serviceTemplate is provided as the
+// sparta.ContextKeyBuildTemplate value.
+func testDecorateService(decorator sparta.ServiceDecoratorHookHandler,
+	serviceTemplate *gof.Template) (*gof.Template, error) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	ctx := context.Background()
+	if serviceTemplate != nil {
+		ctx = context.WithValue(ctx, sparta.ContextKeyBuildTemplate, serviceTemplate)
+	}
+	template := gof.NewTemplate()
+	_, decorateErr := decorator.DecorateService(ctx,
+		"DecoratorService",
+		template,
+		nil,
+		"buildID",
+		awsv2.Config{},
+		false,
+		&logger)
+	return template, decorateErr
+}
+
+// testDashboardServiceTemplate returns a service template with a resource
+// for each dashboard widget preset
+func testDashboardServiceTemplate() *gof.Template {
+	template := gof.NewTemplate()
+	template.Resources["HelloLambda"] = &goflambda.Function{}
+	template.Resources["OrdersQueue"] = &gofsqs.Queue{}
+	template.Resources["OrdersTable"] = &gofdynamodb.Table{}
+	template.Resources["OrdersAPI"] = &gofapigateway.RestApi{Name: "OrdersAPI"}
+	template.Resources["HelloAlarm"] = &gofcloudwatch.Alarm{}
+	return template
+}
+
+// testResourcesOfType returns the template resources of the given type
+func testResourcesOfType(template *gof.Template, resourceType string) []gof.Resource {
+	resources := []gof.Resource{}
+	for _, eachResource := range template.Resources {
+		if eachResource.AWSCloudFormationType() == resourceType {
+			resources = append(resources, eachResource)
+		}
+	}
+	return resources
+}
+
+// testResourcePropertiesOfType returns the JSON Properties of the template
+// resources of the given type
+func testResourcePropertiesOfType(t *testing.T,
+	template *gof.Template,
+	resourceType string) []map[string]interface{} {
+	templateBytes, templateBytesErr := template.JSON()
+	if templateBytesErr != nil {
+		t.Fatal(templateBytesErr)
+	}
+	var templateData struct {
+		Resources map[string]struct {
+			Type       string
+			Properties map[string]interface{}
+		}
+	}
+	unmarshalErr := json.Unmarshal(templateBytes, &templateData)
+	if unmarshalErr != nil {
+		t.Fatal(unmarshalErr)
+	}
+	properties := []map[string]interface{}{}
+	for _, eachResource := range templateData.Resources {
+		if eachResource.Type == resourceType {
+			properties = append(properties, eachResource.Properties)
+		}
+	}
+	return properties
+}
+
+// testAlarmPolicyServiceTemplate returns a service template with a plain
+// function and a Kinesis stream function with a DLQ and reserved
+// concurrency
+func testAlarmPolicyServiceTemplate() *gof.Template {
+	template := gof.NewTemplate()
+	template.Resources["PlainLambda"] = &goflambda.Function{}
+	template.Resources["StreamLambda"] = &goflambda.Function{
+		Timeout:                      10,
+		ReservedConcurrentExecutions: 20,
+		DeadLetterConfig: &goflambda.Function_DeadLetterConfig{
+			TargetArn: gof.GetAtt("DeadLetterQueue", "Arn"),
+		},
+	}
+	template.Resources["StreamMapping"] = &goflambda.EventSourceMapping{
+		FunctionName:     gof.GetAtt("StreamLambda", "Arn"),
+		EventSourceArn:   "arn:aws:kinesis:us-west-2:123412341234:stream/events",
+		StartingPosition: "LATEST",
+	}
+	return template
+}
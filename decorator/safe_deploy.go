@@ -20,7 +20,9 @@ import (
 // that adds the necessary information for CodeDeploy
 func codeDeployLambdaUpdateDecorator(updateType string,
 	codeDeployApplicationName string,
-	codeDeployRoleName string) sparta.TemplateDecorator {
+	codeDeployRoleName string,
+	preHook *sparta.LambdaAWSInfo,
+	postHook *sparta.LambdaAWSInfo) sparta.TemplateDecorator {
 	return func(ctx context.Context,
 		serviceName string,
 		lambdaResourceName string,
@@ -71,11 +73,18 @@ func codeDeployLambdaUpdateDecorator(updateType string,
 			FunctionName:    gof.Ref(lambdaResourceName),
 			Name:            "live",
 		}
+		aliasUpdate := &gofpolicies.CodeDeployLambdaAliasUpdate{
+			ApplicationName:     gof.Ref(codeDeployApplicationName),
+			DeploymentGroupName: gof.Ref(codeDeploymentGroupResourceName),
+		}
+		if preHook != nil {
+			aliasUpdate.BeforeAllowTrafficHook = gof.Ref(preHook.LogicalResourceName())
+		}
+		if postHook != nil {
+			aliasUpdate.AfterAllowTrafficHook = gof.Ref(postHook.LogicalResourceName())
+		}
 		aliasResource.AWSCloudFormationUpdatePolicy = &gofpolicies.UpdatePolicy{
-			CodeDeployLambdaAliasUpdate: &gofpolicies.CodeDeployLambdaAliasUpdate{
-				ApplicationName:     codeDeployApplicationName,
-				DeploymentGroupName: codeDeploymentGroupResourceName,
-			},
+			CodeDeployLambdaAliasUpdate: aliasUpdate,
 		}
 
 		template.Resources[aliasResourceName] = aliasResource
@@ -84,7 +93,10 @@ func codeDeployLambdaUpdateDecorator(updateType string,
 }
 
 // CodeDeployServiceUpdateDecorator is a service level decorator that attaches
-// the CodeDeploy safe update to an upgrade operation.
+// the CodeDeploy safe update to an upgrade operation. The optional preHook
+// and postHook functions are the BeforeAllowTraffic and AfterAllowTraffic
+// lifecycle hooks. Use CodeDeploySmokeTests to generate hooks that
+// validate the new function versions.
 // Ref: https://github.com/awslabs/serverless-application-model/blob/master/docs/safe_lambda_deployments.rst
 //
 func CodeDeployServiceUpdateDecorator(updateType string,
@@ -101,29 +113,38 @@ func CodeDeployServiceUpdateDecorator(updateType string,
 
 	// Add the Execution status
 	// See: https://github.com/awslabs/serverless-application-model/blob/master/docs/safe_lambda_deployments.rst#traffic-shifting-using-codedeploy
+	hooks := []*sparta.LambdaAWSInfo{}
 	for _, eachFunc := range []*sparta.LambdaAWSInfo{preHook, postHook} {
-		if eachFunc != nil {
-			eachFunc.RoleDefinition.Privileges = append(preHook.RoleDefinition.Privileges,
+		if eachFunc != nil && eachFunc.RoleDefinition != nil {
+			eachFunc.RoleDefinition.Privileges = append(eachFunc.RoleDefinition.Privileges,
 				sparta.IAMRolePrivilege{
-					Actions: []string{"codedeploy:PutLifecycleEventHookExecutionStatus"},
-					Resource: []string{"",
-						"arn:aws:codedeploy:",
+					Actions: []string{"codedeploy:GetDeployment",
+						"codedeploy:PutLifecycleEventHookExecutionStatus"},
+					Resource: gof.Join("", []string{
+						"arn:",
+						gof.Ref("AWS::Partition"),
+						":codedeploy:",
 						gof.Ref("AWS::Region"),
 						":",
 						gof.Ref("AWS::AccountId"),
 						":deploymentgroup:",
-						codeDeployApplicationName,
-						"/*"},
+						gof.Ref(codeDeployApplicationName),
+						"/*"}),
 				},
 			)
 		}
+		if eachFunc != nil {
+			hooks = append(hooks, eachFunc)
+		}
 	}
 
 	// Add the decorator to each lambda
 	for _, eachLambda := range lambdaFuncs {
 		safeDeployDecorator := codeDeployLambdaUpdateDecorator(updateType,
 			codeDeployApplicationName,
-			codeDeployRoleResourceName)
+			codeDeployRoleResourceName,
+			preHook,
+			postHook)
 		eachLambda.Decorators = append(eachLambda.Decorators,
 			sparta.TemplateDecoratorHookFunc(safeDeployDecorator))
 	}
@@ -160,6 +181,25 @@ func CodeDeployServiceUpdateDecorator(updateType string,
 				},
 			},
 		}
+		// The managed policy only allows CodeDeploy to invoke hooks
+		// named CodeDeployHook_*
+		if len(hooks) != 0 {
+			hookARNs := []string{}
+			for _, eachHook := range hooks {
+				hookARNs = append(hookARNs, gof.GetAtt(eachHook.LogicalResourceName(), "Arn"))
+			}
+			codeDeployRoleResource.Policies = []gofiam.Role_Policy{{
+				PolicyName: "InvokeLifecycleHooks",
+				PolicyDocument: sparta.ArbitraryJSONObject{
+					"Version": "2012-10-17",
+					"Statement": []sparta.ArbitraryJSONObject{{
+						"Action":   []string{"lambda:InvokeFunction"},
+						"Effect":   "Allow",
+						"Resource": hookARNs,
+					}},
+				},
+			}}
+		}
 		template.Resources[codeDeployRoleResourceName] = codeDeployRoleResource
 
 		// Ship it...
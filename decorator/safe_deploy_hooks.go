@@ -0,0 +1,389 @@
+package decorator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsv2Lambda "github.com/aws/aws-sdk-go-v2/service/lambda"
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	"github.com/jmespath/go-jmespath"
+	sparta "github.com/mweagle/Sparta/v3"
+	spartaAWS "github.com/mweagle/Sparta/v3/aws"
+	spartaCodeDeploy "github.com/mweagle/Sparta/v3/aws/codedeploy"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// smokeTestEnvVarPrefix is the hook function environment variable prefix
+	// that maps a target function logical name to its physical name
+	smokeTestEnvVarPrefix = "SPARTA_SMOKE_TEST_"
+	// smokeTestHookTimeout is the default hook function timeout in seconds
+	smokeTestHookTimeout = 300
+)
+
+// SmokeTestAssertion is an assertion on the JSON response of a smoke test
+// invocation. The Expression is a JMESPath expression (https://jmespath.org)
+// evaluated against the response. An empty Expression selects the
+// whole response.
+type SmokeTestAssertion struct {
+	Expression string
+	// Equals is the expected value of the expression
+	Equals interface{}
+	// Matches is a regular expression the expression value must match. Non
+	// string values are matched against their JSON representation.
+	Matches string
+	matcher *regexp.Regexp
+}
+
+// AssertEquals returns an assertion that the JMESPath expression value
+// is equal to the expected value
+func AssertEquals(expression string, expected interface{}) *SmokeTestAssertion {
+	return &SmokeTestAssertion{
+		Expression: expression,
+		Equals:     expected,
+	}
+}
+
+// AssertMatches returns an assertion that the JMESPath expression value
+// matches the regular expression
+func AssertMatches(expression string, pattern string) *SmokeTestAssertion {
+	return &SmokeTestAssertion{
+		Expression: expression,
+		Matches:    pattern,
+	}
+}
+
+// AssertExists returns an assertion that the JMESPath expression value
+// isn't null
+func AssertExists(expression string) *SmokeTestAssertion {
+	return &SmokeTestAssertion{
+		Expression: expression,
+	}
+}
+
+func (sta *SmokeTestAssertion) validate() error {
+	if sta.Matches != "" {
+		matcher, matcherErr := regexp.Compile(sta.Matches)
+		if matcherErr != nil {
+			return errors.Wrapf(matcherErr, "Invalid assertion pattern: %s", sta.Matches)
+		}
+		sta.matcher = matcher
+	}
+	_, compileErr := jmespath.Compile(sta.expression())
+	return errors.Wrapf(compileErr, "Invalid assertion expression: %s", sta.Expression)
+}
+
+func (sta *SmokeTestAssertion) expression() string {
+	if sta.Expression == "" {
+		return "@"
+	}
+	return sta.Expression
+}
+
+// Evaluate returns an error if the response doesn't satisfy the assertion
+func (sta *SmokeTestAssertion) Evaluate(response interface{}) error {
+	if sta.Matches != "" && sta.matcher == nil {
+		validateErr := sta.validate()
+		if validateErr != nil {
+			return validateErr
+		}
+	}
+	result, resultErr := jmespath.Search(sta.expression(), response)
+	if resultErr != nil {
+		return errors.Wrapf(resultErr, "Failed to evaluate expression: %s", sta.expression())
+	}
+	if sta.matcher != nil {
+		text, isString := result.(string)
+		if !isString {
+			jsonBytes, _ := json.Marshal(result)
+			text = string(jsonBytes)
+		}
+		if !sta.matcher.MatchString(text) {
+			return errors.Errorf("%s value %s doesn't match %s",
+				sta.expression(),
+				text,
+				sta.Matches)
+		}
+	}
+	if sta.Equals != nil {
+		// Normalize the expected value to the JSON types returned by
+		// the search
+		var expected interface{}
+		expectedBytes, expectedBytesErr := json.Marshal(sta.Equals)
+		if expectedBytesErr != nil {
+			return errors.Wrapf(expectedBytesErr, "Failed to marshal expected value")
+		}
+		_ = json.Unmarshal(expectedBytes, &expected)
+		if !reflect.DeepEqual(expected, result) {
+			return errors.Errorf("%s value %#v doesn't equal %#v",
+				sta.expression(),
+				result,
+				expected)
+		}
+	}
+	if sta.matcher == nil && sta.Equals == nil && result == nil {
+		return errors.Errorf("%s value is null", sta.expression())
+	}
+	return nil
+}
+
+// SmokeTestScenario is an event that's sent to the new function version
+// during a CodeDeploy deployment, together with the assertions
+// the response must satisfy
+type SmokeTestScenario struct {
+	Name       string
+	Event      interface{}
+	Assertions []*SmokeTestAssertion
+}
+
+// Run invokes the function qualifier with the scenario event and evaluates
+// the assertions against the response
+func (sts *SmokeTestScenario) Run(ctx context.Context,
+	lambdaSvc *awsv2Lambda.Client,
+	functionName string,
+	qualifier string) error {
+
+	payload, payloadErr := json.Marshal(sts.Event)
+	if payloadErr != nil {
+		return errors.Wrapf(payloadErr, "Failed to marshal %s event", sts.Name)
+	}
+	invokeOutput, invokeErr := lambdaSvc.Invoke(ctx, &awsv2Lambda.InvokeInput{
+		FunctionName: awsv2.String(functionName),
+		Qualifier:    awsv2.String(qualifier),
+		Payload:      payload,
+	})
+	if invokeErr != nil {
+		return errors.Wrapf(invokeErr, "Failed to invoke %s:%s", functionName, qualifier)
+	}
+	if invokeOutput.FunctionError != nil {
+		return errors.Errorf("%s:%s returned %s error: %s",
+			functionName,
+			qualifier,
+			awsv2.ToString(invokeOutput.FunctionError),
+			string(invokeOutput.Payload))
+	}
+	return sts.evaluate(invokeOutput.Payload)
+}
+
+func (sts *SmokeTestScenario) evaluate(responsePayload []byte) error {
+	var response interface{}
+	if len(responsePayload) != 0 {
+		unmarshalErr := json.Unmarshal(responsePayload, &response)
+		if unmarshalErr != nil {
+			return errors.Wrapf(unmarshalErr, "Failed to unmarshal %s response", sts.Name)
+		}
+	}
+	for _, eachAssertion := range sts.Assertions {
+		evaluateErr := eachAssertion.Evaluate(response)
+		if evaluateErr != nil {
+			return errors.Wrapf(evaluateErr, "Scenario %s failed", sts.Name)
+		}
+	}
+	return nil
+}
+
+// smokeTestTarget is the set of scenarios for a single function
+type smokeTestTarget struct {
+	lambdaAWSInfo        *sparta.LambdaAWSInfo
+	preTrafficScenarios  []*SmokeTestScenario
+	postTrafficScenarios []*SmokeTestScenario
+}
+
+func (stt *smokeTestTarget) envVarName() string {
+	return smokeTestEnvVarPrefix + stt.lambdaAWSInfo.LogicalResourceName()
+}
+
+// CodeDeploySmokeTests generates the BeforeAllowTraffic and AfterAllowTraffic
+// lifecycle hook functions for CodeDeployServiceUpdateDecorator. The
+// BeforeAllowTraffic hook invokes the new function version and the
+// AfterAllowTraffic hook invokes the updated alias. A failing scenario
+// fails the deployment, which rolls it back.
+type CodeDeploySmokeTests struct {
+	targets []*smokeTestTarget
+	timeout int
+}
+
+// NewCodeDeploySmokeTests returns an empty set of smoke tests
+func NewCodeDeploySmokeTests() *CodeDeploySmokeTests {
+	return &CodeDeploySmokeTests{
+		targets: make([]*smokeTestTarget, 0),
+		timeout: smokeTestHookTimeout,
+	}
+}
+
+func (cdst *CodeDeploySmokeTests) target(lambdaAWSInfo *sparta.LambdaAWSInfo) *smokeTestTarget {
+	for _, eachTarget := range cdst.targets {
+		if eachTarget.lambdaAWSInfo == lambdaAWSInfo {
+			return eachTarget
+		}
+	}
+	target := &smokeTestTarget{
+		lambdaAWSInfo: lambdaAWSInfo,
+	}
+	cdst.targets = append(cdst.targets, target)
+	return target
+}
+
+// WithPreTrafficScenarios adds scenarios that run against the new function
+// version before traffic is shifted
+func (cdst *CodeDeploySmokeTests) WithPreTrafficScenarios(lambdaAWSInfo *sparta.LambdaAWSInfo,
+	scenarios ...*SmokeTestScenario) *CodeDeploySmokeTests {
+	target := cdst.target(lambdaAWSInfo)
+	target.preTrafficScenarios = append(target.preTrafficScenarios, scenarios...)
+	return cdst
+}
+
+// WithPostTrafficScenarios adds scenarios that run against the function
+// alias after traffic is shifted
+func (cdst *CodeDeploySmokeTests) WithPostTrafficScenarios(lambdaAWSInfo *sparta.LambdaAWSInfo,
+	scenarios ...*SmokeTestScenario) *CodeDeploySmokeTests {
+	target := cdst.target(lambdaAWSInfo)
+	target.postTrafficScenarios = append(target.postTrafficScenarios, scenarios...)
+	return cdst
+}
+
+// WithTimeout sets the hook function timeout in seconds. The default is
+// 300 seconds.
+func (cdst *CodeDeploySmokeTests) WithTimeout(timeout int) *CodeDeploySmokeTests {
+	cdst.timeout = timeout
+	return cdst
+}
+
+// Hooks returns the BeforeAllowTraffic and AfterAllowTraffic hook functions.
+// Include them in the service's functions and pass them to
+// CodeDeployServiceUpdateDecorator. A hook is nil if there are no
+// scenarios for its lifecycle event.
+func (cdst *CodeDeploySmokeTests) Hooks() (*sparta.LambdaAWSInfo, *sparta.LambdaAWSInfo, error) {
+	var preTrafficCount, postTrafficCount int
+	for _, eachTarget := range cdst.targets {
+		for _, eachScenarios := range [][]*SmokeTestScenario{eachTarget.preTrafficScenarios,
+			eachTarget.postTrafficScenarios} {
+			for _, eachScenario := range eachScenarios {
+				for _, eachAssertion := range eachScenario.Assertions {
+					validateErr := eachAssertion.validate()
+					if validateErr != nil {
+						return nil, nil, errors.Wrapf(validateErr, "Invalid scenario: %s", eachScenario.Name)
+					}
+				}
+			}
+		}
+		preTrafficCount += len(eachTarget.preTrafficScenarios)
+		postTrafficCount += len(eachTarget.postTrafficScenarios)
+	}
+	var preHook, postHook *sparta.LambdaAWSInfo
+	var hookErr error
+	if preTrafficCount != 0 {
+		preHook, hookErr = cdst.newHook("CodeDeployPreTrafficHook", true)
+		if hookErr != nil {
+			return nil, nil, hookErr
+		}
+	}
+	if postTrafficCount != 0 {
+		postHook, hookErr = cdst.newHook("CodeDeployPostTrafficHook", false)
+		if hookErr != nil {
+			return nil, nil, hookErr
+		}
+	}
+	return preHook, postHook, nil
+}
+
+// newHook returns the lifecycle hook function
+func (cdst *CodeDeploySmokeTests) newHook(hookName string, preTraffic bool) (*sparta.LambdaAWSInfo, error) {
+	hookHandler := func(ctx context.Context, event spartaCodeDeploy.LifecycleEvent) error {
+		logger, loggerOk := ctx.Value(sparta.ContextKeyLogger).(*zerolog.Logger)
+		if !loggerOk {
+			nopLogger := zerolog.Nop()
+			logger = &nopLogger
+		}
+		awsConfig, awsConfigErr := spartaAWS.NewConfig(ctx, logger)
+		if awsConfigErr != nil {
+			return awsConfigErr
+		}
+		status := spartaCodeDeploy.StatusSucceeded
+		testErr := cdst.runScenarios(ctx, awsConfig, event.DeploymentID, preTraffic, logger)
+		if testErr != nil {
+			logger.Error().
+				Err(testErr).
+				Str("DeploymentID", event.DeploymentID).
+				Msg("Smoke test failed")
+			status = spartaCodeDeploy.StatusFailed
+		}
+		return spartaCodeDeploy.PutLifecycleEventHookExecutionStatus(ctx,
+			awsConfig,
+			&event,
+			status)
+	}
+	hook, hookErr := sparta.NewAWSLambda(hookName,
+		hookHandler,
+		sparta.IAMRoleDefinition{})
+	if hookErr != nil {
+		return nil, hookErr
+	}
+	hook.Options.Timeout = cdst.timeout
+	hook.Options.Environment = make(map[string]string)
+	for _, eachTarget := range cdst.targets {
+		scenarios := eachTarget.postTrafficScenarios
+		if preTraffic {
+			scenarios = eachTarget.preTrafficScenarios
+		}
+		if len(scenarios) == 0 {
+			continue
+		}
+		hook.Options.Environment[eachTarget.envVarName()] = gof.Ref(eachTarget.lambdaAWSInfo.LogicalResourceName())
+		hook.RoleDefinition.Privileges = append(hook.RoleDefinition.Privileges,
+			sparta.IAMRolePrivilege{
+				Actions: []string{"lambda:InvokeFunction"},
+				Resource: gof.Join("", []string{
+					gof.GetAtt(eachTarget.lambdaAWSInfo.LogicalResourceName(), "Arn"),
+					":*",
+				}),
+			})
+	}
+	return hook, nil
+}
+
+// runScenarios runs the scenarios for each function updated by the deployment
+func (cdst *CodeDeploySmokeTests) runScenarios(ctx context.Context,
+	awsConfig awsv2.Config,
+	deploymentID string,
+	preTraffic bool,
+	logger *zerolog.Logger) error {
+
+	deploymentTargets, deploymentTargetsErr := spartaCodeDeploy.DeploymentLambdaTargets(ctx,
+		awsConfig,
+		deploymentID)
+	if deploymentTargetsErr != nil {
+		return deploymentTargetsErr
+	}
+	lambdaSvc := awsv2Lambda.NewFromConfig(awsConfig)
+	for _, eachDeploymentTarget := range deploymentTargets {
+		for _, eachTarget := range cdst.targets {
+			if os.Getenv(eachTarget.envVarName()) != eachDeploymentTarget.Name {
+				continue
+			}
+			scenarios := eachTarget.postTrafficScenarios
+			qualifier := eachDeploymentTarget.Alias
+			if preTraffic {
+				scenarios = eachTarget.preTrafficScenarios
+				qualifier = eachDeploymentTarget.TargetVersion
+			}
+			for _, eachScenario := range scenarios {
+				runErr := eachScenario.Run(ctx, lambdaSvc, eachDeploymentTarget.Name, qualifier)
+				if runErr != nil {
+					return runErr
+				}
+				logger.Info().
+					Str("Function", fmt.Sprintf("%s:%s", eachDeploymentTarget.Name, qualifier)).
+					Str("Scenario", eachScenario.Name).
+					Msg("Smoke test passed")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package decorator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	goflambda "github.com/awslabs/goformation/v5/cloudformation/lambda"
+	sparta "github.com/mweagle/Sparta/v3"
+	"github.com/rs/zerolog"
+)
+
+func TestSmokeTestAssertions(t *testing.T) {
+	scenario := &SmokeTestScenario{
+		Name: "Hello",
+		Assertions: []*SmokeTestAssertion{
+			AssertEquals("statusCode", 200),
+			AssertEquals("body.tags", []string{"a", "b"}),
+			AssertMatches("body.message", "^Hello"),
+			AssertMatches("statusCode", "^2\\d\\d$"),
+			AssertExists("body.id"),
+		},
+	}
+	response := `{"statusCode": 200, "body": {"message": "Hello World", "id": "1234", "tags": ["a", "b"]}}`
+	evaluateErr := scenario.evaluate([]byte(response))
+	if evaluateErr != nil {
+		t.Fatal(evaluateErr)
+	}
+	failures := []*SmokeTestAssertion{
+		AssertEquals("statusCode", 500),
+		AssertMatches("body.message", "^Goodbye"),
+		AssertExists("body.missing"),
+	}
+	for _, eachAssertion := range failures {
+		failingScenario := &SmokeTestScenario{
+			Name:       "Failure",
+			Assertions: []*SmokeTestAssertion{eachAssertion},
+		}
+		if failingScenario.evaluate([]byte(response)) == nil {
+			t.Fatalf("Expected assertion failure: %#v", eachAssertion)
+		}
+	}
+}
+
+func TestCodeDeploySmokeTestHooks(t *testing.T) {
+	lambdaFn := testSLOLambda()
+	invalidTests := NewCodeDeploySmokeTests().
+		WithPreTrafficScenarios(lambdaFn, &SmokeTestScenario{
+			Name:       "Invalid",
+			Assertions: []*SmokeTestAssertion{AssertMatches("body", "[")},
+		})
+	_, _, invalidErr := invalidTests.Hooks()
+	if invalidErr == nil {
+		t.Fatalf("Expected invalid pattern error")
+	}
+
+	smokeTests := NewCodeDeploySmokeTests().
+		WithPreTrafficScenarios(lambdaFn, &SmokeTestScenario{
+			Name:       "Hello",
+			Event:      map[string]string{"name": "World"},
+			Assertions: []*SmokeTestAssertion{AssertEquals("statusCode", 200)},
+		})
+	preHook, postHook, hooksErr := smokeTests.Hooks()
+	if hooksErr != nil {
+		t.Fatal(hooksErr)
+	}
+	if preHook == nil || postHook != nil {
+		t.Fatalf("Expected only a BeforeAllowTraffic hook")
+	}
+	if len(preHook.Options.Environment) != 1 || len(preHook.RoleDefinition.Privileges) != 1 {
+		t.Fatalf("Expected target function environment and privileges")
+	}
+
+	// Attach the hook to the alias update policy
+	CodeDeployServiceUpdateDecorator("AllAtOnce",
+		[]*sparta.LambdaAWSInfo{lambdaFn},
+		preHook,
+		postHook)
+	if len(preHook.RoleDefinition.Privileges) != 2 {
+		t.Fatalf("Expected CodeDeploy hook privileges")
+	}
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	template := gof.NewTemplate()
+	_, decorateErr := codeDeployLambdaUpdateDecorator("AllAtOnce",
+		"Application",
+		"Role",
+		preHook,
+		postHook)(context.Background(),
+		"SmokeTestService",
+		lambdaFn.LogicalResourceName(),
+		&goflambda.Function{},
+		nil,
+		nil,
+		"buildID",
+		template,
+		&logger)
+	if decorateErr != nil {
+		t.Fatal(decorateErr)
+	}
+	jsonBytes, _ := template.JSON()
+	hookIndex := strings.Index(string(jsonBytes), `"BeforeAllowTrafficHook"`)
+	if hookIndex < 0 ||
+		!strings.HasPrefix(strings.Join(strings.Fields(string(jsonBytes[hookIndex:])), ""),
+			`"BeforeAllowTrafficHook":{"Ref":"`+preHook.LogicalResourceName()+`"`) {
+		t.Fatalf("Expected BeforeAllowTrafficHook reference: %s", string(jsonBytes))
+	}
+
+	// Allow CodeDeploy to invoke the hook
+	serviceTemplate := gof.NewTemplate()
+	_, serviceErr := CodeDeployServiceUpdateDecorator("AllAtOnce",
+		nil,
+		preHook,
+		nil)(context.Background(),
+		"SmokeTestService",
+		serviceTemplate,
+		nil,
+		"buildID",
+		awsv2.Config{},
+		false,
+		&logger)
+	if serviceErr != nil {
+		t.Fatal(serviceErr)
+	}
+	serviceJSON, _ := serviceTemplate.JSON()
+	if !strings.Contains(string(serviceJSON), "InvokeLifecycleHooks") {
+		t.Fatalf("Expected CodeDeploy role hook policy: %s", string(serviceJSON))
+	}
+}
@@ -0,0 +1,340 @@
+package decorator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	gofcloudwatch "github.com/awslabs/goformation/v5/cloudformation/cloudwatch"
+	goflambda "github.com/awslabs/goformation/v5/cloudformation/lambda"
+	sparta "github.com/mweagle/Sparta/v3"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// burnRateWindow is a multi-window burn rate alarm definition. The alarm
+// fires when both the long and short windows consume the error budget
+// faster than the budget fraction allows. See
+// https://sre.google/workbook/alerting-on-slos/#6-multiwindow-multi-burn-rate-alerts
+type burnRateWindow struct {
+	name           string
+	long           time.Duration
+	short          time.Duration
+	budgetConsumed float64
+}
+
+// Fast burn: 2% of the budget in an hour. Slow burn: 5% of the budget in
+// six hours. For a 30 day window these are burn rates of 14.4 and 6.
+var sloBurnRateWindows = []*burnRateWindow{
+	{
+		name:           "FastBurn",
+		long:           time.Hour,
+		short:          5 * time.Minute,
+		budgetConsumed: 0.02,
+	},
+	{
+		name:           "SlowBurn",
+		long:           6 * time.Hour,
+		short:          30 * time.Minute,
+		budgetConsumed: 0.05,
+	},
+}
+
+// burnRate returns the rate at which the window consumes the error budget
+func (brw *burnRateWindow) burnRate(sloWindow time.Duration) float64 {
+	return brw.budgetConsumed * sloWindow.Hours() / brw.long.Hours()
+}
+
+// ServiceLevelObjective is an availability or latency objective for a
+// Lambda function or API Gateway method. It satisfies
+// sparta.ServiceDecoratorHookHandler and creates multi-window burn rate
+// alarms and a stack output that the status command uses to report the
+// remaining error budget.
+type ServiceLevelObjective struct {
+	definition   *sparta.SLODefinition
+	alarmActions []string
+}
+
+func newServiceLevelObjective(name string,
+	objective float64,
+	window time.Duration,
+	namespace string,
+	dimensions map[string]string) *ServiceLevelObjective {
+	return &ServiceLevelObjective{
+		definition: &sparta.SLODefinition{
+			Name:          name,
+			Objective:     objective,
+			WindowSeconds: int64(window.Seconds()),
+			Namespace:     namespace,
+			Dimensions:    dimensions,
+		},
+	}
+}
+
+func lambdaSLODimensions(lambdaAWSInfo *sparta.LambdaAWSInfo) map[string]string {
+	return map[string]string{
+		"FunctionName": fmt.Sprintf("${%s}", lambdaAWSInfo.LogicalResourceName()),
+	}
+}
+
+// API Gateway method metrics require detailed CloudWatch metrics
+// to be enabled for the stage
+func apiMethodSLODimensions(api *sparta.API,
+	resourcePath string,
+	httpMethod string) map[string]string {
+	return map[string]string{
+		"ApiName":  api.Name(),
+		"Stage":    api.StageName(),
+		"Resource": resourcePath,
+		"Method":   httpMethod,
+	}
+}
+
+// NewLambdaAvailabilitySLO returns an objective for the percentage of
+// invocations that don't return an error (eg: 99.9 over 30 days)
+func NewLambdaAvailabilitySLO(name string,
+	lambdaAWSInfo *sparta.LambdaAWSInfo,
+	objective float64,
+	window time.Duration) *ServiceLevelObjective {
+	slo := newServiceLevelObjective(name,
+		objective,
+		window,
+		"AWS/Lambda",
+		lambdaSLODimensions(lambdaAWSInfo))
+	slo.definition.ErrorMetric = "Errors"
+	slo.definition.TotalMetric = "Invocations"
+	return slo
+}
+
+// NewLambdaLatencySLO returns an objective for the percentage of
+// invocations whose duration is less than or equal to the threshold
+func NewLambdaLatencySLO(name string,
+	lambdaAWSInfo *sparta.LambdaAWSInfo,
+	threshold time.Duration,
+	objective float64,
+	window time.Duration) *ServiceLevelObjective {
+	slo := newServiceLevelObjective(name,
+		objective,
+		window,
+		"AWS/Lambda",
+		lambdaSLODimensions(lambdaAWSInfo))
+	slo.definition.LatencyMetric = "Duration"
+	slo.definition.LatencyThresholdMs = float64(threshold.Milliseconds())
+	return slo
+}
+
+// NewAPIMethodAvailabilitySLO returns an objective for the percentage of
+// requests to the API method that don't return a 5XX response. The API
+// stage must enable detailed CloudWatch metrics.
+func NewAPIMethodAvailabilitySLO(name string,
+	api *sparta.API,
+	resourcePath string,
+	httpMethod string,
+	objective float64,
+	window time.Duration) *ServiceLevelObjective {
+	slo := newServiceLevelObjective(name,
+		objective,
+		window,
+		"AWS/ApiGateway",
+		apiMethodSLODimensions(api, resourcePath, httpMethod))
+	slo.definition.ErrorMetric = "5XXError"
+	slo.definition.TotalMetric = "Count"
+	return slo
+}
+
+// NewAPIMethodLatencySLO returns an objective for the percentage of
+// requests to the API method whose latency is less than or equal to the
+// threshold. The API stage must enable detailed CloudWatch metrics.
+func NewAPIMethodLatencySLO(name string,
+	api *sparta.API,
+	resourcePath string,
+	httpMethod string,
+	threshold time.Duration,
+	objective float64,
+	window time.Duration) *ServiceLevelObjective {
+	slo := newServiceLevelObjective(name,
+		objective,
+		window,
+		"AWS/ApiGateway",
+		apiMethodSLODimensions(api, resourcePath, httpMethod))
+	slo.definition.LatencyMetric = "Latency"
+	slo.definition.LatencyThresholdMs = float64(threshold.Milliseconds())
+	return slo
+}
+
+// WithSNSTopics sends burn rate alarm notifications to the given SNS
+// topic ARNs
+func (slo *ServiceLevelObjective) WithSNSTopics(topicARNs ...string) *ServiceLevelObjective {
+	slo.alarmActions = append(slo.alarmActions, topicARNs...)
+	return slo
+}
+
+// Definition returns the serializable objective definition
+func (slo *ServiceLevelObjective) Definition() *sparta.SLODefinition {
+	return slo.definition
+}
+
+// burnRateAlarm returns the alarm that evaluates the bad event ratio
+// over the given window
+func (slo *ServiceLevelObjective) burnRateAlarm(window time.Duration,
+	threshold float64) *gofcloudwatch.Alarm {
+	metricQueries, expression := slo.definition.MetricQueries()
+	dimensions := []gofcloudwatch.Alarm_Dimension{}
+	for _, eachName := range sortedKeys(slo.definition.Dimensions) {
+		dimensions = append(dimensions, gofcloudwatch.Alarm_Dimension{
+			Name:  eachName,
+			Value: gof.Sub(slo.definition.Dimensions[eachName]),
+		})
+	}
+	alarm := &gofcloudwatch.Alarm{
+		AlarmDescription: gof.Join(" ", []string{
+			slo.definition.Name,
+			"bad event ratio over",
+			window.String(),
+			"( Stack:",
+			gof.Ref("AWS::StackName"),
+			")",
+		}),
+		EvaluationPeriods:  1,
+		Threshold:          threshold,
+		ComparisonOperator: "GreaterThanThreshold",
+		TreatMissingData:   "notBreaching",
+	}
+	for _, eachQuery := range metricQueries {
+		alarm.Metrics = append(alarm.Metrics, gofcloudwatch.Alarm_MetricDataQuery{
+			Id: eachQuery.ID,
+			MetricStat: &gofcloudwatch.Alarm_MetricStat{
+				Metric: &gofcloudwatch.Alarm_Metric{
+					Namespace:  slo.definition.Namespace,
+					MetricName: eachQuery.MetricName,
+					Dimensions: dimensions,
+				},
+				Period: int(window.Seconds()),
+				Stat:   eachQuery.Stat,
+			},
+		})
+	}
+	alarm.Metrics = append(alarm.Metrics, gofcloudwatch.Alarm_MetricDataQuery{
+		Id:         sparta.SLOQueryRatio,
+		Expression: expression,
+		Label:      fmt.Sprintf("%s bad event ratio", slo.definition.Name),
+		ReturnData: true,
+	})
+	return alarm
+}
+
+// DecorateService satisfies the sparta.ServiceDecoratorHookHandler interface
+func (slo *ServiceLevelObjective) DecorateService(ctx context.Context,
+	serviceName string,
+	cfTemplate *gof.Template,
+	lambdaFunctionCode *goflambda.Function_Code,
+	buildID string,
+	awsConfig awsv2.Config,
+	noop bool,
+	logger *zerolog.Logger) (context.Context, error) {
+
+	if slo.definition.Objective <= 0 || slo.definition.Objective >= 100 {
+		return ctx, errors.Errorf("SLO %s objective must be between 0 and 100: %f",
+			slo.definition.Name,
+			slo.definition.Objective)
+	}
+	for eachName, eachValue := range slo.definition.Dimensions {
+		if eachValue == "" {
+			return ctx, errors.Errorf("SLO %s %s dimension is empty",
+				slo.definition.Name,
+				eachName)
+		}
+	}
+	sloWindow := slo.definition.Window()
+	for _, eachBurnRate := range sloBurnRateWindows {
+		if sloWindow < eachBurnRate.long {
+			return ctx, errors.Errorf("SLO %s window must be at least %s",
+				slo.definition.Name,
+				eachBurnRate.long)
+		}
+		threshold := eachBurnRate.burnRate(sloWindow) * slo.definition.ErrorBudget()
+		longAlarmName := sparta.CloudFormationResourceName("SLOAlarm",
+			slo.definition.Name,
+			eachBurnRate.name,
+			"Long")
+		shortAlarmName := sparta.CloudFormationResourceName("SLOAlarm",
+			slo.definition.Name,
+			eachBurnRate.name,
+			"Short")
+		cfTemplate.Resources[longAlarmName] = slo.burnRateAlarm(eachBurnRate.long, threshold)
+		cfTemplate.Resources[shortAlarmName] = slo.burnRateAlarm(eachBurnRate.short, threshold)
+
+		compositeAlarmName := sparta.CloudFormationResourceName("SLOCompositeAlarm",
+			slo.definition.Name,
+			eachBurnRate.name)
+		cfTemplate.Resources[compositeAlarmName] = &gofcloudwatch.CompositeAlarm{
+			AlarmName: fmt.Sprintf("%s-%s-%s", serviceName, slo.definition.Name, eachBurnRate.name),
+			AlarmDescription: fmt.Sprintf("%s error budget burn rate exceeds %.1f",
+				slo.definition.Name,
+				eachBurnRate.burnRate(sloWindow)),
+			AlarmRule: gof.Sub(fmt.Sprintf(`ALARM("${%s}") AND ALARM("${%s}")`,
+				longAlarmName,
+				shortAlarmName)),
+			AlarmActions: slo.alarmActions,
+		}
+	}
+
+	// Publish the definition for the status command
+	definitionJSON, definitionJSONErr := json.Marshal(slo.definition)
+	if definitionJSONErr != nil {
+		return ctx, errors.Wrapf(definitionJSONErr, "Failed to marshal SLO definition")
+	}
+	outputName := sparta.CloudFormationResourceName(sparta.SLOOutputPrefix, slo.definition.Name)
+	cfTemplate.Outputs[outputName] = gof.Output{
+		Description: fmt.Sprintf("%s service level objective", slo.definition.Name),
+		Value:       gof.Sub(string(definitionJSON)),
+	}
+	return ctx, nil
+}
+
+// NewSLOWidget returns a graph of the objective's bad event ratio together
+// with its error budget
+func NewSLOWidget(slo *ServiceLevelObjective) *DashboardWidget {
+	metricQueries, expression := slo.definition.MetricQueries()
+	metrics := []*DashboardMetric{
+		{
+			ID:         sparta.SLOQueryRatio,
+			Expression: expression,
+			Label:      "Bad event ratio",
+		},
+	}
+	for _, eachQuery := range metricQueries {
+		metrics = append(metrics, &DashboardMetric{
+			Namespace:  slo.definition.Namespace,
+			MetricName: eachQuery.MetricName,
+			Dimensions: slo.definition.Dimensions,
+			Stat:       eachQuery.Stat,
+			ID:         eachQuery.ID,
+			Hidden:     true,
+		})
+	}
+	return NewMetricWidget(fmt.Sprintf("SLO: %s (%g%%)", slo.definition.Name, slo.definition.Objective),
+		metrics...).
+		WithProperty("annotations", map[string]interface{}{
+			"horizontal": []map[string]interface{}{
+				{
+					"label": "Error budget",
+					"value": slo.definition.ErrorBudget(),
+				},
+			},
+		})
+}
+
+// sortedKeys returns the map keys in a stable order for template output
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for eachKey := range values {
+		keys = append(keys, eachKey)
+	}
+	sort.Strings(keys)
+	return keys
+}
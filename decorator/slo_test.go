@@ -0,0 +1,84 @@
+package decorator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	gofcloudwatch "github.com/awslabs/goformation/v5/cloudformation/cloudwatch"
+	sparta "github.com/mweagle/Sparta/v3"
+)
+
+func testSLOLambda() *sparta.LambdaAWSInfo {
+	helloWorld := func(ctx context.Context) (string, error) {
+		return "Hello World", nil
+	}
+	lambdaFn, _ := sparta.NewAWSLambda(sparta.LambdaName(helloWorld),
+		helloWorld,
+		sparta.IAMRoleDefinition{})
+	return lambdaFn
+}
+
+func TestLambdaAvailabilitySLO(t *testing.T) {
+	slo := NewLambdaAvailabilitySLO("Availability",
+		testSLOLambda(),
+		99.9,
+		30*24*time.Hour).
+		WithSNSTopics("arn:aws:sns:us-west-2:123412341234:alerts")
+	template, templateErr := testDecorateService(slo, nil)
+	if templateErr != nil {
+		t.Fatal(templateErr)
+	}
+	thresholds := map[float64]int{}
+	for _, eachResource := range testResourcesOfType(template, "AWS::CloudWatch::Alarm") {
+		alarm := eachResource.(*gofcloudwatch.Alarm)
+		thresholds[float64(int(alarm.Threshold*1e6+0.5))/1e6]++
+	}
+	// 14.4 and 6 times the 0.1% error budget
+	if thresholds[0.0144] != 2 || thresholds[0.006] != 2 {
+		t.Fatalf("Unexpected burn rate thresholds: %#v", thresholds)
+	}
+	composites := testResourcesOfType(template, "AWS::CloudWatch::CompositeAlarm")
+	if len(composites) != 2 {
+		t.Fatalf("Expected fast and slow burn composite alarms. Found: %d", len(composites))
+	}
+	for _, eachResource := range composites {
+		if len(eachResource.(*gofcloudwatch.CompositeAlarm).AlarmActions) != 1 {
+			t.Fatalf("Expected composite alarm SNS action")
+		}
+	}
+	jsonBytes, _ := template.JSON()
+	for _, eachFragment := range []string{
+		`") AND ALARM(\"`,
+		`"bad / total"`,
+		`\"errorMetric\":\"Errors\"`,
+	} {
+		if !strings.Contains(string(jsonBytes), eachFragment) {
+			t.Fatalf("Failed to find %s in template: %s", eachFragment, string(jsonBytes))
+		}
+	}
+	outputName := sparta.CloudFormationResourceName(sparta.SLOOutputPrefix, "Availability")
+	if _, outputExists := template.Outputs[outputName]; !outputExists {
+		t.Fatalf("Expected SLO output: %s", outputName)
+	}
+}
+
+func TestSLOValidation(t *testing.T) {
+	invalidSLOs := []*ServiceLevelObjective{
+		NewLambdaLatencySLO("Objective", testSLOLambda(), time.Second, 100, 30*24*time.Hour),
+		NewLambdaLatencySLO("Window", testSLOLambda(), time.Second, 99, time.Hour),
+		NewAPIMethodAvailabilitySLO("Stage",
+			sparta.NewAPIGateway("SLOAPI", nil),
+			"/hello",
+			"GET",
+			99,
+			30*24*time.Hour),
+	}
+	for _, eachSLO := range invalidSLOs {
+		_, templateErr := testDecorateService(eachSLO, nil)
+		if templateErr == nil {
+			t.Fatalf("Expected SLO validation error: %s", eachSLO.Definition().Name)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package interceptor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	sparta "github.com/mweagle/Sparta/v3"
+	spartaCW "github.com/mweagle/Sparta/v3/aws/cloudwatch"
+	"github.com/rs/zerolog"
+)
+
+// EmbeddedMetrics properties
+const (
+	// EmbeddedMetricsPropertyRequestID is the property that stores the AWS
+	// request ID in every embedded metric document
+	EmbeddedMetricsPropertyRequestID = "requestId"
+	// EmbeddedMetricsPropertyBuildID is the property that stores the
+	// service build ID in every embedded metric document
+	EmbeddedMetricsPropertyBuildID = "buildId"
+)
+
+// embeddedMetricsInterceptor is an implementation of
+// sparta.LambdaEventInterceptors that creates a MetricsContext for each
+// invocation and flushes it when the invocation completes
+type embeddedMetricsInterceptor struct {
+	namespace     string
+	dimensionSets []map[string]string
+	sink          io.Writer
+}
+
+func (emi *embeddedMetricsInterceptor) Begin(ctx context.Context, msg json.RawMessage) context.Context {
+	metricsContext := spartaCW.NewMetricsContext(emi.namespace).
+		WithProperty(EmbeddedMetricsPropertyBuildID, sparta.StampedBuildID)
+	for _, eachSet := range emi.dimensionSets {
+		metricsContext.WithDimensionSet(eachSet)
+	}
+	lambdaContext, lambdaContextOk := lambdacontext.FromContext(ctx)
+	if lambdaContextOk {
+		metricsContext.WithProperty(EmbeddedMetricsPropertyRequestID, lambdaContext.AwsRequestID)
+	}
+	return spartaCW.ContextWithMetrics(ctx, metricsContext)
+}
+
+func (emi *embeddedMetricsInterceptor) BeforeSetup(ctx context.Context, msg json.RawMessage) context.Context {
+	return ctx
+}
+func (emi *embeddedMetricsInterceptor) AfterSetup(ctx context.Context, msg json.RawMessage) context.Context {
+	return ctx
+}
+func (emi *embeddedMetricsInterceptor) BeforeDispatch(ctx context.Context, msg json.RawMessage) context.Context {
+	return ctx
+}
+func (emi *embeddedMetricsInterceptor) AfterDispatch(ctx context.Context, msg json.RawMessage) context.Context {
+	return ctx
+}
+
+func (emi *embeddedMetricsInterceptor) Complete(ctx context.Context, msg json.RawMessage) context.Context {
+	metricsContext := spartaCW.MetricsContextFromContext(ctx)
+	if metricsContext == nil {
+		return ctx
+	}
+	flushErr := metricsContext.FlushToSink(emi.sink)
+	if flushErr != nil {
+		logger, loggerOk := ctx.Value(sparta.ContextKeyLogger).(*zerolog.Logger)
+		if loggerOk {
+			logger.Error().
+				Err(flushErr).
+				Msg("Failed to publish embedded metrics")
+		}
+	}
+	return ctx
+}
+
+// RegisterEmbeddedMetricsInterceptor creates a cloudwatch.MetricsContext
+// for each invocation that publishes metrics to the namespace with the
+// optional dimension sets. Handlers access it with
+// cloudwatch.MetricsContextFromContext(ctx). The metrics are written to
+// stdout in the CloudWatch Embedded Metric Format exactly once, when the
+// invocation completes.
+func RegisterEmbeddedMetricsInterceptor(handler *sparta.LambdaEventInterceptors,
+	namespace string,
+	dimensionSets ...map[string]string) *sparta.LambdaEventInterceptors {
+	interceptor := &embeddedMetricsInterceptor{
+		namespace:     namespace,
+		dimensionSets: dimensionSets,
+		sink:          os.Stdout,
+	}
+	if handler == nil {
+		handler = &sparta.LambdaEventInterceptors{}
+	}
+	return handler.Register(interceptor)
+}
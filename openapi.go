@@ -0,0 +1,622 @@
+//go:build !lambdabinary
+// +build !lambdabinary
+
+package sparta
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	spartaAPIGateway "github.com/mweagle/Sparta/v3/aws/apigateway"
+	"github.com/pkg/errors"
+)
+
+var (
+	openAPITimeType       = reflect.TypeOf(time.Time{})
+	openAPIRawMessageType = reflect.TypeOf(json.RawMessage{})
+	openAPIResponseType   = reflect.TypeOf(spartaAPIGateway.Response{})
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Go type schemas
+//
+
+// openAPISchemaBuilder creates schemas for Go types. Named struct types
+// are added to the component schemas and referenced with $ref.
+type openAPISchemaBuilder struct {
+	schemas map[string]OpenAPISchema
+	names   map[reflect.Type]string
+}
+
+func newOpenAPISchemaBuilder(schemas map[string]OpenAPISchema) *openAPISchemaBuilder {
+	return &openAPISchemaBuilder{
+		schemas: schemas,
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+func (osb *openAPISchemaBuilder) schema(goType reflect.Type) OpenAPISchema {
+	for goType.Kind() == reflect.Ptr {
+		goType = goType.Elem()
+	}
+	switch goType {
+	case openAPITimeType:
+		return OpenAPISchema{"type": "string", "format": "date-time"}
+	case openAPIRawMessageType:
+		return OpenAPISchema{}
+	}
+	switch goType.Kind() {
+	case reflect.Bool:
+		return OpenAPISchema{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return OpenAPISchema{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return OpenAPISchema{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return OpenAPISchema{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return OpenAPISchema{"type": "number", "format": "double"}
+	case reflect.String:
+		return OpenAPISchema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if goType.Elem().Kind() == reflect.Uint8 {
+			return OpenAPISchema{"type": "string", "format": "byte"}
+		}
+		return OpenAPISchema{"type": "array", "items": osb.schema(goType.Elem())}
+	case reflect.Map:
+		return OpenAPISchema{"type": "object", "additionalProperties": osb.schema(goType.Elem())}
+	case reflect.Struct:
+		if goType.Name() == "" {
+			return osb.structSchema(goType)
+		}
+		name, nameExists := osb.names[goType]
+		if !nameExists {
+			name = goType.Name()
+			for index := 2; osb.schemas[name] != nil; index++ {
+				name = fmt.Sprintf("%s%d", goType.Name(), index)
+			}
+			// Register the name before visiting the fields to support
+			// recursive types
+			osb.names[goType] = name
+			osb.schemas[name] = OpenAPISchema{}
+			osb.schemas[name] = osb.structSchema(goType)
+		}
+		return OpenAPISchema{"$ref": openAPIComponentSchemaPrefix + name}
+	}
+	return OpenAPISchema{}
+}
+
+func (osb *openAPISchemaBuilder) structSchema(structType reflect.Type) OpenAPISchema {
+	properties := make(map[string]interface{})
+	required := []string{}
+	osb.structProperties(structType, properties, &required)
+	schema := OpenAPISchema{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) != 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (osb *openAPISchemaBuilder) structProperties(structType reflect.Type,
+	properties map[string]interface{},
+	required *[]string) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		tagParts := strings.Split(tag, ",")
+		name := tagParts[0]
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		// Embedded structs without a JSON name are flattened
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			osb.structProperties(fieldType, properties, required)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = osb.schema(field.Type)
+		omitEmpty := false
+		for _, eachOption := range tagParts[1:] {
+			omitEmpty = omitEmpty || eachOption == "omitempty"
+		}
+		if !omitEmpty && field.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// requestBodyType returns the Go type of the `body` field in the
+// handler's event, if the handler defines one
+func requestBodyType(eventType reflect.Type) reflect.Type {
+	for eventType != nil && eventType.Kind() == reflect.Ptr {
+		eventType = eventType.Elem()
+	}
+	if eventType == nil || eventType.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < eventType.NumField(); i++ {
+		field := eventType.Field(i)
+		if strings.Split(field.Tag.Get("json"), ",")[0] == "body" &&
+			field.Type.Kind() != reflect.Interface {
+			return field.Type
+		}
+	}
+	return nil
+}
+
+// responseBodyType returns the Go type of the handler's response, unless
+// it's an untyped value or an apigateway.Response
+func responseBodyType(responseType reflect.Type) reflect.Type {
+	for responseType != nil && responseType.Kind() == reflect.Ptr {
+		responseType = responseType.Elem()
+	}
+	if responseType == nil ||
+		responseType.Kind() == reflect.Interface ||
+		responseType == openAPIResponseType {
+		return nil
+	}
+	return responseType
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Document
+//
+
+// openAPIModelSchemas returns the content type schemas for the models. Named
+// models are added to the component schemas.
+func openAPIModelSchemas(models map[string]*Model,
+	schemas map[string]OpenAPISchema) (map[string]*OpenAPIMediaType, error) {
+	content := make(map[string]*OpenAPIMediaType)
+	for eachContentType, eachModel := range models {
+		if eachModel == nil || eachModel.Schema == "" {
+			continue
+		}
+		var schema OpenAPISchema
+		unmarshalErr := json.Unmarshal([]byte(eachModel.Schema), &schema)
+		if unmarshalErr != nil {
+			return nil, errors.Wrapf(unmarshalErr,
+				"Failed to unmarshal model %s schema",
+				eachModel.Name)
+		}
+		// The JSON Schema draft isn't part of the OpenAPI schema object
+		delete(schema, "$schema")
+		if eachModel.Description != "" {
+			schema["description"] = eachModel.Description
+		}
+		if eachModel.Name != "" {
+			schemas[eachModel.Name] = schema
+			schema = OpenAPISchema{"$ref": openAPIComponentSchemaPrefix + eachModel.Name}
+		}
+		content[eachContentType] = &OpenAPIMediaType{Schema: schema}
+	}
+	return content, nil
+}
+
+// openAPIParameters returns the path parameters and the method request
+// parameters (method.request.{path|querystring|header}.NAME)
+func openAPIParameters(pathPart string, method *Method) []*OpenAPIParameter {
+	parameters := make(map[string]*OpenAPIParameter)
+	for _, eachMatch := range reOpenAPIPathParam.FindAllStringSubmatch(pathPart, -1) {
+		parameters["path."+eachMatch[1]] = &OpenAPIParameter{
+			Name:     eachMatch[1],
+			In:       "path",
+			Required: true,
+		}
+	}
+	for eachParam, eachRequired := range method.Parameters {
+		parts := strings.SplitN(eachParam, ".", 4)
+		if len(parts) != 4 {
+			continue
+		}
+		location := parts[2]
+		switch location {
+		case "querystring":
+			location = "query"
+		case "path", "header":
+		default:
+			continue
+		}
+		key := location + "." + parts[3]
+		if _, exists := parameters[key]; !exists {
+			parameters[key] = &OpenAPIParameter{
+				Name:     parts[3],
+				In:       location,
+				Required: eachRequired || location == "path",
+			}
+		}
+	}
+	keys := make([]string, 0, len(parameters))
+	for eachKey := range parameters {
+		keys = append(keys, eachKey)
+	}
+	sort.Strings(keys)
+	openAPIParams := make([]*OpenAPIParameter, 0, len(keys))
+	for _, eachKey := range keys {
+		parameter := parameters[eachKey]
+		parameter.Schema = OpenAPISchema{"type": "string"}
+		openAPIParams = append(openAPIParams, parameter)
+	}
+	return openAPIParams
+}
+
+// openAPIResponseHeaders returns the headers in the method.response.header.NAME
+// response parameters
+func openAPIResponseHeaders(parameters map[string]bool) map[string]*OpenAPIHeader {
+	if len(parameters) == 0 {
+		return nil
+	}
+	headers := make(map[string]*OpenAPIHeader)
+	for eachParam := range parameters {
+		headerName := strings.TrimPrefix(eachParam, "method.response.header.")
+		if headerName != eachParam {
+			headers[headerName] = &OpenAPIHeader{
+				Schema: OpenAPISchema{"type": "string"},
+			}
+		}
+	}
+	return headers
+}
+
+// openAPICORSResponseParameters returns the static CORS integration
+// response values
+func openAPICORSResponseParameters(api *API) map[string]string {
+	headers := defaultCORSHeaders
+	if api.CORSOptions != nil && len(api.CORSOptions.Headers) != 0 {
+		headers = api.CORSOptions.Headers
+	}
+	params := make(map[string]string)
+	for eachHeader, eachValue := range headers {
+		params[fmt.Sprintf("method.response.header.%s", eachHeader)] = fmt.Sprintf("'%v'", eachValue)
+	}
+	return params
+}
+
+// openAPISecurityRequirement returns the security requirement for the
+// method and adds the schemes to the components
+func openAPISecurityRequirement(method *Method,
+	securitySchemes map[string]*OpenAPISecurityScheme) map[string][]string {
+	requirement := make(map[string][]string)
+	if method.authorizer != nil {
+		schemeName := reOpenAPIOperationID.ReplaceAllString(method.authorizer.name, "")
+		if _, exists := securitySchemes[schemeName]; !exists {
+			scheme := &OpenAPISecurityScheme{
+				Type: "apiKey",
+				Name: "Authorization",
+				In:   "header",
+			}
+			switch method.authorizer.authorizerType {
+			case AuthorizerTypeIAM:
+				scheme.AuthType = "awsSigv4"
+			case AuthorizerTypeCognitoUserPools:
+				scheme.AuthType = "cognito_user_pools"
+			default:
+				scheme.AuthType = "custom"
+			}
+			securitySchemes[schemeName] = scheme
+		}
+		scopes := method.AuthorizationScopes
+		if scopes == nil {
+			scopes = []string{}
+		}
+		requirement[schemeName] = scopes
+	} else if method.authorizationID != "" {
+		schemeName := ""
+		authRef, authRefErr := resolveResourceRef(method.authorizationID)
+		if authRefErr == nil && authRef != nil && authRef.RefType != resourceLiteral {
+			schemeName = authRef.ResourceName
+		}
+		if schemeName == "" {
+			schemeName = reOpenAPIOperationID.ReplaceAllString(method.authorizationID, "")
+		}
+		if _, exists := securitySchemes[schemeName]; !exists {
+			securitySchemes[schemeName] = &OpenAPISecurityScheme{
+				Type:     "apiKey",
+				Name:     "Authorization",
+				In:       "header",
+				AuthType: "custom",
+			}
+		}
+		requirement[schemeName] = []string{}
+	}
+	if method.APIKeyRequired {
+		securitySchemes[openAPIAPIKeySchemeName] = &OpenAPISecurityScheme{
+			Type: "apiKey",
+			Name: "x-api-key",
+			In:   "header",
+		}
+		requirement[openAPIAPIKeySchemeName] = []string{}
+	}
+	if len(requirement) == 0 {
+		return nil
+	}
+	return requirement
+}
+
+// openAPIOperation returns the operation for the resource method
+func (api *API) openAPIOperation(resource *Resource,
+	method *Method,
+	components *OpenAPIComponents,
+	schemaBuilder *openAPISchemaBuilder) (*OpenAPIOperation, error) {
+
+	lambdaName := resource.parentLambda.lambdaFunctionName()
+	operation := &OpenAPIOperation{
+		OperationID: openAPIOperationID(method.httpMethod, resource.pathPart),
+		Summary:     lambdaName,
+		Parameters:  openAPIParameters(resource.pathPart, method),
+		Responses:   make(map[string]*OpenAPIResponse),
+	}
+	eventType, responseType := resource.parentLambda.HandlerTypes()
+
+	// Request body
+	requestContent, requestContentErr := openAPIModelSchemas(method.Models, components.Schemas)
+	if requestContentErr != nil {
+		return nil, requestContentErr
+	}
+	if len(requestContent) == 0 {
+		bodyType := requestBodyType(eventType)
+		switch method.httpMethod {
+		case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions:
+			bodyType = nil
+		}
+		if bodyType != nil {
+			requestContent["application/json"] = &OpenAPIMediaType{
+				Schema: schemaBuilder.schema(bodyType),
+			}
+		}
+	}
+	if len(requestContent) != 0 {
+		operation.RequestBody = &OpenAPIRequestBody{
+			Content: requestContent,
+		}
+	}
+
+	// Responses. Only the default status code and the codes with models
+	// or headers are included.
+	corsEnabled := api.corsEnabled()
+	for eachStatusCode, eachResponse := range method.Responses {
+		if eachResponse == nil {
+			continue
+		}
+		isDefault := eachStatusCode == method.defaultHTTPResponseCode
+		if !isDefault && len(eachResponse.Models) == 0 && len(eachResponse.Parameters) == 0 {
+			continue
+		}
+		responseContent, responseContentErr := openAPIModelSchemas(eachResponse.Models,
+			components.Schemas)
+		if responseContentErr != nil {
+			return nil, responseContentErr
+		}
+		if isDefault && len(responseContent) == 0 {
+			if bodyType := responseBodyType(responseType); bodyType != nil {
+				responseContent["application/json"] = &OpenAPIMediaType{
+					Schema: schemaBuilder.schema(bodyType),
+				}
+			}
+		}
+		responseParams := make(map[string]bool)
+		for eachParam, eachRequired := range eachResponse.Parameters {
+			responseParams[eachParam] = eachRequired
+		}
+		if corsEnabled {
+			for eachParam, eachRequired := range corsMethodResponseParams(api) {
+				responseParams[eachParam] = eachRequired
+			}
+		}
+		openAPIResponse := &OpenAPIResponse{
+			Description: http.StatusText(eachStatusCode),
+			Headers:     openAPIResponseHeaders(responseParams),
+		}
+		if len(responseContent) != 0 {
+			openAPIResponse.Content = responseContent
+		}
+		operation.Responses[strconv.Itoa(eachStatusCode)] = openAPIResponse
+	}
+	if len(operation.Responses) == 0 {
+		operation.Responses["default"] = &OpenAPIResponse{
+			Description: "Default response",
+		}
+	}
+
+	// Security
+	requirement := openAPISecurityRequirement(method, components.SecuritySchemes)
+	if requirement != nil {
+		operation.Security = []map[string][]string{requirement}
+	}
+
+	// Integration
+	integrationURI := fmt.Sprintf("arn:aws:apigateway:${AWS::Region}:lambda:path/2015-03-31/functions/${%s.Arn}/invocations",
+		resource.parentLambda.LogicalResourceName())
+	if method.Integration.integrationType == integrationTypeAWSProxy {
+		operation.Integration = map[string]interface{}{
+			"type":       "aws_proxy",
+			"httpMethod": http.MethodPost,
+			"uri":        integrationURI,
+		}
+		return operation, nil
+	}
+	requestTemplates, requestTemplatesErr := methodRequestTemplates(method)
+	if requestTemplatesErr != nil {
+		return nil, requestTemplatesErr
+	}
+	integrationResponses := make(map[string]interface{})
+	for eachStatusCode, eachResponse := range method.Integration.Responses {
+		selectionPattern := eachResponse.SelectionPattern
+		if selectionPattern == "" {
+			selectionPattern = "default"
+		}
+		responseParams := make(map[string]string)
+		for eachParam, eachValue := range eachResponse.Parameters {
+			responseParams[eachParam] = eachValue
+		}
+		if corsEnabled {
+			for eachParam, eachValue := range openAPICORSResponseParameters(api) {
+				responseParams[eachParam] = eachValue
+			}
+		}
+		integrationResponse := map[string]interface{}{
+			"statusCode": strconv.Itoa(eachStatusCode),
+		}
+		if len(responseParams) != 0 {
+			integrationResponse["responseParameters"] = responseParams
+		}
+		if len(eachResponse.Templates) != 0 {
+			integrationResponse["responseTemplates"] = eachResponse.Templates
+		}
+		integrationResponses[selectionPattern] = integrationResponse
+	}
+	operation.Integration = map[string]interface{}{
+		"type":             "aws",
+		"httpMethod":       http.MethodPost,
+		"uri":              integrationURI,
+		"requestTemplates": requestTemplates,
+		"responses":        integrationResponses,
+	}
+	if len(method.Integration.Parameters) != 0 {
+		operation.Integration["requestParameters"] = method.Integration.Parameters
+	}
+	if len(method.Integration.CacheKeyParameters) != 0 {
+		operation.Integration["cacheKeyParameters"] = method.Integration.CacheKeyParameters
+	}
+	if method.Integration.CacheNamespace != "" {
+		operation.Integration["cacheNamespace"] = method.Integration.CacheNamespace
+	}
+	if method.Integration.Credentials != "" {
+		operation.Integration["credentials"] = method.Integration.Credentials
+	}
+	return operation, nil
+}
+
+// openAPICORSOperation returns the OPTIONS preflight operation
+func (api *API) openAPICORSOperation() *OpenAPIOperation {
+	return &OpenAPIOperation{
+		Responses: map[string]*OpenAPIResponse{
+			"200": {
+				Description: "CORS preflight response",
+				Headers:     openAPIResponseHeaders(corsMethodResponseParams(api)),
+			},
+		},
+		Integration: map[string]interface{}{
+			"type": "mock",
+			"requestTemplates": map[string]string{
+				"application/json": "{\"statusCode\": 200}",
+				"text/plain":       "statusCode: 200",
+			},
+			"responses": map[string]interface{}{
+				"default": map[string]interface{}{
+					"statusCode":         "200",
+					"responseParameters": openAPICORSResponseParameters(api),
+					"responseTemplates": map[string]string{
+						"application/*": "",
+						"text/*":        "",
+					},
+				},
+			},
+		},
+	}
+}
+
+// OpenAPI returns the OpenAPI 3.0 document for the API. Request and
+// response schemas are produced from the method Models or, if there are
+// none, from the Go types of the handler's event `body` field and response.
+// The x-amazon-apigateway-integration uri values are Fn::Sub expressions.
+func (api *API) OpenAPI() (*OpenAPIDocument, error) {
+	doc := &OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info: &OpenAPIInfo{
+			Title:       api.name,
+			Description: api.Description,
+			Version:     "1.0.0",
+		},
+		Paths: make(map[string]OpenAPIPathItem),
+	}
+	components := &OpenAPIComponents{
+		Schemas:         make(map[string]OpenAPISchema),
+		SecuritySchemes: make(map[string]*OpenAPISecurityScheme),
+	}
+	schemaBuilder := newOpenAPISchemaBuilder(components.Schemas)
+
+	if api.stage != nil {
+		doc.Servers = []*OpenAPIServer{{
+			URL: fmt.Sprintf("https://{restApiId}.execute-api.{region}.amazonaws.com/%s",
+				api.stage.name),
+			Variables: map[string]*OpenAPIServerVariable{
+				"restApiId": {
+					Default:     "restApiId",
+					Description: "The API Gateway RestApi ID",
+				},
+				"region": {
+					Default: "us-east-1",
+				},
+			},
+		}}
+		if api.stage.Description != "" {
+			doc.Info.Description = strings.TrimSpace(doc.Info.Description + "\n\n" + api.stage.Description)
+		}
+	}
+
+	// Iterate in a stable order so that schema names are deterministic
+	resourceKeys := make([]string, 0, len(api.resources))
+	for eachKey := range api.resources {
+		resourceKeys = append(resourceKeys, eachKey)
+	}
+	sort.Strings(resourceKeys)
+	for _, eachKey := range resourceKeys {
+		eachResource := api.resources[eachKey]
+		pathItem, pathItemExists := doc.Paths[eachResource.pathPart]
+		if !pathItemExists {
+			pathItem = make(OpenAPIPathItem)
+			doc.Paths[eachResource.pathPart] = pathItem
+		}
+		methodNames := make([]string, 0, len(eachResource.Methods))
+		for eachMethodName := range eachResource.Methods {
+			methodNames = append(methodNames, eachMethodName)
+		}
+		sort.Strings(methodNames)
+		for _, eachMethodName := range methodNames {
+			operation, operationErr := api.openAPIOperation(eachResource,
+				eachResource.Methods[eachMethodName],
+				components,
+				schemaBuilder)
+			if operationErr != nil {
+				return nil, errors.Wrapf(operationErr,
+					"Failed to create OpenAPI operation for %s %s",
+					eachMethodName,
+					eachResource.pathPart)
+			}
+			operationKey := strings.ToLower(eachMethodName)
+			if eachMethodName == proxyHTTPMethod {
+				operationKey = "x-amazon-apigateway-any-method"
+			}
+			pathItem[operationKey] = operation
+		}
+		if api.corsEnabled() {
+			if _, exists := pathItem["options"]; !exists {
+				pathItem["options"] = api.openAPICORSOperation()
+			}
+		}
+	}
+	if len(components.Schemas) != 0 || len(components.SecuritySchemes) != 0 {
+		doc.Components = components
+	}
+	return doc, nil
+}
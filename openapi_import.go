@@ -0,0 +1,407 @@
+package sparta
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	yamlwrapper "github.com/sanathkr/yaml"
+)
+
+const (
+	// openAPIComponentParameterPrefix is the prefix of component parameter
+	// references
+	openAPIComponentParameterPrefix = "#/components/parameters/"
+	// openAPIModelSchemaDraft is the JSON Schema draft used by API Gateway
+	// models
+	openAPIModelSchemaDraft = "http://json-schema.org/draft-04/schema#"
+)
+
+// openAPIImportMethods are the path item keys that are operations
+var openAPIImportMethods = []string{
+	http.MethodDelete,
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodPatch,
+	http.MethodPost,
+	http.MethodPut,
+}
+
+// openAPIImportDocument is the subset of the OpenAPI document used by the
+// importer. Path items are decoded separately since they may include
+// non-operation keys.
+type openAPIImportDocument struct {
+	OpenAPI    string                                `json:"openapi"`
+	Info       *OpenAPIInfo                          `json:"info"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components *OpenAPIComponents                    `json:"components"`
+}
+
+// OpenAPIImportReport describes the differences between the OpenAPI
+// document and the handlers supplied to NewAPIGatewayFromOpenAPI
+type OpenAPIImportReport struct {
+	// UnboundOperations are the operation IDs without a handler. They
+	// aren't added to the API.
+	UnboundOperations []string
+	// UnusedHandlers are the handler keys that don't match an operation ID
+	UnusedHandlers []string
+	// UnsupportedSecuritySchemes are the security schemes that aren't
+	// applied to the imported methods. Only API key requirements are
+	// imported. Use NewAuthorizerMethod to add authorizers.
+	UnsupportedSecuritySchemes []string
+}
+
+// IsComplete returns true if every operation has a handler and every
+// handler has an operation
+func (report *OpenAPIImportReport) IsComplete() bool {
+	return len(report.UnboundOperations) == 0 && len(report.UnusedHandlers) == 0
+}
+
+// Error returns an error describing the unbound operations and unused
+// handlers, or nil if the import is complete
+func (report *OpenAPIImportReport) Error() error {
+	if report.IsComplete() {
+		return nil
+	}
+	messages := []string{}
+	if len(report.UnboundOperations) != 0 {
+		messages = append(messages,
+			fmt.Sprintf("operations without a handler: %s", strings.Join(report.UnboundOperations, ", ")))
+	}
+	if len(report.UnusedHandlers) != 0 {
+		messages = append(messages,
+			fmt.Sprintf("handlers without an operation: %s", strings.Join(report.UnusedHandlers, ", ")))
+	}
+	return errors.Errorf("Incomplete OpenAPI import (%s)", strings.Join(messages, "; "))
+}
+
+// openAPIImporter builds the API resources for an OpenAPI document
+type openAPIImporter struct {
+	doc       *openAPIImportDocument
+	api       *API
+	handlers  map[string]*LambdaAWSInfo
+	resources map[string]*Resource
+	report    *OpenAPIImportReport
+	bound     map[string]bool
+	schemes   map[string]bool
+}
+
+// resolveSchema returns the schema with the component references replaced
+// by their definitions. Recursive references are replaced with an empty
+// schema since API Gateway models can't reference themselves.
+func (importer *openAPIImporter) resolveSchema(schema interface{}, visiting map[string]bool) interface{} {
+	switch typedSchema := schema.(type) {
+	case map[string]interface{}:
+		if ref, refOk := typedSchema["$ref"].(string); refOk {
+			name := strings.TrimPrefix(ref, openAPIComponentSchemaPrefix)
+			component, componentExists := OpenAPISchema(nil), false
+			if importer.doc.Components != nil {
+				component, componentExists = importer.doc.Components.Schemas[name]
+			}
+			if !componentExists || visiting[name] {
+				return map[string]interface{}{}
+			}
+			visiting[name] = true
+			resolved := importer.resolveSchema(map[string]interface{}(component), visiting)
+			delete(visiting, name)
+			return resolved
+		}
+		resolved := make(map[string]interface{}, len(typedSchema))
+		for eachKey, eachValue := range typedSchema {
+			resolved[eachKey] = importer.resolveSchema(eachValue, visiting)
+		}
+		return resolved
+	case OpenAPISchema:
+		return importer.resolveSchema(map[string]interface{}(typedSchema), visiting)
+	case []interface{}:
+		resolved := make([]interface{}, len(typedSchema))
+		for index, eachValue := range typedSchema {
+			resolved[index] = importer.resolveSchema(eachValue, visiting)
+		}
+		return resolved
+	}
+	return schema
+}
+
+// models returns the API Gateway models for the content schemas
+func (importer *openAPIImporter) models(content map[string]*OpenAPIMediaType,
+	defaultName string) (map[string]*Model, error) {
+	models := make(map[string]*Model)
+	contentTypes := make([]string, 0, len(content))
+	for eachContentType := range content {
+		contentTypes = append(contentTypes, eachContentType)
+	}
+	sort.Strings(contentTypes)
+	for index, eachContentType := range contentTypes {
+		mediaType := content[eachContentType]
+		if mediaType == nil || mediaType.Schema == nil {
+			continue
+		}
+		name := defaultName
+		if index != 0 {
+			name = fmt.Sprintf("%s%d", defaultName, index+1)
+		}
+		if ref, refOk := mediaType.Schema["$ref"].(string); refOk &&
+			strings.HasPrefix(ref, openAPIComponentSchemaPrefix) {
+			name = strings.TrimPrefix(ref, openAPIComponentSchemaPrefix)
+		}
+		resolved, _ := importer.resolveSchema(mediaType.Schema, map[string]bool{}).(map[string]interface{})
+		if resolved == nil {
+			resolved = map[string]interface{}{}
+		}
+		resolved["$schema"] = openAPIModelSchemaDraft
+		schemaJSON, schemaJSONErr := json.Marshal(resolved)
+		if schemaJSONErr != nil {
+			return nil, errors.Wrapf(schemaJSONErr, "Failed to marshal %s schema", name)
+		}
+		models[eachContentType] = &Model{
+			Name:   reOpenAPIOperationID.ReplaceAllString(name, ""),
+			Schema: string(schemaJSON),
+		}
+	}
+	return models, nil
+}
+
+// parameter returns the parameter, resolving component references
+func (importer *openAPIImporter) parameter(param *OpenAPIParameter) *OpenAPIParameter {
+	if param == nil || param.Ref == "" {
+		return param
+	}
+	if importer.doc.Components == nil {
+		return nil
+	}
+	return importer.doc.Components.Parameters[strings.TrimPrefix(param.Ref,
+		openAPIComponentParameterPrefix)]
+}
+
+// security applies the operation security requirements to the method
+func (importer *openAPIImporter) security(requirements []map[string][]string,
+	method *Method) {
+	for _, eachRequirement := range requirements {
+		for eachSchemeName := range eachRequirement {
+			var scheme *OpenAPISecurityScheme
+			if importer.doc.Components != nil {
+				scheme = importer.doc.Components.SecuritySchemes[eachSchemeName]
+			}
+			if scheme != nil &&
+				scheme.Type == "apiKey" &&
+				scheme.In == "header" &&
+				strings.EqualFold(scheme.Name, "x-api-key") &&
+				scheme.AuthType == "" {
+				method.APIKeyRequired = true
+				continue
+			}
+			importer.schemes[eachSchemeName] = true
+		}
+	}
+}
+
+// importOperation adds the method for the operation
+func (importer *openAPIImporter) importOperation(pathPart string,
+	httpMethod string,
+	pathParams []*OpenAPIParameter,
+	operation *OpenAPIOperation) error {
+
+	operationID := operation.OperationID
+	if operationID == "" {
+		operationID = openAPIOperationID(httpMethod, pathPart)
+	}
+	lambdaAWSInfo, handlerExists := importer.handlers[operationID]
+	if !handlerExists || lambdaAWSInfo == nil {
+		importer.report.UnboundOperations = append(importer.report.UnboundOperations, operationID)
+		return nil
+	}
+	importer.bound[operationID] = true
+
+	// Resources are keyed by function and path
+	resourceKey := fmt.Sprintf("%s%s", lambdaAWSInfo.lambdaFunctionName(), pathPart)
+	resource, resourceExists := importer.resources[resourceKey]
+	if !resourceExists {
+		var resourceErr error
+		resource, resourceErr = importer.api.NewResource(pathPart, lambdaAWSInfo)
+		if resourceErr != nil {
+			return resourceErr
+		}
+		importer.resources[resourceKey] = resource
+	}
+
+	// The default status code is the first 2XX response
+	statusCodes := []int{}
+	for eachStatus := range operation.Responses {
+		statusCode, statusCodeErr := strconv.Atoi(eachStatus)
+		if statusCodeErr == nil {
+			statusCodes = append(statusCodes, statusCode)
+		}
+	}
+	sort.Ints(statusCodes)
+	defaultStatusCode := http.StatusOK
+	for _, eachStatusCode := range statusCodes {
+		if eachStatusCode >= 200 && eachStatusCode < 300 {
+			defaultStatusCode = eachStatusCode
+			break
+		}
+	}
+	method, methodErr := resource.NewMethod(httpMethod, defaultStatusCode, statusCodes...)
+	if methodErr != nil {
+		return methodErr
+	}
+
+	// Operation parameters override path parameters with the same location
+	// and name
+	for _, eachParams := range [][]*OpenAPIParameter{pathParams, operation.Parameters} {
+		for _, eachParam := range eachParams {
+			param := importer.parameter(eachParam)
+			if param == nil {
+				continue
+			}
+			location := param.In
+			switch location {
+			case "query":
+				location = "querystring"
+			case "path", "header":
+			default:
+				continue
+			}
+			method.Parameters[fmt.Sprintf("method.request.%s.%s", location, param.Name)] =
+				param.Required || location == "path"
+		}
+	}
+
+	// Request models
+	if operation.RequestBody != nil {
+		models, modelsErr := importer.models(operation.RequestBody.Content,
+			fmt.Sprintf("%sRequest", operationID))
+		if modelsErr != nil {
+			return modelsErr
+		}
+		method.Models = models
+		for eachContentType := range operation.RequestBody.Content {
+			switch eachContentType {
+			case "application/json",
+				"text/plain",
+				"application/x-www-form-urlencoded",
+				"multipart/form-data":
+				method.SupportedRequestContentTypes = append(method.SupportedRequestContentTypes,
+					eachContentType)
+			}
+		}
+		sort.Strings(method.SupportedRequestContentTypes)
+	}
+
+	// Responses
+	for eachStatus, eachResponse := range operation.Responses {
+		statusCode, statusCodeErr := strconv.Atoi(eachStatus)
+		if statusCodeErr != nil || eachResponse == nil {
+			continue
+		}
+		response := method.Responses[statusCode]
+		for eachHeader := range eachResponse.Headers {
+			response.Parameters[fmt.Sprintf("method.response.header.%s", eachHeader)] = false
+		}
+		models, modelsErr := importer.models(eachResponse.Content,
+			fmt.Sprintf("%s%dResponse", operationID, statusCode))
+		if modelsErr != nil {
+			return modelsErr
+		}
+		for eachContentType, eachModel := range models {
+			response.Models[eachContentType] = eachModel
+		}
+	}
+	importer.security(operation.Security, method)
+	return nil
+}
+
+// NewAPIGatewayFromOpenAPI returns an API whose resources and methods are
+// defined by an OpenAPI 3 JSON or YAML document. Each operation is bound
+// to the handlers entry with the same key as its operationId. Operations
+// without an operationId use the same camelCase ID as API.OpenAPI (eg:
+// getUsersId). Request parameters, request and response schemas and
+// response headers are imported as method Parameters, Models and
+// Responses. The report lists the operations without a handler and the
+// handlers without an operation.
+func NewAPIGatewayFromOpenAPI(name string,
+	stage *Stage,
+	document []byte,
+	handlers map[string]*LambdaAWSInfo) (*API, *OpenAPIImportReport, error) {
+
+	jsonDocument, jsonDocumentErr := yamlwrapper.YAMLToJSON(document)
+	if jsonDocumentErr != nil {
+		return nil, nil, errors.Wrapf(jsonDocumentErr, "Failed to parse OpenAPI document")
+	}
+	var doc openAPIImportDocument
+	unmarshalErr := json.Unmarshal(jsonDocument, &doc)
+	if unmarshalErr != nil {
+		return nil, nil, errors.Wrapf(unmarshalErr, "Failed to unmarshal OpenAPI document")
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, nil, errors.Errorf("Unsupported OpenAPI version: %s", doc.OpenAPI)
+	}
+	api := NewAPIGateway(name, stage)
+	if doc.Info != nil {
+		api.Description = doc.Info.Description
+	}
+	importer := &openAPIImporter{
+		doc:       &doc,
+		api:       api,
+		handlers:  handlers,
+		resources: make(map[string]*Resource),
+		report:    &OpenAPIImportReport{},
+		bound:     make(map[string]bool),
+		schemes:   make(map[string]bool),
+	}
+
+	pathParts := make([]string, 0, len(doc.Paths))
+	for eachPath := range doc.Paths {
+		pathParts = append(pathParts, eachPath)
+	}
+	sort.Strings(pathParts)
+	for _, eachPath := range pathParts {
+		pathItem := doc.Paths[eachPath]
+		var pathParams []*OpenAPIParameter
+		if rawParams, rawParamsExists := pathItem["parameters"]; rawParamsExists {
+			paramsErr := json.Unmarshal(rawParams, &pathParams)
+			if paramsErr != nil {
+				return nil, nil, errors.Wrapf(paramsErr, "Failed to unmarshal %s parameters", eachPath)
+			}
+		}
+		for _, eachMethod := range openAPIImportMethods {
+			rawOperation, rawOperationExists := pathItem[strings.ToLower(eachMethod)]
+			if !rawOperationExists {
+				continue
+			}
+			var operation OpenAPIOperation
+			operationErr := json.Unmarshal(rawOperation, &operation)
+			if operationErr != nil {
+				return nil, nil, errors.Wrapf(operationErr,
+					"Failed to unmarshal %s %s operation",
+					eachMethod,
+					eachPath)
+			}
+			importErr := importer.importOperation(eachPath, eachMethod, pathParams, &operation)
+			if importErr != nil {
+				return nil, nil, errors.Wrapf(importErr,
+					"Failed to import %s %s operation",
+					eachMethod,
+					eachPath)
+			}
+		}
+	}
+	for eachOperationID := range handlers {
+		if !importer.bound[eachOperationID] {
+			importer.report.UnusedHandlers = append(importer.report.UnusedHandlers, eachOperationID)
+		}
+	}
+	for eachSchemeName := range importer.schemes {
+		importer.report.UnsupportedSecuritySchemes = append(importer.report.UnsupportedSecuritySchemes,
+			eachSchemeName)
+	}
+	sort.Strings(importer.report.UnboundOperations)
+	sort.Strings(importer.report.UnusedHandlers)
+	sort.Strings(importer.report.UnsupportedSecuritySchemes)
+	return api, importer.report, nil
+}
@@ -0,0 +1,166 @@
+package sparta
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+const openAPIImportTestDocument = `
+openapi: 3.0.1
+info:
+  title: Users
+  description: User management
+  version: "1.0"
+paths:
+  /users:
+    post:
+      operationId: createUser
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/User'
+      responses:
+        "201":
+          description: Created
+          headers:
+            Location:
+              schema:
+                type: string
+        "400":
+          description: Bad request
+  /users/{id}:
+    parameters:
+      - $ref: '#/components/parameters/UserID'
+    get:
+      operationId: getUser
+      parameters:
+        - name: verbose
+          in: query
+          schema:
+            type: boolean
+      security:
+        - ApiKey: []
+        - OAuth: []
+      responses:
+        "200":
+          description: User
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/User'
+    delete:
+      operationId: deleteUser
+      responses:
+        "204":
+          description: Deleted
+components:
+  parameters:
+    UserID:
+      name: id
+      in: path
+      required: true
+      schema:
+        type: string
+  securitySchemes:
+    ApiKey:
+      type: apiKey
+      name: x-api-key
+      in: header
+    OAuth:
+      type: oauth2
+  schemas:
+    User:
+      type: object
+      properties:
+        id:
+          type: string
+        manager:
+          $ref: '#/components/schemas/User'
+`
+
+func openAPIImportTestHandler(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+func TestNewAPIGatewayFromOpenAPI(t *testing.T) {
+	usersFn, _ := NewAWSLambda("OpenAPIImportUsers", openAPIImportTestHandler, IAMRoleDefinition{})
+	auditFn, _ := NewAWSLambda("OpenAPIImportAudit", openAPIImportTestHandler, IAMRoleDefinition{})
+	api, report, importErr := NewAPIGatewayFromOpenAPI("OpenAPIImportTest",
+		NewStage("v1"),
+		[]byte(openAPIImportTestDocument),
+		map[string]*LambdaAWSInfo{
+			"createUser": usersFn,
+			"getUser":    usersFn,
+			"auditUser":  auditFn,
+		})
+	if importErr != nil {
+		t.Fatal(importErr)
+	}
+
+	// Report
+	if report.IsComplete() || report.Error() == nil {
+		t.Fatalf("Expected incomplete import report: %#v", report)
+	}
+	if !reflect.DeepEqual(report.UnboundOperations, []string{"deleteUser"}) ||
+		!reflect.DeepEqual(report.UnusedHandlers, []string{"auditUser"}) ||
+		!reflect.DeepEqual(report.UnsupportedSecuritySchemes, []string{"OAuth"}) {
+		t.Fatalf("Unexpected import report: %#v", report)
+	}
+	if api.Description != "User management" || len(api.resources) != 2 {
+		t.Fatalf("Unexpected API: %#v", api)
+	}
+
+	// Request models and response headers
+	postMethod := api.resources[usersFn.lambdaFunctionName()+"/users"].Methods[http.MethodPost]
+	if postMethod == nil ||
+		postMethod.defaultHTTPResponseCode != http.StatusCreated ||
+		len(postMethod.Responses) != 2 {
+		t.Fatalf("Unexpected POST method: %#v", postMethod)
+	}
+	if _, headerExists := postMethod.Responses[http.StatusCreated].Parameters["method.response.header.Location"]; !headerExists {
+		t.Fatalf("Expected Location response header: %#v", postMethod.Responses[http.StatusCreated])
+	}
+	requestModel := postMethod.Models["application/json"]
+	if requestModel == nil || requestModel.Name != "User" {
+		t.Fatalf("Unexpected request model: %#v", requestModel)
+	}
+	var requestSchema map[string]interface{}
+	schemaErr := json.Unmarshal([]byte(requestModel.Schema), &requestSchema)
+	if schemaErr != nil {
+		t.Fatal(schemaErr)
+	}
+	properties, _ := requestSchema["properties"].(map[string]interface{})
+	if requestSchema["$schema"] != openAPIModelSchemaDraft ||
+		!reflect.DeepEqual(properties["manager"], map[string]interface{}{}) {
+		t.Fatalf("Unexpected request schema: %s", requestModel.Schema)
+	}
+
+	// Parameters and security
+	getMethod := api.resources[usersFn.lambdaFunctionName()+"/users/{id}"].Methods[http.MethodGet]
+	expectedParams := map[string]bool{
+		"method.request.path.id":             true,
+		"method.request.querystring.verbose": false,
+	}
+	if getMethod == nil ||
+		!getMethod.APIKeyRequired ||
+		!reflect.DeepEqual(getMethod.Parameters, expectedParams) {
+		t.Fatalf("Unexpected GET method: %#v", getMethod)
+	}
+	if getMethod.Responses[http.StatusOK].Models["application/json"] == nil {
+		t.Fatalf("Expected GET response model: %#v", getMethod.Responses[http.StatusOK])
+	}
+}
+
+func TestNewAPIGatewayFromOpenAPIVersion(t *testing.T) {
+	_, _, importErr := NewAPIGatewayFromOpenAPI("OpenAPIImportTest",
+		nil,
+		[]byte(`{"swagger": "2.0", "paths": {}}`),
+		nil)
+	if importErr == nil {
+		t.Fatal("Expected unsupported version error")
+	}
+}
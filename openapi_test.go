@@ -0,0 +1,135 @@
+//go:build !lambdabinary
+// +build !lambdabinary
+
+package sparta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	gof "github.com/awslabs/goformation/v5/cloudformation"
+	spartaAWSEvents "github.com/mweagle/Sparta/v3/aws/events"
+	"github.com/rs/zerolog"
+)
+
+type openAPITestAddress struct {
+	Street string `json:"street"`
+	City   string `json:"city,omitempty"`
+}
+
+type openAPITestUser struct {
+	ID       string              `json:"id"`
+	Name     string              `json:"name"`
+	Tags     []string            `json:"tags,omitempty"`
+	Address  *openAPITestAddress `json:"address"`
+	Created  time.Time           `json:"created"`
+	Manager  *openAPITestUser    `json:"manager,omitempty"`
+	internal string
+}
+
+type openAPITestRequest struct {
+	spartaAWSEvents.APIGatewayEnvelope
+	Body openAPITestUser `json:"body"`
+}
+
+func openAPITestHandler(ctx context.Context, request openAPITestRequest) (*openAPITestUser, error) {
+	return &request.Body, nil
+}
+
+func TestOpenAPI(t *testing.T) {
+	api := NewAPIGateway("OpenAPITest", NewStage("v1"))
+	api.CORSEnabled = true
+	lambdaFn, _ := NewAWSLambda("OpenAPIUsers", openAPITestHandler, IAMRoleDefinition{})
+
+	usersResource, _ := api.NewResource("/users/{id}", lambdaFn)
+	getMethod, _ := usersResource.NewAuthorizedMethod(http.MethodGet,
+		gof.Ref("UserAuthorizer"),
+		http.StatusOK)
+	getMethod.Parameters["method.request.querystring.verbose"] = false
+	getMethod.Parameters["method.request.header.X-Trace"] = true
+	getMethod.APIKeyRequired = true
+	putMethod, _ := usersResource.NewMethod(http.MethodPut, http.StatusCreated)
+	putMethod.Responses[http.StatusConflict].Models["application/json"] = &Model{
+		Name:   "Conflict",
+		Schema: `{"$schema": "http://json-schema.org/draft-04/schema#", "type": "object", "properties": {"message": {"type": "string"}}}`,
+	}
+
+	doc, docErr := api.OpenAPI()
+	if docErr != nil {
+		t.Fatal(docErr)
+	}
+	pathItem := doc.Paths["/users/{id}"]
+	if pathItem == nil || pathItem["get"] == nil || pathItem["put"] == nil || pathItem["options"] == nil {
+		t.Fatalf("Expected GET, PUT and OPTIONS operations: %#v", doc.Paths)
+	}
+
+	// Parameters and security
+	getOperation := pathItem["get"]
+	if getOperation.OperationID != "getUsersId" ||
+		len(getOperation.Parameters) != 3 ||
+		getOperation.RequestBody != nil {
+		t.Fatalf("Unexpected GET operation: %#v", getOperation)
+	}
+	if len(getOperation.Security) != 1 ||
+		getOperation.Security[0]["UserAuthorizer"] == nil ||
+		getOperation.Security[0][openAPIAPIKeySchemeName] == nil {
+		t.Fatalf("Expected authorizer and API key security: %#v", getOperation.Security)
+	}
+	if doc.Components.SecuritySchemes["UserAuthorizer"].AuthType != "custom" {
+		t.Fatalf("Expected custom authorizer security scheme")
+	}
+
+	// Go type and model schemas
+	putOperation := pathItem["put"]
+	if putOperation.RequestBody.Content["application/json"].Schema["$ref"] != "#/components/schemas/openAPITestUser" {
+		t.Fatalf("Expected request body schema reference: %#v", putOperation.RequestBody)
+	}
+	if putOperation.Responses["201"].Content["application/json"] == nil ||
+		putOperation.Responses["409"].Content["application/json"].Schema["$ref"] != "#/components/schemas/Conflict" {
+		t.Fatalf("Unexpected PUT responses: %#v", putOperation.Responses)
+	}
+	if putOperation.Responses["201"].Headers["Access-Control-Allow-Origin"] == nil {
+		t.Fatalf("Expected CORS response headers")
+	}
+	userSchema := doc.Components.Schemas["openAPITestUser"]
+	properties := userSchema["properties"].(map[string]interface{})
+	if len(properties) != 6 {
+		t.Fatalf("Unexpected user schema properties: %#v", properties)
+	}
+	if strings.Join(userSchema["required"].([]string), ",") != "created,id,name" {
+		t.Fatalf("Unexpected required properties: %#v", userSchema["required"])
+	}
+	if _, schemaExists := doc.Components.Schemas["openAPITestAddress"]; !schemaExists {
+		t.Fatalf("Expected nested struct component schema")
+	}
+	if _, draftExists := doc.Components.Schemas["Conflict"]["$schema"]; draftExists {
+		t.Fatalf("Expected JSON Schema draft to be removed")
+	}
+
+	// Integration
+	integration := putOperation.Integration
+	if integration["type"] != "aws" ||
+		!strings.Contains(integration["uri"].(string), "${"+lambdaFn.LogicalResourceName()+".Arn}") {
+		t.Fatalf("Unexpected integration: %#v", integration)
+	}
+	if pathItem["options"].Integration["type"] != "mock" {
+		t.Fatalf("Expected CORS mock integration")
+	}
+
+	// Describe output
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	var output bytes.Buffer
+	describeErr := DescribeOpenAPI(api, &output, &logger)
+	if describeErr != nil {
+		t.Fatal(describeErr)
+	}
+	var decoded map[string]interface{}
+	if json.Unmarshal(output.Bytes(), &decoded) != nil || decoded["openapi"] != OpenAPIVersion {
+		t.Fatalf("Unexpected OpenAPI output: %s", output.String())
+	}
+}
@@ -0,0 +1,135 @@
+package sparta
+
+import (
+	"regexp"
+	"strings"
+)
+
+// OpenAPIVersion is the OpenAPI specification version produced by
+// API.OpenAPI
+const OpenAPIVersion = "3.0.3"
+
+const (
+	// openAPIAPIKeySchemeName is the security scheme for methods
+	// with APIKeyRequired
+	openAPIAPIKeySchemeName = "api_key"
+	// openAPIComponentSchemaPrefix is the prefix of component schema references
+	openAPIComponentSchemaPrefix = "#/components/schemas/"
+)
+
+var (
+	reOpenAPIPathParam   = regexp.MustCompile(`\{([^}]+?)\+?\}`)
+	reOpenAPIOperationID = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// OpenAPISchema is a JSON Schema object in an OpenAPI document
+type OpenAPISchema map[string]interface{}
+
+// OpenAPIInfo is the OpenAPI document metadata
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenAPIServerVariable is a substitution variable in a server URL
+type OpenAPIServerVariable struct {
+	Default     string `json:"default"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIServer is the URL of the deployed API
+type OpenAPIServer struct {
+	URL       string                            `json:"url"`
+	Variables map[string]*OpenAPIServerVariable `json:"variables,omitempty"`
+}
+
+// OpenAPIParameter is a path, query string or header parameter
+type OpenAPIParameter struct {
+	Name     string        `json:"name"`
+	In       string        `json:"in"`
+	Required bool          `json:"required,omitempty"`
+	Schema   OpenAPISchema `json:"schema,omitempty"`
+	// Ref is a reference to a component parameter
+	// (eg: #/components/parameters/PageSize)
+	Ref string `json:"$ref,omitempty"`
+}
+
+// OpenAPIMediaType is the schema for a single content type
+type OpenAPIMediaType struct {
+	Schema OpenAPISchema `json:"schema,omitempty"`
+}
+
+// OpenAPIRequestBody is an operation's request body
+type OpenAPIRequestBody struct {
+	Description string                       `json:"description,omitempty"`
+	Required    bool                         `json:"required,omitempty"`
+	Content     map[string]*OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIHeader is a response header
+type OpenAPIHeader struct {
+	Schema OpenAPISchema `json:"schema,omitempty"`
+}
+
+// OpenAPIResponse is a single operation response
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Headers     map[string]*OpenAPIHeader    `json:"headers,omitempty"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIOperation is a single HTTP method on a path. Integration is the
+// API Gateway integration extension. See
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/api-gateway-swagger-extensions-integration.html
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+	Security    []map[string][]string       `json:"security,omitempty"`
+	Integration map[string]interface{}      `json:"x-amazon-apigateway-integration,omitempty"`
+}
+
+// OpenAPIPathItem is the set of operations for a path, keyed by the
+// lowercase HTTP method
+type OpenAPIPathItem map[string]*OpenAPIOperation
+
+// OpenAPISecurityScheme is an authorizer or API key requirement
+type OpenAPISecurityScheme struct {
+	Type        string                 `json:"type"`
+	Description string                 `json:"description,omitempty"`
+	Name        string                 `json:"name,omitempty"`
+	In          string                 `json:"in,omitempty"`
+	AuthType    string                 `json:"x-amazon-apigateway-authtype,omitempty"`
+	Authorizer  map[string]interface{} `json:"x-amazon-apigateway-authorizer,omitempty"`
+}
+
+// OpenAPIComponents are the shared schemas and security schemes
+type OpenAPIComponents struct {
+	Schemas         map[string]OpenAPISchema          `json:"schemas,omitempty"`
+	Parameters      map[string]*OpenAPIParameter      `json:"parameters,omitempty"`
+	SecuritySchemes map[string]*OpenAPISecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// OpenAPIDocument is an OpenAPI 3.0 (https://spec.openapis.org/oas/v3.0.3)
+// description of an API
+type OpenAPIDocument struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       *OpenAPIInfo               `json:"info"`
+	Servers    []*OpenAPIServer           `json:"servers,omitempty"`
+	Paths      map[string]OpenAPIPathItem `json:"paths"`
+	Components *OpenAPIComponents         `json:"components,omitempty"`
+}
+
+// openAPIOperationID returns a camelCase operation ID (eg: getHelloWorldId)
+func openAPIOperationID(httpMethod string, pathPart string) string {
+	operationID := strings.ToLower(httpMethod)
+	for _, eachWord := range reOpenAPIOperationID.Split(pathPart, -1) {
+		if eachWord != "" {
+			operationID += strings.ToUpper(eachWord[0:1]) + eachWord[1:]
+		}
+	}
+	return operationID
+}
@@ -25,6 +25,37 @@ type S3Site struct {
 	// values will be scoped to a `userdata` key in the MANIFEST.json
 	// object
 	UserManifestData map[string]interface{}
+	// ContentRules are glob-based rules (eg: `assets/**/*.js`) that set the
+	// Cache-Control and Content-Type metadata of matching objects. For each
+	// value, the first matching rule wins.
+	ContentRules []*S3SiteContentRule
+	// PrecompressedEncodings are the content encodings (`gzip`, `br`) of the
+	// `.gz` and `.br` variants published alongside the objects with one of
+	// the resources.PrecompressedExtensions
+	PrecompressedEncodings []string
+	// ImmutableAssetPattern is a regular expression that matches content-hashed
+	// file names (see resources.DefaultImmutableAssetPattern). Matching objects
+	// are published with a long-lived immutable Cache-Control value and are
+	// excluded from the S3SiteInvalidationPaths output.
+	ImmutableAssetPattern string
+	// CloudFrontDistributionARN is the ARN of a CloudFront distribution that
+	// reads the bucket with Origin Access Control. If non-empty, the bucket
+	// blocks public access and isn't configured for website hosting. This is
+	// set by the decorator.CloudFrontSiteDistributionDecorator family.
+	CloudFrontDistributionARN string
+}
+
+// S3SiteContentRule sets the metadata of the S3Site objects that match
+// Pattern. Patterns use path.Match syntax with the addition of `**` to match
+// zero or more directories. Patterns without a `/` match the file's base name.
+type S3SiteContentRule struct {
+	// Pattern is the glob pattern relative to the resources root
+	Pattern string
+	// CacheControl is the optional Cache-Control value
+	CacheControl string
+	// ContentType is the optional Content-Type value. Defaults to the
+	// extension's MIME type.
+	ContentType string
 }
 
 // CloudFormationS3ResourceName returns the stable CloudformationResource name that
@@ -21,6 +21,13 @@ const (
 	// that stores the S3 backed static site provisioned with this Sparta application
 	// @enum OutputKey
 	OutputS3SiteURL = "S3SiteURL"
+
+	// OutputS3SiteInvalidationPaths is the keyname used in the CloudFormation
+	// Output that stores the comma separated paths of the S3 site objects
+	// that changed in the most recent provisioning operation. Content-hashed
+	// immutable objects are excluded.
+	// @enum OutputKey
+	OutputS3SiteInvalidationPaths = "S3SiteInvalidationPaths"
 )
 
 // Create the resource, which will be part of the stack definition and use a CustomResource
@@ -68,28 +75,39 @@ func (s3Site *S3Site) export(serviceName string,
 	// 1 - Create the S3 bucket.  The "BucketName" property is empty s.t.
 	// AWS will assign a unique one.
 
-	s3WebsiteConfig := &gofs3.Bucket_WebsiteConfiguration{
-		ErrorDocument: *s3Site.WebsiteConfiguration.ErrorDocument.Key,
-		IndexDocument: *s3Site.WebsiteConfiguration.IndexDocument.Suffix,
-	}
-	s3Bucket := &gofs3.Bucket{
-		AccessControl:        "PublicRead",
-		WebsiteConfiguration: s3WebsiteConfig,
+	// Sites served by CloudFront with Origin Access Control are private
+	s3Bucket := &gofs3.Bucket{}
+	s3BucketResourceName := s3Site.CloudFormationS3ResourceName()
+	if s3Site.CloudFrontDistributionARN != "" {
+		s3Bucket.PublicAccessBlockConfiguration = &gofs3.Bucket_PublicAccessBlockConfiguration{
+			BlockPublicAcls:       true,
+			BlockPublicPolicy:     true,
+			IgnorePublicAcls:      true,
+			RestrictPublicBuckets: true,
+		}
+		template.Outputs[OutputS3SiteURL] = gof.Output{
+			Description: "S3 bucket regional domain name",
+			Value:       gof.GetAtt(s3BucketResourceName, "RegionalDomainName"),
+		}
+	} else {
+		s3Bucket.AccessControl = "PublicRead"
+		s3Bucket.WebsiteConfiguration = &gofs3.Bucket_WebsiteConfiguration{
+			ErrorDocument: *s3Site.WebsiteConfiguration.ErrorDocument.Key,
+			IndexDocument: *s3Site.WebsiteConfiguration.IndexDocument.Suffix,
+		}
+		template.Outputs[OutputS3SiteURL] = gof.Output{
+			Description: "S3 Website URL",
+			Value:       gof.GetAtt(s3BucketResourceName, "WebsiteURL"),
+		}
 	}
 	s3Bucket.BucketName = s3Site.BucketName
 	s3Bucket.AWSCloudFormationDeletionPolicy = "Delete"
-	s3BucketResourceName := s3Site.CloudFormationS3ResourceName()
 	template.Resources[s3BucketResourceName] = s3Bucket
 
-	template.Outputs[OutputS3SiteURL] = gof.Output{
-		Description: "S3 Website URL",
-		Value:       gof.GetAtt(s3BucketResourceName, "WebsiteURL"),
-	}
-
 	// Represents the S3 ARN that is provisioned
 	s3SiteBucketResourceValue := gof.Join("", []string{
 		"arn:aws:s3:::",
-		s3BucketResourceName,
+		gof.Ref(s3BucketResourceName),
 	})
 	s3SiteBucketAllKeysResourceValue := gof.Join("", []string{
 		"arn:aws:s3:::",
@@ -100,21 +118,37 @@ func (s3Site *S3Site) export(serviceName string,
 	// 2 - Add a bucket policy to enable anonymous access, as the PublicRead
 	// canned ACL doesn't seem to do what is implied.
 	// TODO - determine if this is needed or if PublicRead is being misued
+	bucketPolicyStatement := ArbitraryJSONObject{
+		"Sid":    "PublicReadGetObject",
+		"Effect": "Allow",
+		"Principal": ArbitraryJSONObject{
+			"AWS": "*",
+		},
+		"Action":   "s3:GetObject",
+		"Resource": s3SiteBucketAllKeysResourceValue,
+	}
+	// Or limit access to the CloudFront distribution
+	if s3Site.CloudFrontDistributionARN != "" {
+		bucketPolicyStatement = ArbitraryJSONObject{
+			"Sid":    "CloudFrontReadGetObject",
+			"Effect": "Allow",
+			"Principal": ArbitraryJSONObject{
+				"Service": "cloudfront.amazonaws.com",
+			},
+			"Action":   "s3:GetObject",
+			"Resource": s3SiteBucketAllKeysResourceValue,
+			"Condition": ArbitraryJSONObject{
+				"StringEquals": ArbitraryJSONObject{
+					"AWS:SourceArn": s3Site.CloudFrontDistributionARN,
+				},
+			},
+		}
+	}
 	s3SiteBucketPolicy := &gofs3.BucketPolicy{
 		Bucket: gof.Ref(s3BucketResourceName),
 		PolicyDocument: ArbitraryJSONObject{
-			"Version": "2012-10-17",
-			"Statement": []ArbitraryJSONObject{
-				{
-					"Sid":    "PublicReadGetObject",
-					"Effect": "Allow",
-					"Principal": ArbitraryJSONObject{
-						"AWS": "*",
-					},
-					"Action":   "s3:GetObject",
-					"Resource": s3SiteBucketAllKeysResourceValue,
-				},
-			},
+			"Version":   "2012-10-17",
+			"Statement": []ArbitraryJSONObject{bucketPolicyStatement},
 		},
 	}
 	s3BucketPolicyResourceName := stableCloudformationResourceName("S3SiteBucketPolicy")
@@ -133,6 +167,7 @@ func (s3Site *S3Site) export(serviceName string,
 	})
 	statements = append(statements, spartaIAM.PolicyStatement{
 		Action: []string{"s3:DeleteObject",
+			"s3:GetObject",
 			"s3:PutObject",
 			"s3:DeleteObjects"},
 		Effect:   "Allow",
@@ -231,9 +266,24 @@ func (s3Site *S3Site) export(serviceName string,
 		CustomResourceRequest: cfCustomResources.CustomResourceRequest{
 			ServiceToken: gof.GetAtt(lambdaResourceName, "Arn"),
 		},
-		SrcKeyName: s3ResourcesKey,
-		SrcBucket:  s3ArtifactBucket,
-		DestBucket: gof.Ref(s3BucketResourceName),
+		SrcKeyName:             s3ResourcesKey,
+		SrcBucket:              s3ArtifactBucket,
+		DestBucket:             gof.Ref(s3BucketResourceName),
+		IndexDocument:          *s3Site.WebsiteConfiguration.IndexDocument.Suffix,
+		PrecompressedEncodings: s3Site.PrecompressedEncodings,
+		ImmutableAssetPattern:  s3Site.ImmutableAssetPattern,
+	}
+	for _, eachRule := range s3Site.ContentRules {
+		zipRequest.ContentRules = append(zipRequest.ContentRules,
+			&cfCustomResources.ZipToS3BucketContentRule{
+				Pattern:      eachRule.Pattern,
+				CacheControl: eachRule.CacheControl,
+				ContentType:  eachRule.ContentType,
+			})
+	}
+	validateErr := zipRequest.Validate()
+	if validateErr != nil {
+		return errors.Wrapf(validateErr, "Invalid S3Site content configuration")
 	}
 
 	// Build the manifest data with any output info...
@@ -255,6 +305,11 @@ func (s3Site *S3Site) export(serviceName string,
 	}
 	zipResource.Properties = cfCustomResources.ToCustomResourceProperties(zipRequest)
 	template.Resources[customResourceName] = zipResource
+
+	template.Outputs[OutputS3SiteInvalidationPaths] = gof.Output{
+		Description: "S3 site paths to invalidate",
+		Value:       gof.GetAtt(customResourceName, "InvalidationPaths"),
+	}
 	return nil
 }
 
@@ -0,0 +1,127 @@
+package sparta
+
+import (
+	"fmt"
+	"time"
+)
+
+// SLOOutputPrefix is the prefix of the stack Output keys that store the
+// service level objectives reported by the status command
+const SLOOutputPrefix = "SpartaSLO"
+
+// SLO metric query IDs
+const (
+	// SLOQueryBad is the query ID of the bad event count for availability
+	// objectives
+	SLOQueryBad = "bad"
+	// SLOQueryGoodPercent is the query ID of the percentage of good events
+	// for latency objectives
+	SLOQueryGoodPercent = "good"
+	// SLOQueryTotal is the query ID of the total event count
+	SLOQueryTotal = "total"
+	// SLOQueryRatio is the query ID of the bad event ratio expression
+	SLOQueryRatio = "ratio"
+)
+
+// SLOMetricQuery is a single metric used to compute a service level
+// indicator
+type SLOMetricQuery struct {
+	ID         string
+	MetricName string
+	Stat       string
+}
+
+// SLODefinition is a service level objective. It is serialized to a stack
+// Output so that the status command can report the remaining error budget.
+// Availability objectives compare the ErrorMetric and TotalMetric counts.
+// Latency objectives measure the percentage of LatencyMetric values less
+// than or equal to LatencyThresholdMs.
+type SLODefinition struct {
+	Name string `json:"name"`
+	// Objective is the target percentage of good events (eg: 99.9)
+	Objective float64 `json:"objective"`
+	// WindowSeconds is the compliance window
+	WindowSeconds int64 `json:"windowSeconds"`
+	// Namespace and Dimensions identify the metrics. Dimension values
+	// may be Fn::Sub expressions in the template.
+	Namespace  string            `json:"namespace"`
+	Dimensions map[string]string `json:"dimensions"`
+	// Availability objectives
+	ErrorMetric string `json:"errorMetric,omitempty"`
+	TotalMetric string `json:"totalMetric,omitempty"`
+	// Latency objectives
+	LatencyMetric      string  `json:"latencyMetric,omitempty"`
+	LatencyThresholdMs float64 `json:"latencyThresholdMs,omitempty"`
+}
+
+// Window returns the compliance window
+func (def *SLODefinition) Window() time.Duration {
+	return time.Duration(def.WindowSeconds) * time.Second
+}
+
+// ErrorBudget returns the allowed ratio of bad events (eg: 0.001 for
+// a 99.9% objective)
+func (def *SLODefinition) ErrorBudget() float64 {
+	return 1 - (def.Objective / 100)
+}
+
+// IsLatency returns true if this is a latency objective
+func (def *SLODefinition) IsLatency() bool {
+	return def.LatencyMetric != ""
+}
+
+// MetricQueries returns the metric queries and the metric math expression
+// that computes the ratio of bad events
+func (def *SLODefinition) MetricQueries() ([]SLOMetricQuery, string) {
+	if def.IsLatency() {
+		return []SLOMetricQuery{
+			{
+				ID:         SLOQueryGoodPercent,
+				MetricName: def.LatencyMetric,
+				Stat:       fmt.Sprintf("PR(:%g)", def.LatencyThresholdMs),
+			},
+			{
+				ID:         SLOQueryTotal,
+				MetricName: def.LatencyMetric,
+				Stat:       "SampleCount",
+			},
+		}, fmt.Sprintf("(100 - %s) / 100", SLOQueryGoodPercent)
+	}
+	return []SLOMetricQuery{
+		{
+			ID:         SLOQueryBad,
+			MetricName: def.ErrorMetric,
+			Stat:       "Sum",
+		},
+		{
+			ID:         SLOQueryTotal,
+			MetricName: def.TotalMetric,
+			Stat:       "Sum",
+		},
+	}, fmt.Sprintf("%s / %s", SLOQueryBad, SLOQueryTotal)
+}
+
+// BadEventRatio returns the ratio of bad events given the values of each
+// metric query, keyed by query ID and then by period timestamp. The boolean
+// is false if there were no events.
+func (def *SLODefinition) BadEventRatio(queryValues map[string]map[time.Time]float64) (float64, bool) {
+	var bad, total float64
+	for eachTimestamp, eachTotal := range queryValues[SLOQueryTotal] {
+		total += eachTotal
+		if def.IsLatency() {
+			goodPercent, goodPercentExists := queryValues[SLOQueryGoodPercent][eachTimestamp]
+			if goodPercentExists {
+				bad += eachTotal * (100 - goodPercent) / 100
+			}
+		}
+	}
+	if !def.IsLatency() {
+		for _, eachBad := range queryValues[SLOQueryBad] {
+			bad += eachBad
+		}
+	}
+	if total <= 0 {
+		return 0, false
+	}
+	return bad / total, true
+}
@@ -942,6 +942,41 @@ func (info *LambdaAWSInfo) RequireCustomResource(roleNameOrIAMRoleDefinition int
 	return resourceInfo.logicalName(), nil
 }
 
+// takesContext returns true if the handler's first argument is a
+// context.Context
+func takesContext(handler reflect.Type) bool {
+	handlerTakesContext := false
+	if handler.NumIn() > 0 {
+		contextType := reflect.TypeOf((*context.Context)(nil)).Elem()
+		argumentType := handler.In(0)
+		handlerTakesContext = argumentType.Implements(contextType)
+	}
+	return handlerTakesContext
+}
+
+// HandlerTypes returns the Go types of the handler's event argument
+// and response value. Either value is nil if the handler doesn't
+// accept an event or doesn't return a value.
+func (info *LambdaAWSInfo) HandlerTypes() (eventType reflect.Type, responseType reflect.Type) {
+	if info.handlerSymbol == nil {
+		return nil, nil
+	}
+	handlerType := reflect.TypeOf(info.handlerSymbol)
+	if handlerType.Kind() != reflect.Func {
+		return nil, nil
+	}
+	// Same argument rules as the tappedHandler dispatcher
+	takesContext := takesContext(handlerType)
+	if (handlerType.NumIn() == 1 && !takesContext) ||
+		handlerType.NumIn() == 2 {
+		eventType = handlerType.In(handlerType.NumIn() - 1)
+	}
+	if handlerType.NumOut() > 1 {
+		responseType = handlerType.Out(0)
+	}
+	return eventType, responseType
+}
+
 // LogicalResourceName returns the stable, content-addressable logical
 // name for this LambdaAWSInfo value. This is the CloudFormation
 // resource name
@@ -122,4 +122,8 @@ const (
 	ContextKeyBuildID
 	// ContextKeyBuildBinaryName is the name of the binary we're building
 	ContextKeyBuildBinaryName
+	// ContextKeyBuildTemplate is the *gof.Template being assembled. Hooks
+	// may inspect it to discover existing resources, but must treat it
+	// as read-only
+	ContextKeyBuildTemplate
 )
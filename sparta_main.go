@@ -97,6 +97,8 @@ var CommandLineOptions = struct {
 	Execute   *cobra.Command
 	Describe  *cobra.Command
 	Explore   *cobra.Command
+	Invoke    *cobra.Command
+	Logs      *cobra.Command
 	Profile   *cobra.Command
 	Status    *cobra.Command
 }{}
@@ -210,7 +212,9 @@ var optionsProvision optionsProvisionStruct
 // Describe options
 type optionsDescribeStruct struct {
 	OutputFile string `validate:"required"`
-	S3Bucket   string `validate:"required"`
+	S3Bucket   string `validate:"-"`
+	Format     string `validate:"eq=html|eq=mermaid|eq=dot|eq=json"`
+	OpenAPI    bool   `validate:"-"`
 }
 
 var optionsDescribe optionsDescribeStruct
@@ -223,6 +227,30 @@ type optionsExploreStruct struct {
 
 var optionsExplore optionsExploreStruct
 
+/*============================================================================*/
+// Invoke options
+type optionsInvokeStruct struct {
+	FunctionName string        `validate:"required"`
+	EventFile    string        `validate:"-"`
+	Tail         bool          `validate:"-"`
+	Async        bool          `validate:"-"`
+	Timeout      time.Duration `validate:"-"`
+}
+
+var optionsInvoke optionsInvokeStruct
+
+/*============================================================================*/
+// Logs options
+type optionsLogsStruct struct {
+	MinLevel  string        `validate:"omitempty,eq=trace|eq=debug|eq=info|eq=warn|eq=error|eq=fatal|eq=panic"`
+	RequestID string        `validate:"-"`
+	Match     string        `validate:"-"`
+	Since     time.Duration `validate:"min=0"`
+	Insights  string        `validate:"-"`
+}
+
+var optionsLogs optionsLogsStruct
+
 /*============================================================================*/
 // Profile options
 type optionsProfileStruct struct {
@@ -234,8 +262,16 @@ var optionsProfile optionsProfileStruct
 
 /*============================================================================*/
 // Status options
+const (
+	// StatusFormatText is the human readable status report
+	StatusFormatText = "text"
+	// StatusFormatJSON is the machine readable status report
+	StatusFormatJSON = "json"
+)
+
 type optionsStatusStruct struct {
-	Redact bool `validate:"-"`
+	Redact bool   `validate:"-"`
+	Format string `validate:"eq=text|eq=json"`
 }
 
 var optionsStatus optionsStatusStruct
@@ -389,19 +425,29 @@ func init() {
 	CommandLineOptions.Describe = &cobra.Command{
 		Use:          "describe",
 		Short:        "Describe service",
-		Long:         `Produce an HTML report of the service`,
+		Long:         `Produce an HTML report, Mermaid flowchart, Graphviz DOT digraph or JSON graph of the service, or the OpenAPI document of its REST API`,
 		SilenceUsage: true,
 	}
 	CommandLineOptions.Describe.Flags().StringVarP(&optionsDescribe.OutputFile,
 		"out",
 		"o",
 		"",
-		"Output file for HTML description")
+		"Output file for the description")
 	CommandLineOptions.Describe.Flags().StringVarP(&optionsDescribe.S3Bucket,
 		"s3Bucket",
 		"s",
 		"",
 		"S3 Bucket to use for Lambda source")
+	CommandLineOptions.Describe.Flags().StringVarP(&optionsDescribe.Format,
+		"format",
+		"f",
+		DescribeFormatHTML,
+		"Output format (html, mermaid, dot, json)")
+	CommandLineOptions.Describe.Flags().BoolVarP(&optionsDescribe.OpenAPI,
+		"openapi",
+		"",
+		false,
+		"Write the OpenAPI 3.0 document for the REST API instead of the service graph")
 
 	// Explore
 	CommandLineOptions.Explore = &cobra.Command{
@@ -416,6 +462,70 @@ func init() {
 		[]string{"json"},
 		"One or more file extensions to include as sample inputs")
 
+	// Invoke
+	CommandLineOptions.Invoke = &cobra.Command{
+		Use:   "invoke",
+		Short: "Invoke a provisioned function",
+		Long: `Invoke a provisioned function with an optional event file and print the
+response payload. Use "-" as the event file to read the event from stdin.`,
+		SilenceUsage: true,
+	}
+	CommandLineOptions.Invoke.Flags().StringVar(&optionsInvoke.FunctionName,
+		"function",
+		"",
+		"Function name, logical resource name, physical name or ARN to invoke")
+	CommandLineOptions.Invoke.Flags().StringVarP(&optionsInvoke.EventFile,
+		"event",
+		"e",
+		"",
+		"JSON event file to use as the invocation payload")
+	CommandLineOptions.Invoke.Flags().BoolVar(&optionsInvoke.Tail,
+		"tail",
+		false,
+		"Follow the function's CloudWatch Logs until the invocation completes")
+	CommandLineOptions.Invoke.Flags().BoolVarP(&optionsInvoke.Async,
+		"async",
+		"a",
+		false,
+		"Invoke the function asynchronously")
+	CommandLineOptions.Invoke.Flags().DurationVar(&optionsInvoke.Timeout,
+		"timeout",
+		15*time.Minute,
+		"Maximum duration to follow the CloudWatch Logs")
+
+	// Logs
+	CommandLineOptions.Logs = &cobra.Command{
+		Use:   "logs",
+		Short: "Tail the logs of every function in a provisioned service",
+		Long: `Tail the CloudWatch Logs of every function in a provisioned service or run
+a CloudWatch Logs Insights query across all of the service's log groups`,
+		SilenceUsage: true,
+	}
+	CommandLineOptions.Logs.Flags().StringVar(&optionsLogs.MinLevel,
+		"minLevel",
+		"",
+		"Only include zerolog messages at or above this level [trace, debug, info, warn, error, fatal, panic]")
+	CommandLineOptions.Logs.Flags().StringVarP(&optionsLogs.RequestID,
+		"requestID",
+		"r",
+		"",
+		"Only include messages for this request ID")
+	CommandLineOptions.Logs.Flags().StringVarP(&optionsLogs.Match,
+		"match",
+		"m",
+		"",
+		"Only include messages that match this regular expression")
+	CommandLineOptions.Logs.Flags().DurationVarP(&optionsLogs.Since,
+		"since",
+		"s",
+		0,
+		"Include the messages logged over this duration (eg: 30m)")
+	CommandLineOptions.Logs.Flags().StringVarP(&optionsLogs.Insights,
+		"insights",
+		"i",
+		"",
+		"CloudWatch Logs Insights query to run over the --since window (default 1h)")
+
 	// Profile
 	CommandLineOptions.Profile = &cobra.Command{
 		Use:          "profile",
@@ -445,6 +555,11 @@ func init() {
 		"r",
 		false,
 		"Redact AWS Account ID from report")
+	CommandLineOptions.Status.Flags().StringVarP(&optionsStatus.Format,
+		"format",
+		"f",
+		StatusFormatText,
+		"Output format (text, json). The exit code is non-zero if the service is unhealthy")
 }
 
 // CommandLineOptionsHook allows embedding applications the ability
@@ -524,6 +639,8 @@ func ParseOptions(handler CommandLineOptionsHook) error {
 		CommandLineOptions.Execute,
 		CommandLineOptions.Describe,
 		CommandLineOptions.Explore,
+		CommandLineOptions.Invoke,
+		CommandLineOptions.Logs,
 		CommandLineOptions.Profile,
 		CommandLineOptions.Status,
 	}
@@ -167,6 +167,31 @@ func Describe(serviceName string,
 	return errors.New("Describe not supported for this binary")
 }
 
+// DescribeWithFormat is not available in the AWS Lambda binary
+func DescribeWithFormat(serviceName string,
+	serviceDescription string,
+	lambdaAWSInfos []*LambdaAWSInfo,
+	api APIGateway,
+	site *S3Site,
+	s3BucketName string,
+	buildTags string,
+	linkerFlags string,
+	outputFormat string,
+	outputWriter io.Writer,
+	workflowHooks *WorkflowHooks,
+	logger *zerolog.Logger) error {
+	logger.Error().Msg("DescribeWithFormat() not supported in AWS Lambda binary")
+	return errors.New("DescribeWithFormat not supported for this binary")
+}
+
+// DescribeOpenAPI is not available in the AWS Lambda binary
+func DescribeOpenAPI(api APIGateway,
+	outputWriter io.Writer,
+	logger *zerolog.Logger) error {
+	logger.Error().Msg("DescribeOpenAPI() not supported in AWS Lambda binary")
+	return errors.New("DescribeOpenAPI not supported for this binary")
+}
+
 // Explore is an interactive command that brings up a GUI to test
 // lambda functions previously deployed into AWS lambda. It's not supported in the
 // AWS binary build
@@ -182,6 +207,36 @@ func Explore(serviceName string,
 	return errors.New("Explore not supported for this binary")
 }
 
+// Invoke calls a provisioned function. It's not supported in the AWS
+// binary build
+func Invoke(ctx context.Context,
+	serviceName string,
+	lambdaAWSInfos []*LambdaAWSInfo,
+	functionName string,
+	eventPayload []byte,
+	tail bool,
+	async bool,
+	timeout time.Duration,
+	outputWriter io.Writer,
+	logger *zerolog.Logger) error {
+	return errors.New("Invoke not supported for this binary")
+}
+
+// Logs tails the logs of every function in the service. It's not
+// supported in the AWS binary build
+func Logs(ctx context.Context,
+	serviceName string,
+	lambdaAWSInfos []*LambdaAWSInfo,
+	minLevel string,
+	requestID string,
+	pattern string,
+	since time.Duration,
+	insightsQuery string,
+	outputWriter io.Writer,
+	logger *zerolog.Logger) error {
+	return errors.New("Logs not supported for this binary")
+}
+
 // Profile is the interactive command used to pull S3 assets locally into /tmp
 // and run ppro against the cached profiles
 func Profile(serviceName string,
@@ -202,6 +257,19 @@ func Status(ctx context.Context,
 	return errors.New("Status not supported for this binary")
 }
 
+// StatusWithFormat is the command that produces a formatted status report
+// for a given stack
+func StatusWithFormat(ctx context.Context,
+	serviceName string,
+	serviceDescription string,
+	lambdaAWSInfos []*LambdaAWSInfo,
+	redact bool,
+	outputFormat string,
+	outputWriter io.Writer,
+	logger *zerolog.Logger) error {
+	return errors.New("StatusWithFormat not supported for this binary")
+}
+
 func platformLogSysInfo(lambdaFunc string, logger *zerolog.Logger) {
 
 	// Setup the files and their respective log levels
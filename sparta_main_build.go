@@ -6,8 +6,10 @@ package sparta
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"time"
 
 	"github.com/pkg/errors"
@@ -20,6 +22,21 @@ func platformLogSysInfo(lambdaFunc string, logger *zerolog.Logger) {
 	// NOP
 }
 
+// commandLoggerOutput returns the file that receives the command's log
+// output. Commands that write machine readable output to stdout log to
+// stderr so that the output can be parsed.
+func commandLoggerOutput(cmd *cobra.Command) *os.File {
+	if cmd == CommandLineOptions.Status &&
+		optionsStatus.Format == StatusFormatJSON {
+		return os.Stderr
+	}
+	// The invocation payload and tailed logs are written to stdout
+	if cmd == CommandLineOptions.Invoke {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
 // RegisterCodePipelineEnvironment is part of a CodePipeline deployment
 // and defines the environments available for deployment. Environments
 // are defined the `environmentName`. The values defined in the
@@ -85,9 +102,10 @@ func MainEx(serviceName string,
 		disableColors := OptionsGlobal.DisableColors ||
 			isRunningInAWS() ||
 			OptionsGlobal.LogFormat == "json"
-		logger, loggerErr := NewLoggerForOutput(OptionsGlobal.LogLevel,
+		logger, loggerErr := newLoggerForFile(OptionsGlobal.LogLevel,
 			OptionsGlobal.LogFormat,
-			disableColors)
+			disableColors,
+			commandLoggerOutput(cmd))
 		if nil != loggerErr {
 			return loggerErr
 		}
@@ -312,7 +330,14 @@ func MainEx(serviceName string,
 				}
 			}()
 
-			describeErr := Describe(serviceName,
+			if optionsDescribe.OpenAPI {
+				describeErr := DescribeOpenAPI(api, fileWriter, OptionsGlobal.Logger)
+				if describeErr == nil {
+					describeErr = fileWriter.Sync()
+				}
+				return describeErr
+			}
+			describeErr := DescribeWithFormat(serviceName,
 				serviceDescription,
 				lambdaAWSInfos,
 				api,
@@ -320,6 +345,7 @@ func MainEx(serviceName string,
 				optionsDescribe.S3Bucket,
 				OptionsGlobal.BuildTags,
 				OptionsGlobal.LinkerFlags,
+				optionsDescribe.Format,
 				fileWriter,
 				workflowHooks,
 				OptionsGlobal.Logger)
@@ -356,6 +382,66 @@ func MainEx(serviceName string,
 	}
 	CommandLineOptions.Root.AddCommand(CommandLineOptions.Explore)
 
+	//////////////////////////////////////////////////////////////////////////////
+	// Invoke
+	if nil == CommandLineOptions.Invoke.RunE {
+		CommandLineOptions.Invoke.RunE = func(cmd *cobra.Command, args []string) error {
+			validateErr := validate.Struct(optionsInvoke)
+			if nil != validateErr {
+				return validateErr
+			}
+			var eventPayload []byte
+			var eventPayloadErr error
+			switch optionsInvoke.EventFile {
+			case "":
+				eventPayload = []byte("{}")
+			case "-":
+				eventPayload, eventPayloadErr = ioutil.ReadAll(os.Stdin)
+			default:
+				/* #nosec */
+				eventPayload, eventPayloadErr = ioutil.ReadFile(optionsInvoke.EventFile)
+			}
+			if eventPayloadErr != nil {
+				return errors.Wrapf(eventPayloadErr, "Failed to read event file")
+			}
+			return Invoke(context.Background(),
+				serviceName,
+				lambdaAWSInfos,
+				optionsInvoke.FunctionName,
+				eventPayload,
+				optionsInvoke.Tail,
+				optionsInvoke.Async,
+				optionsInvoke.Timeout,
+				os.Stdout,
+				OptionsGlobal.Logger)
+		}
+	}
+	CommandLineOptions.Root.AddCommand(CommandLineOptions.Invoke)
+
+	//////////////////////////////////////////////////////////////////////////////
+	// Logs
+	if nil == CommandLineOptions.Logs.RunE {
+		CommandLineOptions.Logs.RunE = func(cmd *cobra.Command, args []string) error {
+			validateErr := validate.Struct(optionsLogs)
+			if nil != validateErr {
+				return validateErr
+			}
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer cancel()
+			return Logs(ctx,
+				serviceName,
+				lambdaAWSInfos,
+				optionsLogs.MinLevel,
+				optionsLogs.RequestID,
+				optionsLogs.Match,
+				optionsLogs.Since,
+				optionsLogs.Insights,
+				os.Stdout,
+				OptionsGlobal.Logger)
+		}
+	}
+	CommandLineOptions.Root.AddCommand(CommandLineOptions.Logs)
+
 	//////////////////////////////////////////////////////////////////////////////
 	// Profile
 	if nil == CommandLineOptions.Profile.RunE {
@@ -382,10 +468,13 @@ func MainEx(serviceName string,
 			if nil != validateErr {
 				return validateErr
 			}
-			return Status(context.Background(),
+			return StatusWithFormat(context.Background(),
 				serviceName,
 				serviceDescription,
+				lambdaAWSInfos,
 				optionsStatus.Redact,
+				optionsStatus.Format,
+				os.Stdout,
 				OptionsGlobal.Logger)
 		}
 	}